@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+// Exit codes of the config commands besides 0 for success and 1 for other errors
+const (
+	exitConfigMissing = 2 // The config file does not exist
+	exitConfigInvalid = 3 // The config file can't be parsed or its settings are invalid
+)
+
+// exitError is an error terminating the program with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+type ConfigCmd struct {
+	Init     ConfigInitCmd     `cmd:"" help:"Write a commented config file"`
+	Path     ConfigPathCmd     `cmd:"" help:"Print the path of the config file"`
+	Show     ConfigShowCmd     `cmd:"" help:"Print the loaded settings with expanded paths"`
+	Validate ConfigValidateCmd `cmd:"" help:"Check the config file and the directories of its sets, reports all problems"`
+	Migrate  ConfigMigrateCmd  `cmd:"" help:"Rewrite an older config file in the current format, keeping a backup"`
+}
+
+type ConfigInitCmd struct {
+	Force bool `name:"force" help:"Replace an existing config file"`
+}
+
+type ConfigPathCmd struct {
+}
+
+type ConfigShowCmd struct {
+}
+
+type ConfigValidateCmd struct {
+}
+
+type ConfigMigrateCmd struct {
+}
+
+func (c *ConfigInitCmd) Run() error {
+	configFile := configFilePath()
+	if err := settings.WriteTemplate(configFile, c.Force); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w, use --force to replace it", err)
+		}
+		return err
+	}
+	fmt.Println("Created config file", configFile)
+	return nil
+}
+
+func (c *ConfigPathCmd) Run() error {
+	fmt.Println(configFilePath())
+	return nil
+}
+
+func (c *ConfigShowCmd) Run() error {
+	s, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	content, err := s.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
+
+func (c *ConfigValidateCmd) Run() error {
+	s, configFile, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	problems := s.Problems(true)
+	if len(problems) == 0 {
+		fmt.Printf("Config file '%s' is valid\n", configFile)
+		return nil
+	}
+	fmt.Printf("Config file '%s' has %d problems:\n", configFile, len(problems))
+	for _, problem := range problems {
+		fmt.Println(" ", problem)
+	}
+	return exitError{exitConfigInvalid, fmt.Errorf("config file '%s' is invalid", configFile)}
+}
+
+func (c *ConfigMigrateCmd) Run() error {
+	configFile := configFilePath()
+	content, err := os.ReadFile(configFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return exitError{exitConfigMissing, fmt.Errorf("config file '%s' does not exist", configFile)}
+	}
+	if err != nil {
+		return err
+	}
+	options := []settings.LoadOption{settings.WithFileFormat(settings.FileFormatOf(configFile))}
+	if CLI.NoStrict {
+		options = append(options, settings.NonStrict())
+	}
+	version, err := settings.FileVersion(content, options...)
+	if err != nil {
+		return exitError{exitConfigInvalid, fmt.Errorf("cannot load config file '%s': %w", configFile, err)}
+	}
+	if version == settings.CurrentVersion {
+		fmt.Printf("Config file '%s' has the current version %d\n", configFile, version)
+		return nil
+	}
+	s, warnings, err := settings.Migrate(content, options...)
+	if err != nil {
+		return exitError{exitConfigInvalid, fmt.Errorf("cannot migrate config file '%s': %w", configFile, err)}
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", configFile, version)
+	if err := os.WriteFile(backup, content, 0o600); err != nil {
+		return fmt.Errorf("cannot write backup: %w", err)
+	}
+	if err := s.SaveToFile(configFile); err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Println("Warning:", warning)
+	}
+	fmt.Printf("Migrated config file '%s' from version %d to %d, the old file is kept as '%s'\n",
+		configFile, version, settings.CurrentVersion, backup)
+	return nil
+}
+
+// loadConfig loads the settings like loadSettings. A missing file or invalid content is
+// returned as exitError.
+func loadConfig() (settings.Settings, string, error) {
+	s, configFile, err := loadSettings()
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, configFile, exitError{exitConfigMissing,
+			fmt.Errorf("config file '%s' does not exist, create it with 'config init'", configFile)}
+	}
+	if err != nil {
+		return s, configFile, exitError{exitConfigInvalid, err}
+	}
+	return s, configFile, nil
+}
+
+// configFilePath returns the config file given by --config or GO_HOMEBANK_CSV_CONFIG,
+// otherwise the default config file
+func configFilePath() string {
+	if CLI.ConfigFile != "" {
+		return CLI.ConfigFile
+	}
+	return settings.DefaultFilePath()
+}
+
+// loadSettings loads the settings from the config file and normalizes their paths. The
+// relative paths are resolved against the directory of the config file. Unknown keys are
+// an error unless --no-strict is given.
+func loadSettings() (settings.Settings, string, error) {
+	var s settings.Settings
+	configFile := configFilePath()
+	var options []settings.LoadOption
+	if CLI.NoStrict {
+		options = append(options, settings.NonStrict())
+	}
+	if err := s.LoadFromFile(configFile, options...); err != nil {
+		return s, configFile, fmt.Errorf("cannot load config file '%s': %w", configFile, err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		return s, configFile, err
+	}
+	return s, configFile, nil
+}
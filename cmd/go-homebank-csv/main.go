@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -11,25 +20,139 @@ import (
 	"github.com/sercxanto/go-homebank-csv/pkg/parser"
 )
 
+// DateRangeFlags restrict the converted records to a range of dates
+type DateRangeFlags struct {
+	From  string `name:"from" placeholder:"YYYY-MM-DD" help:"Only convert records on or after this date"`
+	To    string `name:"to" placeholder:"YYYY-MM-DD" help:"Only convert records on or before this date"`
+	Month string `name:"month" placeholder:"YYYY-MM" help:"Only convert records of this month, shortcut for --from/--to"`
+	Year  string `name:"year" placeholder:"YYYY" help:"Only convert records of this year, shortcut for --from/--to"`
+}
+
 type ConvertCmd struct {
-	Format  *parser.SourceFormat `name:"format" help:"Format of input file, if not given it will be guessed. For a list of supported formats see the command 'list-formats'"`
-	Infile  string               `arg:"" name:"infile" type:"existingfile" help:"Input file" type:"path"`
-	Outfile string               `arg:"" name:"outfile" type:"path" help:"CSV file ready to import into homebank" type:"path"`
+	Format                  *parser.SourceFormat `name:"format" help:"Format of input file, if not given it will be guessed. For a list of supported formats see the command 'list-formats'"`
+	Infile                  string               `arg:"" name:"infile" type:"existingfile" help:"Input file" type:"path"`
+	Outfile                 string               `arg:"" name:"outfile" type:"path" help:"CSV file ready to import into homebank" type:"path"`
+	CategoryPrefix          string               `name:"category-prefix" help:"Prefix prepended to the categories of the converted entries, separated by ':'"`
+	CategoryPrefixAlways    bool                 `name:"category-prefix-always" help:"Set the category prefix also as category for entries without category"`
+	RouteInfoToMemo         bool                 `name:"route-info-to-memo" help:"Move the content of the info field into the memo field"`
+	RouteMemoToInfo         bool                 `name:"route-memo-to-info" help:"Move the content of the memo field into the info field"`
+	NoUnicodeNormalization  bool                 `name:"no-unicode-normalization" help:"Do not normalize text fields to Unicode NFC"`
+	SkipSecurityTrades      bool                 `name:"skip-security-trades" help:"Skip buying and selling of securities (TradeRepublic only)"`
+	OwnAccounts             []string             `name:"own-account" placeholder:"IBAN" help:"IBAN of an own account, transfers to it get the payment type 'bank transfer'. Can be repeated (Bunq only)"`
+	GnuCashAccount          string               `name:"gnucash-account" placeholder:"ACCOUNT" help:"Full account name of the imported bank account, e.g. 'Assets:Current Assets:Checking Account' (GnuCash only)"`
+	OutbankAccount          string               `name:"outbank-account" placeholder:"ACCOUNT" help:"Convert only entries of this account, by default all are converted (Outbank only)"`
+	PaymentTypes            parser.PaymentTypes  `name:"payment-type" placeholder:"TYPE=CODE" help:"Homebank payment code of a transaction type, e.g. 'Lastschrift=11', overrides the defaults shown by 'list-formats'. Can be repeated (Comdirect, DKB, Volksbank only)"`
+	Sort                    parser.SortOrder     `name:"sort" default:"none" placeholder:"ORDER" help:"Order of the converted entries: none (order of the input file), date-asc or date-desc"`
+	Lenient                 bool                 `name:"lenient" help:"Skip entries which can't be parsed, e.g. because of an invalid date or amount, and print their errors"`
+	Encoding                parser.Encoding      `name:"encoding" default:"auto" placeholder:"ENCODING" help:"Encoding of the input file: auto (detect), utf-8, utf-16le, utf-16be, iso-8859-1 or windows-1252"`
+	ComdirectValutaFallback bool                 `name:"comdirect-valuta-fallback" help:"Take the date of entries with an empty 'Buchungstag' from 'Wertstellung (Valuta)' instead of failing (Comdirect only)"`
+	Rules                   string               `name:"rules" type:"existingfile" placeholder:"FILE" help:"YAML file with rules mapping the converted entries to categories and payees"`
+	OutDelimiter            string               `name:"out-delimiter" placeholder:"CHAR" help:"Field delimiter of the output file, a single character or 'tab', ';' if not given"`
+	OutDecimal              string               `name:"out-decimal" placeholder:"CHAR" help:"Decimal separator of the amounts in the output file, '.' or ',', '.' if not given"`
+	NoHeader                bool                 `name:"no-header" help:"Don't write the header line into the output file"`
+	OutDateFormat           string               `name:"out-date-format" placeholder:"LAYOUT" help:"Format of the dates in the output file as Go time layout, e.g. '02.01.2006' for day.month.year, '2006-01-02' if not given"`
+	DateRangeFlags
 }
 
 type ListFormatsCmd struct {
 }
 
 type BatchConvertCmd struct {
+	Overwrite  *settings.OverwritePolicy `name:"overwrite" placeholder:"POLICY" help:"Whether existing output files are converted again: never, always or if-newer (input file newer than output file). Overrides the setting of the config file"`
+	DryRun     bool                      `name:"dry-run" help:"Show what would be converted without writing any files"`
+	Watch      bool                      `name:"watch" help:"After the conversion keep running and convert new files in the input directories until interrupted"`
+	CreateDirs bool                      `name:"create-dirs" help:"Create missing output directories of all sets"`
+	ResetState bool                      `name:"reset-state" help:"Ignore the state files of the sets and replace them, so all files are converted again"`
+	JSON       bool                      `name:"json" help:"Print the final status of all files as JSON, the progress is printed to stderr then"`
+	Strict     bool                      `name:"strict" help:"Check the directories of all enabled sets before converting and fail if one is missing, not accessible or nested in a recursive set"`
+	DateRangeFlags
 }
 
 var CLI struct {
+	Verbose bool   `name:"verbose" short:"v" help:"Print debug messages, e.g. about the format detection, to stderr"`
+	LogFile string `name:"log-file" type:"path" placeholder:"FILE" help:"Write debug messages as JSON to this file"`
+	// The field name differs from the flag to not clash with the command "config"
+	ConfigFile string `name:"config" type:"path" env:"GO_HOMEBANK_CSV_CONFIG" placeholder:"FILE" help:"Config file to use instead of the default one, relative paths in it are resolved against its directory"`
+	NoStrict   bool   `name:"no-strict" help:"Ignore unknown keys in the config file, e.g. of settings of later versions, instead of failing"`
+
 	Convert      ConvertCmd      `cmd:"" default:"withargs" help:"Convert CSV"`
 	BatchConvert BatchConvertCmd `cmd:"" help:"Batch convert CSV"`
 	ListFormats  ListFormatsCmd  `cmd:"" help:"Lists supported formats"`
+	Config       ConfigCmd       `cmd:"" help:"Create, show and check the config file of batch-convert"`
+}
+
+// dateRange returns the date range given by the flags.
+// --month and --year are expanded to the whole month or year and may not be
+// combined with each other or with --from/--to.
+func (d DateRangeFlags) dateRange() (parser.DateRange, error) {
+	if d.Month != "" && d.Year != "" {
+		return parser.DateRange{}, errors.New("--month and --year cannot be used together")
+	}
+	if (d.Month != "" || d.Year != "") && (d.From != "" || d.To != "") {
+		return parser.DateRange{}, errors.New("--month and --year cannot be combined with --from or --to")
+	}
+	if d.Month != "" {
+		return parser.MonthRange(d.Month)
+	}
+	if d.Year != "" {
+		return parser.YearRange(d.Year)
+	}
+
+	r, err := parser.ParseDateRange(d.From, d.To)
+	if err != nil {
+		return parser.DateRange{}, fmt.Errorf("invalid --from or --to: %w", err)
+	}
+	return r, nil
+}
+
+// printRowErrors prints the errors of the entries skipped in lenient mode to w
+func printRowErrors(w io.Writer, rowErrors []parser.ParserError, indent string) {
+	if len(rowErrors) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%sSkipped %d entries:\n", indent, len(rowErrors))
+	for _, e := range rowErrors {
+		fmt.Fprintf(w, "%s  %s\n", indent, e.Error())
+	}
+}
+
+// printDropped prints the number of entries outside of the date range to w
+func printDropped(w io.Writer, dropped int, indent string) {
+	if dropped == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%sDropped %d entries outside of the date range\n", indent, dropped)
 }
 
-func (c *ConvertCmd) Run() error {
+func (c *ConvertCmd) Run(logger *slog.Logger) error {
+	dateRange, err := c.dateRange()
+	if err != nil {
+		return err
+	}
+	if err := parser.CheckCategoryPrefix(c.CategoryPrefix); err != nil {
+		return err
+	}
+	if err := parser.CheckFieldRouting(c.RouteInfoToMemo, c.RouteMemoToInfo); err != nil {
+		return err
+	}
+	if err := parser.CheckPaymentTypes(c.PaymentTypes); err != nil {
+		return err
+	}
+	outputOptions, err := parser.ParseOutputOptions(c.OutDelimiter, c.OutDecimal, c.NoHeader, c.OutDateFormat)
+	if err != nil {
+		return fmt.Errorf("invalid output options: %w", err)
+	}
+	var rules *parser.Rules
+	if c.Rules != "" {
+		ruleList, err := settings.LoadRulesFromFile(c.Rules)
+		if err != nil {
+			return fmt.Errorf("cannot load rules from '%s': %w", c.Rules, err)
+		}
+		if rules, err = parser.NewRules(ruleList); err != nil {
+			return err
+		}
+	}
+
 	var formatString string
 	if c.Format == nil {
 		formatString = "autodetect format"
@@ -37,42 +160,99 @@ func (c *ConvertCmd) Run() error {
 		formatString = fmt.Sprintf("format '%s'", *c.Format)
 	}
 	fmt.Printf("Converting file '%s' (%s) to file '%s'\n", c.Infile, formatString, c.Outfile)
+	if !dateRange.IsZero() {
+		fmt.Printf("Converting only entries %s\n", dateRange)
+	}
 
-	var p parser.Parser
-
-	if c.Format == nil {
-		p = parser.GetGuessedParser(c.Infile)
-		if p == nil {
-			return fmt.Errorf("Cannot deduce format for file '%s'", c.Infile)
-		}
-		fmt.Printf("Detected format '%s'\n", p.GetFormat())
-	} else {
-		p = parser.GetParser(*c.Format)
+	parseOptions := parser.ParseOptions{
+		Lenient:                 c.Lenient,
+		Encoding:                c.Encoding,
+		ComdirectValutaFallback: c.ComdirectValutaFallback,
+		Logger:                  logger,
 	}
-	if err := p.ParseFile(c.Infile); err != nil {
+	result, err := parser.Convert(c.Infile, c.Outfile, c.Format, parser.WithParseOptions(parseOptions),
+		parser.WithConfigure(func(p parser.Parser) {
+			p.SetDateRange(dateRange)
+			p.SetCategoryPrefix(c.CategoryPrefix, c.CategoryPrefixAlways)
+			p.SetFieldRouting(c.RouteInfoToMemo, c.RouteMemoToInfo)
+			p.SetUnicodeNormalization(!c.NoUnicodeNormalization)
+			p.SetFormatOptions(parser.FormatOptions{
+				SkipSecurityTrades: c.SkipSecurityTrades,
+				OwnAccounts:        c.OwnAccounts,
+				GnuCashAccount:     c.GnuCashAccount,
+				OutbankAccount:     c.OutbankAccount,
+				PaymentTypes:       c.PaymentTypes,
+			})
+			p.SetSortOrder(c.Sort)
+			p.SetRules(rules)
+			p.SetOutputOptions(outputOptions)
+		}))
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Found %d entries\n", p.GetNumberOfEntries())
-	return p.ConvertToHomebank(c.Outfile)
+	if c.Format == nil {
+		fmt.Printf("Detected format '%s'\n", result.Format)
+	}
+	fmt.Printf("Found %d entries\n", result.Entries)
+	printRowErrors(os.Stdout, result.RowErrors, "")
+	printDropped(os.Stdout, result.Dropped, "")
+	fmt.Printf("Wrote %d entries\n", result.Written)
+	return nil
 }
 
-func (c *BatchConvertCmd) Run() error {
-	var s settings.Settings
-	configFile, err := s.LoadFromDefaultFile()
+func (c *BatchConvertCmd) Run(logger *slog.Logger) error {
+	dateRange, err := c.dateRange()
+	if err != nil {
+		return err
+	}
+	if c.Watch && c.DryRun {
+		return errors.New("--watch cannot be combined with --dry-run")
+	}
+	if c.Watch && c.JSON {
+		return errors.New("--watch cannot be combined with --json")
+	}
+	// Keeps stdout free for the status in JSON
+	var out io.Writer = os.Stdout
+	if c.JSON {
+		out = os.Stderr
+	}
+
+	s, configFile, err := loadSettings()
 	if err != nil {
 		return err
 	}
-	fmt.Println("Loaded configuration from", configFile)
+	fmt.Fprintln(out, "Loaded configuration from", configFile)
 	if s.CheckValidity() != nil {
 		return s.CheckValidity()
 	}
 	if len(s.BatchConvert.Sets) == 0 {
 		return errors.New("No batchconvert sets defined in config file")
 	}
-	fmt.Println("Found", len(s.BatchConvert.Sets), "sets:")
-	for _, set := range s.BatchConvert.Sets {
-		fmt.Println(" ", set.Name, ":", set.InputDir)
+	fmt.Fprintln(out, "Found", len(s.BatchConvert.Sets), "sets:")
+	for i, set := range s.BatchConvert.Sets {
+		if set.GetEnabled() {
+			fmt.Fprintln(out, " ", set.Name, ":", strings.Join(set.GetInputDirs(), ", "))
+		} else {
+			fmt.Fprintln(out, " ", set.Name, ": disabled, skipped")
+		}
+		s.BatchConvert.Sets[i].DateRange = dateRange
+		if c.Overwrite != nil {
+			s.BatchConvert.Sets[i].Overwrite = *c.Overwrite
+		}
+		if c.CreateDirs {
+			s.BatchConvert.Sets[i].CreateOutputDir = true
+		}
 	}
+	if c.Strict {
+		if err := s.CheckValidityDeep(); err != nil {
+			return exitError{exitConfigInvalid, err}
+		}
+	}
+	if !dateRange.IsZero() {
+		fmt.Fprintln(out, "Converting only entries", dateRange)
+	}
+	s.BatchConvert.DryRun = c.DryRun
+	s.BatchConvert.ResetState = c.ResetState
 
 	// Remember last conversion state for each file to not show duplicate output
 	fileStatus := make(map[string]batchconvert.ConversionStatus, 20)
@@ -91,39 +271,274 @@ func (c *BatchConvertCmd) Run() error {
 				fileStatus[f.InputFile] = f.Status
 				if changed {
 					if f.Status == batchconvert.ConversionInProgress {
-						fmt.Println("  In Progress:", f.InputFile)
+						fmt.Fprintln(out, "  In Progress:", f.InputFile)
 					} else if f.Status == batchconvert.ConversionSuccess {
-						fmt.Println("  Success:", f.InputFile)
+						fmt.Fprintln(out, "  Success:", f.InputFile+durationSuffix(f))
+						if f.MergedInto != "" {
+							fmt.Fprintln(out, "    Merged into", f.MergedInto)
+						}
+						printRowErrors(out, f.RowErrors, "    ")
+						printDropped(out, f.Dropped, "    ")
+						if f.Duplicates > 0 {
+							fmt.Fprintf(out, "    Suppressed %d entries of previously converted files\n", f.Duplicates)
+						}
+						if f.Archived != "" {
+							fmt.Fprintln(out, "    Moved input file to", f.Archived)
+						}
+						if f.CommandError != nil {
+							fmt.Fprintln(out, "    "+f.CommandError.Error())
+						}
 					} else if f.Status == batchconvert.ConversionError {
-						fmt.Println("  Failed:", f.InputFile)
-					} else if f.Status == batchconvert.Skipped {
-						fmt.Println("  Skipped:", f.InputFile)
+						fmt.Fprintln(out, "  Failed:", f.InputFile+durationSuffix(f))
+						if f.Error != nil {
+							fmt.Fprintln(out, "    "+f.Error.Error())
+						}
+					} else if f.Status == batchconvert.Skipped || f.Status == batchconvert.SkippedEmpty {
+						fmt.Fprintf(out, "  Skipped: %s (%s)\n", f.InputFile, f.SkipReason)
+					} else if f.Status == batchconvert.WouldConvert {
+						fmt.Fprintln(out, "  Would convert:", f.InputFile, "->", f.OutputFile)
+						printRowErrors(out, f.RowErrors, "    ")
+						printDropped(out, f.Dropped, "    ")
+						if f.Duplicates > 0 {
+							fmt.Fprintf(out, "    Would suppress %d entries of previously converted files\n", f.Duplicates)
+						}
+					} else if f.Status == batchconvert.WouldFail {
+						fmt.Fprintln(out, "  Would fail:", f.InputFile)
+						if f.Error != nil {
+							fmt.Fprintln(out, "    "+f.Error.Error())
+						}
+					} else if f.Status == batchconvert.WouldSkip {
+						fmt.Fprintf(out, "  Would skip: %s (%s)\n", f.InputFile, f.SkipReason)
 					}
 				}
 			}
 		}
 	}
 
-	fmt.Println("BatchConvert starting ...")
-	_, err = batchconvert.BatchConvert(s.BatchConvert, time.Now(), cb, nil)
+	// Stop after the current file on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if c.DryRun {
+		fmt.Fprintln(out, "BatchConvert dry run starting, no files are written ...")
+	} else {
+		fmt.Fprintln(out, "BatchConvert starting ...")
+	}
+	status, err := batchconvert.BatchConvertContext(ctx, s.BatchConvert, cb, nil, batchconvert.WithLogger(logger))
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(out, "BatchConvert cancelled")
+		printBatchSummary(out, status.Summary(), c.DryRun)
+		if c.JSON {
+			return errors.Join(err, printStatusJSON(status))
+		}
+		return err
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println("BatchConvert finished")
-	return nil
+	fmt.Fprintln(out, "BatchConvert finished")
+	summary := status.Summary()
+	printBatchSummary(out, summary, c.DryRun)
+	finishErr := batchconvert.RunOnFinishCommand(ctx, s.BatchConvert, status)
+	if finishErr != nil {
+		fmt.Fprintln(out, "On finish command failed:", finishErr)
+	}
+	if c.JSON {
+		if err := printStatusJSON(status); err != nil {
+			return err
+		}
+	}
+	if c.Watch {
+		fmt.Fprintln(out, "Watching for new files, stop with Ctrl-C ...")
+		err := batchconvert.Watch(ctx, s.BatchConvert, cb, nil, batchconvert.WithLogger(logger))
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		fmt.Fprintln(out, "Watch stopped")
+	}
+	if summary.FailedSets > 0 {
+		return fmt.Errorf("conversion of %d sets failed", summary.FailedSets)
+	}
+	if failed := summary.Files[batchconvert.ConversionError]; failed > 0 {
+		return fmt.Errorf("conversion of %d files failed", failed)
+	}
+	if summary.FailedCommands > 0 {
+		return fmt.Errorf("success command of %d files failed", summary.FailedCommands)
+	}
+	return finishErr
+}
+
+// durationSuffix returns the duration of the conversion of the file in parentheses,
+// only in verbose mode
+func durationSuffix(f batchconvert.FileStatus) string {
+	if !CLI.Verbose {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", f.Duration().Round(time.Millisecond))
+}
+
+// printStatusJSON prints the status of all files as indented JSON to stdout
+func printStatusJSON(status batchconvert.BatchStatus) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(status)
+}
+
+// printBatchSummary prints the number of files per conversion status and the number
+// of converted entries of each set and of all sets, and the sets which are disabled or failed
+func printBatchSummary(out io.Writer, summary batchconvert.Summary, dryRun bool) {
+	for _, set := range summary.Sets {
+		fmt.Fprintf(out, "  %s:\n", set.Name)
+		if set.Error != nil {
+			fmt.Fprintln(out, "    Failed:", set.Error)
+			continue
+		}
+		if set.DisabledSets > 0 {
+			fmt.Fprintln(out, "    Disabled")
+			continue
+		}
+		printSummaryCounts(out, set, dryRun, "    ")
+	}
+	fmt.Fprintln(out, "  Total:")
+	printSummaryCounts(out, summary, dryRun, "    ")
+	if summary.DisabledSets > 0 {
+		var disabled []string
+		for _, set := range summary.Sets {
+			if set.DisabledSets > 0 {
+				disabled = append(disabled, set.Name)
+			}
+		}
+		fmt.Fprintln(out, "    Disabled sets:", strings.Join(disabled, ", "))
+	}
+	if summary.FailedSets > 0 {
+		var failed []string
+		for _, set := range summary.Sets {
+			if set.Error != nil {
+				failed = append(failed, set.Name)
+			}
+		}
+		fmt.Fprintln(out, "    Failed sets:", strings.Join(failed, ", "))
+	}
+}
+
+// printSummaryCounts prints the counts of the summary, each line prefixed by indent
+func printSummaryCounts(out io.Writer, summary batchconvert.Summary, dryRun bool, indent string) {
+	files := summary.Files
+	if dryRun {
+		fmt.Fprintf(out, "%sWould convert: %d, would fail: %d, would skip: %d, not started: %d\n", indent,
+			files[batchconvert.WouldConvert], files[batchconvert.WouldFail], files[batchconvert.WouldSkip],
+			files[batchconvert.NotStartedYet])
+	} else {
+		fmt.Fprintf(out, "%sConverted: %d, failed: %d, skipped: %d, not started: %d\n", indent,
+			files[batchconvert.ConversionSuccess], files[batchconvert.ConversionError],
+			files[batchconvert.Skipped]+files[batchconvert.SkippedEmpty], files[batchconvert.NotStartedYet])
+	}
+	fmt.Fprintf(out, "%sEntries: %d, duration: %s, conversion of files: %s\n", indent, summary.Entries,
+		summary.Duration.Round(time.Millisecond), summary.FileDuration.Round(time.Millisecond))
+	if summary.FailedCommands > 0 {
+		fmt.Fprintf(out, "%sFailed success commands: %d\n", indent, summary.FailedCommands)
+	}
 }
 
 func (l *ListFormatsCmd) Run() error {
 	for _, f := range parser.GetSourceFormats() {
 		fmt.Println(f)
+		printPaymentTypes(parser.GetDefaultPaymentTypes(f))
 	}
 	return nil
 }
 
+// printPaymentTypes prints the payment codes of the transaction types sorted by name
+func printPaymentTypes(types parser.PaymentTypes) {
+	if len(types) == 0 {
+		return
+	}
+	transactionTypes := make([]string, 0, len(types))
+	for t := range types {
+		transactionTypes = append(transactionTypes, t)
+	}
+	sort.Strings(transactionTypes)
+	fmt.Println("  Payment types:")
+	for _, t := range transactionTypes {
+		fmt.Printf("    %s=%d\n", t, types[t])
+	}
+}
+
+// newLogger returns the logger for the debug messages, a text handler writing to stderr
+// with verbose and a JSON handler writing to logFile if not empty. It returns nil if both
+// are disabled. The returned function closes the log file.
+func newLogger(verbose bool, logFile string) (*slog.Logger, func() error, error) {
+	var handlers []slog.Handler
+	closeLog := func() error { return nil }
+	handlerOptions := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if verbose {
+		handlers = append(handlers, slog.NewTextHandler(os.Stderr, handlerOptions))
+	}
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot open log file: %w", err)
+		}
+		closeLog = f.Close
+		handlers = append(handlers, slog.NewJSONHandler(f, handlerOptions))
+	}
+	switch len(handlers) {
+	case 0:
+		return nil, closeLog, nil
+	case 1:
+		return slog.New(handlers[0]), closeLog, nil
+	default:
+		return slog.New(multiHandler(handlers)), closeLog, nil
+	}
+}
+
+// multiHandler passes the log records to all of its handlers
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
 func main() {
 	ctx := kong.Parse(&CLI)
-	err := ctx.Run()
-	if err != nil {
-		fmt.Println(err)
+	logger, closeLog, err := newLogger(CLI.Verbose, CLI.LogFile)
+	ctx.FatalIfErrorf(err)
+	err = errors.Join(ctx.Run(logger), closeLog())
+	var exitErr exitError
+	if errors.As(err, &exitErr) {
+		ctx.Errorf("%s", err)
+		ctx.Exit(exitErr.code)
 	}
+	ctx.FatalIfErrorf(err)
 }
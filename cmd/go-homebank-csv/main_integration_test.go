@@ -0,0 +1,1093 @@
+//go:build integration
+
+// Integration tests running the real go-homebank-csv binary.
+//
+// Run them with:
+//
+//	go test -tags integration ./cmd/...
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/batchconvert"
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// binaryPath is the path of the binary built once for all tests in TestMain
+var binaryPath string
+
+func TestMain(m *testing.M) {
+	tmpDir, err := os.MkdirTemp("", "go-homebank-csv-integration")
+	if err != nil {
+		fmt.Println("Failed to create temporary directory:", err)
+		os.Exit(1)
+	}
+
+	binaryPath = filepath.Join(tmpDir, "go-homebank-csv")
+	if runtime.GOOS == "windows" {
+		binaryPath += ".exe"
+	}
+	build := exec.Command("go", "build", "-o", binaryPath, ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Printf("Failed to build binary: %s\n%s", err, out)
+		os.RemoveAll(tmpDir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(tmpDir)
+	os.Exit(code)
+}
+
+type cliResult struct {
+	exitCode int
+	stdout   string
+	stderr   string
+}
+
+// runCli runs the binary with the given arguments and additional environment variables
+func runCli(t *testing.T, env []string, args ...string) cliResult {
+	t.Helper()
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := cliResult{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Failed to run binary: %s", err)
+	}
+	return result
+}
+
+func parserTestfile(elem ...string) string {
+	return filepath.Join(append([]string{"..", "..", "pkg", "parser", "testfiles"}, elem...)...)
+}
+
+// batchconvertTestfile returns the absolute path of a test file of batchconvert, relative
+// paths in config files would be resolved against their directory
+func batchconvertTestfile(elem ...string) string {
+	path, err := filepath.Abs(filepath.Join(append([]string{"..", "..", "internal", "pkg", "batchconvert", "testfiles"}, elem...)...))
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+func areFilesEqual(t *testing.T, file1, file2 string) bool {
+	t.Helper()
+	content1, err := os.ReadFile(file1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content2, err := os.ReadFile(file2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Trim possible all line endings to avoid differences on Windows
+	// and with git autocrlf settings
+	return strings.ReplaceAll(string(content1), "\r", "") == strings.ReplaceAll(string(content2), "\r", "")
+}
+
+func TestIntegrationListFormats(t *testing.T) {
+	result := runCli(t, nil, "list-formats")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	for _, f := range parser.GetSourceFormats() {
+		if !strings.Contains(result.stdout, f.String()+"\n") {
+			t.Errorf("Expected format '%s' in output '%s'", f, result.stdout)
+		}
+	}
+	if !strings.Contains(result.stdout, "DKB\n  Payment types:\n    Lastschrift=11\n") {
+		t.Errorf("Expected default payment types in output '%s'", result.stdout)
+	}
+}
+
+func TestIntegrationConvertLogging(t *testing.T) {
+	tmpDir := t.TempDir()
+	outfile := filepath.Join(tmpDir, "output.csv")
+	logFile := filepath.Join(tmpDir, "log.json")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	result := runCli(t, nil, "convert", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if result.stderr != "" {
+		t.Errorf("Expected no logs without --verbose, got '%s'", result.stderr)
+	}
+
+	result = runCli(t, nil, "--verbose", "--log-file", logFile, "convert", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stderr, `level=DEBUG msg="detected format"`) {
+		t.Errorf("Expected text logs in stderr '%s'", result.stderr)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"msg":"detected format"`) || !strings.Contains(string(content), `"format":"Volksbank"`) {
+		t.Errorf("Expected JSON logs in log file, got '%s'", content)
+	}
+}
+
+func TestIntegrationConvertPaymentTypes(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("dkb", "dkb.csv")
+
+	result := runCli(t, nil, "convert", "--format=dkb", "--payment-type=lastschrift=8", "--payment-type=Eingang=4", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	content, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"2024-12-10;4;", "2024-09-30;8;"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, content)
+		}
+	}
+
+	result = runCli(t, nil, "convert", "--format=dkb", "--payment-type=Lastschrift=12", infile, outfile)
+	if result.exitCode == 0 {
+		t.Error("Expected a failure for an invalid payment code")
+	}
+}
+
+func TestIntegrationConvertAutodetect(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	result := runCli(t, nil, "convert", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Detected format 'Volksbank'") {
+		t.Errorf("Expected detected format in output '%s'", result.stdout)
+	}
+	if !strings.Contains(result.stdout, "Found 4 entries") {
+		t.Errorf("Expected number of entries in output '%s'", result.stdout)
+	}
+	if result.stderr != "" {
+		t.Errorf("Expected empty stderr, got '%s'", result.stderr)
+	}
+	if !areFilesEqual(t, parserTestfile("volksbank", "homebank.csv"), outfile) {
+		t.Error("Output file differs from expected file")
+	}
+}
+
+func TestIntegrationConvertExplicitFormat(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("dkb", "dkb.csv")
+
+	result := runCli(t, nil, "convert", "--format=dkb", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "(format 'DKB')") {
+		t.Errorf("Expected format in output '%s'", result.stdout)
+	}
+	if !areFilesEqual(t, parserTestfile("dkb", "homebank.csv"), outfile) {
+		t.Error("Output file differs from expected file")
+	}
+}
+
+func TestIntegrationConvertLenient(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_nok_wrongbetrag.csv")
+
+	result := runCli(t, nil, "convert", infile, outfile)
+	if result.exitCode == 0 {
+		t.Fatal("Expected a failure without --lenient")
+	}
+
+	result = runCli(t, nil, "convert", "--lenient", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Detected format 'Volksbank'") {
+		t.Errorf("Expected detected format in output '%s'", result.stdout)
+	}
+	if !strings.Contains(result.stdout, "Skipped 1 entries:\n  DataParsingError in line 2 in field name 'Betrag': ") {
+		t.Errorf("Expected skipped entry in output '%s'", result.stdout)
+	}
+	if _, err := os.Stat(outfile); err != nil {
+		t.Errorf("Expected output file: %v", err)
+	}
+}
+
+func TestIntegrationConvertRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	outfile := filepath.Join(tmpDir, "output.csv")
+	infile := parserTestfile("dkb", "dkb.csv")
+
+	rulesFile := filepath.Join(tmpDir, "rules.yml")
+	rules := "rules:\n  - match:\n      payee_regex: \"anderer Bank\"\n    set:\n      category: \"Transfer\"\n      payee: \"Other bank\"\n"
+	if err := os.WriteFile(rulesFile, []byte(rules), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result := runCli(t, nil, "convert", "--rules", rulesFile, infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	content, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), ";Other bank;Verwendungszweck;-2000.00;Transfer;") {
+		t.Errorf("Expected mapped entry in output '%s'", content)
+	}
+
+	invalidRulesFile := filepath.Join(tmpDir, "invalid_rules.yml")
+	if err := os.WriteFile(invalidRulesFile, []byte("rules:\n  - match:\n      payee_regex: \"REWE(\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result = runCli(t, nil, "convert", "--rules", invalidRulesFile, infile, outfile)
+	if result.exitCode == 0 {
+		t.Error("Expected a failure for an invalid rule")
+	}
+	if !strings.Contains(result.stderr, "invalid payee_regex") {
+		t.Errorf("Expected regex error in stderr '%s'", result.stderr)
+	}
+}
+
+func TestIntegrationConvertSort(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	result := runCli(t, nil, "convert", "--sort=date-asc", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	content, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dates []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n")[1:] {
+		dates = append(dates, strings.SplitN(line, ";", 2)[0])
+	}
+	expected := []string{"2023-09-29", "2023-09-29", "2023-10-02", "2023-10-04"}
+	if strings.Join(dates, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected dates %v, got %v", expected, dates)
+	}
+
+	result = runCli(t, nil, "convert", "--sort=random", infile, outfile)
+	if result.exitCode == 0 {
+		t.Error("Expected a failure for an invalid sort order")
+	}
+}
+
+func TestIntegrationConvertOutputOptions(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--out-delimiter=tab", "--out-decimal=,"}, "homebank_comma_tab.csv"},
+		{[]string{"--out-decimal=,", "--no-header", "--out-date-format=02.01.2006"}, "homebank_dmy_noheader.csv"},
+	}
+	for _, tc := range testCases {
+		args := append(append([]string{"convert"}, tc.args...), infile, outfile)
+		result := runCli(t, nil, args...)
+		if result.exitCode != 0 {
+			t.Fatalf("%v: expected exit code 0, got %d (stderr: %s)", tc.args, result.exitCode, result.stderr)
+		}
+		content, err := os.ReadFile(outfile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := os.ReadFile(parserTestfile("volksbank", tc.expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != string(expected) {
+			t.Errorf("%v: expected:\n%s\ngot:\n%s", tc.args, expected, content)
+		}
+	}
+
+	result := runCli(t, nil, "convert", "--out-delimiter=,", "--out-decimal=,", infile, outfile)
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "delimiter and decimal separator must differ") {
+		t.Errorf("Expected a failure for equal delimiter and decimal separator, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+}
+
+func TestIntegrationConvertMonth(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	result := runCli(t, nil, "convert", "--month=2023-10", infile, outfile)
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "2023-10-01 to 2023-10-31") {
+		t.Errorf("Expected date range in output '%s'", result.stdout)
+	}
+	if !strings.Contains(result.stdout, "Dropped 2 entries outside of the date range") {
+		t.Errorf("Expected dropped entries in output '%s'", result.stdout)
+	}
+}
+
+func TestIntegrationConvertErrors(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	infile := parserTestfile("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedStderr string
+	}{
+		{
+			"undetectable format",
+			[]string{"convert", parserTestfile("paypal", "Download_nok_noheader.CSV"), outfile},
+			"cannot deduce format",
+		},
+		{
+			"invalid amount in detected format",
+			[]string{"convert", parserTestfile("volksbank", "Umsaetze_nok_wrongbetrag.csv"), outfile},
+			"format Volksbank: DataParsingError in file",
+		},
+		{
+			"wrong format",
+			[]string{"convert", "--format=DKB", infile, outfile},
+			"HeaderError",
+		},
+		{
+			"invalid amount",
+			[]string{"convert", "--format=Volksbank", parserTestfile("volksbank", "Umsaetze_nok_wrongbetrag.csv"), outfile},
+			"Umsaetze_nok_wrongbetrag.csv' in line 2 in field name 'Betrag': strconv.ParseFloat",
+		},
+		{
+			"unknown format",
+			[]string{"convert", "--format=bogus", infile, outfile},
+			"unsupported format 'bogus'",
+		},
+		{
+			"non existing input file",
+			[]string{"convert", "non_existing_file.csv", outfile},
+			"non_existing_file.csv",
+		},
+		{
+			"month combined with from",
+			[]string{"convert", "--month=2023-10", "--from=2023-10-01", infile, outfile},
+			"cannot be combined",
+		},
+	}
+
+	for _, tc := range testCases {
+		result := runCli(t, nil, tc.args...)
+		if result.exitCode == 0 {
+			t.Errorf("%s: expected non-zero exit code", tc.name)
+		}
+		if !strings.Contains(result.stderr, tc.expectedStderr) {
+			t.Errorf("%s: expected '%s' in stderr '%s'", tc.name, tc.expectedStderr, result.stderr)
+		}
+	}
+}
+
+func TestIntegrationBatchConvert(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	inputDir, err := filepath.Abs(batchconvertTestfile("input", "volksbank"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    format: Volksbank
+`, inputDir, outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := []string{"XDG_CONFIG_HOME=" + configHome}
+
+	// The dry run writes nothing
+	result := runCli(t, env, "batch-convert", "--dry-run")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	for _, expected := range []string{"Would convert:", "Would convert: 1, would fail: 0, would skip: 0, not started: 0"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+	if files, err := os.ReadDir(outputDir); err != nil || len(files) != 0 {
+		t.Errorf("Expected empty output directory after dry run, got %v (%v)", files, err)
+	}
+
+	result = runCli(t, env, "batch-convert")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	for _, expected := range []string{"Found 1 sets:", "Success:", "BatchConvert finished"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+
+	filename := "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	if !areFilesEqual(t, batchconvertTestfile("expected_output", "volksbank", filename), filepath.Join(outputDir, filename)) {
+		t.Error("Output file differs from expected file")
+	}
+
+	// Second run skips the already converted file
+	result = runCli(t, env, "batch-convert")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Skipped:") || !strings.Contains(result.stdout, "(output file exists)") {
+		t.Errorf("Expected 'Skipped:' with reason in output '%s'", result.stdout)
+	}
+
+	// The command line overrides the overwrite policy of the config file
+	result = runCli(t, env, "batch-convert", "--overwrite", "always")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Success:") {
+		t.Errorf("Expected 'Success:' in output '%s'", result.stdout)
+	}
+	// In verbose mode the duration of each file is printed
+	result = runCli(t, env, "--verbose", "batch-convert", "--overwrite", "always")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !regexp.MustCompile(`Success: .+ \([0-9.]+m?s\)`).MatchString(result.stdout) {
+		t.Errorf("Expected 'Success:' with duration in output '%s'", result.stdout)
+	}
+	result = runCli(t, env, "batch-convert", "--overwrite", "sometimes")
+	if result.exitCode == 0 {
+		t.Error("Expected non-zero exit code for invalid overwrite policy")
+	}
+}
+
+func TestIntegrationBatchConvertNoConfig(t *testing.T) {
+	env := []string{"XDG_CONFIG_HOME=" + t.TempDir(), "XDG_CONFIG_DIRS=" + t.TempDir()}
+	result := runCli(t, env, "batch-convert")
+	if result.exitCode == 0 {
+		t.Error("Expected non-zero exit code")
+	}
+	if result.stderr == "" {
+		t.Error("Expected error message on stderr")
+	}
+}
+
+func TestIntegrationBatchConvertFailure(t *testing.T) {
+	configHome := t.TempDir()
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(inputDir, "invalid.csv"), []byte("no bank export"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Invalid
+    inputdir: %q
+    outputdir: %q
+`, inputDir, outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCli(t, []string{"XDG_CONFIG_HOME=" + configHome}, "batch-convert")
+	if result.exitCode == 0 {
+		t.Error("Expected non-zero exit code for failed file")
+	}
+	for _, expected := range []string{"Failed:", "Invalid:", "Total:", "Converted: 0, failed: 1, skipped: 0, not started: 0", "Entries: 0"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+	if !strings.Contains(result.stderr, "conversion of 1 files failed") {
+		t.Errorf("Expected error message in stderr '%s'", result.stderr)
+	}
+}
+
+func TestIntegrationBatchConvertDisabledSet(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	closedOutputDir := t.TempDir()
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The set of the closed account shares its input directory with the new one
+	inputDir := batchconvertTestfile("input", "volksbank")
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Closed
+    enabled: false
+    inputdir: %q
+    outputdir: %q
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+`, inputDir, closedOutputDir, inputDir, outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCli(t, []string{"XDG_CONFIG_HOME=" + configHome}, "batch-convert")
+	if result.exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", result.exitCode, result.stderr)
+	}
+	for _, expected := range []string{"Closed : disabled, skipped", "Closed:\n    Disabled", "Converted: 1, failed: 0", "Disabled sets: Closed"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+	if entries, err := os.ReadDir(closedOutputDir); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no output of the disabled set, got %v, %v", entries, err)
+	}
+}
+
+func TestIntegrationBatchConvertFailedSet(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The output directory of the first set is missing, e.g. an unmounted share
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Share
+    inputdir: %q
+    outputdir: %q
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+`, t.TempDir(), filepath.Join(t.TempDir(), "missing"), batchconvertTestfile("input", "volksbank"), outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCli(t, []string{"XDG_CONFIG_HOME=" + configHome}, "batch-convert")
+	if result.exitCode == 0 {
+		t.Error("Expected non-zero exit code for failed set")
+	}
+	for _, expected := range []string{"Share:\n    Failed:", "Converted: 1, failed: 0", "Failed sets: Share"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+	if !strings.Contains(result.stderr, "conversion of 1 sets failed") {
+		t.Errorf("Expected error message in stderr '%s'", result.stderr)
+	}
+	expectedFile := batchconvertTestfile("expected_output", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	if !areFilesEqual(t, expectedFile, filepath.Join(outputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv")) {
+		t.Error("Expected conversion of the other set")
+	}
+}
+
+func TestIntegrationBatchConvertStrict(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Share
+    inputdir: %q
+    outputdir: %q
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+`, t.TempDir(), filepath.Join(t.TempDir(), "missing"), batchconvertTestfile("input", "volksbank"), outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is converted if one of the sets can't be converted
+	result := runCli(t, []string{"XDG_CONFIG_HOME=" + configHome}, "batch-convert", "--strict")
+	if result.exitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.exitCode)
+	}
+	if !strings.Contains(result.stderr, "set 'Share': OutputDir") {
+		t.Errorf("Expected error for the missing output directory in stderr '%s'", result.stderr)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Errorf("Expected no converted files, got %v", entries)
+	}
+
+	result = runCli(t, []string{"XDG_CONFIG_HOME=" + configHome}, "batch-convert", "--strict", "--create-dirs")
+	if result.exitCode != 0 {
+		t.Errorf("Expected exit code 0 with --create-dirs, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+}
+
+func TestIntegrationBatchConvertCreateDirs(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := filepath.Join(t.TempDir(), "new", "homebank")
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+`, batchconvertTestfile("input", "volksbank"), outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"XDG_CONFIG_HOME=" + configHome}
+
+	if result := runCli(t, env, "batch-convert"); result.exitCode == 0 {
+		t.Error("Expected non-zero exit code for missing output directory")
+	}
+	result := runCli(t, env, "batch-convert", "--create-dirs")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", result.exitCode, result.stderr)
+	}
+	expectedFile := batchconvertTestfile("expected_output", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	if !areFilesEqual(t, expectedFile, filepath.Join(outputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv")) {
+		t.Error("Expected converted file in the created output directory")
+	}
+}
+
+func TestIntegrationBatchConvertJSON(t *testing.T) {
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+`, batchconvertTestfile("input", "volksbank"), outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"XDG_CONFIG_HOME=" + configHome}
+
+	result := runCli(t, env, "batch-convert", "--json")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", result.exitCode, result.stderr)
+	}
+	// Only the status is printed to stdout, the progress to stderr
+	var status batchconvert.BatchStatus
+	if err := json.Unmarshal([]byte(result.stdout), &status); err != nil {
+		t.Fatalf("Expected status as JSON, got %v: %s", err, result.stdout)
+	}
+	if len(status) != 1 || len(status[0].Files) != 1 {
+		t.Fatalf("Expected status of a single file, got %v", status)
+	}
+	if f := status[0].Files[0]; f.Status != batchconvert.ConversionSuccess || f.Format == nil || *f.Format != parser.Volksbank || f.FinishedAt.IsZero() {
+		t.Errorf("Expected converted Volksbank file, got %v", f)
+	}
+	if !strings.Contains(result.stdout, `"Status": "converted"`) {
+		t.Errorf("Expected textual status, got %s", result.stdout)
+	}
+	if !strings.Contains(result.stderr, "BatchConvert finished") {
+		t.Errorf("Expected progress on stderr, got %s", result.stderr)
+	}
+
+	// Skipped in the second run
+	result = runCli(t, env, "batch-convert", "--json")
+	if err := json.Unmarshal([]byte(result.stdout), &status); err != nil {
+		t.Fatal(err)
+	}
+	if f := status[0].Files[0]; f.Status != batchconvert.Skipped || f.SkipReason == "" {
+		t.Errorf("Expected skipped file, got %v", f)
+	}
+
+	if result := runCli(t, env, "batch-convert", "--json", "--watch"); result.exitCode == 0 {
+		t.Error("Expected error for --json with --watch")
+	}
+}
+
+func TestIntegrationBatchConvertResetState(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	configHome := t.TempDir()
+	outputDir := t.TempDir()
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    state: true
+`, batchconvertTestfile("input", "volksbank"), outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"XDG_CONFIG_HOME=" + configHome}
+	outfile := filepath.Join(outputDir, filename)
+
+	if result := runCli(t, env, "batch-convert"); result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", result.exitCode, result.stderr)
+	}
+	if err := os.Rename(outfile, filepath.Join(outputDir, "2023-"+filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The renamed output file is not converted again
+	result := runCli(t, env, "batch-convert")
+	if result.exitCode != 0 || !strings.Contains(result.stdout, "converted before according to the state file") {
+		t.Errorf("Expected file skipped by the state, got %d: %s", result.exitCode, result.stdout)
+	}
+	if _, err := os.Stat(outfile); err == nil {
+		t.Error("Expected no new output file")
+	}
+
+	result = runCli(t, env, "batch-convert", "--reset-state")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", result.exitCode, result.stderr)
+	}
+	if !areFilesEqual(t, batchconvertTestfile("expected_output", "volksbank", filename), outfile) {
+		t.Error("Expected file converted again after the reset")
+	}
+}
+
+func TestIntegrationBatchConvertWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Interrupting the process is not supported on Windows")
+	}
+	configHome := t.TempDir()
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	configDir := filepath.Join(configHome, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    format: Volksbank
+`, inputDir, outputDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"XDG_CONFIG_HOME=" + configHome}
+
+	result := runCli(t, env, "batch-convert", "--watch", "--dry-run")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "--watch cannot be combined with --dry-run") {
+		t.Errorf("Expected error for --watch with --dry-run, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+
+	cmd := exec.Command(binaryPath, "batch-convert", "--watch")
+	cmd.Env = append(os.Environ(), env...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	content, err := os.ReadFile(batchconvertTestfile("input", "volksbank", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Created after the start of the watch
+	time.Sleep(500 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(inputDir, filename), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outfile := filepath.Join(outputDir, filename)
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(100 * time.Millisecond) {
+		if _, err := os.Stat(outfile); err == nil {
+			break
+		}
+	}
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected exit code 0 after interrupt, got %v (stdout: %s)", err, stdout.String())
+	}
+	if !areFilesEqual(t, batchconvertTestfile("expected_output", "volksbank", filename), outfile) {
+		t.Error("Output file differs from expected file")
+	}
+	for _, expected := range []string{"Watching for new files", "Success:", "Watch stopped"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, stdout.String())
+		}
+	}
+}
+
+func TestIntegrationConfig(t *testing.T) {
+	configHome := t.TempDir()
+	env := []string{"XDG_CONFIG_HOME=" + configHome, "XDG_CONFIG_DIRS=" + t.TempDir()}
+	configFile := filepath.Join(configHome, "go-homebank-csv", "config.yml")
+
+	result := runCli(t, env, "config", "path")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if strings.TrimSpace(result.stdout) != configFile {
+		t.Errorf("Expected path '%s', got '%s'", configFile, result.stdout)
+	}
+
+	// A missing config file has its own exit code
+	for _, command := range []string{"show", "validate"} {
+		result = runCli(t, env, "config", command)
+		if result.exitCode != 2 {
+			t.Errorf("%s: expected exit code 2 for missing config file, got %d (stderr: %s)", command, result.exitCode, result.stderr)
+		}
+	}
+
+	result = runCli(t, env, "config", "init")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if content, err := os.ReadFile(configFile); err != nil || string(content) != settings.Template {
+		t.Errorf("Expected the config template, got '%s' (%v)", content, err)
+	}
+	result = runCli(t, env, "config", "init")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "--force") {
+		t.Errorf("Expected error mentioning --force for existing config file, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	result = runCli(t, env, "config", "validate")
+	if result.exitCode != 0 {
+		t.Errorf("Expected valid template, got %d (stdout: %s, stderr: %s)", result.exitCode, result.stdout, result.stderr)
+	}
+
+	inputDir := t.TempDir()
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    filemaxagedays: -1
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    createoutputdir: true
+`, inputDir, filepath.Join(inputDir, "missing"), filepath.Join(inputDir, "missing"), filepath.Join(inputDir, "out"))
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result = runCli(t, env, "config", "validate")
+	if result.exitCode != 3 {
+		t.Errorf("Expected exit code 3 for invalid config file, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	for _, expected := range []string{"4 problems", "FileMaxAge < 0", "OutputDir: ", "InputDir: ", "duplicate Name 'Volksbank'"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+
+	result = runCli(t, env, "config", "show")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	for _, expected := range []string{"name: Volksbank", "filemaxagedays: -1d", "createoutputdir: true"} {
+		if !strings.Contains(result.stdout, expected) {
+			t.Errorf("Expected '%s' in output '%s'", expected, result.stdout)
+		}
+	}
+
+	if err := os.WriteFile(configFile, []byte("batchconvert: ["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result = runCli(t, env, "config", "validate")
+	if result.exitCode != 3 {
+		t.Errorf("Expected exit code 3 for invalid YAML, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+}
+
+func TestIntegrationBatchConvertConfigFlag(t *testing.T) {
+	// The default config file has no sets and must not be used
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "go-homebank-csv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "go-homebank-csv", "config.yml"), []byte("batchconvert:\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative paths are resolved against the directory of the config file
+	configDir := t.TempDir()
+	inputDir := filepath.Join(configDir, "input")
+	if err := os.CopyFS(inputDir, os.DirFS(batchconvertTestfile("input", "volksbank"))); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(name string, outputDir string) string {
+		config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: input
+    outputdir: %s
+    createoutputdir: true
+    format: Volksbank
+`, outputDir)
+		configFile := filepath.Join(configDir, name)
+		if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return configFile
+	}
+	flagConfig := writeConfig("flag.yml", "flag-output")
+	envConfig := writeConfig("env.yml", "env-output")
+	filename := "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+
+	env := []string{"XDG_CONFIG_HOME=" + configHome, "XDG_CONFIG_DIRS=" + t.TempDir()}
+	result := runCli(t, env, "batch-convert")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "No batchconvert sets") {
+		t.Errorf("Expected default config file without sets, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+
+	env = append(env, "GO_HOMEBANK_CSV_CONFIG="+envConfig)
+	result = runCli(t, env, "batch-convert")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Loaded configuration from "+envConfig) {
+		t.Errorf("Expected config file of environment variable in output '%s'", result.stdout)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "env-output", filename)); err != nil {
+		t.Errorf("Expected output file of environment config: %s", err)
+	}
+
+	// The flag takes precedence over the environment variable
+	result = runCli(t, env, "--config", flagConfig, "batch-convert")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Loaded configuration from "+flagConfig) {
+		t.Errorf("Expected config file of flag in output '%s'", result.stdout)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "flag-output", filename)); err != nil {
+		t.Errorf("Expected output file of flag config: %s", err)
+	}
+
+	result = runCli(t, env, "--config", filepath.Join(configDir, "missing.yml"), "batch-convert")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "missing.yml") {
+		t.Errorf("Expected error for missing config file, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	result = runCli(t, env, "--config", flagConfig, "config", "path")
+	if strings.TrimSpace(result.stdout) != flagConfig {
+		t.Errorf("Expected path '%s', got '%s'", flagConfig, result.stdout)
+	}
+}
+
+func TestIntegrationConfigUnknownKey(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: Volksbank
+    inputdir: %q
+    outputdir: %q
+    fileglobpatern: "*.csv"
+`, t.TempDir(), t.TempDir())
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCli(t, nil, "--config", configFile, "batch-convert")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "fileglobpatern") {
+		t.Errorf("Expected error naming the unknown key, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	result = runCli(t, nil, "--config", configFile, "config", "validate")
+	if result.exitCode != 3 {
+		t.Errorf("Expected exit code 3 for unknown key, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	result = runCli(t, nil, "--config", configFile, "--no-strict", "batch-convert")
+	if result.exitCode != 0 {
+		t.Errorf("Expected exit code 0 with --no-strict, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+}
+
+func TestIntegrationConfigMigrate(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	old := `# My accounts
+batchconvert:
+  sets:
+  - name: DKB
+    inputdir: ~/Downloads
+    outputdir: ~/finance/dkb
+    # Only the latest exports
+    filemaxagedays: 14
+`
+	if err := os.WriteFile(configFile, []byte(old), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCli(t, nil, "--config", configFile, "config", "migrate")
+	if result.exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+	backup := configFile + ".v0.bak"
+	if !strings.Contains(result.stdout, "from version 0 to 1") || !strings.Contains(result.stdout, backup) {
+		t.Errorf("Expected migration message, got '%s'", result.stdout)
+	}
+	if content, err := os.ReadFile(backup); err != nil || string(content) != old {
+		t.Errorf("Expected backup of the old file, got '%s' (%v)", content, err)
+	}
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The paths are kept as given, the comments are kept
+	for _, expected := range []string{"version: 1\n", "inputdir: ~/Downloads\n", "# My accounts", "# Only the latest exports"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in migrated file:\n%s", expected, content)
+		}
+	}
+
+	result = runCli(t, nil, "--config", configFile, "config", "migrate")
+	if result.exitCode != 0 || !strings.Contains(result.stdout, "current version 1") {
+		t.Errorf("Expected no migration of current version, got %d (stdout: %s, stderr: %s)", result.exitCode, result.stdout, result.stderr)
+	}
+
+	if err := os.WriteFile(configFile, []byte("version: 99\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result = runCli(t, nil, "--config", configFile, "config", "migrate")
+	if result.exitCode != 3 || !strings.Contains(result.stderr, "please upgrade") {
+		t.Errorf("Expected upgrade error with exit code 3, got %d (stderr: %s)", result.exitCode, result.stderr)
+	}
+}
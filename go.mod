@@ -3,8 +3,10 @@ module github.com/sercxanto/go-homebank-csv
 go 1.23
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/adrg/xdg v0.5.3
 	github.com/alecthomas/kong v1.6.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/goccy/go-yaml v1.15.13
 	github.com/xuri/excelize/v2 v2.9.0
 	golang.org/x/text v0.21.0
@@ -0,0 +1,46 @@
+// Package atomicfile implements writing files atomically.
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+)
+
+// Write writes file with write. The content is written to a temporary file in the same
+// directory first, which is renamed to file on success and removed on error. So an
+// interrupted write never leaves a truncated file. The file is created with the
+// permissions perm (before umask).
+func Write(file string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := createTemp(file, perm)
+	if err != nil {
+		return err
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// createTemp creates a new hidden temporary file next to file with the permissions perm,
+// e.g. ".Umsaetze.csv.1234.tmp".
+func createTemp(file string, perm os.FileMode) (*os.File, error) {
+	dir, base := filepath.Split(file)
+	for {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, rand.Uint32()))
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	err := Write(outfile, 0o600, func(w io.Writer) error {
+		_, err := w.Write([]byte("content"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(outfile); err != nil || string(content) != "content" {
+		t.Errorf("Expected 'content', got '%s' (%v)", content, err)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(outfile)); len(files) != 1 {
+		t.Errorf("Expected no temporary files, got %v", files)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(outfile); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected permissions 0600, got %v (%v)", info.Mode().Perm(), err)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	if err := os.WriteFile(outfile, []byte("previous"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	errWrite := errors.New("write failed")
+	err := Write(outfile, 0o600, func(w io.Writer) error {
+		if _, err := w.Write([]byte("partial")); err != nil {
+			return err
+		}
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("Expected '%v', got '%v'", errWrite, err)
+	}
+	if content, err := os.ReadFile(outfile); err != nil || string(content) != "previous" {
+		t.Errorf("Expected 'previous', got '%s' (%v)", content, err)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(outfile)); len(files) != 1 {
+		t.Errorf("Expected no temporary files, got %v", files)
+	}
+}
@@ -0,0 +1,97 @@
+package batchconvert
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// rename renames a file, it is a variable to be replaced in tests
+var rename = os.Rename
+
+// archiveMu serializes the moves of files as they must not choose the same target
+var archiveMu sync.Mutex
+
+// archiveFile moves file into archiveDir, which is created if needed, and returns its new path.
+// If a file with the same name exists in archiveDir, a numeric suffix is appended,
+// e.g. "Umsaetze_1.csv".
+func archiveFile(file string, archiveDir string) (string, error) {
+	if err := os.MkdirAll(archiveDir, 0o700); err != nil {
+		return "", err
+	}
+
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+
+	target, err := freePath(filepath.Join(archiveDir, filepath.Base(file)))
+	if err != nil {
+		return "", err
+	}
+	if err := moveFile(file, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// freePath returns path if no file exists there, otherwise path with the first
+// numeric suffix not taken yet
+func freePath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 0; ; i++ {
+		candidate := path
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+		}
+		_, err := os.Lstat(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// moveFile moves src to dst. If both are on different file systems, src is copied and removed.
+func moveFile(src string, dst string) error {
+	err := rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFileContent(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFileContent copies the content and permissions of src to the new file dst.
+// On error dst is removed again.
+func copyFileContent(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
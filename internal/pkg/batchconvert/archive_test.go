@@ -0,0 +1,62 @@
+package batchconvert
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestArchiveFile(t *testing.T) {
+	inputDir := t.TempDir()
+	archiveDir := filepath.Join(inputDir, "processed")
+	for _, name := range []string{"a.csv", "b.csv"} {
+		if err := os.WriteFile(filepath.Join(inputDir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archived, err := archiveFile(filepath.Join(inputDir, "a.csv"), archiveDir)
+	if err != nil || archived != filepath.Join(archiveDir, "a.csv") {
+		t.Fatalf("Expected '%s', got '%s', %v", filepath.Join(archiveDir, "a.csv"), archived, err)
+	}
+	info, err := os.Stat(archiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o700 {
+		t.Errorf("Expected permissions 0700 of the archive directory, got %o", info.Mode().Perm())
+	}
+
+	// Existing files in the archive directory are not overwritten
+	for _, expected := range []string{"a_1.csv", "a_2.csv"} {
+		if err := os.WriteFile(filepath.Join(inputDir, "a.csv"), []byte(expected), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		archived, err := archiveFile(filepath.Join(inputDir, "a.csv"), archiveDir)
+		if err != nil || archived != filepath.Join(archiveDir, expected) {
+			t.Errorf("Expected '%s', got '%s', %v", filepath.Join(archiveDir, expected), archived, err)
+		}
+		if content, err := os.ReadFile(archived); err != nil || string(content) != expected {
+			t.Errorf("Expected content '%s', got '%s', %v", expected, content, err)
+		}
+	}
+
+	// Moving to another file system falls back to copy and remove
+	orig := rename
+	t.Cleanup(func() { rename = orig })
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	archived, err = archiveFile(filepath.Join(inputDir, "b.csv"), archiveDir)
+	if err != nil || archived != filepath.Join(archiveDir, "b.csv") {
+		t.Fatalf("Expected '%s', got '%s', %v", filepath.Join(archiveDir, "b.csv"), archived, err)
+	}
+	if content, err := os.ReadFile(archived); err != nil || string(content) != "b.csv" {
+		t.Errorf("Expected content 'b.csv', got '%s', %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(inputDir, "b.csv")); !os.IsNotExist(err) {
+		t.Errorf("Expected input file to be removed, got %v", err)
+	}
+}
@@ -2,24 +2,34 @@
 package batchconvert
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/atomicfile"
 	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
 	"github.com/sercxanto/go-homebank-csv/pkg/parser"
 )
 
-// getTimeFromMaxAgeDays returns the time.Time for the given fileMaxAgeDays
-// if fileMaxAgeDays is 0, the zero time is returned (January 1, year 1, 00:00:00 UTC.)
-func getTimeFromMaxAgeDays(fileMaxAgeDays uint, now time.Time) time.Time {
-	if fileMaxAgeDays == 0 {
+// getTimeFromMaxAge returns the time.Time for the given fileMaxAge
+// if fileMaxAge is 0 or negative, the zero time is returned (January 1, year 1, 00:00:00 UTC.)
+func getTimeFromMaxAge(fileMaxAge settings.MaxAge, now time.Time) time.Time {
+	if fileMaxAge <= 0 {
 		return time.Time{}
 	}
-	return now.AddDate(0, 0, -int(fileMaxAgeDays))
+	return now.Add(-time.Duration(fileMaxAge))
 }
 
 // findFiles returns a list of files matching the given glob pattern and max age
@@ -27,7 +37,10 @@ func getTimeFromMaxAgeDays(fileMaxAgeDays uint, now time.Time) time.Time {
 // A file is considered matching if its modification time is younger than the given max age.
 // A minTime of zero time (January 1, year 1, 00:00:00 UTC.) is considered matching all files.
 // An empty fileGlobPattern is considered matching all files.
-func findFiles(inputDir string, fileGlobPattern string, minTime time.Time) ([]string, error) {
+// Files whose base name matches one of excludeGlobPatterns and the directories excludeDirs,
+// e.g. the archive directory within inputDir, are left out. Directories matching the
+// glob pattern are left out as well, see also inputFileInfo for symlinks.
+func findFiles(inputDir string, fileGlobPattern string, excludeGlobPatterns []string, minTime time.Time, excludeDirs []string, followSymlinks bool, logger *slog.Logger) ([]string, error) {
 	if len(inputDir) == 0 {
 		return nil, nil
 	}
@@ -40,20 +53,72 @@ func findFiles(inputDir string, fileGlobPattern string, minTime time.Time) ([]st
 	if err != nil {
 		return nil, err
 	}
+	if err := checkGlobPatterns(excludeGlobPatterns); err != nil {
+		return nil, err
+	}
 	matchingFiles := make([]string, 0, len(files))
 	for i := 0; i < len(files); i++ {
-		fileInfo, err := os.Stat(files[i])
+		if isExcluded(filepath.Base(files[i]), excludeGlobPatterns) || isExcludedDir(files[i], excludeDirs) {
+			continue
+		}
+		fileInfo, err := inputFileInfo(files[i], followSymlinks, logger)
 		if err != nil {
 			return nil, err
 		}
-		if minTime.IsZero() {
+		if fileInfo != nil && !fileInfo.IsDir() && isModifiedSince(fileInfo, minTime) {
 			matchingFiles = append(matchingFiles, files[i])
-		} else {
-			modTime := fileInfo.ModTime()
-			if modTime.After(minTime) || modTime.Equal(minTime) {
-				matchingFiles = append(matchingFiles, files[i])
+		}
+	}
+
+	// sort matchingFiles alphabetically to keep the order consistent
+	sort.Strings(matchingFiles)
+
+	return matchingFiles, nil
+}
+
+// findFilesRecursive is like findFiles, but searches also in the subdirectories of inputDir.
+// The glob pattern is matched against the base name of the files, directories are not returned.
+// The directories excludeDirs are not searched, e.g. the output directory within inputDir.
+// Symlinks to directories are not searched either.
+func findFilesRecursive(inputDir string, fileGlobPattern string, excludeGlobPatterns []string, minTime time.Time, excludeDirs []string, followSymlinks bool, logger *slog.Logger) ([]string, error) {
+	if len(inputDir) == 0 {
+		return nil, nil
+	}
+	if fileGlobPattern == "" {
+		fileGlobPattern = "*"
+	}
+	if err := checkGlobPatterns(append([]string{fileGlobPattern}, excludeGlobPatterns...)); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(inputDir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	var matchingFiles []string
+	err := filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != inputDir && isExcludedDir(path, excludeDirs) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		if matched, _ := filepath.Match(fileGlobPattern, d.Name()); !matched || isExcluded(d.Name(), excludeGlobPatterns) {
+			return nil
+		}
+		fileInfo, err := inputFileInfo(path, followSymlinks, logger)
+		if err != nil {
+			return err
+		}
+		if fileInfo != nil && !fileInfo.IsDir() && isModifiedSince(fileInfo, minTime) {
+			matchingFiles = append(matchingFiles, path)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// sort matchingFiles alphabetically to keep the order consistent
@@ -62,28 +127,154 @@ func findFiles(inputDir string, fileGlobPattern string, minTime time.Time) ([]st
 	return matchingFiles, nil
 }
 
+// inputFileInfo returns the file info of a found input file, the one of the target for
+// symlinks. Without followSymlinks nil is returned for symlinks. Dangling symlinks are
+// logged as warning and nil is returned as well.
+func inputFileInfo(path string, followSymlinks bool, logger *slog.Logger) (fs.FileInfo, error) {
+	linkInfo, err := os.Lstat(path)
+	if err != nil || linkInfo.Mode()&fs.ModeSymlink == 0 {
+		return linkInfo, err
+	}
+	if !followSymlinks {
+		if logger != nil {
+			logger.Debug("ignoring symlink", "file", path)
+		}
+		return nil, nil
+	}
+	fileInfo, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if logger != nil {
+			logger.Warn("skipping dangling symlink", "file", path)
+		}
+		return nil, nil
+	}
+	return fileInfo, err
+}
+
+// checkGlobPatterns returns an error if one of the patterns is malformed
+func checkGlobPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isExcluded reports whether the file name matches one of the glob patterns
+func isExcluded(name string, excludeGlobPatterns []string) bool {
+	for _, pattern := range excludeGlobPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isModifiedSince reports whether the file has been modified at or after minTime.
+// A minTime of zero time is considered matching all files.
+func isModifiedSince(fileInfo fs.FileInfo, minTime time.Time) bool {
+	if minTime.IsZero() {
+		return true
+	}
+	modTime := fileInfo.ModTime()
+	return modTime.After(minTime) || modTime.Equal(minTime)
+}
+
+// isExcludedDir reports whether path denotes one of the directories excludeDirs
+func isExcludedDir(path string, excludeDirs []string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range excludeDirs {
+		if absDir, err := filepath.Abs(dir); err == nil && absDir == abs {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	NotStartedYet        = iota // Conversion has not started yet
-	Skipped                     // File is skipped because it already exists in the output directory
+	Skipped                     // File is skipped by the overwrite policy as it already exists in the output directory
 	ConversionInProgress        // Conversion is in progress
 	ConversionError             // Conversion failed
 	ConversionSuccess           // Conversion was successful
+	WouldSkip                   // Dry run: File would be skipped, see Skipped
+	WouldFail                   // Dry run: Conversion would fail
+	WouldConvert                // Dry run: Conversion would be successful
+	SkippedEmpty                // File is skipped as it contains no entries, only with SkipEmpty
 )
 
 type ConversionStatus int
 
+// conversionStatuses is the mapping between ConversionStatus and its textual representation
+var conversionStatuses = map[ConversionStatus]string{
+	NotStartedYet:        "not-started",
+	Skipped:              "skipped",
+	ConversionInProgress: "in-progress",
+	ConversionError:      "failed",
+	ConversionSuccess:    "converted",
+	WouldSkip:            "would-skip",
+	WouldFail:            "would-fail",
+	WouldConvert:         "would-convert",
+	SkippedEmpty:         "skipped-empty",
+}
+
+// Returns the textual representation of the conversion status
+func (s ConversionStatus) String() string {
+	if text, ok := conversionStatuses[s]; ok {
+		return text
+	}
+	return "unknown conversion status"
+}
+
 // Conversion status of a single file
 type FileStatus struct {
 	InputFile  string               // Absolute path of the input file
 	OutputFile string               // Absolute path of the output file. Only set after conversion started.
 	Status     ConversionStatus     // Status of the conversion
+	SkipReason string               // Why the file was skipped, only set if Status is Skipped, SkippedEmpty or WouldSkip
 	Format     *parser.SourceFormat // Detected source format
+	Entries    int                  // Entries parsed from the input file, including the dropped and duplicate ones
+	RowErrors  []parser.ParserError // Data rows skipped in lenient mode
+	Dropped    int                  // Entries outside of the date range, not written to the output file
+	Duplicates int                  // Entries of previously converted files, not written in dedupe mode
+	Archived   string               // Path the input file was moved to after its conversion
+	MergedInto string               // Output file shared by all files of the set in merge mode
+	Error      error                // Reason of a failed conversion
+	StartedAt  time.Time            // When the conversion started, zero if not started yet
+	FinishedAt time.Time            // When the conversion ended with its final status, zero before
+	Attempts   int                  // Attempts to read and write the file, more than one if transient I/O errors were retried
+	// Reason why OnSuccessCommand failed, the conversion itself succeeded
+	CommandError error
+}
+
+// Duration returns the time taken by the conversion of the file, from parsing the input file
+// to writing the output file. It is zero if the conversion is not finished yet.
+func (f FileStatus) Duration() time.Duration {
+	if f.StartedAt.IsZero() || f.FinishedAt.IsZero() {
+		return 0
+	}
+	return f.FinishedAt.Sub(f.StartedAt)
+}
+
+// dryRunStatuses maps the final statuses of a conversion to the ones of a dry run
+var dryRunStatuses = map[ConversionStatus]ConversionStatus{
+	Skipped:           WouldSkip,
+	ConversionError:   WouldFail,
+	ConversionSuccess: WouldConvert,
+	SkippedEmpty:      WouldSkip,
 }
 
 // Conversion status of a batch
 type BatchSetStatus struct {
-	Files []FileStatus // Status of found files in batch
-	Name  string       // Name of the batch
+	Files    []FileStatus  // Status of found files in batch
+	Name     string        // Name of the batch
+	Duration time.Duration // Time taken by the conversion of the batch
+	Error    error         // Why the set was not converted, e.g. its output directory does not exist
+	Disabled bool          // The set is disabled in the settings and was not converted, see BatchConvertSet.Enabled
 }
 
 // GetStats calculates the number of files that are done and the number of files that are left in the batch set status.
@@ -98,9 +289,69 @@ func (b BatchSetStatus) GetStats() (done uint, left uint) {
 	return
 }
 
+// Summary contains the statistics of the conversion of a set or of all sets
+type Summary struct {
+	Name           string                   // Name of the set, empty for all sets
+	Files          map[ConversionStatus]int // Number of files per conversion status
+	Entries        int                      // Entries of the converted files, see FileStatus.Entries
+	FailedCommands int                      // Files whose OnSuccessCommand failed
+	FailedSets     int                      // Sets not converted, see BatchSetStatus.Error
+	DisabledSets   int                      // Sets skipped as they are disabled, see BatchSetStatus.Disabled
+	Error          error                    // Why the set was not converted, only set for a single set
+	Duration       time.Duration            // Time taken by the conversion
+	FileDuration   time.Duration            // Sum of the durations of the single files, see FileStatus.Duration
+	Sets           []Summary                // Summaries of the single sets, only set for all sets
+}
+
+// Summary returns the statistics of the conversion of the set
+func (b BatchSetStatus) Summary() Summary {
+	summary := Summary{
+		Name:     b.Name,
+		Files:    make(map[ConversionStatus]int),
+		Duration: b.Duration,
+	}
+	if b.Error != nil {
+		summary.FailedSets = 1
+		summary.Error = b.Error
+	}
+	if b.Disabled {
+		summary.DisabledSets = 1
+	}
+	for _, fileStatus := range b.Files {
+		summary.Files[fileStatus.Status]++
+		summary.FileDuration += fileStatus.Duration()
+		if fileStatus.CommandError != nil {
+			summary.FailedCommands++
+		}
+		if fileStatus.Status == ConversionSuccess || fileStatus.Status == WouldConvert {
+			summary.Entries += fileStatus.Entries
+		}
+	}
+	return summary
+}
+
 // Conversion status of all sets
 type BatchStatus []BatchSetStatus
 
+// Summary returns the statistics of the conversion of all sets and of each single set
+func (b BatchStatus) Summary() Summary {
+	summary := Summary{Files: make(map[ConversionStatus]int)}
+	for _, set := range b {
+		setSummary := set.Summary()
+		for status, count := range setSummary.Files {
+			summary.Files[status] += count
+		}
+		summary.Entries += setSummary.Entries
+		summary.FailedCommands += setSummary.FailedCommands
+		summary.FailedSets += setSummary.FailedSets
+		summary.DisabledSets += setSummary.DisabledSets
+		summary.Duration += setSummary.Duration
+		summary.FileDuration += setSummary.FileDuration
+		summary.Sets = append(summary.Sets, setSummary)
+	}
+	return summary
+}
+
 // StatusCallback is a function that is called during the conversion process
 // to report the progress of the conversion.
 //
@@ -110,18 +361,104 @@ type BatchStatus []BatchSetStatus
 //   - userData: any user data that was passed to the BatchConvert function.
 type StatusCallback func(s BatchStatus, userData interface{})
 
+// Option configures optional behaviour of BatchConvert
+type Option func(*options)
+
+type options struct {
+	logger   *slog.Logger
+	now      func() time.Time    // Returns the current time
+	debounce time.Duration       // Only used by Watch
+	only     map[string]struct{} // Used by Watch to convert only these of the found files
+	events   func(Event)         // Used by BatchConvertEvents
+}
+
+// WithLogger sets the logger for debug messages about the found files, the detected
+// formats and the status of each file, e.g. why it is skipped. It is also passed to the
+// parsers. Without a logger nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithClock sets the function returning the current time, time.Now by default. The time
+// at the start of the conversion is used to check the age of the input files and for the
+// date in the output names. The durations of the sets and files are measured with it as well.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// newOptions returns the options set by opts, with the defaults for the others
+func newOptions(opts []Option) options {
+	o := options{now: time.Now, debounce: DefaultDebounce}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // BatchConvert is a function that performs batch conversion of files.
 //
 // It takes the following parameters:
 //
 //   - s: a settings.BatchConvertSet struct containing the settings for the batch conversion.
-//   - now: a time.Time representing the current time.
 //   - c: a StatusCallback function that is called during the conversion process.
 //   - userData: any user data that was passed to the BatchConvert function.
+//   - opts: optional behaviour like WithLogger or WithClock.
 //
 // The converted files are placed in the output directory. The conversion happens only
 // if the file with the same name does not exist yet in the output directory.
-func BatchConvert(s settings.BatchConvertSettings, now time.Time, c StatusCallback, userData interface{}) (status BatchStatus, err error) {
+//
+// With s.DryRun nothing is written, the files get the status WouldSkip, WouldFail or
+// WouldConvert instead. The input files are still parsed.
+//
+// The files of a set are converted by s.Parallelism workers at the same time, one by
+// default. Sets in dedupe mode are always converted one file after the other. The calls
+// of c are serialized, the order of the files in the status does not depend on the
+// order in which their conversions complete.
+//
+// An error is only returned for invalid settings. If a set can't be converted, e.g. as its
+// output directory doesn't exist, the error is set in BatchSetStatus.Error, reported to c,
+// and the next set is converted. With CreateOutputDir a missing output directory of a set
+// is created.
+//
+// The files of a set are converted in the Order of the set. With MaxFilesPerRun only the
+// first files which are not skipped are converted, the others keep the status NotStartedYet.
+//
+// With merge mode all files of a set are converted into a single output file, one
+// after the other. Its path is set in FileStatus.MergedInto of each file.
+//
+// The OnSuccessCommand of a set is run after the successful conversion of each file. If
+// it fails, FileStatus.CommandError is set but the conversion still counts as successful.
+// The OnFinishCommand is not run, see RunOnFinishCommand.
+//
+// With Retries of a set, reading and writing a file is retried after transient I/O errors.
+// The attempts are counted in FileStatus.Attempts.
+//
+// With a state file, the converted input files are recorded in it and skipped as long as
+// they don't change, even if their output files are renamed. Once the state file has been
+// read, it decides alone which files are skipped: a changed input file is converted again
+// although its output file exists. A missing or invalid state file is replaced, with
+// s.ResetState it is not read. Then the existing output files decide as without state
+// file, the input files skipped by them are recorded in the state file. If the state file can't be saved,
+// the set fails after converting its files.
+//
+// Disabled sets are not converted. They are contained in the status without files and
+// with BatchSetStatus.Disabled set, so the status has an entry for each set of s.
+func BatchConvert(s settings.BatchConvertSettings, c StatusCallback, userData interface{}, opts ...Option) (status BatchStatus, err error) {
+	return BatchConvertContext(context.Background(), s, c, userData, opts...)
+}
+
+// BatchConvertContext is like BatchConvert, but stops when ctx is cancelled.
+//
+// The context is checked before each set and file, so the files in progress are
+// converted completely. On cancellation the status so far and the error of ctx
+// are returned, the remaining files keep the status NotStartedYet.
+func BatchConvertContext(ctx context.Context, s settings.BatchConvertSettings, c StatusCallback, userData interface{}, opts ...Option) (status BatchStatus, err error) {
+	o := newOptions(opts)
+	now := o.now()
 
 	if len(s.Sets) == 0 {
 		return nil, nil
@@ -130,92 +467,963 @@ func BatchConvert(s settings.BatchConvertSettings, now time.Time, c StatusCallba
 	if err := s.Sets.CheckValidity(); err != nil {
 		return nil, err
 	}
+	if _, err := parser.NewRules(s.Rules); err != nil {
+		return nil, fmt.Errorf("Rules are invalid: %w", err)
+	}
 
+	var mu sync.Mutex
 	for setNr, set := range s.Sets {
-		var fileInfo os.FileInfo
-		fileInfo, err = os.Stat(set.OutputDir)
-		if err != nil {
+		if err = ctx.Err(); err != nil {
 			return status, err
 		}
-		if !fileInfo.IsDir() {
-			return status, errors.New("outputDir is not a directory")
-		}
-
+		start := o.now()
 		status = append(status, BatchSetStatus{
 			Files: []FileStatus{},
 			Name:  set.Name,
 		})
+		if !set.GetEnabled() {
+			status[setNr].Disabled = true
+			if o.logger != nil {
+				o.logger.Info("skipping disabled set", "set", set.Name)
+			}
+			if c != nil {
+				c(status, userData)
+			}
+			continue
+		}
+		// The error of a set is reported in its status, the other sets are still converted
+		failSet := func(err error) {
+			status[setNr].Error = err
+			status[setNr].Duration = o.now().Sub(start)
+			if o.logger != nil {
+				o.logger.Info("set failed", "set", set.Name, "error", err)
+			}
+			if o.events != nil {
+				o.events(Event{Kind: SetErrorEvent, SetName: set.Name, Err: err})
+			}
+			if c != nil {
+				c(status, userData)
+			}
+		}
 
-		var fileList []string
-		fileList, err = findFiles(set.InputDir, set.FileGlobPattern, getTimeFromMaxAgeDays(uint(set.FileMaxAgeDays), now))
-		if err != nil {
-			return status, err
+		// A missing output directory is created first, in a dry run it is taken as empty
+		outputDirMissing := false
+		if set.CreateOutputDir {
+			// Stat also fails if a parent directory is a file, then creating reports it
+			_, statErr := os.Stat(set.OutputDir)
+			outputDirMissing = statErr != nil
+			if outputDirMissing && !s.DryRun {
+				if mkErr := os.MkdirAll(set.OutputDir, 0o700); mkErr != nil {
+					failSet(fmt.Errorf("cannot create output directory: %w", mkErr))
+					continue
+				}
+				if o.logger != nil {
+					o.logger.Info("created output directory", "set", set.Name, "dir", set.OutputDir)
+				}
+				outputDirMissing = false
+			}
+		}
+		if !outputDirMissing {
+			fileInfo, statErr := os.Stat(set.OutputDir)
+			if statErr != nil {
+				failSet(statErr)
+				continue
+			}
+			if !fileInfo.IsDir() {
+				failSet(errors.New("outputDir is not a directory"))
+				continue
+			}
+		}
+
+		sc := &setConversion{
+			set:            set,
+			setNr:          setNr,
+			now:            now,
+			clock:          o.now,
+			commandTimeout: s.CommandTimeout,
+			claimed:        make(map[string]string),
+			mu:             &mu,
+			status:         status,
+			dryRun:         s.DryRun,
+			c:              c,
+			userData:       userData,
+			logger:         o.logger,
+			events:         o.events,
+		}
+
+		// The rules of the set take precedence over the global rules
+		var setErr error
+		sc.rules, setErr = parser.NewRules(slices.Concat(set.Rules, s.Rules))
+		if setErr != nil {
+			failSet(setErr)
+			continue
+		}
+
+		sc.dateRange, setErr = set.GetDateRange()
+		if setErr != nil {
+			failSet(setErr)
+			continue
+		}
+
+		sc.output, setErr = set.GetOutputOptions()
+		if setErr != nil {
+			failSet(setErr)
+			continue
 		}
 
-		for _, infile := range fileList {
+		if stateFile := set.GetStateFile(); stateFile != "" {
+			var stateErr error
+			sc.state, stateErr = loadState(stateFile, s.ResetState)
+			if stateErr != nil && o.logger != nil {
+				o.logger.Warn("ignoring state file", "set", set.Name, "error", stateErr)
+			}
+		}
+
+		// Fingerprints of the entries in the output directory, only used in dedupe mode
+		if set.Dedupe && outputDirMissing {
+			sc.known = make(fingerprints)
+		} else if set.Dedupe {
+			sc.known, setErr = readFingerprints(set.OutputDir, set.PreserveStructure)
+			if setErr != nil {
+				failSet(setErr)
+				continue
+			}
+		}
+
+		fileList, setErr := findSetFiles(set, now, o.logger)
+		if setErr != nil {
+			failSet(setErr)
+			continue
+		}
+		if o.only != nil {
+			fileList = slices.DeleteFunc(fileList, func(file string) bool {
+				_, found := o.only[file]
+				return !found
+			})
+		}
+		if setErr = orderFiles(fileList, set.Order); setErr != nil {
+			failSet(setErr)
+			continue
+		}
+		if o.logger != nil {
+			o.logger.Debug("found input files", "set", set.Name, "dirs", set.GetInputDirs(), "files", fileList)
+		}
+
+		// Files whose output file collides with the one of another file are not converted
+		collisions := make([]error, len(fileList))
+		if !set.Merge {
+			sc.outfiles, collisions = outputFiles(fileList, set, now)
+		}
+		for fileNr, infile := range fileList {
+			if collisions[fileNr] != nil {
+				status[setNr].Files = append(status[setNr].Files, FileStatus{
+					InputFile:  infile,
+					OutputFile: sc.outfiles[fileNr],
+					Status:     sc.outcome(ConversionError),
+					Error:      collisions[fileNr]})
+				setTimes(&status[setNr].Files[fileNr], o.now())
+				sc.logStatus(status[setNr].Files[fileNr])
+				sc.emit(NotStartedYet, status[setNr].Files[fileNr])
+				continue
+			}
 			status[setNr].Files = append(status[setNr].Files, FileStatus{
 				InputFile: infile,
 				Status:    NotStartedYet})
+			sc.emit(NotStartedYet, status[setNr].Files[fileNr])
 		}
 		if c != nil {
 			c(status, userData)
 		}
 
-		for fileNr, infile := range fileList {
-			// get infile without extension
-			outfileBasename := strings.TrimSuffix(infile, filepath.Ext(infile)) + ".csv"
-			outfile := filepath.Join(set.OutputDir, filepath.Base(outfileBasename))
-			status[setNr].Files[fileNr].OutputFile = outfile
-
-			// Skip if output file already exists
-			if _, err := os.Stat(outfile); err == nil {
-				status[setNr].Files[fileNr].Status = Skipped
-				if c != nil {
-					c(status, userData)
-				}
-				continue
+		if set.Merge {
+			err = sc.mergeFiles(ctx, fileList, filepath.Join(set.OutputDir, set.GetMergedOutputName(now)))
+		} else {
+			// The entries of a file are deduplicated against the ones of the previous files
+			parallelism := s.Parallelism
+			if parallelism < 1 || set.Dedupe {
+				parallelism = 1
+			}
+			err = sc.convertFiles(ctx, fileList, collisions, sc.deferredFiles(fileList, collisions), parallelism)
+		}
+		// Also saved if cancelled, for the files converted so far
+		if sc.state != nil && !s.DryRun {
+			if stateErr := sc.state.save(); stateErr != nil {
+				failSet(fmt.Errorf("cannot save state file: %w", stateErr))
 			}
+		}
+		status[setNr].Duration = o.now().Sub(start)
+		if err != nil {
+			return status, err
+		}
+	}
+	return
 
-			var fileParser parser.Parser
-			status[setNr].Files[fileNr].Status = ConversionInProgress
-			if c != nil {
-				c(status, userData)
+}
+
+// findSetFiles returns the input files of the set, the ones of each input directory in
+// turn. The output and archive directories are left out in case they are within an input
+// directory. Files found in several input directories, e.g. nested ones, are returned once.
+// Skipped symlinks are logged to logger, nil disables logging.
+func findSetFiles(set settings.BatchConvertSet, now time.Time, logger *slog.Logger) ([]string, error) {
+	if logger != nil {
+		logger = logger.With("set", set.Name)
+	}
+	minTime := getTimeFromMaxAge(set.FileMaxAge, now)
+	excludeDirs := []string{set.OutputDir, set.GetArchiveDir()}
+	var files []string
+	found := make(map[string]bool)
+	for _, inputDir := range set.GetInputDirs() {
+		var dirFiles []string
+		var err error
+		if set.Recursive {
+			dirFiles, err = findFilesRecursive(inputDir, set.FileGlobPattern, set.ExcludeGlobPatterns, minTime, excludeDirs, set.GetFollowSymlinks(), logger)
+		} else {
+			dirFiles, err = findFiles(inputDir, set.FileGlobPattern, set.ExcludeGlobPatterns, minTime, excludeDirs, set.GetFollowSymlinks(), logger)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range dirFiles {
+			if !found[file] {
+				found[file] = true
+				files = append(files, file)
 			}
+		}
+	}
+	return files, nil
+}
 
-			if set.Format == nil {
-				fileParser = parser.GetGuessedParser(infile)
-				if fileParser == nil {
-					status[setNr].Files[fileNr].Status = ConversionError
-					if c != nil {
-						c(status, userData)
-					}
-					continue
-				}
-			} else {
-				fileParser = parser.GetParser(*set.Format)
-				if err := fileParser.ParseFile(infile); err != nil {
-					status[setNr].Files[fileNr].Status = ConversionError
-					if c != nil {
-						c(status, userData)
-					}
+// orderFiles sorts the files found by findSetFiles by the order of the set. With
+// OrderByName they are kept in the order found, by path within each input directory.
+// Files with the same modification time are kept in that order as well.
+func orderFiles(files []string, order settings.FileOrder) error {
+	if order == settings.OrderByName {
+		return nil
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+	slices.SortStableFunc(files, func(a, b string) int {
+		if order == settings.OrderByMtimeDesc {
+			return modTimes[b].Compare(modTimes[a])
+		}
+		return modTimes[a].Compare(modTimes[b])
+	})
+	return nil
+}
+
+// setConversion is the conversion of the files of a single set
+type setConversion struct {
+	set            settings.BatchConvertSet
+	setNr          int
+	rules          *parser.Rules
+	dateRange      parser.DateRange
+	output         parser.OutputOptions
+	known          fingerprints     // Only used in dedupe mode
+	now            time.Time        // Current time at the start of the conversion
+	clock          func() time.Time // Returns the current time
+	commandTimeout time.Duration
+	outfiles       []string          // Output files of the input files, "" if their name depends on the records
+	claimed        map[string]string // Input files of the output files named by the records, by lower case name
+	state          *conversionState  // Previously converted input files, nil without state file
+
+	mu       *sync.Mutex // Serializes the updates of status and the calls of c
+	status   BatchStatus
+	dryRun   bool
+	c        StatusCallback
+	userData interface{}
+	logger   *slog.Logger // nil disables logging
+	events   func(Event)  // Called with each status change, nil if not used
+}
+
+// convertFiles converts the files without collision error which are not deferred with the
+// given number of workers. It returns the error of ctx if files have not been converted
+// because of a cancellation.
+func (sc *setConversion) convertFiles(ctx context.Context, files []string, collisions []error, deferred []bool, parallelism int) error {
+	var cancelled atomic.Bool
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileNr := range jobs {
+				// Checked again as the job may have been queued before the cancellation
+				if ctx.Err() != nil {
+					cancelled.Store(true)
 					continue
 				}
+				sc.convertFile(ctx, fileNr, files[fileNr])
 			}
-			status[setNr].Files[fileNr].Format = parser.NewSourceFormat(fileParser.GetFormat())
-			if err := fileParser.ConvertToHomebank(outfile); err != nil {
-				status[setNr].Files[fileNr].Status = ConversionError
-				if c != nil {
-					c(status, userData)
-				}
+		}()
+	}
+	for fileNr := range files {
+		if ctx.Err() != nil {
+			cancelled.Store(true)
+			break
+		}
+		if collisions[fileNr] == nil && !deferred[fileNr] {
+			jobs <- fileNr
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if cancelled.Load() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// deferredFiles returns which files are left for the next run as MaxFilesPerRun files are
+// converted before them. Files which are skipped as their output file exists and files with
+// collision error don't count, so each run converts further files.
+func (sc *setConversion) deferredFiles(files []string, collisions []error) []bool {
+	deferred := make([]bool, len(files))
+	if sc.set.MaxFilesPerRun == 0 {
+		return deferred
+	}
+	converted := 0
+	for fileNr, infile := range files {
+		if collisions[fileNr] != nil {
+			continue
+		}
+		if _, found := sc.convertedBefore(infile); found {
+			continue
+		}
+		// In hash skip mode and with names depending on the records it is only known after parsing
+		outfile := sc.outfiles[fileNr]
+		if outfile != "" && sc.set.SkipMode == settings.SkipByName && !sc.stateDecides() {
+			if reason, err := skipReason(sc.set.Overwrite, infile, outfile); err == nil && reason != "" {
 				continue
 			}
-			status[setNr].Files[fileNr].Status = ConversionSuccess
-			if c != nil {
-				c(status, userData)
+		}
+		if converted == sc.set.MaxFilesPerRun {
+			deferred[fileNr] = true
+			continue
+		}
+		converted++
+	}
+	return deferred
+}
+
+// convertedBefore returns the state entry of infile if it is skipped as it was converted
+// before according to the state file and didn't change since. With OverwriteAlways the
+// state file is not consulted.
+func (sc *setConversion) convertedBefore(infile string) (stateEntry, bool) {
+	if sc.state == nil || sc.set.Overwrite == settings.OverwriteAlways {
+		return stateEntry{}, false
+	}
+	return sc.state.converted(infile)
+}
+
+// stateDecides reports whether the state file alone decides which files are skipped, so
+// existing output files are overwritten. This is the case once the state file has been
+// read, except with OverwriteAlways.
+func (sc *setConversion) stateDecides() bool {
+	return sc.state != nil && sc.state.loaded && sc.set.Overwrite != settings.OverwriteAlways
+}
+
+// recordConverted records infile with its output file in the state file, if any
+func (sc *setConversion) recordConverted(infile string, outfile string) {
+	if sc.state == nil || sc.dryRun {
+		return
+	}
+	if err := sc.state.record(infile, outfile, sc.clock()); err != nil && sc.logger != nil {
+		sc.logger.Warn("cannot record converted file in state file", "set", sc.set.Name, "file", infile, "error", err)
+	}
+}
+
+// outcome returns the status of a finished conversion, the one of the dry run if enabled
+func (sc *setConversion) outcome(s ConversionStatus) ConversionStatus {
+	if sc.dryRun {
+		return dryRunStatuses[s]
+	}
+	return s
+}
+
+// update changes the status of the file with f and reports the status to the callback
+func (sc *setConversion) update(fileNr int, f func(*FileStatus)) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	file := &sc.status[sc.setNr].Files[fileNr]
+	previous := file.Status
+	f(file)
+	if file.Status != previous {
+		setTimes(file, sc.clock())
+		sc.logStatus(*file)
+		sc.emit(previous, *file)
+	}
+	if sc.c != nil {
+		sc.c(sc.status, sc.userData)
+	}
+}
+
+// setTimes sets the start and end time of the file after its status changed at now
+func setTimes(f *FileStatus, now time.Time) {
+	switch f.Status {
+	case NotStartedYet:
+		f.StartedAt, f.FinishedAt = time.Time{}, time.Time{}
+	case ConversionInProgress:
+		f.StartedAt = now
+	default:
+		if f.StartedAt.IsZero() {
+			f.StartedAt = now
+		}
+		f.FinishedAt = now
+	}
+}
+
+// logStatus logs the status of a file after it changed
+func (sc *setConversion) logStatus(f FileStatus) {
+	if sc.logger == nil {
+		return
+	}
+	logger := sc.logger.With("set", sc.set.Name, "file", f.InputFile)
+	switch f.Status {
+	case ConversionInProgress:
+		logger.Debug("converting file", "output", f.OutputFile)
+	case Skipped, SkippedEmpty, WouldSkip:
+		logger.Info("skipped file", "output", f.OutputFile, "reason", f.SkipReason)
+	case ConversionError, WouldFail:
+		logger.Info("conversion failed", "error", f.Error)
+	case ConversionSuccess, WouldConvert:
+		logger.Info("converted file", "output", f.OutputFile, "entries", f.Entries, "dropped", f.Dropped, "duplicates", f.Duplicates)
+		if f.CommandError != nil {
+			logger.Info("success command failed", "error", f.CommandError)
+		}
+	}
+}
+
+// parseInputFile parses the input file with the parser of the given format, if nil
+// the format is guessed. On error the parser is returned if the format is known.
+// It is a variable to be replaced in tests.
+var parseInputFile = parser.ParseFileAs
+
+// outputFiles returns the output files in the OutputDir of the set for the input files.
+// The output file has the name of the input file with the extension ".csv", placed in
+// the same subdirectory relative to its input directory with PreserveStructure.
+//
+// If this is the same for several input files, ignoring case, the extension of the
+// input file is kept with DisambiguateOutputNames, e.g. "Umsaetze.xlsx.csv". Without
+// PreserveStructure the subdirectory is prepended then, e.g. "2023_Umsaetze.xlsx.csv".
+// With several input directories the name of the input directory is prepended as well,
+// e.g. "Downloads_Umsaetze.csv.csv". Otherwise, and if the output files still collide,
+// the error of these files in collisions is set.
+//
+// With OutputNameTemplate the output files are named by the template instead. If the
+// name depends on the records, the output file is "" and only known after parsing.
+func outputFiles(infiles []string, set settings.BatchConvertSet, now time.Time) (outfiles []string, collisions []error) {
+	if set.OutputNameTemplate != "" {
+		return templateOutputFiles(infiles, set, now)
+	}
+	inputDirs := set.GetInputDirs()
+	// Path of the input files relative to their input directory, only the base name if
+	// flattened. With several input directories the name of the input directory is kept
+	// in qualifiedNames to tell apart the files with the same path.
+	names := make([]string, len(infiles))
+	qualifiedNames := make([]string, len(infiles))
+	for i, infile := range infiles {
+		inputDir, rel := relativePath(infile, inputDirs)
+		names[i] = rel
+		qualifiedNames[i] = rel
+		if len(inputDirs) > 1 {
+			qualifiedNames[i] = filepath.Join(filepath.Base(inputDir), rel)
+		}
+	}
+	outfile := func(name string) string {
+		if !set.PreserveStructure {
+			name = filepath.Base(name)
+		}
+		return filepath.Join(set.OutputDir, name)
+	}
+
+	outfiles = make([]string, len(infiles))
+	for i, name := range names {
+		outfiles[i] = outfile(strings.TrimSuffix(name, filepath.Ext(name)) + ".csv")
+	}
+	if set.DisambiguateOutputNames {
+		for _, group := range collidingFiles(outfiles) {
+			for _, i := range group {
+				name := qualifiedNames[i]
+				if !set.PreserveStructure {
+					name = strings.ReplaceAll(name, string(filepath.Separator), "_")
+				}
+				outfiles[i] = outfile(name + ".csv")
 			}
+		}
+	}
+	collisions = make([]error, len(infiles))
+	for _, group := range collidingFiles(outfiles) {
+		groupNames := make([]string, 0, len(group))
+		for _, i := range group {
+			groupNames = append(groupNames, "'"+qualifiedNames[i]+"'")
+		}
+		for _, i := range group {
+			collisions[i] = fmt.Errorf("output file '%s' collides, it is the same for the input files %s. "+
+				"Rename them or set 'disambiguateoutputnames'", filepath.Base(outfiles[i]), strings.Join(groupNames, ", "))
+		}
+	}
+	return outfiles, collisions
+}
 
+// templateOutputFiles returns the output files named by OutputNameTemplate for the
+// input files, see outputFiles
+func templateOutputFiles(infiles []string, set settings.BatchConvertSet, now time.Time) (outfiles []string, collisions []error) {
+	outfiles = make([]string, len(infiles))
+	collisions = make([]error, len(infiles))
+	if set.OutputNameNeedsRecords() {
+		return outfiles, collisions
+	}
+	for i, infile := range infiles {
+		outfiles[i], collisions[i] = outputFile(infile, set, settings.OutputNameValues{Today: now})
+	}
+	for _, group := range collidingFiles(outfiles) {
+		groupNames := make([]string, 0, len(group))
+		for _, i := range group {
+			groupNames = append(groupNames, "'"+filepath.Base(infiles[i])+"'")
+		}
+		for _, i := range group {
+			collisions[i] = fmt.Errorf("output file '%s' collides, it is the same for the input files %s. "+
+				"Add '{basename}' to 'outputnametemplate'", filepath.Base(outfiles[i]), strings.Join(groupNames, ", "))
 		}
 	}
-	return
+	return outfiles, collisions
+}
+
+// outputFile returns the output file of infile named by OutputNameTemplate with the
+// values, placed in the same subdirectory relative to its input directory with
+// PreserveStructure. The base name of infile is filled in.
+func outputFile(infile string, set settings.BatchConvertSet, v settings.OutputNameValues) (string, error) {
+	_, rel := relativePath(infile, set.GetInputDirs())
+	v.BaseName = strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	name, err := set.GetOutputName(v)
+	if err != nil {
+		return "", err
+	}
+	dir := set.OutputDir
+	if set.PreserveStructure {
+		dir = filepath.Join(dir, filepath.Dir(rel))
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// recordOutputFile returns the output file of infile named by OutputNameTemplate with the
+// format and the date range of the records of the parsed file. An error is returned if
+// another input file of the set has the same output file.
+func (sc *setConversion) recordOutputFile(infile string, p parser.Parser) (string, error) {
+	v := settings.OutputNameValues{
+		Format: p.GetFormat().String(),
+		Today:  sc.now,
+	}
+	for _, entry := range p.GetEntries() {
+		if v.FirstDate.IsZero() || entry.Date.Before(v.FirstDate) {
+			v.FirstDate = entry.Date
+		}
+		if v.LastDate.IsZero() || entry.Date.After(v.LastDate) {
+			v.LastDate = entry.Date
+		}
+	}
+	outfile, err := outputFile(infile, sc.set, v)
+	if err != nil {
+		return "", err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	key := strings.ToLower(outfile)
+	if other, found := sc.claimed[key]; found {
+		return outfile, fmt.Errorf("output file '%s' collides with the one of '%s'", filepath.Base(outfile), filepath.Base(other))
+	}
+	sc.claimed[key] = infile
+	return outfile, nil
+}
+
+// relativePath returns the first of inputDirs containing file and the path of file
+// relative to it. If none contains it, the base name of file is returned.
+func relativePath(file string, inputDirs []string) (inputDir string, rel string) {
+	for _, dir := range inputDirs {
+		rel, err := filepath.Rel(dir, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir, rel
+		}
+	}
+	return "", filepath.Base(file)
+}
+
+// collidingFiles returns the groups of indices of files with the same name, ignoring
+// case as not all file systems are case-sensitive.
+func collidingFiles(files []string) [][]int {
+	indices := make(map[string][]int, len(files))
+	var keys []string
+	for i, file := range files {
+		if file == "" {
+			continue
+		}
+		key := strings.ToLower(file)
+		if _, found := indices[key]; !found {
+			keys = append(keys, key)
+		}
+		indices[key] = append(indices[key], i)
+	}
+	var groups [][]int
+	for _, key := range keys {
+		if len(indices[key]) > 1 {
+			groups = append(groups, indices[key])
+		}
+	}
+	return groups
+}
+
+// skipReason returns why infile is not converted to the existing outfile according to
+// the overwrite policy, or "" if it is converted.
+func skipReason(policy settings.OverwritePolicy, infile string, outfile string) (string, error) {
+	outInfo, err := os.Stat(outfile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	switch policy {
+	case settings.OverwriteAlways:
+		return "", nil
+	case settings.OverwriteIfNewer:
+		inInfo, err := os.Stat(infile)
+		if err != nil {
+			return "", err
+		}
+		// Equal timestamps are considered up to date
+		if inInfo.ModTime().After(outInfo.ModTime()) {
+			return "", nil
+		}
+		return "output file is not older than input file", nil
+	default:
+		return "output file exists", nil
+	}
+}
+
+// convertFile converts a single file of the set
+func (sc *setConversion) convertFile(ctx context.Context, fileNr int, infile string) {
+	set := sc.set
+
+	outfile := sc.outfiles[fileNr]
+	failed := func(p parser.Parser, err error) {
+		sc.update(fileNr, func(f *FileStatus) {
+			if outfile != "" {
+				f.OutputFile = outfile
+			}
+			if p != nil {
+				f.Format = parser.NewSourceFormat(p.GetFormat())
+			}
+			f.Status = sc.outcome(ConversionError)
+			f.Error = err
+		})
+	}
+
+	if entry, found := sc.convertedBefore(infile); found {
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = entry.OutputFile
+			f.Status = sc.outcome(Skipped)
+			f.SkipReason = "converted before according to the state file"
+		})
+		return
+	}
+
+	// The name of the output file depends on the records, so the file is parsed first
+	var fileParser parser.Parser
+	if outfile == "" {
+		sc.update(fileNr, func(f *FileStatus) {
+			f.Status = ConversionInProgress
+			f.Attempts = 1
+		})
+		err := sc.retry(ctx, func() (err error) {
+			fileParser, err = sc.parse(infile)
+			return err
+		}, fileNr)
+		if err != nil {
+			failed(fileParser, err)
+			return
+		}
+		sc.configure(fileParser)
+		if sc.skipEmpty(fileNr, fileParser) {
+			return
+		}
+		outfile, err = sc.recordOutputFile(infile, fileParser)
+		if err != nil {
+			failed(fileParser, err)
+			return
+		}
+	}
+
+	// Without state file deciding, the existing output file decides
+	var reason string
+	var err error
+	if !sc.stateDecides() {
+		reason, err = skipReason(set.Overwrite, infile, outfile)
+		if err != nil {
+			failed(fileParser, err)
+			return
+		}
+	}
+	// In hash skip mode the content decides whether the file is skipped
+	if reason != "" && set.SkipMode != settings.SkipByHash {
+		sc.recordConverted(infile, outfile)
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = outfile
+			f.Status = sc.outcome(Skipped)
+			f.SkipReason = reason
+		})
+		return
+	}
+
+	sc.update(fileNr, func(f *FileStatus) {
+		f.OutputFile = outfile
+		f.Status = ConversionInProgress
+		f.Attempts = max(f.Attempts, 1)
+	})
+
+	if fileParser == nil {
+		err = sc.retry(ctx, func() (err error) {
+			fileParser, err = sc.parse(infile)
+			return err
+		}, fileNr)
+		if err != nil {
+			failed(fileParser, err)
+			return
+		}
+		sc.configure(fileParser)
+		if sc.skipEmpty(fileNr, fileParser) {
+			return
+		}
+	}
+
+	if set.PreserveStructure && !sc.dryRun {
+		if err := os.MkdirAll(filepath.Dir(outfile), 0o700); err != nil {
+			sc.update(fileNr, func(f *FileStatus) {
+				f.Status = ConversionError
+				f.Error = err
+			})
+			return
+		}
+	}
+
+	var duplicates int
+	var identical bool
+	target := outfile
+	err = sc.retry(ctx, func() (err error) {
+		switch {
+		case reason != "":
+			target, identical, err = convertIfChanged(fileParser, outfile, sc.dryRun)
+		case set.Dedupe:
+			duplicates, err = convertDeduped(fileParser, outfile, sc.known, sc.output, sc.dryRun)
+		case sc.dryRun:
+			// Converted as usual for the number of dropped entries
+			err = fileParser.WriteHomebank(io.Discard)
+		default:
+			err = atomicfile.Write(outfile, outputFilePerm, fileParser.WriteHomebank)
+		}
+		return err
+	}, fileNr)
+	outfile = target
+	if err == nil && identical {
+		sc.recordConverted(infile, outfile)
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = outfile
+			f.Format = parser.NewSourceFormat(fileParser.GetFormat())
+			f.Entries = fileParser.GetNumberOfEntries()
+			f.RowErrors = fileParser.GetRowErrors()
+			f.Dropped = fileParser.GetNumberOfDroppedEntries()
+			f.Status = sc.outcome(Skipped)
+			f.SkipReason = "output file has the same content"
+		})
+		return
+	}
+	if err != nil {
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = outfile
+			f.Format = parser.NewSourceFormat(fileParser.GetFormat())
+			f.RowErrors = fileParser.GetRowErrors()
+			f.Status = sc.outcome(ConversionError)
+			f.Error = err
+		})
+		return
+	}
+
+	sc.recordConverted(infile, outfile)
+
+	// The input file is only moved or deleted after its successful conversion
+	archived, err := sc.handleConverted(infile)
+	var commandErr error
+	if err == nil {
+		commandErr = sc.runSuccessCommand(ctx, outfile)
+	}
+	sc.update(fileNr, func(f *FileStatus) {
+		f.OutputFile = outfile
+		f.Format = parser.NewSourceFormat(fileParser.GetFormat())
+		f.Entries = fileParser.GetNumberOfEntries()
+		f.RowErrors = fileParser.GetRowErrors()
+		f.Dropped = fileParser.GetNumberOfDroppedEntries()
+		f.Duplicates = duplicates
+		f.Archived = archived
+		f.Status = sc.outcome(ConversionSuccess)
+		f.CommandError = commandErr
+		if err != nil {
+			f.Status = ConversionError
+			f.Error = err
+		}
+	})
+}
+
+// skipEmpty sets the status of the file to SkippedEmpty and returns true if the parsed
+// file contains no entries and SkipEmpty is set
+func (sc *setConversion) skipEmpty(fileNr int, p parser.Parser) bool {
+	if !sc.set.SkipEmpty || p.GetNumberOfEntries() > 0 {
+		return false
+	}
+	sc.update(fileNr, func(f *FileStatus) {
+		f.Format = parser.NewSourceFormat(p.GetFormat())
+		f.RowErrors = p.GetRowErrors()
+		f.Status = sc.outcome(SkippedEmpty)
+		f.SkipReason = "input file contains no entries"
+	})
+	return true
+}
+
+// parse parses the input file with the parse options of the set. The formats of the set
+// are tried one after the other until one succeeds, without formats the format is guessed.
+// If all formats fail, no parser is returned.
+func (sc *setConversion) parse(infile string) (parser.Parser, error) {
+	set := sc.set
+	o := parser.ParseOptions{
+		Lenient:                 set.Lenient,
+		Encoding:                set.Encoding,
+		ComdirectValutaFallback: set.ComdirectValutaFallback,
+	}
+	if sc.logger != nil {
+		o.Logger = sc.logger.With("set", set.Name, "file", infile)
+	}
+	switch len(set.Format) {
+	case 0:
+		return parseAs(infile, nil, o)
+	case 1:
+		return parseAs(infile, &set.Format[0], o)
+	}
+	var failures []string
+	for _, format := range set.Format {
+		p, err := parseAs(infile, &format, o)
+		if err == nil {
+			return p, nil
+		}
+		// Reading the file fails the same way for the other formats
+		var parserErr *parser.ParserError
+		if errors.As(err, &parserErr) && parserErr.ErrorType == parser.IOError {
+			return nil, err
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", format, err))
+	}
+	return nil, fmt.Errorf("none of the formats matches (%s)", strings.Join(failures, "; "))
+}
 
+// parseAs parses the input file with the format, nil to guess it
+func parseAs(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+	p, err := parseInputFile(infile, format, o)
+	if p != nil && o.Logger != nil {
+		o.Logger.Debug("parsed file", "format", p.GetFormat().String(), "entries", p.GetNumberOfEntries(), "rowErrors", len(p.GetRowErrors()), "error", err)
+	}
+	return p, err
+}
+
+// configure applies the conversion settings of the set to the parser
+func (sc *setConversion) configure(p parser.Parser) {
+	set := sc.set
+	p.SetDateRange(sc.dateRange)
+	p.SetCategoryPrefix(set.CategoryPrefix, set.CategoryPrefixAlways)
+	p.SetFieldRouting(set.RouteInfoToMemo, set.RouteMemoToInfo)
+	p.SetUnicodeNormalization(!set.NoUnicodeNormalization)
+	p.SetFormatOptions(parser.FormatOptions{
+		SkipSecurityTrades: set.SkipSecurityTrades,
+		OwnAccounts:        set.OwnAccounts,
+		GnuCashAccount:     set.GnuCashAccount,
+		OutbankAccount:     set.OutbankAccount,
+		PaymentTypes:       set.PaymentTypes,
+	})
+	p.SetSortOrder(set.Sort)
+	p.SetRules(sc.rules)
+	p.SetOutputOptions(sc.output)
+}
+
+// handleConverted moves or deletes the converted input file according to OnSuccess.
+// It returns the path the file was moved to. Nothing is done in a dry run.
+func (sc *setConversion) handleConverted(infile string) (archived string, err error) {
+	if sc.dryRun {
+		return "", nil
+	}
+	switch sc.set.OnSuccess {
+	case settings.OnSuccessMove:
+		archived, err = archiveFile(infile, sc.set.GetArchiveDir())
+		if err != nil {
+			err = fmt.Errorf("converted, but cannot move input file: %w", err)
+		}
+	case settings.OnSuccessDelete:
+		if err = os.Remove(infile); err != nil {
+			err = fmt.Errorf("converted, but cannot delete input file: %w", err)
+		}
+	}
+	return archived, err
+}
+
+// convertIfChanged converts the entries of the parser in hash skip mode, where outfile exists.
+//
+// The content is compared with outfile and the files with a numeric suffix, e.g.
+// "Umsaetze-2.csv", "Umsaetze-3.csv". If one of them has the same content, it is returned
+// with identical set. Otherwise the content is written to the first of them which does not
+// exist yet, unless dryRun is set, and that file is returned.
+func convertIfChanged(p parser.Parser, outfile string, dryRun bool) (target string, identical bool, err error) {
+	var content bytes.Buffer
+	if err := p.WriteHomebank(&content); err != nil {
+		return outfile, false, err
+	}
+	ext := filepath.Ext(outfile)
+	base := strings.TrimSuffix(outfile, ext)
+	for i := 1; ; i++ {
+		target = outfile
+		if i > 1 {
+			target = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		existing, err := os.ReadFile(target)
+		if errors.Is(err, fs.ErrNotExist) {
+			if dryRun {
+				return target, false, nil
+			}
+			return target, false, atomicfile.Write(target, outputFilePerm, func(w io.Writer) error {
+				_, err := w.Write(content.Bytes())
+				return err
+			})
+		}
+		if err != nil {
+			return target, false, err
+		}
+		if bytes.Equal(existing, content.Bytes()) {
+			return target, true, nil
+		}
+	}
+}
+
+// convertDeduped writes the entries of the parser to outfile except for the ones known
+// from previously converted files. The written entries are added to known.
+// It returns the number of suppressed entries. With dryRun nothing is written.
+func convertDeduped(p parser.Parser, outfile string, known fingerprints, output parser.OutputOptions, dryRun bool) (int, error) {
+	entries, duplicates := known.removeDuplicates(p.GetEntries(), outfile)
+	if !dryRun {
+		if err := writeEntries(outfile, entries, output); err != nil {
+			return 0, err
+		}
+	}
+	known.add(entries, outfile)
+	return duplicates, nil
 }
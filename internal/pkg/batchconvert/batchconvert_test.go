@@ -1,11 +1,16 @@
 package batchconvert
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"testing"
@@ -33,6 +38,9 @@ type findFilesInputDataList []findFilesInputData
 func (f fileList) createFiles(directory string) error {
 	for _, entry := range f {
 		filePath := filepath.Join(directory, entry.Filename)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+			return err
+		}
 		file, err := os.Create(filePath)
 		if err != nil {
 			return err
@@ -48,6 +56,18 @@ func (f fileList) createFiles(directory string) error {
 	return nil
 }
 
+// clearDurations sets the durations of the sets and the start and end times of the
+// files to zero to compare the status of different runs
+func clearDurations(status BatchStatus) {
+	for i := range status {
+		status[i].Duration = 0
+		for j := range status[i].Files {
+			status[i].Files[j].StartedAt = time.Time{}
+			status[i].Files[j].FinishedAt = time.Time{}
+		}
+	}
+}
+
 func getFilesInDirectory(dir string) ([]string, error) {
 	var files []string
 
@@ -146,9 +166,26 @@ func copyFile(src string, dst string) error {
 	}
 }
 
+func TestGetTimeFromMaxAge(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		maxAge   settings.MaxAge
+		expected time.Time
+	}{
+		{0, time.Time{}},
+		{settings.MaxAgeDays(-1), time.Time{}},
+		{settings.MaxAgeDays(2), time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)},
+		{settings.MaxAge(36 * time.Hour), time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+	} {
+		if minTime := getTimeFromMaxAge(tc.maxAge, now); !minTime.Equal(tc.expected) {
+			t.Errorf("Expected %s for %s, got %s", tc.expected, tc.maxAge, minTime)
+		}
+	}
+}
+
 func TestFindFiles(t *testing.T) {
 
-	outList, err := findFiles("", "", time.Time{})
+	outList, err := findFiles("", "", nil, time.Time{}, nil, true, nil)
 	if err != nil {
 		t.Fatalf("findFiles return error '%s'", err)
 	}
@@ -156,7 +193,7 @@ func TestFindFiles(t *testing.T) {
 		t.Fatalf("findFiles should return nil list")
 	}
 
-	outList, err = findFiles("non-existent-path", "*", time.Time{})
+	outList, err = findFiles("non-existent-path", "*", nil, time.Time{}, nil, true, nil)
 	if err != nil {
 		t.Fatalf("findFiles return error '%s'", err)
 	}
@@ -164,7 +201,7 @@ func TestFindFiles(t *testing.T) {
 		t.Fatalf("findFiles should return nil list")
 	}
 
-	_, err = findFiles("non-existent-path", "[", time.Time{})
+	_, err = findFiles("non-existent-path", "[", nil, time.Time{}, nil, true, nil)
 	if err == nil {
 		t.Fatalf("findFiles should return error")
 	}
@@ -172,42 +209,42 @@ func TestFindFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	now := time.Now()
 	testFiles := &fileList{
-		{"file1.ext1", getTimeFromMaxAgeDays(2, now)},
-		{"file2.ext2", getTimeFromMaxAgeDays(3, now)},
-		{"file3.csv", getTimeFromMaxAgeDays(0, now)},
-		{"file4.csv", getTimeFromMaxAgeDays(1, now)},
-		{"file5.csv", getTimeFromMaxAgeDays(2, now)}}
+		{"file1.ext1", getTimeFromMaxAge(settings.MaxAgeDays(2), now)},
+		{"file2.ext2", getTimeFromMaxAge(settings.MaxAgeDays(3), now)},
+		{"file3.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{"file4.csv", getTimeFromMaxAge(settings.MaxAgeDays(1), now)},
+		{"file5.csv", getTimeFromMaxAge(settings.MaxAgeDays(2), now)}}
 	if err := testFiles.createFiles(tmpDir); err != nil {
 		t.Fatalf("Failed to create files in '%s'", tmpDir)
 	}
 
 	input := &findFilesInputDataList{
-		{"", getTimeFromMaxAgeDays(0, now), []string{
+		{"", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
 			filepath.Join(tmpDir, "file1.ext1"),
 			filepath.Join(tmpDir, "file2.ext2"),
 			filepath.Join(tmpDir, "file3.csv"),
 			filepath.Join(tmpDir, "file4.csv"),
 			filepath.Join(tmpDir, "file5.csv")}},
-		{"*.ext1", getTimeFromMaxAgeDays(0, now), []string{
+		{"*.ext1", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
 			filepath.Join(tmpDir, "file1.ext1")}},
-		{"*.ext2", getTimeFromMaxAgeDays(0, now), []string{
+		{"*.ext2", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
 			filepath.Join(tmpDir, "file2.ext2")}},
-		{"*.csv", getTimeFromMaxAgeDays(0, now), []string{
+		{"*.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
 			filepath.Join(tmpDir, "file3.csv"),
 			filepath.Join(tmpDir, "file4.csv"),
 			filepath.Join(tmpDir, "file5.csv")}},
-		{"*.csv", getTimeFromMaxAgeDays(1, now), []string{
+		{"*.csv", getTimeFromMaxAge(settings.MaxAgeDays(1), now), []string{
 			filepath.Join(tmpDir, "file3.csv"),
 			filepath.Join(tmpDir, "file4.csv")}},
-		{"*.csv", getTimeFromMaxAgeDays(2, now), []string{
+		{"*.csv", getTimeFromMaxAge(settings.MaxAgeDays(2), now), []string{
 			filepath.Join(tmpDir, "file3.csv"),
 			filepath.Join(tmpDir, "file4.csv"),
 			filepath.Join(tmpDir, "file5.csv")}},
-		{"*.ext*", getTimeFromMaxAgeDays(1, now), []string{}},
+		{"*.ext*", getTimeFromMaxAge(settings.MaxAgeDays(1), now), []string{}},
 	}
 
 	for nr, entry := range *input {
-		outList, err := findFiles(tmpDir, entry.FileGlobPattern, entry.MinTime)
+		outList, err := findFiles(tmpDir, entry.FileGlobPattern, nil, entry.MinTime, nil, true, nil)
 		if err != nil {
 			t.Fatalf("findFiles return error '%s'", err)
 		}
@@ -217,9 +254,222 @@ func TestFindFiles(t *testing.T) {
 	}
 }
 
+func TestFindFilesRecursive(t *testing.T) {
+	if outList, err := findFilesRecursive("non-existent-path", "*", nil, time.Time{}, nil, true, nil); err != nil || len(outList) != 0 {
+		t.Errorf("Expected empty list, got %v, %v", outList, err)
+	}
+	if _, err := findFilesRecursive("non-existent-path", "[", nil, time.Time{}, nil, true, nil); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+
+	tmpDir := t.TempDir()
+	now := time.Now()
+	testFiles := fileList{
+		{"file1.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{filepath.Join("2023", "file2.csv"), getTimeFromMaxAge(settings.MaxAgeDays(3), now)},
+		{filepath.Join("2023", "file3.ext"), getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{filepath.Join("2024", "sub", "file4.csv"), getTimeFromMaxAge(settings.MaxAgeDays(1), now)},
+		{filepath.Join("output", "file5.csv"), getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+	}
+	if err := testFiles.createFiles(tmpDir); err != nil {
+		t.Fatalf("Failed to create files in '%s'", tmpDir)
+	}
+
+	input := findFilesInputDataList{
+		{"", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
+			filepath.Join(tmpDir, "2023", "file2.csv"),
+			filepath.Join(tmpDir, "2023", "file3.ext"),
+			filepath.Join(tmpDir, "2024", "sub", "file4.csv"),
+			filepath.Join(tmpDir, "file1.csv")}},
+		{"*.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
+			filepath.Join(tmpDir, "2023", "file2.csv"),
+			filepath.Join(tmpDir, "2024", "sub", "file4.csv"),
+			filepath.Join(tmpDir, "file1.csv")}},
+		{"*.csv", getTimeFromMaxAge(settings.MaxAgeDays(2), now), []string{
+			filepath.Join(tmpDir, "2024", "sub", "file4.csv"),
+			filepath.Join(tmpDir, "file1.csv")}},
+		{"file3*", getTimeFromMaxAge(settings.MaxAgeDays(0), now), []string{
+			filepath.Join(tmpDir, "2023", "file3.ext")}},
+	}
+	for nr, entry := range input {
+		// The output directory within the input directory is not searched
+		outList, err := findFilesRecursive(tmpDir, entry.FileGlobPattern, nil, entry.MinTime, []string{filepath.Join(tmpDir, "output")}, true, nil)
+		if err != nil {
+			t.Fatalf("findFilesRecursive return error '%s'", err)
+		}
+		if !reflect.DeepEqual(outList, entry.ExpectedFiles) {
+			t.Errorf("Testcase %d: Expected %v, got %v", nr, entry.ExpectedFiles, outList)
+		}
+	}
+}
+
+func TestFindFilesExclude(t *testing.T) {
+	if _, err := findFiles("non-existent-path", "*", []string{"["}, time.Time{}, nil, true, nil); err == nil {
+		t.Error("Expected error for invalid exclude pattern")
+	}
+
+	tmpDir := t.TempDir()
+	now := time.Now()
+	testFiles := fileList{
+		{"file1.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{"file1_old.csv", getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{"file2.csv", getTimeFromMaxAge(settings.MaxAgeDays(3), now)},
+		{"file2_old.csv", getTimeFromMaxAge(settings.MaxAgeDays(3), now)},
+		{"statement.pdf", getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{filepath.Join("2023", "file3.csv"), getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+		{filepath.Join("2023", "file3_old.csv"), getTimeFromMaxAge(settings.MaxAgeDays(0), now)},
+	}
+	if err := testFiles.createFiles(tmpDir); err != nil {
+		t.Fatalf("Failed to create files in '%s'", tmpDir)
+	}
+	excludePatterns := []string{"*.pdf", "*_old.csv"}
+
+	outList, err := findFiles(tmpDir, "*.*", excludePatterns, time.Time{}, nil, true, nil)
+	expected := []string{filepath.Join(tmpDir, "file1.csv"), filepath.Join(tmpDir, "file2.csv")}
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+
+	// Excluded files are left out independent of their age
+	outList, err = findFiles(tmpDir, "*.csv", excludePatterns, getTimeFromMaxAge(settings.MaxAgeDays(1), now), nil, true, nil)
+	expected = []string{filepath.Join(tmpDir, "file1.csv")}
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+
+	outList, err = findFilesRecursive(tmpDir, "*.csv", excludePatterns, getTimeFromMaxAge(settings.MaxAgeDays(1), now), nil, true, nil)
+	expected = []string{filepath.Join(tmpDir, "2023", "file3.csv"), filepath.Join(tmpDir, "file1.csv")}
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+}
+
+// createSymlinks creates in dir a dangling symlink "gone.csv", a directory "archive.csv",
+// a symlink "linked.csv" to the file target and a symlink "linkdir" to the directory of target
+func createSymlinks(t *testing.T, dir string, target string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Creating symlinks needs privileges on Windows")
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.csv"), filepath.Join(dir, "gone.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "archive.csv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(absTarget, filepath.Join(dir, "linked.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(absTarget), filepath.Join(dir, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindFilesSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "real.csv"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	createSymlinks(t, tmpDir, filepath.Join("testfiles", "input", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"))
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	outList, err := findFiles(tmpDir, "*.csv", nil, time.Time{}, nil, true, logger)
+	expected := []string{filepath.Join(tmpDir, "linked.csv"), filepath.Join(tmpDir, "real.csv")}
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+	if !strings.Contains(logs.String(), "dangling symlink") || !strings.Contains(logs.String(), "gone.csv") {
+		t.Errorf("Expected warning about the dangling symlink, got '%s'", logs.String())
+	}
+
+	// The linked directory is not searched
+	outList, err = findFilesRecursive(tmpDir, "*.csv", nil, time.Time{}, nil, true, nil)
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+
+	// Without following symlinks they are ignored
+	expected = []string{filepath.Join(tmpDir, "real.csv")}
+	outList, err = findFiles(tmpDir, "*", nil, time.Time{}, nil, false, nil)
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+	outList, err = findFilesRecursive(tmpDir, "*", nil, time.Time{}, nil, false, nil)
+	if err != nil || !reflect.DeepEqual(outList, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, outList, err)
+	}
+}
+
+func TestBatchConvertSymlinks(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	createSymlinks(t, inputDir, filepath.Join("testfiles", "input", "mixed", "Umsaetze_Kreditkarte_2023.10.05.csv"))
+
+	for _, follow := range []bool{true, false} {
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir(), FileGlobPattern: "*.csv", FollowSymlinks: &follow},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status[0].Error != nil {
+			t.Fatalf("Expected set to be converted, got %v", status[0].Error)
+		}
+		expected := []string{filename}
+		if follow {
+			expected = []string{filename, "linked.csv"}
+		}
+		if names := extractFileNames(extractInputFiles(status[0].Files)); !reflect.DeepEqual(names, expected) {
+			t.Errorf("follow %t: expected files %v, got %v", follow, expected, names)
+		}
+		for _, f := range status[0].Files {
+			if f.Status != ConversionSuccess {
+				t.Errorf("follow %t: expected conversion, got %v", follow, f)
+			}
+		}
+	}
+}
+
+func TestBatchConvertExclude(t *testing.T) {
+	outputDir := t.TempDir()
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:                "mixed",
+				InputDir:            filepath.Join("testfiles", "input", "mixed"),
+				OutputDir:           outputDir,
+				ExcludeGlobPatterns: []string{"*.xlsx", "*Kreditkarte*"},
+			},
+		},
+	}
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Excluded files are not part of the status
+	files := status[0].Files
+	if len(files) != 1 || filepath.Base(files[0].InputFile) != "Umsaetze_DE12345678901234567890_2023.10.04.csv" {
+		t.Fatalf("Expected only the Volksbank file, got %v", files)
+	}
+	if files[0].Status != ConversionSuccess {
+		t.Errorf("Expected ConversionSuccess, got %v", files[0].Error)
+	}
+}
+
 func TestBatchConvertNoSets(t *testing.T) {
 	settings := settings.BatchConvertSettings{}
-	status, err := BatchConvert(settings, time.Now(), nil, nil)
+	status, err := BatchConvert(settings, nil, nil)
 	if err != nil {
 		t.Fatalf("BatchConvert should return error")
 	}
@@ -239,7 +489,7 @@ func TestBatchConvertInvalidSet(t *testing.T) {
 			},
 		},
 	}
-	status, err := BatchConvert(settings, time.Now(), nil, nil)
+	status, err := BatchConvert(settings, nil, nil)
 	if err == nil {
 		t.Fatalf("BatchConvert should return error")
 	}
@@ -261,10 +511,76 @@ func TestBatchConvertNonExistentOutputDir(t *testing.T) {
 				InputDir:  workingDir,
 				OutputDir: "/some/non-existing/dir",
 			},
+			{
+				Name:            "other",
+				InputDir:        filepath.Join(workingDir, "testfiles", "input", "volksbank"),
+				OutputDir:       t.TempDir(),
+				FileGlobPattern: "*.csv",
+			},
 		},
 	}
-	if _, err = BatchConvert(settings, time.Now(), nil, nil); err == nil {
-		t.Fatalf("BatchConvert should return error")
+	// The error is reported for the set, the other set is converted anyway
+	var reported error
+	cb := func(s BatchStatus, userData interface{}) {
+		if s[0].Error != nil {
+			reported = s[0].Error
+		}
+	}
+	status, err := BatchConvert(settings, cb, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	if len(status) != 2 || !errors.Is(status[0].Error, os.ErrNotExist) || len(status[0].Files) != 0 {
+		t.Fatalf("Expected not exist error for the first set, got %v", status)
+	}
+	if reported != status[0].Error {
+		t.Errorf("Expected error reported to the callback, got %v", reported)
+	}
+	if status[1].Error != nil || len(status[1].Files) != 1 || status[1].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected conversion of the other set, got %v", status[1])
+	}
+	if summary := status.Summary(); summary.FailedSets != 1 {
+		t.Errorf("Expected 1 failed set, got %d", summary.FailedSets)
+	}
+}
+
+func TestBatchConvertDisabledSet(t *testing.T) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %s", err)
+	}
+	inputDir := filepath.Join(workingDir, "testfiles", "input", "volksbank")
+	disabled := false
+	// The disabled set shares the input directory, its output directory is missing
+	settings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:            "closed",
+				Enabled:         &disabled,
+				InputDir:        inputDir,
+				OutputDir:       "/some/non-existing/dir",
+				FileGlobPattern: "*.csv",
+			},
+			{
+				Name:            "other",
+				InputDir:        inputDir,
+				OutputDir:       t.TempDir(),
+				FileGlobPattern: "*.csv",
+			},
+		},
+	}
+	status, err := BatchConvert(settings, nil, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	if len(status) != 2 || !status[0].Disabled || status[0].Error != nil || len(status[0].Files) != 0 {
+		t.Fatalf("Expected the first set to be disabled, got %v", status)
+	}
+	if status[1].Disabled || len(status[1].Files) != 1 || status[1].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected conversion of the other set, got %v", status[1])
+	}
+	if summary := status.Summary(); summary.DisabledSets != 1 || summary.FailedSets != 0 {
+		t.Errorf("Expected 1 disabled and no failed set, got %+v", summary)
 	}
 }
 
@@ -291,8 +607,12 @@ func TestBatchConvertOutputDirNotDir(t *testing.T) {
 			},
 		},
 	}
-	if _, err = BatchConvert(settings, time.Now(), nil, nil); err == nil {
-		t.Fatalf("BatchConvert should return error")
+	status, err := BatchConvert(settings, nil, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	if len(status) != 1 || status[0].Error == nil || status[0].Error.Error() != "outputDir is not a directory" {
+		t.Fatalf("Expected not a directory error for the set, got %v", status)
 	}
 }
 
@@ -333,7 +653,7 @@ func TestBatchConvertConversionError(t *testing.T) {
 		Sets: []settings.BatchConvertSet{
 			{
 				Name:      "set 1",
-				Format:    parser.NewSourceFormat(parser.Volksbank),
+				Format:    settings.FormatList{parser.Volksbank},
 				InputDir:  inputDir,
 				OutputDir: outputDir,
 			},
@@ -376,18 +696,25 @@ func TestBatchConvertConversionError(t *testing.T) {
 		}
 	}
 
-	if status, err = BatchConvert(settings1, time.Now(), cb, cbUserData); err != nil {
+	if status, err = BatchConvert(settings1, cb, cbUserData); err != nil {
 		t.Fatalf("BatchConvert should not return error")
 	}
+	if !errors.Is(status[0].Files[0].Error, parser.ErrUnknownFormat) {
+		t.Errorf("Expected error for unknown format, got %v", status[0].Files[0].Error)
+	}
 
 	if !reflect.DeepEqual(status, cbStatus) {
 		t.Fatalf("status and cbStatus are not equal")
 	}
 
 	cbUpdateNr = 0
-	if status, err = BatchConvert(settings2, time.Now(), cb, cbUserData); err != nil {
+	if status, err = BatchConvert(settings2, cb, cbUserData); err != nil {
 		t.Fatalf("BatchConvert should return error")
 	}
+	var pError *parser.ParserError
+	if !errors.As(status[0].Files[0].Error, &pError) || pError.ErrorType != parser.HeaderError || pError.File != emptyFilePath {
+		t.Errorf("Expected HeaderError for file '%s', got %v", emptyFilePath, status[0].Files[0].Error)
+	}
 
 	if !reflect.DeepEqual(status, cbStatus) {
 		t.Fatalf("status and cbStatus are not equal")
@@ -413,7 +740,7 @@ func TestBatchConvertBasic(t *testing.T) {
 	volksbankExpectedDir := filepath.Join(testfilesBase, "expected_output", "volksbank")
 	sVolksbank := settings.BatchConvertSet{
 		Name:      "volksbank",
-		Format:    parser.NewSourceFormat(parser.Volksbank),
+		Format:    settings.FormatList{parser.Volksbank},
 		InputDir:  volksbankInputDir,
 		OutputDir: volksbankOutputDir,
 	}
@@ -443,6 +770,8 @@ func TestBatchConvertBasic(t *testing.T) {
 					OutputFile: filepath.Join(volksbankOutputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv"),
 					Status:     ConversionSuccess,
 					Format:     parser.NewSourceFormat(parser.Volksbank),
+					Entries:    4,
+					Attempts:   1,
 				},
 			},
 		},
@@ -454,12 +783,24 @@ func TestBatchConvertBasic(t *testing.T) {
 					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze.csv"),
 					Status:     ConversionSuccess,
 					Format:     parser.NewSourceFormat(parser.Barclaycard),
+					Entries:    6,
+					Attempts:   1,
 				},
 				{
 					InputFile:  filepath.Join(mixedInputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv"),
 					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv"),
 					Status:     ConversionSuccess,
 					Format:     parser.NewSourceFormat(parser.Volksbank),
+					Entries:    4,
+					Attempts:   1,
+				},
+				{
+					InputFile:  filepath.Join(mixedInputDir, "Umsaetze_Kreditkarte_2023.10.05.csv"),
+					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze_Kreditkarte_2023.10.05.csv"),
+					Status:     ConversionSuccess,
+					Format:     parser.NewSourceFormat(parser.VolksbankMastercard),
+					Entries:    3,
+					Attempts:   1,
 				},
 			},
 		},
@@ -479,11 +820,13 @@ func TestBatchConvertBasic(t *testing.T) {
 		}
 	}
 
-	status, err = BatchConvert(settings, time.Time{}, cb, cbUserData)
+	status, err = BatchConvert(settings, cb, cbUserData)
 
 	if err != nil {
 		t.Fatalf("BatchConvert return error '%s'", err)
 	}
+	summary := status.Summary()
+	clearDurations(status)
 
 	if !reflect.DeepEqual(status, expectetedStatus) {
 		t.Fatalf("BatchConvert return wrong status. Status: %v, Expected: %v", status, expectetedStatus)
@@ -499,10 +842,26 @@ func TestBatchConvertBasic(t *testing.T) {
 	}
 
 	done, left = status[1].GetStats()
-	if done != 2 || left != 0 {
+	if done != 3 || left != 0 {
 		t.Fatalf("BatchConvert return wrong status")
 	}
 
+	if summary.Files[ConversionSuccess] != 4 || len(summary.Files) != 1 || summary.Entries != 17 {
+		t.Errorf("Expected 4 converted files with 17 entries, got %v", summary)
+	}
+	if len(summary.Sets) != 2 || summary.Sets[0].Name != "volksbank" || summary.Sets[1].Name != "mixed" {
+		t.Fatalf("Expected summaries of sets 'volksbank' and 'mixed', got %v", summary.Sets)
+	}
+	if summary.Sets[0].Files[ConversionSuccess] != 1 || summary.Sets[0].Entries != 4 {
+		t.Errorf("Expected 1 converted file with 4 entries in set 'volksbank', got %v", summary.Sets[0])
+	}
+	if summary.Sets[1].Files[ConversionSuccess] != 3 || summary.Sets[1].Entries != 13 {
+		t.Errorf("Expected 3 converted files with 13 entries in set 'mixed', got %v", summary.Sets[1])
+	}
+	if summary.Duration != summary.Sets[0].Duration+summary.Sets[1].Duration {
+		t.Errorf("Expected total duration to be the sum of the sets, got %v", summary)
+	}
+
 	areEqual, reason, err := areDirectoriesEqual(volksbankExpectedDir, volksbankOutputDir)
 	if err != nil {
 		t.Fatalf("areDirectoriesEqual return error '%s'", err)
@@ -561,6 +920,7 @@ func TestBatchConvertSkipped(t *testing.T) {
 					InputFile:  filepath.Join(mixedInputDir, "Umsaetze.xlsx"),
 					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze.csv"),
 					Status:     Skipped,
+					SkipReason: "output file exists",
 					Format:     nil,
 				},
 				{
@@ -568,6 +928,16 @@ func TestBatchConvertSkipped(t *testing.T) {
 					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze_DE12345678901234567890_2023.10.04.csv"),
 					Status:     ConversionSuccess,
 					Format:     parser.NewSourceFormat(parser.Volksbank),
+					Entries:    4,
+					Attempts:   1,
+				},
+				{
+					InputFile:  filepath.Join(mixedInputDir, "Umsaetze_Kreditkarte_2023.10.05.csv"),
+					OutputFile: filepath.Join(mixedOutputDir, "Umsaetze_Kreditkarte_2023.10.05.csv"),
+					Status:     ConversionSuccess,
+					Format:     parser.NewSourceFormat(parser.VolksbankMastercard),
+					Entries:    3,
+					Attempts:   1,
 				},
 			},
 		},
@@ -596,11 +966,12 @@ func TestBatchConvertSkipped(t *testing.T) {
 		}
 	}
 
-	status, err = BatchConvert(settings, time.Time{}, cb, cbUserData)
+	status, err = BatchConvert(settings, cb, cbUserData)
 
 	if err != nil {
 		t.Fatalf("BatchConvert return error '%s'", err)
 	}
+	clearDurations(status)
 
 	if !reflect.DeepEqual(status, expectetedStatus) {
 		t.Fatalf("BatchConvert return wrong status. Status: %v, Expected: %v", status, expectetedStatus)
@@ -611,10 +982,19 @@ func TestBatchConvertSkipped(t *testing.T) {
 	}
 
 	done, left := status[0].GetStats()
-	if done != 2 || left != 0 {
+	if done != 3 || left != 0 {
 		t.Fatalf("BatchConvert return wrong status")
 	}
 
+	// The skipped file is not parsed, its entries are not counted
+	summary := status.Summary()
+	if summary.Files[ConversionSuccess] != 2 || summary.Files[Skipped] != 1 || len(summary.Files) != 2 || summary.Entries != 7 {
+		t.Errorf("Expected 2 converted files with 7 entries and 1 skipped file, got %v", summary)
+	}
+	if len(summary.Sets) != 1 || !reflect.DeepEqual(summary.Sets[0].Files, summary.Files) {
+		t.Errorf("Expected the summary of set 'mixed' to match the total, got %v", summary.Sets)
+	}
+
 	areEqual, reason, err := areDirectoriesEqual(mixedExpectedDir, mixedOutputDir)
 	if err != nil {
 		t.Fatalf("areDirectoriesEqual return error '%s'", err)
@@ -623,3 +1003,1727 @@ func TestBatchConvertSkipped(t *testing.T) {
 		t.Errorf("Output directory does not match expected directory. Reason: %s", reason)
 	}
 }
+
+func TestBatchConvertLenient(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "input")
+	if err := os.Mkdir(inputDir, os.ModeDir|0o700); err != nil {
+		t.Fatalf("Failed to create directory '%s'", inputDir)
+	}
+	src := filepath.Join("..", "..", "..", "pkg", "parser", "testfiles", "volksbank", "Umsaetze_nok_wrongbetrag.csv")
+	if err := copyFile(src, filepath.Join(inputDir, "Umsaetze.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lenient := range []bool{false, true} {
+		outputDir := filepath.Join(tmpDir, fmt.Sprintf("output_%v", lenient))
+		if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", outputDir)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "set 1",
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					Lenient:   lenient,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		file := status[0].Files[0]
+		if !lenient {
+			if file.Status != ConversionError {
+				t.Errorf("Expected ConversionError, got '%v'", file.Status)
+			}
+			// The invalid amount is only accepted in lenient mode, the error of the
+			// format with matching header is reported
+			var pError *parser.ParserError
+			if !errors.As(file.Error, &pError) || pError.Line != 2 || pError.Field != "Betrag" {
+				t.Errorf("Expected error in line 2 and field 'Betrag', got %v", file.Error)
+			}
+			if file.Format == nil || *file.Format != parser.Volksbank {
+				t.Errorf("Expected format Volksbank, got %v", file.Format)
+			}
+			continue
+		}
+		if file.Error != nil {
+			t.Errorf("Expected no error, got %v", file.Error)
+		}
+		if file.Status != ConversionSuccess {
+			t.Errorf("Expected ConversionSuccess, got '%v'", file.Status)
+		}
+		if len(file.RowErrors) != 1 || file.RowErrors[0].Line != 2 || file.RowErrors[0].Field != "Betrag" {
+			t.Errorf("Expected row error in line 2 and field 'Betrag', got %v", file.RowErrors)
+		}
+	}
+}
+
+func TestBatchConvertRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "input")
+	outputDir := filepath.Join(tmpDir, "output")
+	for _, dir := range []string{inputDir, outputDir} {
+		if err := os.Mkdir(dir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", dir)
+		}
+	}
+	src := filepath.Join("..", "..", "..", "pkg", "parser", "testfiles", "dkb", "dkb.csv")
+	if err := copyFile(src, filepath.Join(inputDir, "dkb.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	batchSettings := settings.BatchConvertSettings{
+		Rules: []parser.Rule{
+			{Match: parser.RuleMatch{Amount: "negative"}, Set: parser.RuleSet{Category: "Global"}},
+			{Match: parser.RuleMatch{Amount: "positive"}, Set: parser.RuleSet{Category: "Income"}},
+		},
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "set 1",
+				InputDir:  inputDir,
+				OutputDir: outputDir,
+				Rules: []parser.Rule{
+					{Match: parser.RuleMatch{PayeeRegex: "anderer Bank"}, Set: parser.RuleSet{Category: "Transfer"}},
+				},
+			},
+		},
+	}
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	if status[0].Files[0].Status != ConversionSuccess {
+		t.Fatalf("Expected ConversionSuccess, got %v", status[0].Files[0].Error)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "dkb.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rule of the set takes precedence over the global rule
+	for _, expected := range []string{";1000.00;Income;", ";-2000.00;Transfer;"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in output, got:\n%s", expected, content)
+		}
+	}
+
+	batchSettings.Rules[0].Match.PayeeRegex = "REWE("
+	if _, err := BatchConvert(batchSettings, nil, nil); err == nil {
+		t.Error("Expected error for invalid rule")
+	}
+}
+
+func TestBatchConvertDateRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join("testfiles", "input", "volksbank")
+	outputDir := filepath.Join(tmpDir, "output")
+	if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+		t.Fatalf("Failed to create directory '%s'", outputDir)
+	}
+
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "set 1",
+				InputDir:  inputDir,
+				OutputDir: outputDir,
+				DateFrom:  "2023-10-01",
+				DateTo:    "2023-10-31",
+			},
+		},
+	}
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	file := status[0].Files[0]
+	if file.Status != ConversionSuccess {
+		t.Fatalf("Expected ConversionSuccess, got %v", file.Error)
+	}
+	// The two records from September are dropped
+	if file.Dropped != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", file.Dropped)
+	}
+
+	batchSettings.Sets[0].DateFrom = "2023-10-32"
+	if _, err := BatchConvert(batchSettings, nil, nil); err == nil {
+		t.Error("Expected error for invalid DateFrom")
+	}
+}
+
+func TestBatchConvertDedupe(t *testing.T) {
+	inputDir := filepath.Join("testfiles", "input", "dedupe")
+	for _, dedupe := range []bool{false, true} {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", outputDir)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "set 1",
+					Format:    settings.FormatList{parser.Volksbank},
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					Dedupe:    dedupe,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		files := status[0].Files
+		if len(files) != 2 {
+			t.Fatalf("Expected 2 files, got %d", len(files))
+		}
+		for _, f := range files {
+			if f.Status != ConversionSuccess {
+				t.Fatalf("Expected ConversionSuccess for '%s', got %v", f.InputFile, f.Error)
+			}
+		}
+
+		// The second export overlaps the first one in two entries
+		expectedDuplicates := 0
+		expectedLines := 5
+		if dedupe {
+			expectedDuplicates = 2
+			expectedLines = 3
+		}
+		if files[0].Duplicates != 0 {
+			t.Errorf("dedupe %v: expected no duplicates in first file, got %d", dedupe, files[0].Duplicates)
+		}
+		if files[1].Duplicates != expectedDuplicates {
+			t.Errorf("dedupe %v: expected %d duplicates, got %d", dedupe, expectedDuplicates, files[1].Duplicates)
+		}
+		content, err := os.ReadFile(files[1].OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != expectedLines {
+			t.Errorf("dedupe %v: expected %d lines, got:\n%s", dedupe, expectedLines, content)
+		}
+		if dedupe && (!strings.HasPrefix(lines[1], "2023-10-06;") || !strings.HasPrefix(lines[2], "2023-10-04;")) {
+			t.Errorf("Expected only the new entries, got:\n%s", content)
+		}
+	}
+}
+
+func TestBatchConvertOverwrite(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputFile, err := filepath.Abs(filepath.Join("testfiles", "input", "volksbank", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputInfo, err := os.Stat(inputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputTime := inputInfo.ModTime()
+
+	testCases := []struct {
+		name       string
+		policy     settings.OverwritePolicy
+		outputTime time.Time // Modification time of the existing output file
+		converted  bool
+		reason     string
+	}{
+		{"never", settings.OverwriteNever, inputTime.Add(-time.Hour), false, "output file exists"},
+		{"always", settings.OverwriteAlways, inputTime.Add(time.Hour), true, ""},
+		{"if-newer input newer", settings.OverwriteIfNewer, inputTime.Add(-time.Second), true, ""},
+		{"if-newer equal", settings.OverwriteIfNewer, inputTime, false, "output file is not older than input file"},
+		{"if-newer output newer", settings.OverwriteIfNewer, inputTime.Add(time.Second), false, "output file is not older than input file"},
+	}
+	for _, tc := range testCases {
+		for _, dedupe := range []bool{false, true} {
+			// In dedupe mode the previous content of an overwritten file must not count
+			// as duplicate of its new content
+			outputDir := t.TempDir()
+			outputFile := filepath.Join(outputDir, filename)
+			if err := copyFile(filepath.Join("testfiles", "expected_output", "volksbank", filename), outputFile); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(outputFile, tc.outputTime, tc.outputTime); err != nil {
+				t.Fatal(err)
+			}
+
+			batchSettings := settings.BatchConvertSettings{
+				Sets: []settings.BatchConvertSet{
+					{
+						Name:      "volksbank",
+						InputDir:  filepath.Dir(inputFile),
+						OutputDir: outputDir,
+						Overwrite: tc.policy,
+						Dedupe:    dedupe,
+					},
+				},
+			}
+			status, err := BatchConvert(batchSettings, nil, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			f := status[0].Files[0]
+			if tc.converted {
+				if f.Status != ConversionSuccess {
+					t.Errorf("%s (dedupe %v): expected ConversionSuccess, got %v (%v)", tc.name, dedupe, f.Status, f.Error)
+				}
+				if f.Duplicates != 0 {
+					t.Errorf("%s (dedupe %v): expected no duplicates, got %d", tc.name, dedupe, f.Duplicates)
+				}
+				equal, err := areFilesEqual(filepath.Join("testfiles", "expected_output", "volksbank", filename), outputFile)
+				if err != nil || !equal {
+					t.Errorf("%s (dedupe %v): output file differs from expected file (%v)", tc.name, dedupe, err)
+				}
+			} else if f.Status != Skipped {
+				t.Errorf("%s (dedupe %v): expected Skipped, got %v (%v)", tc.name, dedupe, f.Status, f.Error)
+			}
+			if f.SkipReason != tc.reason {
+				t.Errorf("%s (dedupe %v): expected skip reason '%s', got '%s'", tc.name, dedupe, tc.reason, f.SkipReason)
+			}
+		}
+	}
+}
+
+func TestBatchConvertDedupeInvalidOutput(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+	if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+		t.Fatalf("Failed to create directory '%s'", outputDir)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "other.csv"), []byte("no homebank file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "set 1",
+				InputDir:  filepath.Join("testfiles", "input", "dedupe"),
+				OutputDir: outputDir,
+				Dedupe:    true,
+			},
+		},
+	}
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected error of the set only, got %v", err)
+	}
+	if status[0].Error == nil || !strings.Contains(status[0].Error.Error(), "other.csv") {
+		t.Errorf("Expected error for output file which can't be read, got %v", status[0].Error)
+	}
+}
+
+func TestBatchConvertContextCancel(t *testing.T) {
+	outputDir := t.TempDir()
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "mixed",
+				InputDir:  filepath.Join("testfiles", "input", "mixed"),
+				OutputDir: outputDir,
+			},
+		},
+	}
+
+	// Cancelled while the first file is converted
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cb := func(s BatchStatus, userData interface{}) {
+		if s[0].Files[0].Status == ConversionInProgress {
+			cancel()
+		}
+	}
+	status, err := BatchConvertContext(ctx, batchSettings, cb, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(status) != 1 || len(status[0].Files) != 3 {
+		t.Fatalf("Expected status of 3 files, got %v", status)
+	}
+	if status[0].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected the current file to be converted, got status %v", status[0].Files[0].Status)
+	}
+	for _, f := range status[0].Files[1:] {
+		if f.Status != NotStartedYet {
+			t.Errorf("Expected '%s' not to be started, got status %v", f.InputFile, f.Status)
+		}
+	}
+	files, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected 1 output file, got %d", len(files))
+	}
+
+	// Cancelled before the start
+	if _, err := BatchConvertContext(ctx, batchSettings, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBatchConvertParallel(t *testing.T) {
+	// Slows down the parsing to make the benefit of the parallel conversion measurable
+	const delay = 100 * time.Millisecond
+	orig := parseInputFile
+	t.Cleanup(func() { parseInputFile = orig })
+	parseInputFile = func(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+		time.Sleep(delay)
+		return orig(infile, format, o)
+	}
+
+	convert := func(parallelism int) (BatchStatus, time.Duration) {
+		outputDir := t.TempDir()
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "mixed",
+					InputDir:  filepath.Join("testfiles", "input", "mixed"),
+					OutputDir: outputDir,
+				},
+			},
+			Parallelism: parallelism,
+		}
+		var calls int
+		cb := func(s BatchStatus, userData interface{}) {
+			calls++ // Not synchronized, the race detector reports concurrent calls
+		}
+		start := time.Now()
+		status, err := BatchConvert(batchSettings, cb, nil)
+		duration := time.Since(start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status) != 1 || len(status[0].Files) != 3 {
+			t.Fatalf("Expected status of 3 files, got %v", status)
+		}
+		// The output directory and the duration differ between the runs
+		clearDurations(status)
+		for i := range status[0].Files {
+			status[0].Files[i].OutputFile = filepath.Base(status[0].Files[i].OutputFile)
+		}
+		return status, duration
+	}
+
+	sequentialStatus, sequential := convert(1)
+	parallelStatus, parallel := convert(3)
+
+	if !reflect.DeepEqual(sequentialStatus, parallelStatus) {
+		t.Errorf("Status differs:\n%v\n%v", sequentialStatus, parallelStatus)
+	}
+	if sequential < 3*delay {
+		t.Errorf("Expected sequential conversion to take at least %v, took %v", 3*delay, sequential)
+	}
+	if parallel > sequential*2/3 {
+		t.Errorf("Expected parallel conversion to be faster, took %v compared to %v", parallel, sequential)
+	}
+}
+
+func TestBatchConvertFormatList(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	var tried []parser.SourceFormat
+	orig := parseInputFile
+	t.Cleanup(func() { parseInputFile = orig })
+	parseInputFile = func(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+		if format != nil {
+			tried = append(tried, *format)
+		}
+		return orig(infile, format, o)
+	}
+	convert := func(formats settings.FormatList) FileStatus {
+		t.Helper()
+		tried = nil
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "volksbank",
+					InputDir:  filepath.Join("testfiles", "input", "volksbank"),
+					OutputDir: t.TempDir(),
+					Format:    formats,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status[0].Files[0]
+	}
+
+	// The formats are tried in order until one matches
+	f := convert(settings.FormatList{parser.VolksbankMastercard, parser.Volksbank, parser.DKB})
+	if f.Status != ConversionSuccess || f.Format == nil || *f.Format != parser.Volksbank {
+		t.Errorf("Expected conversion as Volksbank, got %v", f)
+	}
+	if expected := []parser.SourceFormat{parser.VolksbankMastercard, parser.Volksbank}; !reflect.DeepEqual(tried, expected) {
+		t.Errorf("Expected formats %v to be tried, got %v", expected, tried)
+	}
+	equal, err := areFilesEqual(filepath.Join("testfiles", "expected_output", "volksbank", filename), f.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Error("Output file differs")
+	}
+
+	// A single format behaves as before, the parser reports its format on failure
+	f = convert(settings.FormatList{parser.DKB})
+	if f.Status != ConversionError || f.Format == nil || *f.Format != parser.DKB {
+		t.Errorf("Expected DKB conversion to fail, got %v", f)
+	}
+
+	// Fails with the errors of all formats if none matches
+	f = convert(settings.FormatList{parser.Amex, parser.DKB})
+	if f.Status != ConversionError || f.Format != nil {
+		t.Errorf("Expected failed conversion without format, got %v", f)
+	}
+	if f.Error == nil || !strings.Contains(f.Error.Error(), "Amex:") || !strings.Contains(f.Error.Error(), "DKB:") {
+		t.Errorf("Expected errors of both formats, got %v", f.Error)
+	}
+	if expected := []parser.SourceFormat{parser.Amex, parser.DKB}; !reflect.DeepEqual(tried, expected) {
+		t.Errorf("Expected formats %v to be tried, got %v", expected, tried)
+	}
+}
+
+func TestBatchConvertOutputNameCollision(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+
+	// Umsaetze.csv and Umsaetze.xlsx are both converted to Umsaetze.csv
+	inputDir := t.TempDir()
+	copies := map[string]string{
+		filepath.Join(testfilesBase, "input", "mixed", "Umsaetze.xlsx"):   "Umsaetze.xlsx",
+		filepath.Join(testfilesBase, "input", "volksbank", volksbankFile): "Umsaetze.csv",
+		filepath.Join(testfilesBase, "input", "mixed", volksbankFile):     volksbankFile,
+	}
+	for src, dst := range copies {
+		if err := copyFile(src, filepath.Join(inputDir, dst)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, disambiguate := range []bool{false, true} {
+		outputDir := t.TempDir()
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:                    "collision",
+					InputDir:                inputDir,
+					OutputDir:               outputDir,
+					DisambiguateOutputNames: disambiguate,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("disambiguate %v: %v", disambiguate, err)
+		}
+		files := status[0].Files
+		if len(files) != 3 {
+			t.Fatalf("disambiguate %v: expected 3 files, got %v", disambiguate, files)
+		}
+		if files[2].Status != ConversionSuccess || files[2].OutputFile != filepath.Join(outputDir, volksbankFile) {
+			t.Errorf("disambiguate %v: expected conversion of '%s', got %v", disambiguate, volksbankFile, files[2])
+		}
+
+		if !disambiguate {
+			for _, f := range files[:2] {
+				if f.Status != ConversionError || f.OutputFile != filepath.Join(outputDir, "Umsaetze.csv") {
+					t.Errorf("Expected ConversionError for '%s', got %v", f.InputFile, f)
+				}
+				if f.Error == nil || !strings.Contains(f.Error.Error(), "'Umsaetze.csv', 'Umsaetze.xlsx'") {
+					t.Errorf("Expected collision error for '%s', got %v", f.InputFile, f.Error)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(outputDir, "Umsaetze.csv")); err == nil {
+				t.Error("Expected no output file 'Umsaetze.csv'")
+			}
+			continue
+		}
+
+		expected := map[string]string{
+			"Umsaetze.csv.csv":  filepath.Join(testfilesBase, "expected_output", "volksbank", volksbankFile),
+			"Umsaetze.xlsx.csv": filepath.Join(testfilesBase, "expected_output", "mixed", "Umsaetze.csv"),
+		}
+		for _, f := range files[:2] {
+			if f.Status != ConversionSuccess {
+				t.Errorf("Expected ConversionSuccess for '%s', got %v", f.InputFile, f.Error)
+				continue
+			}
+			expectedFile, ok := expected[filepath.Base(f.OutputFile)]
+			if !ok {
+				t.Errorf("Unexpected output file '%s' of '%s'", f.OutputFile, f.InputFile)
+				continue
+			}
+			if equal, err := areFilesEqual(expectedFile, f.OutputFile); err != nil || !equal {
+				t.Errorf("Output file '%s' differs from '%s' (%v)", f.OutputFile, expectedFile, err)
+			}
+		}
+	}
+}
+
+func TestBatchConvertInputDirs(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	const creditCardFile = "Umsaetze_Kreditkarte_2023.10.05.csv"
+
+	// Both input directories contain an export named Umsaetze.csv
+	baseDir := t.TempDir()
+	downloadsDir := filepath.Join(baseDir, "Downloads")
+	syncDir := filepath.Join(baseDir, "Sync")
+	copies := map[string]string{
+		filepath.Join(testfilesBase, "input", "mixed", volksbankFile):     filepath.Join(downloadsDir, "Umsaetze.csv"),
+		filepath.Join(testfilesBase, "input", "mixed", creditCardFile):    filepath.Join(syncDir, "Umsaetze.csv"),
+		filepath.Join(testfilesBase, "input", "volksbank", volksbankFile): filepath.Join(syncDir, volksbankFile),
+	}
+	for src, dst := range copies {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyFile(src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, disambiguate := range []bool{false, true} {
+		outputDir := t.TempDir()
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:                    "giro",
+					InputDir:                downloadsDir,
+					InputDirs:               []string{syncDir},
+					OutputDir:               outputDir,
+					DisambiguateOutputNames: disambiguate,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("disambiguate %v: %v", disambiguate, err)
+		}
+		files := status[0].Files
+		if inputFiles := []string{filepath.Join(downloadsDir, "Umsaetze.csv"), filepath.Join(syncDir, "Umsaetze.csv"), filepath.Join(syncDir, volksbankFile)}; !reflect.DeepEqual(extractInputFiles(files), inputFiles) {
+			t.Fatalf("disambiguate %v: expected files %v, got %v", disambiguate, inputFiles, extractInputFiles(files))
+		}
+		if files[2].Status != ConversionSuccess || files[2].OutputFile != filepath.Join(outputDir, volksbankFile) {
+			t.Errorf("disambiguate %v: expected conversion of '%s', got %v", disambiguate, volksbankFile, files[2])
+		}
+
+		if !disambiguate {
+			expectedError := fmt.Sprintf("'%s', '%s'", filepath.Join("Downloads", "Umsaetze.csv"), filepath.Join("Sync", "Umsaetze.csv"))
+			for _, f := range files[:2] {
+				if f.Status != ConversionError || f.Error == nil || !strings.Contains(f.Error.Error(), expectedError) {
+					t.Errorf("Expected collision error for '%s', got %v", f.InputFile, f.Error)
+				}
+			}
+			continue
+		}
+
+		expected := []string{filepath.Join(outputDir, "Downloads_Umsaetze.csv.csv"), filepath.Join(outputDir, "Sync_Umsaetze.csv.csv")}
+		for i, f := range files[:2] {
+			if f.Status != ConversionSuccess || f.OutputFile != expected[i] {
+				t.Errorf("Expected conversion of '%s' to '%s', got %v", f.InputFile, expected[i], f)
+			}
+		}
+	}
+}
+
+func TestBatchConvertOutputNameTemplate(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	const creditCardFile = "Umsaetze_Kreditkarte_2023.10.05.csv"
+
+	inputDir := t.TempDir()
+	for _, name := range []string{volksbankFile, creditCardFile} {
+		if err := copyFile(filepath.Join(testfilesBase, "input", "mixed", name), filepath.Join(inputDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputDir := t.TempDir()
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:               "giro",
+				InputDir:           inputDir,
+				OutputDir:          outputDir,
+				OutputNameTemplate: "{set}_{firstdate}_{lastdate}.csv",
+			},
+		},
+	}
+
+	// The output files are named by the date range of their records
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		volksbankFile:  "giro_2023-09-29_2023-10-04.csv",
+		creditCardFile: "giro_2023-10-02_2023-10-05.csv",
+	}
+	for _, f := range status[0].Files {
+		name := filepath.Base(f.InputFile)
+		if f.Status != ConversionSuccess || f.OutputFile != filepath.Join(outputDir, expected[name]) {
+			t.Fatalf("Expected conversion of '%s' to '%s', got %v", name, expected[name], f)
+		}
+		equal, err := areFilesEqual(filepath.Join(testfilesBase, "expected_output", "mixed", name), f.OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal {
+			t.Errorf("Unexpected content of '%s'", f.OutputFile)
+		}
+	}
+
+	// The rendered names are used to detect the existing output files
+	status, err = BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range status[0].Files {
+		name := filepath.Base(f.InputFile)
+		if f.Status != Skipped || f.SkipReason != "output file exists" || f.OutputFile != filepath.Join(outputDir, expected[name]) {
+			t.Errorf("Expected '%s' to be skipped, got %v", name, f)
+		}
+	}
+
+	// A copy with the same records collides with the original
+	if err := copyFile(filepath.Join(inputDir, volksbankFile), filepath.Join(inputDir, "Copy.csv")); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	status, err = BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := status[0].Files
+	if len(files) != 3 || files[0].Status != ConversionSuccess || files[2].Status != ConversionSuccess {
+		t.Fatalf("Expected conversion of the first and last file, got %v", files)
+	}
+	if files[1].Status != ConversionError || files[1].Error == nil || !strings.Contains(files[1].Error.Error(), "collides with the one of 'Copy.csv'") {
+		t.Errorf("Expected collision error, got %v", files[1])
+	}
+}
+
+func TestBatchConvertSkipEmpty(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The comdirect export contains only pending transactions
+	const emptyFile = "umsaetze_1234567890_20231006_1804.csv"
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	copies := map[string]string{
+		filepath.Join(testfilesBase, "input", "empty", emptyFile):         emptyFile,
+		filepath.Join(testfilesBase, "input", "volksbank", volksbankFile): volksbankFile,
+	}
+	for src, name := range copies {
+		if err := copyFile(src, filepath.Join(inputDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	convert := func(skipEmpty bool, dryRun bool, merge bool) (BatchSetStatus, string) {
+		t.Helper()
+		outputDir := t.TempDir()
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "giro",
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					SkipEmpty: skipEmpty,
+					Merge:     merge,
+				},
+			},
+			DryRun: dryRun,
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status[0], outputDir
+	}
+
+	// By default the output file contains only the header
+	status, outputDir := convert(false, false, false)
+	if f := status.Files[1]; f.Status != ConversionSuccess || f.Entries != 0 {
+		t.Fatalf("Expected conversion of '%s' without entries, got %v", emptyFile, f)
+	}
+	if content, err := os.ReadFile(filepath.Join(outputDir, emptyFile)); err != nil || strings.Count(string(content), "\n") != 1 {
+		t.Errorf("Expected output file with header only, got '%s' (%v)", content, err)
+	}
+
+	status, outputDir = convert(true, false, false)
+	f := status.Files[1]
+	if f.Status != SkippedEmpty || f.SkipReason != "input file contains no entries" || f.Format == nil || *f.Format != parser.Comdirect {
+		t.Errorf("Expected '%s' to be skipped as empty, got %v", emptyFile, f)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, emptyFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no output file for '%s', got %v", emptyFile, err)
+	}
+	if f := status.Files[0]; f.Status != ConversionSuccess {
+		t.Errorf("Expected conversion of '%s', got %v", volksbankFile, f)
+	}
+	if summary := status.Summary(); summary.Files[SkippedEmpty] != 1 || summary.Files[ConversionSuccess] != 1 {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+
+	status, _ = convert(true, true, false)
+	if f := status.Files[1]; f.Status != WouldSkip || f.SkipReason != "input file contains no entries" {
+		t.Errorf("Expected '%s' to be skipped in dry run, got %v", emptyFile, f)
+	}
+
+	// Not merged either
+	status, outputDir = convert(true, false, true)
+	if status.Files[0].Status != ConversionSuccess || status.Files[1].Status != SkippedEmpty {
+		t.Errorf("Expected merge of '%s' only, got %v", volksbankFile, status.Files)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "giro.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 1+status.Files[0].Entries {
+		t.Errorf("Expected merged file with the %d entries of '%s', got %d lines", status.Files[0].Entries, volksbankFile, lines)
+	}
+}
+
+func TestBatchConvertOrderAndMaxFilesPerRun(t *testing.T) {
+	src := filepath.Join("testfiles", "input", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	inputDir := t.TempDir()
+	now := time.Now()
+	// b.csv is the newest file, c.csv the oldest
+	modTimes := map[string]time.Time{
+		"a.csv": now.Add(-2 * time.Hour),
+		"b.csv": now.Add(-time.Hour),
+		"c.csv": now.Add(-3 * time.Hour),
+	}
+	for name, modTime := range modTimes {
+		file := filepath.Join(inputDir, name)
+		if err := copyFile(src, file); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	convert := func(set settings.BatchConvertSet) BatchSetStatus {
+		t.Helper()
+		status, err := BatchConvert(settings.BatchConvertSettings{Sets: []settings.BatchConvertSet{set}}, nil, nil, WithClock(func() time.Time { return now }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status[0]
+	}
+	check := func(status BatchSetStatus, names []string, statuses []ConversionStatus) {
+		t.Helper()
+		if got := extractFileNames(extractInputFiles(status.Files)); !reflect.DeepEqual(got, names) {
+			t.Errorf("Expected files in order %v, got %v", names, got)
+		}
+		for i, f := range status.Files {
+			if f.Status != statuses[i] {
+				t.Errorf("Expected status %d for '%s', got %d", statuses[i], filepath.Base(f.InputFile), f.Status)
+			}
+		}
+	}
+
+	for order, names := range map[settings.FileOrder][]string{
+		settings.OrderByName:      {"a.csv", "b.csv", "c.csv"},
+		settings.OrderByMtimeDesc: {"b.csv", "a.csv", "c.csv"},
+		settings.OrderByMtimeAsc:  {"c.csv", "a.csv", "b.csv"},
+	} {
+		status := convert(settings.BatchConvertSet{Name: "giro", InputDir: inputDir, OutputDir: t.TempDir(), Order: order})
+		check(status, names, []ConversionStatus{ConversionSuccess, ConversionSuccess, ConversionSuccess})
+	}
+
+	// The newest files first, the one beyond the limit is left for the next run
+	set := settings.BatchConvertSet{
+		Name:           "giro",
+		InputDir:       inputDir,
+		OutputDir:      t.TempDir(),
+		Order:          settings.OrderByMtimeDesc,
+		MaxFilesPerRun: 2,
+	}
+	status := convert(set)
+	check(status, []string{"b.csv", "a.csv", "c.csv"}, []ConversionStatus{ConversionSuccess, ConversionSuccess, NotStartedYet})
+	if done, left := status.GetStats(); done != 2 || left != 1 {
+		t.Errorf("Expected 2 files done and 1 left, got %d and %d", done, left)
+	}
+	if _, err := os.Stat(filepath.Join(set.OutputDir, "c.csv")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no output file of c.csv, got %v", err)
+	}
+
+	// The skipped files don't count
+	status = convert(set)
+	check(status, []string{"b.csv", "a.csv", "c.csv"}, []ConversionStatus{Skipped, Skipped, ConversionSuccess})
+	if done, left := status.GetStats(); done != 3 || left != 0 {
+		t.Errorf("Expected 3 files done, got %d and %d left", done, left)
+	}
+}
+
+func TestBatchConvertCreateOutputDir(t *testing.T) {
+	inputDir, err := filepath.Abs(filepath.Join("testfiles", "input", "volksbank"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseDir := t.TempDir()
+	notADir := filepath.Join(baseDir, "file.txt")
+	if err := os.WriteFile(notADir, []byte("no directory"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	newDir := filepath.Join(baseDir, "new", "homebank")
+	sets := func(dryRun bool, outputDirs ...string) settings.BatchConvertSettings {
+		// Different patterns as the sets share the input directory
+		patterns := []string{"*.csv", "Umsaetze*", "*2023*"}
+		s := settings.BatchConvertSettings{DryRun: dryRun}
+		for i, dir := range outputDirs {
+			s.Sets = append(s.Sets, settings.BatchConvertSet{
+				Name:            fmt.Sprintf("set%d", i),
+				InputDir:        inputDir,
+				OutputDir:       dir,
+				FileGlobPattern: patterns[i],
+				CreateOutputDir: true,
+			})
+		}
+		return s
+	}
+
+	// Nothing is created in a dry run
+	status, err := BatchConvert(sets(true, newDir), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0].Files[0].Status != WouldConvert {
+		t.Errorf("Expected conversion in dry run, got %v", status[0].Files[0])
+	}
+	if _, err := os.Stat(newDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no output directory in dry run, got %v", err)
+	}
+
+	// The set which can't create its output directory fails, the others are converted
+	failingDir := filepath.Join(notADir, "homebank")
+	status, err = BatchConvert(sets(false, newDir, failingDir, t.TempDir()), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(newDir)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Expected created output directory, got %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o700 {
+		t.Errorf("Expected permissions 0700 of the output directory, got %o", info.Mode().Perm())
+	}
+	if status[0].Error != nil || status[0].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected conversion into the created directory, got %v", status[0])
+	}
+	if status[1].Error == nil || len(status[1].Files) != 0 {
+		t.Errorf("Expected error for '%s', got %v", failingDir, status[1])
+	}
+	if status[2].Error != nil || status[2].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected conversion of the set after the failed one, got %v", status[2])
+	}
+	if summary := status.Summary(); summary.FailedSets != 1 || summary.Sets[1].Error == nil {
+		t.Errorf("Expected a failed set in the summary, got %v", summary)
+	}
+
+	// An existing file is not replaced by a directory
+	status, err = BatchConvert(sets(false, notADir), nil, nil)
+	if err != nil || status[0].Error == nil || status[0].Error.Error() != "outputDir is not a directory" {
+		t.Errorf("Expected not a directory error, got %v (%v)", status[0].Error, err)
+	}
+
+	// Without the option a missing output directory fails the set
+	s := sets(false, filepath.Join(baseDir, "missing"))
+	s.Sets[0].CreateOutputDir = false
+	status, err = BatchConvert(s, nil, nil)
+	if err != nil || !errors.Is(status[0].Error, os.ErrNotExist) {
+		t.Errorf("Expected error for missing output directory, got %v (%v)", status[0].Error, err)
+	}
+}
+
+// extractInputFiles returns the input files of the file statuses
+func extractInputFiles(files []FileStatus) []string {
+	inputFiles := make([]string, 0, len(files))
+	for _, f := range files {
+		inputFiles = append(inputFiles, f.InputFile)
+	}
+	return inputFiles
+}
+
+func TestBatchConvertDryRun(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixedInputDir := filepath.Join(testfilesBase, "input", "mixed")
+
+	// Two files to be converted, one to be skipped as its output file exists and one which fails
+	inputDir := t.TempDir()
+	for _, name := range []string{"Umsaetze.xlsx", "Umsaetze_DE12345678901234567890_2023.10.04.csv", "Umsaetze_Kreditkarte_2023.10.05.csv"} {
+		if err := copyFile(filepath.Join(mixedInputDir, name), filepath.Join(inputDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "unknown.csv"), []byte("no;known;format\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dedupe := range []bool{false, true} {
+		outputDir := t.TempDir()
+		if err := copyFile(filepath.Join(testfilesBase, "expected_output", "mixed", "Umsaetze.csv"), filepath.Join(outputDir, "Umsaetze.csv")); err != nil {
+			t.Fatal(err)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "mixed",
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					Dedupe:    dedupe,
+				},
+			},
+			DryRun: true,
+		}
+		var cbStatus BatchStatus
+		cb := func(s BatchStatus, userData interface{}) {
+			cbStatus = s
+		}
+		dryRunStatus, err := BatchConvert(batchSettings, cb, nil)
+		if err != nil {
+			t.Fatalf("dedupe %v: %v", dedupe, err)
+		}
+		if !reflect.DeepEqual(dryRunStatus, cbStatus) {
+			t.Errorf("dedupe %v: return status and callback status do not match", dedupe)
+		}
+		files, err := getFilesInDirectory(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Errorf("dedupe %v: expected no files written in dry run, got %v", dedupe, files)
+		}
+
+		batchSettings.DryRun = false
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("dedupe %v: %v", dedupe, err)
+		}
+
+		// Apart from the status the dry run reports the same as the real run
+		clearDurations(dryRunStatus)
+		clearDurations(status)
+		for i, f := range dryRunStatus[0].Files {
+			if dryRunStatuses[status[0].Files[i].Status] != f.Status {
+				t.Errorf("dedupe %v: status of '%s' is %v in dry run and %v in real run", dedupe, f.InputFile, f.Status, status[0].Files[i].Status)
+			}
+			dryRunStatus[0].Files[i].Status = status[0].Files[i].Status
+		}
+		if !reflect.DeepEqual(dryRunStatus, status) {
+			t.Errorf("dedupe %v: dry run status differs from real run:\n%v\n%v", dedupe, dryRunStatus, status)
+		}
+
+		var counts [WouldConvert + 1]int
+		for _, f := range status[0].Files {
+			counts[f.Status]++
+		}
+		if counts[ConversionSuccess] != 2 || counts[Skipped] != 1 || counts[ConversionError] != 1 {
+			t.Errorf("dedupe %v: unexpected status of the files %v", dedupe, status)
+		}
+	}
+}
+
+func TestBatchConvertRecursive(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	const creditCardFile = "Umsaetze_Kreditkarte_2023.10.05.csv"
+
+	// The exports of both years have the same name
+	inputDir := t.TempDir()
+	copies := map[string]string{
+		filepath.Join(testfilesBase, "input", "mixed", volksbankFile):   filepath.Join("2023", "Umsaetze.csv"),
+		filepath.Join(testfilesBase, "input", "mixed", creditCardFile):  filepath.Join("2024", "Umsaetze.csv"),
+		filepath.Join(testfilesBase, "input", "mixed", "Umsaetze.xlsx"): filepath.Join("2024", "cards", "Umsaetze.xlsx"),
+	}
+	for src, dst := range copies {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(inputDir, dst)), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyFile(src, filepath.Join(inputDir, dst)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		preserveStructure bool
+		disambiguate      bool
+		expected          map[string]string // Output file relative to OutputDir, expected content
+	}{
+		{
+			"flattened", false, false,
+			map[string]string{},
+		},
+		{
+			"flattened disambiguated", false, true,
+			map[string]string{
+				"2023_Umsaetze.csv.csv":        filepath.Join(testfilesBase, "expected_output", "mixed", volksbankFile),
+				"2024_Umsaetze.csv.csv":        filepath.Join(testfilesBase, "expected_output", "mixed", creditCardFile),
+				"2024_cards_Umsaetze.xlsx.csv": filepath.Join(testfilesBase, "expected_output", "mixed", "Umsaetze.csv"),
+			},
+		},
+		{
+			"preserved structure", true, false,
+			map[string]string{
+				filepath.Join("2023", "Umsaetze.csv"):          filepath.Join(testfilesBase, "expected_output", "mixed", volksbankFile),
+				filepath.Join("2024", "Umsaetze.csv"):          filepath.Join(testfilesBase, "expected_output", "mixed", creditCardFile),
+				filepath.Join("2024", "cards", "Umsaetze.csv"): filepath.Join(testfilesBase, "expected_output", "mixed", "Umsaetze.csv"),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		outputDir := t.TempDir()
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:                    "recursive",
+					InputDir:                inputDir,
+					OutputDir:               outputDir,
+					Recursive:               true,
+					PreserveStructure:       tc.preserveStructure,
+					DisambiguateOutputNames: tc.disambiguate,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		files := status[0].Files
+		if len(files) != 3 {
+			t.Fatalf("%s: expected 3 files, got %v", tc.name, files)
+		}
+
+		if len(tc.expected) == 0 {
+			// All files are flattened to Umsaetze.csv
+			for _, f := range files {
+				if f.Status != ConversionError {
+					t.Errorf("%s: expected ConversionError for '%s', got %v", tc.name, f.InputFile, f.Status)
+				}
+			}
+			if files[0].Error == nil || !strings.Contains(files[0].Error.Error(), filepath.Join("2023", "Umsaetze.csv")) {
+				t.Errorf("%s: expected collision error, got %v", tc.name, files[0].Error)
+			}
+			if outFiles, _ := os.ReadDir(outputDir); len(outFiles) != 0 {
+				t.Errorf("%s: expected no output files, got %v", tc.name, outFiles)
+			}
+			continue
+		}
+
+		for _, f := range files {
+			if f.Status != ConversionSuccess {
+				t.Errorf("%s: expected ConversionSuccess for '%s', got %v", tc.name, f.InputFile, f.Error)
+			}
+		}
+		outFiles, err := findFilesRecursive(outputDir, "", nil, time.Time{}, nil, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outFiles) != len(tc.expected) {
+			t.Errorf("%s: expected %d output files, got %v", tc.name, len(tc.expected), outFiles)
+		}
+		for rel, expectedFile := range tc.expected {
+			if equal, err := areFilesEqual(expectedFile, filepath.Join(outputDir, rel)); err != nil || !equal {
+				t.Errorf("%s: output file '%s' differs from '%s' (%v)", tc.name, rel, expectedFile, err)
+			}
+		}
+		if tc.preserveStructure && runtime.GOOS != "windows" {
+			info, err := os.Stat(filepath.Join(outputDir, "2024", "cards"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o700 {
+				t.Errorf("%s: expected permissions 0700 of the created subdirectory, got %o", tc.name, info.Mode().Perm())
+			}
+		}
+	}
+}
+
+func TestBatchConvertOnSuccess(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	const creditCardFile = "Umsaetze_Kreditkarte_2023.10.05.csv"
+
+	testCases := []struct {
+		name       string
+		onSuccess  settings.SuccessAction
+		archiveDir string   // Relative to the temporary directory
+		inputFiles []string // Expected files in the input directory after the run
+	}{
+		{"keep", settings.OnSuccessKeep, "", []string{"Umsaetze.xlsx", volksbankFile, creditCardFile, "unknown.csv"}},
+		{"move", settings.OnSuccessMove, "", []string{"Umsaetze.xlsx", "processed", "unknown.csv"}},
+		{"move to archivedir", settings.OnSuccessMove, "archive", []string{"Umsaetze.xlsx", "unknown.csv"}},
+		{"delete", settings.OnSuccessDelete, "", []string{"Umsaetze.xlsx", "unknown.csv"}},
+	}
+	for _, tc := range testCases {
+		// Umsaetze.xlsx is skipped and unknown.csv fails, so both are kept
+		tmpDir := t.TempDir()
+		inputDir := filepath.Join(tmpDir, "input")
+		outputDir := filepath.Join(tmpDir, "output")
+		for _, dir := range []string{inputDir, outputDir} {
+			if err := os.Mkdir(dir, 0o700); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range []string{"Umsaetze.xlsx", volksbankFile, creditCardFile} {
+			if err := copyFile(filepath.Join(testfilesBase, "input", "mixed", name), filepath.Join(inputDir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(inputDir, "unknown.csv"), []byte("no;known;format\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyFile(filepath.Join(testfilesBase, "expected_output", "mixed", "Umsaetze.csv"), filepath.Join(outputDir, "Umsaetze.csv")); err != nil {
+			t.Fatal(err)
+		}
+
+		archiveDir := filepath.Join(inputDir, "processed")
+		set := settings.BatchConvertSet{
+			Name:      "mixed",
+			InputDir:  inputDir,
+			OutputDir: outputDir,
+			OnSuccess: tc.onSuccess,
+		}
+		if tc.archiveDir != "" {
+			archiveDir = filepath.Join(tmpDir, tc.archiveDir)
+			set.ArchiveDir = archiveDir
+		}
+		batchSettings := settings.BatchConvertSettings{Sets: []settings.BatchConvertSet{set}}
+
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for _, f := range status[0].Files {
+			expectedArchived := ""
+			if tc.onSuccess == settings.OnSuccessMove && f.Status == ConversionSuccess {
+				expectedArchived = filepath.Join(archiveDir, filepath.Base(f.InputFile))
+			}
+			if f.Archived != expectedArchived {
+				t.Errorf("%s: expected '%s' archived as '%s', got '%s'", tc.name, f.InputFile, expectedArchived, f.Archived)
+			}
+		}
+
+		entries, err := os.ReadDir(inputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var inputFiles []string
+		for _, entry := range entries {
+			inputFiles = append(inputFiles, entry.Name())
+		}
+		if !reflect.DeepEqual(inputFiles, tc.inputFiles) {
+			t.Errorf("%s: expected input files %v, got %v", tc.name, tc.inputFiles, inputFiles)
+		}
+		if tc.onSuccess == settings.OnSuccessMove {
+			files, err := getFilesInDirectory(archiveDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := []string{volksbankFile, creditCardFile}; !reflect.DeepEqual(extractFileNames(files), expected) {
+				t.Errorf("%s: expected archived files %v, got %v", tc.name, expected, extractFileNames(files))
+			}
+		}
+
+		// The archive directory is not searched for input files
+		status, err = BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		expectedFiles := len(tc.inputFiles)
+		if tc.onSuccess == settings.OnSuccessMove && tc.archiveDir == "" {
+			expectedFiles--
+		}
+		if len(status[0].Files) != expectedFiles {
+			t.Errorf("%s: expected %d files in second run, got %v", tc.name, expectedFiles, status[0].Files)
+		}
+	}
+}
+
+func TestBatchConvertSkipModeHash(t *testing.T) {
+	testfilesBase, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixedInputDir := filepath.Join(testfilesBase, "input", "mixed")
+	mixedExpectedDir := filepath.Join(testfilesBase, "expected_output", "mixed")
+	const volksbankFile = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	const creditCardFile = "Umsaetze_Kreditkarte_2023.10.05.csv"
+
+	// The bank always uses the same name for its exports
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	inputFile := filepath.Join(inputDir, "Umsaetze.csv")
+	export := func(name string) {
+		if err := copyFile(filepath.Join(mixedInputDir, name), inputFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	convert := func(skipMode settings.SkipMode, dryRun bool) FileStatus {
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "reused",
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					SkipMode:  skipMode,
+				},
+			},
+			DryRun: dryRun,
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status[0].Files) != 1 {
+			t.Fatalf("Expected 1 file, got %v", status[0].Files)
+		}
+		return status[0].Files[0]
+	}
+	check := func(f FileStatus, expectedStatus ConversionStatus, expectedOutput string) {
+		t.Helper()
+		if f.Status != expectedStatus || f.OutputFile != filepath.Join(outputDir, expectedOutput) {
+			t.Errorf("Expected status %v and output file '%s', got %v and '%s' (%v)", expectedStatus, expectedOutput, f.Status, f.OutputFile, f.Error)
+		}
+	}
+
+	export(volksbankFile)
+	check(convert(settings.SkipByHash, false), ConversionSuccess, "Umsaetze.csv")
+
+	// The same export again is skipped
+	f := convert(settings.SkipByHash, false)
+	check(f, Skipped, "Umsaetze.csv")
+	if f.SkipReason != "output file has the same content" {
+		t.Errorf("Unexpected skip reason '%s'", f.SkipReason)
+	}
+
+	// A new export with the same name is skipped by name, but converted by content
+	export(creditCardFile)
+	check(convert(settings.SkipByName, false), Skipped, "Umsaetze.csv")
+	check(convert(settings.SkipByHash, true), WouldConvert, "Umsaetze-2.csv")
+	if _, err := os.Stat(filepath.Join(outputDir, "Umsaetze-2.csv")); err == nil {
+		t.Error("Expected no file written in dry run")
+	}
+	check(convert(settings.SkipByHash, false), ConversionSuccess, "Umsaetze-2.csv")
+	check(convert(settings.SkipByHash, false), Skipped, "Umsaetze-2.csv")
+
+	// The first export is still known
+	export(volksbankFile)
+	check(convert(settings.SkipByHash, false), Skipped, "Umsaetze.csv")
+
+	for name, expected := range map[string]string{"Umsaetze.csv": volksbankFile, "Umsaetze-2.csv": creditCardFile} {
+		if equal, err := areFilesEqual(filepath.Join(mixedExpectedDir, expected), filepath.Join(outputDir, name)); err != nil || !equal {
+			t.Errorf("Output file '%s' differs from '%s' (%v)", name, expected, err)
+		}
+	}
+	if files, _ := os.ReadDir(outputDir); len(files) != 2 {
+		t.Errorf("Expected 2 output files, got %v", files)
+	}
+}
+
+func TestBatchConvertMerge(t *testing.T) {
+	inputDir := filepath.Join("testfiles", "input", "dedupe")
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	for _, dedupe := range []bool{false, true} {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", outputDir)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:             "giro",
+					Format:           settings.FormatList{parser.Volksbank},
+					InputDir:         inputDir,
+					OutputDir:        outputDir,
+					Dedupe:           dedupe,
+					Merge:            true,
+					MergedOutputName: "{set}_{date}.csv",
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil, WithClock(func() time.Time { return now }))
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		files := status[0].Files
+		if len(files) != 2 {
+			t.Fatalf("Expected 2 files, got %d", len(files))
+		}
+		mergedFile := filepath.Join(outputDir, "giro_2024-01-31.csv")
+		for _, f := range files {
+			if f.Status != ConversionSuccess {
+				t.Fatalf("Expected ConversionSuccess for '%s', got %v", f.InputFile, f.Error)
+			}
+			if f.MergedInto != mergedFile || f.OutputFile != mergedFile {
+				t.Errorf("Expected '%s' as merged output file, got '%s'", mergedFile, f.MergedInto)
+			}
+		}
+		outputFiles, err := getFilesInDirectory(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outputFiles) != 1 {
+			t.Errorf("Expected only the merged output file, got %v", outputFiles)
+		}
+
+		// The second export overlaps the first one in two entries
+		expectedDuplicates := 0
+		expectedLines := 8
+		if dedupe {
+			expectedDuplicates = 2
+			expectedLines = 6
+		}
+		if files[1].Duplicates != expectedDuplicates {
+			t.Errorf("dedupe %v: expected %d duplicates, got %d", dedupe, expectedDuplicates, files[1].Duplicates)
+		}
+		content, err := os.ReadFile(mergedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != expectedLines {
+			t.Fatalf("dedupe %v: expected %d lines, got:\n%s", dedupe, expectedLines, content)
+		}
+		if !strings.HasPrefix(lines[1], "2023-09-29;") || !strings.HasPrefix(lines[len(lines)-1], "2023-10-06;") {
+			t.Errorf("Expected the entries sorted by date, got:\n%s", content)
+		}
+	}
+}
+
+func TestBatchConvertMergeSkipped(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+	if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+		t.Fatalf("Failed to create directory '%s'", outputDir)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "giro",
+				InputDir:  filepath.Join("testfiles", "input", "dedupe"),
+				OutputDir: outputDir,
+				Merge:     true,
+			},
+		},
+	}
+	for run, expected := range []ConversionStatus{ConversionSuccess, Skipped} {
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		for _, f := range status[0].Files {
+			if f.Status != expected {
+				t.Errorf("Run %d: expected status %v for '%s', got %v", run, expected, f.InputFile, f.Status)
+			}
+			if f.MergedInto != filepath.Join(outputDir, "giro.csv") {
+				t.Errorf("Run %d: expected default merged output file 'giro.csv', got '%s'", run, f.MergedInto)
+			}
+		}
+	}
+}
+
+func TestBatchConvertMergeFailure(t *testing.T) {
+	inputDir := t.TempDir()
+	const validFile = "Umsaetze_DE12345678901234567890_2023.10.02.csv"
+	if err := copyFile(filepath.Join("testfiles", "input", "dedupe", validFile), filepath.Join(inputDir, validFile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "invalid.csv"), []byte("no bank export"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, failFast := range []bool{false, true} {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", outputDir)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:          "giro",
+					InputDir:      inputDir,
+					OutputDir:     outputDir,
+					Merge:         true,
+					MergeFailFast: failFast,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		files := status[0].Files
+		if len(files) != 2 {
+			t.Fatalf("Expected 2 files, got %d", len(files))
+		}
+		// The files are sorted, "Umsaetze_..." comes before "invalid.csv"
+		if files[1].Status != ConversionError {
+			t.Errorf("failFast %v: expected ConversionError for invalid file, got %v", failFast, files[1].Status)
+		}
+
+		mergedFile := filepath.Join(outputDir, "giro.csv")
+		content, err := os.ReadFile(mergedFile)
+		if failFast {
+			if files[0].Status != ConversionError || files[0].Error == nil {
+				t.Errorf("Expected ConversionError for valid file in fail fast mode, got %v", files[0].Status)
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected no merged output file in fail fast mode, got error %v", err)
+			}
+			continue
+		}
+		if files[0].Status != ConversionSuccess {
+			t.Errorf("Expected ConversionSuccess for valid file, got %v", files[0].Error)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 4 {
+			t.Errorf("Expected the entries of the valid file, got:\n%s", content)
+		}
+	}
+}
+
+func TestBatchConvertMergeFailFastSkipped(t *testing.T) {
+	inputDir := t.TempDir()
+	const validFile = "Umsaetze_DE12345678901234567890_2023.10.02.csv"
+	if err := copyFile(filepath.Join("testfiles", "input", "dedupe", validFile), filepath.Join(inputDir, validFile)); err != nil {
+		t.Fatal(err)
+	}
+	// Sorted between the valid and the invalid file
+	if err := copyFile(filepath.Join("testfiles", "input", "empty", "umsaetze_1234567890_20231006_1804.csv"), filepath.Join(inputDir, "empty.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "invalid.csv"), []byte("no bank export"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "later.csv"), []byte("no bank export"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:          "giro",
+				InputDir:      inputDir,
+				OutputDir:     t.TempDir(),
+				Merge:         true,
+				MergeFailFast: true,
+				SkipEmpty:     true,
+			},
+		},
+	}
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatalf("BatchConvert should not return error, got %v", err)
+	}
+	expected := map[string]ConversionStatus{
+		validFile:     ConversionError,
+		"empty.csv":   SkippedEmpty,
+		"invalid.csv": ConversionError,
+		"later.csv":   ConversionError,
+	}
+	if len(status[0].Files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(status[0].Files))
+	}
+	for _, f := range status[0].Files {
+		if f.Status != expected[filepath.Base(f.InputFile)] {
+			t.Errorf("Expected status %v for '%s', got %v", expected[filepath.Base(f.InputFile)], f.InputFile, f.Status)
+		}
+		if f.Status == SkippedEmpty && f.Error != nil {
+			t.Errorf("Expected no error for skipped file '%s', got %v", f.InputFile, f.Error)
+		}
+	}
+}
+
+func TestBatchConvertOutputOptions(t *testing.T) {
+	inputDir := filepath.Join("testfiles", "input", "dedupe")
+	for _, merge := range []bool{false, true} {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		if err := os.Mkdir(outputDir, os.ModeDir|0o700); err != nil {
+			t.Fatalf("Failed to create directory '%s'", outputDir)
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:                   "giro",
+					Format:                 settings.FormatList{parser.Volksbank},
+					InputDir:               inputDir,
+					OutputDir:              outputDir,
+					Merge:                  merge,
+					OutputDelimiter:        "tab",
+					OutputDecimalSeparator: ",",
+					OutputNoHeader:         true,
+					OutputDateFormat:       "02.01.2006",
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatalf("BatchConvert should not return error, got %v", err)
+		}
+		for _, f := range status[0].Files {
+			if f.Status != ConversionSuccess {
+				t.Fatalf("merge %v: expected ConversionSuccess for '%s', got %v", merge, f.InputFile, f.Error)
+			}
+		}
+		outfile := filepath.Join(outputDir, "Umsaetze_DE12345678901234567890_2023.10.06.csv")
+		if merge {
+			outfile = filepath.Join(outputDir, "giro.csv")
+		}
+		content, err := os.ReadFile(outfile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "06.10.2023\t11\t\tStadtwerke\tAbschlag Strom Oktober\t-85,50\tSonstiges\t\n"
+		if !strings.Contains(string(content), expected) || strings.Contains(string(content), "date") {
+			t.Errorf("merge %v: expected line %q without header, got:\n%s", merge, expected, content)
+		}
+	}
+
+	// Invalid options are rejected before converting
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:                   "giro",
+				InputDir:               inputDir,
+				OutputDir:              t.TempDir(),
+				OutputDelimiter:        ",",
+				OutputDecimalSeparator: ",",
+			},
+		},
+	}
+	if _, err := BatchConvert(batchSettings, nil, nil); err == nil {
+		t.Error("Expected error for delimiter equal to the decimal separator")
+	}
+}
+
+func TestBatchConvertLogger(t *testing.T) {
+	outputDir := t.TempDir()
+	mixedExpectedDir := filepath.Join("testfiles", "expected_output", "mixed")
+	if err := copyFile(filepath.Join(mixedExpectedDir, "Umsaetze.csv"), filepath.Join(outputDir, "Umsaetze.csv")); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:      "mixed",
+				InputDir:  filepath.Join("testfiles", "input", "mixed"),
+				OutputDir: outputDir,
+			},
+		},
+	}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := BatchConvert(batchSettings, nil, nil, WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`msg="found input files" set=mixed`,
+		`msg="detected format"`,
+		`msg="parsed file" set=mixed file=testfiles/input/mixed/Umsaetze_Kreditkarte_2023.10.05.csv format=VolksbankMastercard entries=3`,
+		`msg="skipped file" set=mixed file=testfiles/input/mixed/Umsaetze.xlsx`,
+		`reason="output file exists"`,
+		`msg="converted file" set=mixed file=testfiles/input/mixed/Umsaetze_Kreditkarte_2023.10.05.csv`,
+	} {
+		if !strings.Contains(logs.String(), filepath.FromSlash(expected)) {
+			t.Errorf("Expected '%s' in logs:\n%s", expected, logs.String())
+		}
+	}
+}
+
+func TestBatchConvertClock(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir()},
+		},
+	}
+	// Each call of the clock advances it by a second
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	calls := 0
+	clock := func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Second)
+	}
+	status, err := BatchConvert(batchSettings, nil, nil, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := status[0].Files[0]
+	if f.Status != ConversionSuccess {
+		t.Fatalf("Expected conversion, got %v", f)
+	}
+	if !f.StartedAt.Equal(start.Add(3*time.Second)) || !f.FinishedAt.Equal(start.Add(4*time.Second)) {
+		t.Errorf("Expected conversion from 10:00:03 to 10:00:04, got %s to %s", f.StartedAt, f.FinishedAt)
+	}
+	if f.Duration() != time.Second {
+		t.Errorf("Expected file duration of 1s, got %s", f.Duration())
+	}
+	if status[0].Duration != 3*time.Second {
+		t.Errorf("Expected set duration of 3s, got %s", status[0].Duration)
+	}
+	summary := status.Summary()
+	if summary.FileDuration != time.Second || summary.Sets[0].FileDuration != time.Second || summary.Duration != 3*time.Second {
+		t.Errorf("Expected durations in the summary, got %v", summary)
+	}
+
+	// The files are checked against the age at the start of the conversion
+	batchSettings.Sets[0].FileMaxAge = settings.MaxAgeDays(1)
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	status, err = BatchConvert(batchSettings, nil, nil, WithClock(func() time.Time { return time.Now().AddDate(0, 0, 2) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status[0].Files) != 0 {
+		t.Errorf("Expected no files younger than a day, got %v", status[0].Files)
+	}
+}
+
+func TestFileStatusDuration(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		f        FileStatus
+		expected time.Duration
+	}{
+		{FileStatus{}, 0},
+		{FileStatus{StartedAt: start}, 0},
+		{FileStatus{StartedAt: start, FinishedAt: start.Add(1500 * time.Millisecond)}, 1500 * time.Millisecond},
+	} {
+		if d := tc.f.Duration(); d != tc.expected {
+			t.Errorf("Expected duration %s of %v, got %s", tc.expected, tc.f, d)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+// DefaultCommandTimeout is the time after which OnSuccessCommand and OnFinishCommand are
+// killed if CommandTimeout is not set
+const DefaultCommandTimeout = time.Minute
+
+// Values of the environment variable GHC_STATUS
+const (
+	commandStatusSuccess = "success" // All files were converted or skipped
+	commandStatusFailed  = "failed"  // At least one file failed
+)
+
+// runSuccessCommand runs the OnSuccessCommand of the set for the converted outfile.
+// Nothing is run without command or in a dry run.
+func (sc *setConversion) runSuccessCommand(ctx context.Context, outfile string) error {
+	if len(sc.set.OnSuccessCommand) == 0 || sc.dryRun {
+		return nil
+	}
+	return runCommand(ctx, sc.set.OnSuccessCommand, sc.commandTimeout,
+		"GHC_OUTPUT_FILE="+outfile,
+		"GHC_SET="+sc.set.Name,
+		"GHC_STATUS="+commandStatusSuccess)
+}
+
+// RunOnFinishCommand runs the OnFinishCommand of the settings after the conversion of all
+// sets with the resulting status. GHC_STATUS is "failed" if a file failed, otherwise
+// "success". Nothing is run without command or in a dry run.
+func RunOnFinishCommand(ctx context.Context, s settings.BatchConvertSettings, status BatchStatus) error {
+	if len(s.OnFinishCommand) == 0 || s.DryRun {
+		return nil
+	}
+	result := commandStatusSuccess
+	if status.Summary().Files[ConversionError] > 0 {
+		result = commandStatusFailed
+	}
+	return runCommand(ctx, s.OnFinishCommand, s.CommandTimeout, "GHC_STATUS="+result)
+}
+
+// runCommand runs the command with the environment variables added to the ones of the
+// process. The command is killed after timeout, DefaultCommandTimeout if zero, or if ctx
+// is cancelled. The output of a failed command is part of the error.
+func runCommand(ctx context.Context, command []string, timeout time.Duration, env ...string) error {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	// Don't wait for children of the command still holding the output open
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("command '%s' timed out after %s", command[0], timeout)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("command '%s' stopped: %w", command[0], ctx.Err())
+	}
+	if output := strings.TrimSpace(string(out)); output != "" {
+		return fmt.Errorf("command '%s' failed: %w: %s", command[0], err, output)
+	}
+	return fmt.Errorf("command '%s' failed: %w", command[0], err)
+}
@@ -0,0 +1,143 @@
+package batchconvert
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+// skipWithoutShell skips the test on Windows, the commands are run by sh
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("The test commands need sh")
+	}
+}
+
+// logCommand returns a shell command appending the environment variables of the
+// conversion to logFile
+func logCommand(logFile string) []string {
+	return []string{"sh", "-c", `echo "$GHC_SET $GHC_STATUS $GHC_OUTPUT_FILE" >> "$0"`, logFile}
+}
+
+func TestBatchConvertOnSuccessCommand(t *testing.T) {
+	skipWithoutShell(t)
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	logFile := filepath.Join(t.TempDir(), "commands.log")
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:             "volksbank",
+				InputDir:         inputDir,
+				OutputDir:        outputDir,
+				OnSuccessCommand: logCommand(logFile),
+			},
+		},
+	}
+
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := status[0].Files[0]; f.Status != ConversionSuccess || f.CommandError != nil {
+		t.Fatalf("Expected conversion without command error, got %v", f)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "volksbank success " + filepath.Join(outputDir, filename) + "\n"; string(content) != expected {
+		t.Errorf("Expected command output '%s', got '%s'", expected, content)
+	}
+
+	// Not run for skipped files
+	if _, err := BatchConvert(batchSettings, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(logFile); strings.Count(string(content), "\n") != 1 {
+		t.Errorf("Expected a single command run, got '%s'", content)
+	}
+
+	// A failed command doesn't fail the conversion
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	batchSettings.Sets[0].OnSuccessCommand = []string{"sh", "-c", "echo no connection; exit 3"}
+	status, err = BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := status[0].Files[0]
+	if f.Status != ConversionSuccess || f.CommandError == nil || !strings.Contains(f.CommandError.Error(), "no connection") {
+		t.Errorf("Expected conversion with command error, got %v", f)
+	}
+	if summary := status.Summary(); summary.FailedCommands != 1 || summary.Files[ConversionSuccess] != 1 {
+		t.Errorf("Expected a failed command in the summary, got %v", summary)
+	}
+
+	// The command is killed after the timeout
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	batchSettings.Sets[0].OnSuccessCommand = []string{"sleep", "10"}
+	batchSettings.CommandTimeout = 100 * time.Millisecond
+	start := time.Now()
+	status, err = BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := status[0].Files[0]; f.CommandError == nil || !strings.Contains(f.CommandError.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", f.CommandError)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected command to be killed, took %s", elapsed)
+	}
+}
+
+func TestRunOnFinishCommand(t *testing.T) {
+	skipWithoutShell(t)
+	logFile := filepath.Join(t.TempDir(), "commands.log")
+	s := settings.BatchConvertSettings{OnFinishCommand: logCommand(logFile)}
+	status := BatchStatus{
+		{Name: "giro", Files: []FileStatus{{Status: ConversionSuccess}}},
+		{Name: "visa", Files: []FileStatus{{Status: ConversionError}}},
+	}
+	if err := RunOnFinishCommand(context.Background(), s, status); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunOnFinishCommand(context.Background(), s, status[:1]); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := " failed \n success \n"; string(content) != expected {
+		t.Errorf("Expected command output '%s', got '%s'", expected, content)
+	}
+
+	// Not run in a dry run
+	s.DryRun = true
+	if err := RunOnFinishCommand(context.Background(), s, status); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(logFile); strings.Count(string(content), "\n") != 2 {
+		t.Errorf("Expected no command run in a dry run, got '%s'", content)
+	}
+
+	// A cancelled context stops the command
+	s.DryRun = false
+	s.OnFinishCommand = []string{"sleep", "10"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := RunOnFinishCommand(ctx, s, status); err == nil || !strings.Contains(err.Error(), "stopped") {
+		t.Errorf("Expected stopped command, got %v", err)
+	}
+}
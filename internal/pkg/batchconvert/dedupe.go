@@ -0,0 +1,72 @@
+package batchconvert
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/atomicfile"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// fingerprints maps transaction fingerprints to the output files containing them,
+// see parser.Transaction.Fingerprint
+type fingerprints map[string][]string
+
+// readFingerprints returns the fingerprints of all entries of the homebank CSV files
+// in outputDir, i.e. of the previously converted files. With recursive the files in
+// the subdirectories of outputDir are read as well.
+func readFingerprints(outputDir string, recursive bool) (fingerprints, error) {
+	var files []string
+	var err error
+	if recursive {
+		files, err = findFilesRecursive(outputDir, "*.csv", nil, time.Time{}, nil, true, nil)
+	} else {
+		files, err = filepath.Glob(filepath.Join(outputDir, "*.csv"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := make(fingerprints)
+	for _, file := range files {
+		p := parser.GetParser(parser.Homebank)
+		if err := p.ParseFile(file); err != nil {
+			return nil, fmt.Errorf("cannot read previously converted file '%s': %w", file, err)
+		}
+		f.add(p.GetEntries(), file)
+	}
+	return f, nil
+}
+
+// add adds the fingerprints of the entries contained in file
+func (f fingerprints) add(entries []parser.Transaction, file string) {
+	for _, e := range entries {
+		fp := e.Fingerprint()
+		if !slices.Contains(f[fp], file) {
+			f[fp] = append(f[fp], file)
+		}
+	}
+}
+
+// removeDuplicates returns the entries whose fingerprint is not contained in a file
+// other than outfile and the number of removed entries. The previous content of an
+// overwritten outfile is ignored. Entries which are equal to each other are kept.
+func (f fingerprints) removeDuplicates(entries []parser.Transaction, outfile string) ([]parser.Transaction, int) {
+	unique := make([]parser.Transaction, 0, len(entries))
+	for _, e := range entries {
+		files := f[e.Fingerprint()]
+		if !slices.ContainsFunc(files, func(file string) bool { return file != outfile }) {
+			unique = append(unique, e)
+		}
+	}
+	return unique, len(entries) - len(unique)
+}
+
+// writeEntries writes the entries to the homebank CSV file outfile with the output options
+func writeEntries(outfile string, entries []parser.Transaction, output parser.OutputOptions) error {
+	return atomicfile.Write(outfile, outputFilePerm, func(w io.Writer) error {
+		return parser.WriteHomebankCSV(w, entries, parser.WithOutputOptions(output))
+	})
+}
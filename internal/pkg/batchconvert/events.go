@@ -0,0 +1,80 @@
+package batchconvert
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+// EventKind is the kind of an Event
+type EventKind int
+
+const (
+	FileEvent     EventKind = iota // The conversion status of a single file changed
+	SetErrorEvent                  // The set can't be converted, see BatchSetStatus.Error
+)
+
+// Event is a change of the conversion status of a single file, or the error of a set
+type Event struct {
+	Kind       EventKind        // Kind of the event, only SetName and Err are set for SetErrorEvent
+	SetName    string           // Name of the set of the file
+	InputFile  string           // Absolute path of the input file
+	OldStatus  ConversionStatus // Status before the change
+	NewStatus  ConversionStatus // Status after the change
+	OutputFile string           // Absolute path of the output file, if already known
+	Err        error            // Reason of a failed conversion or set
+}
+
+// withEvents sets the function called with each status change, see BatchConvertEvents
+func withEvents(f func(Event)) Option {
+	return func(o *options) {
+		o.events = f
+	}
+}
+
+// BatchConvertEvents converts the files of all sets like BatchConvertContext, but reports
+// the progress as events instead of calling a StatusCallback with the whole status.
+//
+// Each found file is reported first with OldStatus and NewStatus NotStartedYet, then each
+// change of its status. A set which can't be converted, e.g. as its output directory is
+// missing, is reported with a SetErrorEvent. The events are sent in the order in which the changes happen, the
+// ones of a file and of a set are never reordered. The sets are converted one after the
+// other, so all events of a set are sent before the ones of the next set. The conversion
+// waits for the events to be received.
+//
+// The events channel is closed when the conversion is done. Then the error of the
+// conversion, nil on success, is sent on the error channel. Events of changes after ctx
+// is cancelled may be left out.
+func BatchConvertEvents(ctx context.Context, s settings.BatchConvertSettings, opts ...Option) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errc := make(chan error, 1)
+	send := func(e Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		_, err := BatchConvertContext(ctx, s, nil, nil, slices.Concat(opts, []Option{withEvents(send)})...)
+		close(events)
+		errc <- err
+		close(errc)
+	}()
+	return events, errc
+}
+
+// emit reports the change of the status of the file from old
+func (sc *setConversion) emit(old ConversionStatus, f FileStatus) {
+	if sc.events == nil {
+		return
+	}
+	sc.events(Event{
+		SetName:    sc.set.Name,
+		InputFile:  f.InputFile,
+		OldStatus:  old,
+		NewStatus:  f.Status,
+		OutputFile: f.OutputFile,
+		Err:        f.Error,
+	})
+}
@@ -0,0 +1,188 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+func TestBatchConvertEvents(t *testing.T) {
+	mixedDir := filepath.Join("testfiles", "input", "mixed")
+	inputDir := t.TempDir()
+	entries, err := os.ReadDir(mixedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := copyFile(filepath.Join(mixedDir, entry.Name()), filepath.Join(inputDir, entry.Name())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Fails as its format can't be detected
+	if err := os.WriteFile(filepath.Join(inputDir, "invalid.csv"), []byte("no;export\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "mixed", InputDir: inputDir, OutputDir: t.TempDir()},
+			{Name: "again", InputDir: inputDir, OutputDir: t.TempDir(), FileGlobPattern: "Umsaetze*"},
+		},
+		Parallelism: 4,
+	}
+	events, errc := BatchConvertEvents(context.Background(), batchSettings)
+
+	// Received by several consumers, the lock keeps the order of the events
+	var mu sync.Mutex
+	var received []Event
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				e, ok := <-events
+				if ok {
+					received = append(received, e)
+				}
+				mu.Unlock()
+				if !ok {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	// Per file the events follow each other, starting with the found file
+	type fileKey struct{ set, file string }
+	last := make(map[fileKey]ConversionStatus)
+	var sets []string
+	for _, e := range received {
+		if len(sets) == 0 || sets[len(sets)-1] != e.SetName {
+			sets = append(sets, e.SetName)
+		}
+		key := fileKey{e.SetName, filepath.Base(e.InputFile)}
+		previous, found := last[key]
+		if !found && (e.OldStatus != NotStartedYet || e.NewStatus != NotStartedYet) {
+			t.Errorf("Expected found event first for %v, got %v", key, e)
+		}
+		if found && e.OldStatus != previous {
+			t.Errorf("Expected old status %d for %v, got %v", previous, key, e)
+		}
+		last[key] = e.NewStatus
+	}
+	if expected := []string{"mixed", "again"}; len(sets) != 2 || sets[0] != expected[0] || sets[1] != expected[1] {
+		t.Errorf("Expected events of the sets %v one after the other, got %v", expected, sets)
+	}
+	expected := map[fileKey]ConversionStatus{
+		{"mixed", "Umsaetze.xlsx"}:                                  ConversionSuccess,
+		{"mixed", "Umsaetze_DE12345678901234567890_2023.10.04.csv"}: ConversionSuccess,
+		{"mixed", "Umsaetze_Kreditkarte_2023.10.05.csv"}:            ConversionSuccess,
+		{"mixed", "invalid.csv"}:                                    ConversionError,
+		{"again", "Umsaetze.xlsx"}:                                  ConversionSuccess,
+		{"again", "Umsaetze_DE12345678901234567890_2023.10.04.csv"}: ConversionSuccess,
+		{"again", "Umsaetze_Kreditkarte_2023.10.05.csv"}:            ConversionSuccess,
+	}
+	if len(last) != len(expected) {
+		t.Errorf("Expected events of %d files, got %d", len(expected), len(last))
+	}
+	for key, status := range expected {
+		if last[key] != status {
+			t.Errorf("Expected final status %d for %v, got %d", status, key, last[key])
+		}
+	}
+	for _, e := range received {
+		if e.NewStatus == ConversionError && e.Err == nil {
+			t.Errorf("Expected error in event %v", e)
+		}
+	}
+}
+
+func TestBatchConvertEventsCancel(t *testing.T) {
+	inputDir := t.TempDir()
+	src := filepath.Join("testfiles", "input", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	for _, name := range []string{"a.csv", "b.csv", "c.csv"} {
+		if err := copyFile(src, filepath.Join(inputDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir()},
+		},
+	}
+
+	// Cancelled as soon as the conversion of the first file started, the changes after
+	// the cancellation may be left out
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errc := BatchConvertEvents(ctx, batchSettings)
+	started := 0
+	for e := range events {
+		if e.NewStatus == ConversionInProgress {
+			started++
+			cancel()
+		}
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	if started != 1 {
+		t.Errorf("Expected a single started file, got %d", started)
+	}
+
+	// Invalid settings are reported without events
+	batchSettings.Sets[0].OutputDir = ""
+	events, errc = BatchConvertEvents(context.Background(), batchSettings)
+	if _, ok := <-events; ok {
+		t.Error("Expected no events")
+	}
+	if err := <-errc; err == nil {
+		t.Error("Expected error for invalid settings")
+	}
+}
+
+func TestBatchConvertEventsSetError(t *testing.T) {
+	inputDir := t.TempDir()
+	src := filepath.Join("testfiles", "input", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	if err := copyFile(src, filepath.Join(inputDir, "a.csv")); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "missing", InputDir: inputDir, OutputDir: filepath.Join(t.TempDir(), "missing"), FileGlobPattern: "*.csv"},
+			{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir()},
+		},
+	}
+	events, errc := BatchConvertEvents(context.Background(), batchSettings)
+	var received []Event
+	for e := range events {
+		received = append(received, e)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed set is reported before the files of the next set
+	if len(received) == 0 || received[0].Kind != SetErrorEvent || received[0].SetName != "missing" || received[0].Err == nil {
+		t.Fatalf("Expected set error event of set 'missing' first, got %v", received)
+	}
+	for _, e := range received[1:] {
+		if e.Kind != FileEvent || e.SetName != "volksbank" {
+			t.Errorf("Expected file event of set 'volksbank', got %v", e)
+		}
+	}
+	if last := received[len(received)-1]; last.NewStatus != ConversionSuccess {
+		t.Errorf("Expected converted file last, got %v", last)
+	}
+}
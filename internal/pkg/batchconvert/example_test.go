@@ -0,0 +1,65 @@
+package batchconvert_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/batchconvert"
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// As batchconvert is an internal package, this example is not rendered on pkg.go.dev.
+// It is meant as internal documentation, shown by the local pkgsite of "make doc-serve",
+// and is run with the tests.
+func ExampleBatchConvert() {
+	inputDir, err := os.MkdirTemp("", "input")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(inputDir)
+	outputDir, err := os.MkdirTemp("", "output")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(outputDir)
+
+	filename := "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	content, err := os.ReadFile(filepath.Join("testfiles", "input", "volksbank", filename))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, filename), content, 0o644); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	s, err := settings.New(settings.WithSet(settings.BatchConvertSet{
+		Name:            "Volksbank",
+		InputDir:        inputDir,
+		OutputDir:       outputDir,
+		Format:          settings.FormatList{parser.Volksbank},
+		FileGlobPattern: "*.csv",
+	}))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	status, err := batchconvert.BatchConvert(s.BatchConvert, nil, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, set := range status {
+		for _, file := range set.Files {
+			fmt.Printf("%s: %s converted: %t\n", set.Name, filepath.Base(file.OutputFile),
+				file.Status == batchconvert.ConversionSuccess)
+		}
+	}
+	// Output: Volksbank: Umsaetze_DE12345678901234567890_2023.10.04.csv converted: true
+}
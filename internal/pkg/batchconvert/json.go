@@ -0,0 +1,159 @@
+package batchconvert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// MarshalJSON returns the textual representation of the conversion status as JSON string
+func (s ConversionStatus) MarshalJSON() ([]byte, error) {
+	if _, ok := conversionStatuses[s]; !ok {
+		return nil, fmt.Errorf("unknown conversion status %d", int(s))
+	}
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON sets the conversion status from its textual representation as JSON string.
+// The comparison is case-insensitive.
+func (s *ConversionStatus) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	for key, value := range conversionStatuses {
+		if strings.EqualFold(value, text) {
+			*s = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conversion status '%s'", text)
+}
+
+// rowErrorJSON is the JSON representation of a parser.ParserError, with the error type
+// and the underlying error as text
+type rowErrorJSON struct {
+	ErrorType string
+	Line      int    `json:",omitempty"`
+	Column    int    `json:",omitempty"`
+	Field     string `json:",omitempty"`
+	File      string `json:",omitempty"`
+	Err       string `json:",omitempty"`
+}
+
+// Without the methods of FileStatus and BatchSetStatus, to marshal their other fields
+type (
+	fileStatusFields     FileStatus
+	batchSetStatusFields BatchSetStatus
+)
+
+// fileStatusJSON is the JSON representation of FileStatus, with the errors as messages
+type fileStatusJSON struct {
+	fileStatusFields
+	RowErrors    []rowErrorJSON `json:",omitempty"`
+	Error        string         `json:",omitempty"`
+	CommandError string         `json:",omitempty"`
+}
+
+// batchSetStatusJSON is the JSON representation of BatchSetStatus, with the error as message
+type batchSetStatusJSON struct {
+	batchSetStatusFields
+	Error string `json:",omitempty"`
+}
+
+// MarshalJSON returns the status as JSON object. Errors are given as their messages, the
+// format by its name.
+func (f FileStatus) MarshalJSON() ([]byte, error) {
+	v := fileStatusJSON{
+		fileStatusFields: fileStatusFields(f),
+		Error:            errorMessage(f.Error),
+		CommandError:     errorMessage(f.CommandError),
+	}
+	for _, e := range f.RowErrors {
+		v.RowErrors = append(v.RowErrors, rowErrorJSON{
+			ErrorType: e.ErrorType.String(),
+			Line:      e.Line,
+			Column:    e.Column,
+			Field:     e.Field,
+			File:      e.File,
+			Err:       errorMessage(e.Err),
+		})
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON sets the status from a JSON object as returned by MarshalJSON. The errors
+// only keep their messages.
+func (f *FileStatus) UnmarshalJSON(data []byte) error {
+	var v fileStatusJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = FileStatus(v.fileStatusFields)
+	f.Error = messageError(v.Error)
+	f.CommandError = messageError(v.CommandError)
+	f.RowErrors = nil
+	for _, e := range v.RowErrors {
+		errorType, err := parseErrorType(e.ErrorType)
+		if err != nil {
+			return err
+		}
+		f.RowErrors = append(f.RowErrors, parser.ParserError{
+			ErrorType: errorType,
+			Line:      e.Line,
+			Column:    e.Column,
+			Field:     e.Field,
+			File:      e.File,
+			Err:       messageError(e.Err),
+		})
+	}
+	return nil
+}
+
+// MarshalJSON returns the status as JSON object with the error as message
+func (b BatchSetStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(batchSetStatusJSON{
+		batchSetStatusFields: batchSetStatusFields(b),
+		Error:                errorMessage(b.Error),
+	})
+}
+
+// UnmarshalJSON sets the status from a JSON object as returned by MarshalJSON
+func (b *BatchSetStatus) UnmarshalJSON(data []byte) error {
+	var v batchSetStatusJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = BatchSetStatus(v.batchSetStatusFields)
+	b.Error = messageError(v.Error)
+	return nil
+}
+
+// errorMessage returns the message of err, empty if nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// messageError returns an error with the message, nil if empty
+func messageError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// parseErrorType returns the parser error type with the textual representation
+func parseErrorType(text string) (parser.ParserErrorType, error) {
+	for t := parser.IOError; t <= parser.FormatError; t++ {
+		if t.String() == text {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown parser error type '%s'", text)
+}
@@ -0,0 +1,174 @@
+package batchconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+func TestConversionStatusString(t *testing.T) {
+	for status := NotStartedYet; status <= SkippedEmpty; status++ {
+		text := ConversionStatus(status).String()
+		if text == "" || text == "unknown conversion status" {
+			t.Errorf("Expected text of status %d, got '%s'", status, text)
+		}
+	}
+	if text := ConversionStatus(99).String(); text != "unknown conversion status" {
+		t.Errorf("Expected unknown conversion status, got '%s'", text)
+	}
+}
+
+func TestConversionStatusJSON(t *testing.T) {
+	for status := NotStartedYet; status <= SkippedEmpty; status++ {
+		data, err := json.Marshal(ConversionStatus(status))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s ConversionStatus
+		if err := json.Unmarshal(data, &s); err != nil || s != ConversionStatus(status) {
+			t.Errorf("Expected round trip of %s to %d, got %d (%v)", data, status, s, err)
+		}
+	}
+
+	var s ConversionStatus
+	if err := json.Unmarshal([]byte(`"Would-Convert"`), &s); err != nil || s != WouldConvert {
+		t.Errorf("Expected case-insensitive status, got %d (%v)", s, err)
+	}
+	if err := json.Unmarshal([]byte(`"done"`), &s); err == nil {
+		t.Error("Expected error for unknown status")
+	}
+	if err := json.Unmarshal([]byte(`4`), &s); err == nil {
+		t.Error("Expected error for numeric status")
+	}
+	if _, err := json.Marshal(ConversionStatus(99)); err == nil {
+		t.Error("Expected error for unknown status")
+	}
+}
+
+func TestBatchStatusJSON(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	status := BatchStatus{
+		{
+			Name:     "giro",
+			Duration: 1500 * time.Millisecond,
+			Files: []FileStatus{
+				{
+					InputFile:  "/input/Umsaetze.csv",
+					OutputFile: "/output/Umsaetze.csv",
+					Status:     ConversionSuccess,
+					Format:     parser.NewSourceFormat(parser.Volksbank),
+					Entries:    4,
+					RowErrors: []parser.ParserError{
+						{ErrorType: parser.DataParsingError, Line: 7, Field: "Betrag", File: "/input/Umsaetze.csv", Err: errors.New("invalid amount")},
+						{ErrorType: parser.FormatError, Line: 9, Column: 3},
+					},
+					Dropped:      1,
+					Duplicates:   2,
+					Archived:     "/input/processed/Umsaetze.csv",
+					StartedAt:    start,
+					FinishedAt:   start.Add(time.Second),
+					Attempts:     2,
+					CommandError: errors.New("command 'sync' failed"),
+				},
+				{
+					InputFile:  "/input/invalid.csv",
+					Status:     ConversionError,
+					Error:      &parser.ParserError{ErrorType: parser.HeaderError, File: "/input/invalid.csv"},
+					StartedAt:  start,
+					FinishedAt: start,
+					Attempts:   1,
+				},
+				{
+					InputFile:  "/input/Umsaetze-old.csv",
+					OutputFile: "/output/Umsaetze-old.csv",
+					Status:     Skipped,
+					SkipReason: "output file exists",
+				},
+			},
+		},
+		{
+			Name:  "visa",
+			Error: errors.New("output directory '/missing' does not exist"),
+		},
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"Status":"converted"`, `"Format":"Volksbank"`, `"Format":null`, `"ErrorType":"DataParsingError"`,
+		`"Err":"invalid amount"`, `"CommandError":"command 'sync' failed"`, `"StartedAt":"2024-03-01T10:00:00Z"`,
+		`"Error":"HeaderError in file '/input/invalid.csv'"`, `"Error":"output directory '/missing' does not exist"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in %s", expected, data)
+		}
+	}
+
+	var decoded BatchStatus
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("JSON differs after round trip:\n%s\n%s", data, again)
+	}
+	// The errors only keep their messages
+	if fmt.Sprint(decoded) != fmt.Sprint(status) {
+		t.Errorf("Status differs after round trip:\n%v\n%v", status, decoded)
+	}
+	if f := decoded[0].Files[0]; !f.StartedAt.Equal(start) || f.Attempts != 2 || f.RowErrors[0].Error() != status[0].Files[0].RowErrors[0].Error() {
+		t.Errorf("Expected fields to be kept, got %v", f)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"Files":[{"RowErrors":[{"ErrorType":"OtherError"}]}]}]`), &decoded); err == nil {
+		t.Error("Expected error for unknown parser error type")
+	}
+}
+
+func TestBatchConvertJSON(t *testing.T) {
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "mixed", InputDir: filepath.Join("testfiles", "input", "mixed"), OutputDir: t.TempDir()},
+		},
+	}
+	before := time.Now()
+	status, err := BatchConvert(batchSettings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range status[0].Files {
+		if f.StartedAt.Before(before) || f.FinishedAt.Before(f.StartedAt) {
+			t.Errorf("Expected start and end time of the conversion, got %s and %s", f.StartedAt, f.FinishedAt)
+		}
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded BatchStatus
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("JSON differs after round trip:\n%s\n%s", data, again)
+	}
+	if len(decoded) != 1 || len(decoded[0].Files) != 3 || decoded[0].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected status of 3 converted files, got %v", decoded)
+	}
+}
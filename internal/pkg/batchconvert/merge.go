@@ -0,0 +1,199 @@
+package batchconvert
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// mergeFiles converts the files of the set in merge mode into the single homebank CSV
+// file outfile.
+//
+// The files are parsed one after the other and their entries are written together,
+// sorted by date. They are written newest first with the sort order date-desc, otherwise
+// oldest first. In dedupe mode also the entries contained in a previous file of the set
+// are left out. The merged file is only converted again if the overwrite policy skips
+// none of the files. OnSuccessCommand is run once for the merged file.
+//
+// The entries of the other files are written if a file fails. With MergeFailFast nothing
+// is written then and the other files fail as well, except the skipped ones. If the set is cancelled, nothing is
+// written and the error of ctx is returned, the files not failed before get the status
+// NotStartedYet again.
+func (sc *setConversion) mergeFiles(ctx context.Context, files []string, outfile string) error {
+	set := sc.set
+	if len(files) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, len(files))
+	for fileNr, infile := range files {
+		reason, err := skipReason(set.Overwrite, infile, outfile)
+		if err != nil {
+			sc.failFiles(files, outfile, err)
+			return nil
+		}
+		reasons[fileNr] = reason
+	}
+	if !slices.Contains(reasons, "") {
+		for fileNr := range files {
+			sc.update(fileNr, func(f *FileStatus) {
+				f.OutputFile = outfile
+				f.MergedInto = outfile
+				f.Status = sc.outcome(Skipped)
+				f.SkipReason = reasons[fileNr]
+			})
+		}
+		return nil
+	}
+
+	var entries []parser.Transaction
+	parsed := make([]bool, len(files))
+	for fileNr, infile := range files {
+		if err := ctx.Err(); err != nil {
+			sc.resetFiles(files, parsed)
+			return err
+		}
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = outfile
+			f.MergedInto = outfile
+			f.Status = ConversionInProgress
+			f.Attempts = 1
+		})
+
+		var fileParser parser.Parser
+		err := sc.retry(ctx, func() (err error) {
+			fileParser, err = sc.parse(infile)
+			return err
+		}, fileNr)
+		if err != nil {
+			sc.update(fileNr, func(f *FileStatus) {
+				if fileParser != nil {
+					f.Format = parser.NewSourceFormat(fileParser.GetFormat())
+				}
+				f.Status = sc.outcome(ConversionError)
+				f.Error = err
+			})
+			if set.MergeFailFast {
+				err = fmt.Errorf("not merged as '%s' failed", filepath.Base(infile))
+				// Only the merged files and the ones not converted yet, skipped files keep
+				// their status
+				for otherNr := range files {
+					if parsed[otherNr] || otherNr > fileNr {
+						sc.update(otherNr, func(f *FileStatus) {
+							f.OutputFile = outfile
+							f.MergedInto = outfile
+							f.Status = sc.outcome(ConversionError)
+							f.Error = err
+						})
+					}
+				}
+				return nil
+			}
+			continue
+		}
+		sc.configure(fileParser)
+		if sc.skipEmpty(fileNr, fileParser) {
+			continue
+		}
+
+		fileEntries := fileParser.GetEntries()
+		var duplicates int
+		if set.Dedupe {
+			// Added with the input file to leave out its entries in the following files
+			fileEntries, duplicates = sc.known.removeDuplicates(fileEntries, outfile)
+			sc.known.add(fileEntries, infile)
+		}
+		entries = append(entries, fileEntries...)
+		parsed[fileNr] = true
+		sc.update(fileNr, func(f *FileStatus) {
+			f.Format = parser.NewSourceFormat(fileParser.GetFormat())
+			f.Entries = fileParser.GetNumberOfEntries()
+			f.RowErrors = fileParser.GetRowErrors()
+			f.Dropped = fileParser.GetNumberOfDroppedEntries()
+			f.Duplicates = duplicates
+		})
+	}
+	if !slices.Contains(parsed, true) {
+		return nil
+	}
+
+	if set.Sort == parser.SortDateDesc {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Date.After(entries[j].Date)
+		})
+	} else {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Date.Before(entries[j].Date)
+		})
+	}
+
+	if !sc.dryRun {
+		var parsedNrs []int
+		for fileNr := range files {
+			if parsed[fileNr] {
+				parsedNrs = append(parsedNrs, fileNr)
+			}
+		}
+		err := sc.retry(ctx, func() error {
+			return writeEntries(outfile, entries, sc.output)
+		}, parsedNrs...)
+		if err != nil {
+			for fileNr := range files {
+				if parsed[fileNr] {
+					sc.update(fileNr, func(f *FileStatus) {
+						f.Status = ConversionError
+						f.Error = err
+					})
+				}
+			}
+			return nil
+		}
+	}
+
+	// Run once for the merged file, its failure is reported for each merged file
+	commandErr := sc.runSuccessCommand(ctx, outfile)
+	for fileNr, infile := range files {
+		if !parsed[fileNr] {
+			continue
+		}
+		// The input files are only moved or deleted after the merged file is written
+		archived, err := sc.handleConverted(infile)
+		sc.update(fileNr, func(f *FileStatus) {
+			f.Archived = archived
+			f.Status = sc.outcome(ConversionSuccess)
+			f.CommandError = commandErr
+			if err != nil {
+				f.Status = ConversionError
+				f.Error = err
+			}
+		})
+	}
+	return nil
+}
+
+// failFiles sets the status of all files to failed with err
+func (sc *setConversion) failFiles(files []string, outfile string, err error) {
+	for fileNr := range files {
+		sc.update(fileNr, func(f *FileStatus) {
+			f.OutputFile = outfile
+			f.MergedInto = outfile
+			f.Status = sc.outcome(ConversionError)
+			f.Error = err
+		})
+	}
+}
+
+// resetFiles sets the status of the parsed files back to NotStartedYet
+func (sc *setConversion) resetFiles(files []string, parsed []bool) {
+	for fileNr, infile := range files {
+		if parsed[fileNr] {
+			sc.update(fileNr, func(f *FileStatus) {
+				*f = FileStatus{InputFile: infile, Status: NotStartedYet}
+			})
+		}
+	}
+}
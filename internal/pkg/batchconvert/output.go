@@ -0,0 +1,6 @@
+package batchconvert
+
+// outputFilePerm are the permissions of the output and state files before umask, the
+// ones of os.Create. They are written with atomicfile.Write, so an interrupted conversion
+// never leaves a truncated output file, which would be skipped by later runs.
+const outputFilePerm = 0o666
@@ -0,0 +1,84 @@
+package batchconvert
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// failingParser fails after writing the first bytes of the output
+type failingParser struct {
+	parser.Parser
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (p failingParser) WriteHomebank(w io.Writer) error {
+	if _, err := w.Write([]byte("date;payment;")); err != nil {
+		return err
+	}
+	return errWriteFailed
+}
+
+func TestBatchConvertNoPartialOutput(t *testing.T) {
+	orig := parseInputFile
+	t.Cleanup(func() { parseInputFile = orig })
+	parseInputFile = func(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+		p, err := orig(infile, format, o)
+		if err != nil {
+			return p, err
+		}
+		return failingParser{p}, nil
+	}
+
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	for _, overwrite := range []settings.OverwritePolicy{settings.OverwriteNever, settings.OverwriteAlways} {
+		// With overwrite the previous output file is kept
+		outputDir := t.TempDir()
+		if overwrite == settings.OverwriteAlways {
+			if err := os.WriteFile(filepath.Join(outputDir, filename), []byte("previous"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		batchSettings := settings.BatchConvertSettings{
+			Sets: []settings.BatchConvertSet{
+				{
+					Name:      "volksbank",
+					InputDir:  filepath.Join("testfiles", "input", "volksbank"),
+					OutputDir: outputDir,
+					Overwrite: overwrite,
+				},
+			},
+		}
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := status[0].Files[0]
+		if f.Status != ConversionError || !errors.Is(f.Error, errWriteFailed) {
+			t.Errorf("overwrite %s: expected ConversionError, got %v (%v)", overwrite, f.Status, f.Error)
+		}
+
+		files, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if overwrite == settings.OverwriteNever {
+			if len(files) != 0 {
+				t.Errorf("overwrite %s: expected no output files, got %v", overwrite, files)
+			}
+			continue
+		}
+		if len(files) != 1 {
+			t.Errorf("overwrite %s: expected only the previous output file, got %v", overwrite, files)
+		}
+		if content, err := os.ReadFile(filepath.Join(outputDir, filename)); err != nil || string(content) != "previous" {
+			t.Errorf("overwrite %s: expected previous content, got '%s' (%v)", overwrite, content, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// DefaultRetryDelay is the time waited between the attempts if RetryDelay is not set
+const DefaultRetryDelay = time.Second
+
+// isTransient reports whether err is an I/O error which may go away if retried, e.g. of a
+// network share. Missing files, denied access and invalid content are not retried.
+func isTransient(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return false
+	}
+	var parserErr *parser.ParserError
+	if errors.As(err, &parserErr) {
+		return parserErr.ErrorType == parser.IOError
+	}
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	return errors.As(err, &pathErr) || errors.As(err, &linkErr)
+}
+
+// retry calls f until it succeeds or fails with an error which is not transient, at most
+// Retries times more than once. RetryDelay is waited between the attempts, the last error
+// is returned if ctx is cancelled meanwhile. The attempts are recorded in the status of the
+// files.
+func (sc *setConversion) retry(ctx context.Context, f func() error, fileNrs ...int) error {
+	delay := sc.set.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	err := f()
+	for attempt := 2; err != nil && attempt <= sc.set.Retries+1 && isTransient(err); attempt++ {
+		if sc.logger != nil {
+			sc.logger.Info("retrying after transient error", "set", sc.set.Name, "attempt", attempt, "error", err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		for _, fileNr := range fileNrs {
+			sc.update(fileNr, func(f *FileStatus) {
+				f.Attempts = max(f.Attempts, attempt)
+			})
+		}
+		err = f()
+	}
+	return err
+}
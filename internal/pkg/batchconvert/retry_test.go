@@ -0,0 +1,141 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+func TestIsTransient(t *testing.T) {
+	eio := &fs.PathError{Op: "read", Path: "a.csv", Err: syscall.EIO}
+	testCases := []struct {
+		err       error
+		transient bool
+	}{
+		{eio, true},
+		{&parser.ParserError{ErrorType: parser.IOError, Err: eio}, true},
+		{fmt.Errorf("wrapped: %w", eio), true},
+		{&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EIO}, true},
+		{&parser.ParserError{ErrorType: parser.IOError, Err: &fs.PathError{Op: "open", Path: "a.csv", Err: fs.ErrNotExist}}, false},
+		{&fs.PathError{Op: "open", Path: "a.csv", Err: fs.ErrPermission}, false},
+		{&parser.ParserError{ErrorType: parser.HeaderError}, false},
+		{errors.New("no export"), false},
+	}
+	for _, tc := range testCases {
+		if transient := isTransient(tc.err); transient != tc.transient {
+			t.Errorf("%v: expected transient %t, got %t", tc.err, tc.transient, transient)
+		}
+	}
+}
+
+func TestBatchConvertRetries(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first attempts fail as if the network share was gone
+	var mu sync.Mutex
+	var calls, failures int
+	orig := parseInputFile
+	t.Cleanup(func() { parseInputFile = orig })
+	parseInputFile = func(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= failures {
+			return nil, &parser.ParserError{ErrorType: parser.IOError, Err: &fs.PathError{Op: "read", Path: infile, Err: syscall.EIO}}
+		}
+		return orig(infile, format, o)
+	}
+	convert := func(set settings.BatchConvertSet, failing int) FileStatus {
+		t.Helper()
+		calls, failures = 0, failing
+		status, err := BatchConvert(settings.BatchConvertSettings{Sets: []settings.BatchConvertSet{set}}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status[0].Files[0]
+	}
+
+	set := settings.BatchConvertSet{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir(), Retries: 2, RetryDelay: time.Millisecond}
+	f := convert(set, 1)
+	if f.Status != ConversionSuccess || f.Attempts != 2 {
+		t.Errorf("Expected success after 2 attempts, got %v", f)
+	}
+	equal, err := areFilesEqual(filepath.Join("testfiles", "expected_output", "volksbank", filename), f.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Error("Output file differs")
+	}
+
+	// Fails after the retries are used up
+	set.OutputDir = t.TempDir()
+	f = convert(set, 3)
+	if f.Status != ConversionError || f.Attempts != 3 || !isTransient(f.Error) {
+		t.Errorf("Expected I/O error after 3 attempts, got %v", f)
+	}
+
+	// Not retried by default
+	set.Retries = 0
+	f = convert(set, 1)
+	if f.Status != ConversionError || f.Attempts != 1 || calls != 1 {
+		t.Errorf("Expected error without retry, got %v after %d calls", f, calls)
+	}
+
+	// An invalid file is not retried
+	set.Retries = 2
+	if err := os.Remove(filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, filename), []byte("no;export\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f = convert(set, 0)
+	if f.Status != ConversionError || f.Attempts != 1 || calls != 1 {
+		t.Errorf("Expected invalid file not to be retried, got %v after %d calls", f, calls)
+	}
+}
+
+func TestBatchConvertRetryCancel(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	orig := parseInputFile
+	t.Cleanup(func() { parseInputFile = orig })
+	parseInputFile = func(infile string, format *parser.SourceFormat, o parser.ParseOptions) (parser.Parser, error) {
+		return nil, &parser.ParserError{ErrorType: parser.IOError, Err: &fs.PathError{Op: "read", Path: infile, Err: syscall.EIO}}
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "volksbank", InputDir: inputDir, OutputDir: t.TempDir(), Retries: 5, RetryDelay: time.Minute},
+		},
+	}
+
+	// The wait for the next attempt ends with the cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	status, _ := BatchConvertContext(ctx, batchSettings, nil, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected retry to be cancelled, took %s", elapsed)
+	}
+	if f := status[0].Files[0]; f.Status != ConversionError || f.Attempts != 1 {
+		t.Errorf("Expected error after a single attempt, got %v", f)
+	}
+}
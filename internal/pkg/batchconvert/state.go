@@ -0,0 +1,159 @@
+package batchconvert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/atomicfile"
+)
+
+// stateFileVersion is the version of the format of the state file
+const stateFileVersion = 1
+
+// stateEntry describes a successfully converted input file
+type stateEntry struct {
+	Size       int64     // Size of the input file in bytes
+	ModTime    time.Time // Modification time of the input file
+	Hash       string    // SHA-256 of the content of the input file, hex encoded
+	OutputFile string    // Output file the input file was converted to
+	Converted  time.Time // When the input file was converted
+}
+
+// stateFileContent is the content of the state file as JSON
+type stateFileContent struct {
+	Version int
+	Files   map[string]stateEntry // By absolute path of the input file
+}
+
+// conversionState are the input files converted by previous runs of a set, stored in
+// the state file. It is safe for concurrent use.
+type conversionState struct {
+	file    string
+	mu      sync.Mutex
+	files   map[string]stateEntry
+	changed bool
+	loaded  bool // Whether the files were read from the state file
+}
+
+// loadState reads the state file. A missing file results in an empty state which is not
+// loaded. With reset
+// the file is not read and it is replaced by the next save. If the file can't be read,
+// e.g. as it is corrupted, the state is empty as well and the error is returned.
+func loadState(file string, reset bool) (*conversionState, error) {
+	s := &conversionState{file: file, files: make(map[string]stateEntry), changed: reset}
+	if reset {
+		return s, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var content stateFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return s, fmt.Errorf("invalid state file '%s': %w", file, err)
+	}
+	if content.Version != stateFileVersion {
+		return s, fmt.Errorf("unsupported version %d of state file '%s'", content.Version, file)
+	}
+	if content.Files != nil {
+		s.files = content.Files
+	}
+	s.loaded = true
+	return s, nil
+}
+
+// converted returns the entry of infile if it was converted before and didn't change since
+func (s *conversionState) converted(infile string) (stateEntry, bool) {
+	key, err := filepath.Abs(infile)
+	if err != nil {
+		return stateEntry{}, false
+	}
+	s.mu.Lock()
+	entry, found := s.files[key]
+	s.mu.Unlock()
+	if !found {
+		return stateEntry{}, false
+	}
+	// The content is only hashed if size and modification time match
+	info, err := os.Stat(infile)
+	if err != nil || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		return stateEntry{}, false
+	}
+	current, err := newStateEntry(infile, entry.OutputFile, entry.Converted)
+	if err != nil {
+		return stateEntry{}, false
+	}
+	return entry, current.Hash == entry.Hash
+}
+
+// record adds infile converted to outfile at now
+func (s *conversionState) record(infile string, outfile string, now time.Time) error {
+	key, err := filepath.Abs(infile)
+	if err != nil {
+		return err
+	}
+	entry, err := newStateEntry(infile, outfile, now)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = entry
+	s.changed = true
+	return nil
+}
+
+// save writes the state file if the state changed
+func (s *conversionState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.changed {
+		return nil
+	}
+	err := atomicfile.Write(s.file, outputFilePerm, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stateFileContent{Version: stateFileVersion, Files: s.files})
+	})
+	if err != nil {
+		return err
+	}
+	s.changed = false
+	return nil
+}
+
+// newStateEntry returns the entry of the current content of infile
+func newStateEntry(infile string, outfile string, converted time.Time) (stateEntry, error) {
+	f, err := os.Open(infile)
+	if err != nil {
+		return stateEntry{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return stateEntry{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return stateEntry{}, err
+	}
+	return stateEntry{
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		Hash:       hex.EncodeToString(h.Sum(nil)),
+		OutputFile: outfile,
+		Converted:  converted,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package batchconvert
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+func TestBatchConvertState(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	infile := filepath.Join(inputDir, filename)
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), infile); err != nil {
+		t.Fatal(err)
+	}
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "volksbank", InputDir: inputDir, OutputDir: outputDir, State: true},
+		},
+	}
+	convert := func() FileStatus {
+		t.Helper()
+		status, err := BatchConvert(batchSettings, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status[0].Error != nil {
+			t.Fatal(status[0].Error)
+		}
+		return status[0].Files[0]
+	}
+	// renameOutput moves the output file into a subdirectory like sorting it by year
+	renameOutput := func() {
+		t.Helper()
+		yearDir := filepath.Join(outputDir, "2023")
+		if err := os.MkdirAll(yearDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(outputDir, filename), filepath.Join(yearDir, filename)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stateFile := filepath.Join(outputDir, settings.DefaultStateFileName)
+
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Fatalf("Expected conversion, got %v", f)
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Fatalf("Expected state file: %v", err)
+	}
+
+	// Skipped although the output file was moved
+	renameOutput()
+	f := convert()
+	if f.Status != Skipped || f.SkipReason != "converted before according to the state file" {
+		t.Errorf("Expected file skipped by the state, got %v", f)
+	}
+	if f.OutputFile != filepath.Join(outputDir, filename) {
+		t.Errorf("Expected recorded output file, got '%s'", f.OutputFile)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, filename)); err == nil {
+		t.Error("Expected no new output file")
+	}
+
+	// A changed input file is converted again
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(infile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Errorf("Expected changed file to be converted, got %v", f)
+	}
+	renameOutput()
+	if f := convert(); f.Status != Skipped {
+		t.Errorf("Expected file skipped by the updated state, got %v", f)
+	}
+
+	// A changed input file is converted again although its output file still exists
+	later = later.Add(time.Hour)
+	if err := os.Chtimes(infile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, filename), []byte("old output"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Errorf("Expected changed file with existing output to be converted, got %v", f)
+	}
+	expectedFile := filepath.Join("testfiles", "expected_output", "volksbank", filename)
+	if equal, err := areFilesEqual(expectedFile, filepath.Join(outputDir, filename)); err != nil || !equal {
+		t.Errorf("Expected the existing output file to be replaced (%v)", err)
+	}
+	renameOutput()
+
+	// Not consulted if output files are always overwritten
+	batchSettings.Sets[0].Overwrite = settings.OverwriteAlways
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Errorf("Expected conversion with overwrite always, got %v", f)
+	}
+	batchSettings.Sets[0].Overwrite = settings.OverwriteNever
+
+	// The state is ignored and replaced after a reset
+	renameOutput()
+	batchSettings.ResetState = true
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Errorf("Expected conversion after reset, got %v", f)
+	}
+	batchSettings.ResetState = false
+
+	// Without usable state file the output file decides, the skipped file is recorded in
+	// the state file written again
+	if err := os.WriteFile(stateFile, []byte("{no json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if f := convert(); f.Status != Skipped || f.SkipReason != "output file exists" {
+		t.Errorf("Expected file skipped as its output exists, got %v", f)
+	}
+	state, err := loadState(stateFile, false)
+	if err != nil {
+		t.Fatalf("Expected valid state file, got %v", err)
+	}
+	if _, found := state.converted(infile); !found {
+		t.Error("Expected skipped file in the state file")
+	}
+	renameOutput()
+	if f := convert(); f.Status != Skipped || f.SkipReason != "converted before according to the state file" {
+		t.Errorf("Expected file skipped by the state, got %v", f)
+	}
+
+	// The same without state file
+	if err := os.Remove(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	if f := convert(); f.Status != ConversionSuccess {
+		t.Errorf("Expected conversion without state file, got %v", f)
+	}
+}
+
+func TestBatchConvertStateFile(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	if err := copyFile(filepath.Join("testfiles", "input", "volksbank", filename), filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	stateFile := filepath.Join(t.TempDir(), "volksbank.json")
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{Name: "volksbank", InputDir: inputDir, OutputDir: outputDir, StateFile: stateFile},
+		},
+		DryRun: true,
+	}
+
+	// Not written in a dry run
+	if _, err := BatchConvert(batchSettings, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file in a dry run, got %v", err)
+	}
+
+	batchSettings.DryRun = false
+	if _, err := BatchConvert(batchSettings, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(stateFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, found := state.converted(filepath.Join(inputDir, filename)); !found || entry.OutputFile != filepath.Join(outputDir, filename) {
+		t.Errorf("Expected converted file in the state, got %v", entry)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, settings.DefaultStateFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file in the output directory, got %v", err)
+	}
+
+	// A state file which can't be written fails the set
+	batchSettings.Sets[0].StateFile = filepath.Join(t.TempDir(), "missing", "state.json")
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	// It is reported to the callback and logged
+	var reported error
+	cb := func(s BatchStatus, userData interface{}) {
+		reported = s[0].Error
+	}
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, nil))
+	status, err := BatchConvert(batchSettings, cb, nil, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0].Error == nil || status[0].Files[0].Status != ConversionSuccess {
+		t.Errorf("Expected converted file and set error, got %v", status[0])
+	}
+	if reported == nil || !strings.Contains(reported.Error(), "cannot save state file") {
+		t.Errorf("Expected state file error reported to the callback, got %v", reported)
+	}
+	if !strings.Contains(logged.String(), "set failed") {
+		t.Errorf("Expected state file error to be logged, got %q", logged.String())
+	}
+
+	// And sent as set error event after the events of the file
+	batchSettings.Sets[0].OutputDir = t.TempDir()
+	events, errc := BatchConvertEvents(context.Background(), batchSettings)
+	var last Event
+	for e := range events {
+		last = e
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if last.Kind != SetErrorEvent || last.Err == nil {
+		t.Errorf("Expected set error event last, got %v", last)
+	}
+}
@@ -0,0 +1,258 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+// DefaultDebounce is the default time the input directories have to stay unchanged before
+// Watch converts the new files, see WithDebounce
+const DefaultDebounce = 2 * time.Second
+
+// maxFailureDelay limits the time after which Watch retries the files of a failed set
+const maxFailureDelay = 10 * time.Minute
+
+// WithDebounce sets how long the input directories have to stay unchanged before Watch
+// converts the new files. This avoids converting files which are still being downloaded.
+func WithDebounce(d time.Duration) Option {
+	return func(o *options) {
+		o.debounce = d
+	}
+}
+
+// fileState is the state of a file found by Watch
+type fileState struct {
+	size    int64
+	modTime time.Time
+	due     time.Time // When the file is converted, zero if handled
+}
+
+// Watch converts the input files of the sets which are created or changed after it has
+// been started, until ctx is cancelled. Then the error of ctx is returned.
+//
+// The input directories are watched with the notifications of the operating system, with
+// Recursive also their subdirectories. Changes on network shares made by other machines
+// may not be notified. The new or changed files are converted once no file changed for
+// the debounce duration, e.g. when the browser finished the download. Temporary files of
+// downloads which are renamed afterwards are ignored if they don't match FileGlobPattern,
+// otherwise they vanish before they are converted.
+//
+// The files are converted as by BatchConvertContext, with the same glob, age and skip rules.
+// Each conversion is reported to c with the status of the set containing only the converted
+// files. Files existing at the start are not converted, run BatchConvertContext before to
+// convert them. Disabled sets are not watched. If a set fails, e.g. as its output directory
+// is missing, its files are retried after a delay doubling with each failure.
+func Watch(ctx context.Context, s settings.BatchConvertSettings, c StatusCallback, userData interface{}, opts ...Option) error {
+	o := newOptions(opts)
+	if err := s.Sets.CheckValidity(); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot watch input directories: %w", err)
+	}
+	defer watcher.Close()
+
+	// The known state of the input files of each set, the existing files are handled
+	known := make([]map[string]fileState, len(s.Sets))
+	// The number of consecutive failures of each set
+	failures := make([]int, len(s.Sets))
+	for setNr, set := range s.Sets {
+		if !set.GetEnabled() {
+			continue
+		}
+		for _, dir := range set.GetInputDirs() {
+			if err := watchDir(watcher, dir, set.Recursive); err != nil {
+				return err
+			}
+		}
+		files, err := statSetFiles(set, time.Time{}, o.now())
+		if err != nil {
+			return err
+		}
+		known[setNr] = files
+	}
+
+	// The input directories are scanned once they did not change for the debounce
+	// duration, or when files are due again
+	timer := time.NewTimer(o.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Create) {
+				if err := watchCreatedDir(watcher, s.Sets, event.Name); err != nil {
+					return err
+				}
+			}
+			timer.Reset(o.debounce)
+			continue
+		case err := <-watcher.Errors:
+			return fmt.Errorf("cannot watch input directories: %w", err)
+		case <-timer.C:
+		}
+		now := o.now()
+		var next time.Time
+		for setNr, set := range s.Sets {
+			if !set.GetEnabled() {
+				continue
+			}
+			// New or changed files are due now, as nothing changed for the debounce duration
+			files, err := statSetFiles(set, now, now)
+			if err != nil {
+				return err
+			}
+			ready := make(map[string]struct{})
+			for file, state := range files {
+				previous, found := known[setNr][file]
+				if found && previous.size == state.size && previous.modTime.Equal(state.modTime) {
+					state.due = previous.due
+				}
+				if !state.due.IsZero() && !state.due.After(now) {
+					ready[file] = struct{}{}
+				}
+				files[file] = state
+			}
+			if len(ready) > 0 {
+				setSettings := s
+				setSettings.Sets = settings.BatchConvertSets{set}
+				only := func(o *options) { o.only = ready }
+				status, err := BatchConvertContext(ctx, setSettings, c, userData, slices.Concat(opts, []Option{only})...)
+				if err != nil {
+					return err
+				}
+				// A failed set has no files, they are retried later instead of with each scan
+				if status[0].Error != nil {
+					failures[setNr]++
+					retry := now.Add(failureDelay(o.debounce, failures[setNr]))
+					for file := range ready {
+						state := files[file]
+						state.due = retry
+						files[file] = state
+					}
+				} else {
+					failures[setNr] = 0
+				}
+				// Unchanged files are not converted again, the ones beyond MaxFilesPerRun
+				// stay due and are converted with the next scan
+				for _, f := range status[0].Files {
+					if state, found := files[f.InputFile]; found && f.Status != NotStartedYet {
+						state.due = time.Time{}
+						files[f.InputFile] = state
+					}
+				}
+			}
+			known[setNr] = files
+			for _, state := range files {
+				if !state.due.IsZero() && (next.IsZero() || state.due.Before(next)) {
+					next = state.due
+				}
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(max(next.Sub(now), 0))
+		}
+	}
+}
+
+// failureDelay returns the time after which the files of a set are retried after the
+// given number of consecutive failures, doubling the debounce duration with each failure
+func failureDelay(debounce time.Duration, failures int) time.Duration {
+	delay := max(debounce, time.Second)
+	for i := 1; i < failures && delay < maxFailureDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxFailureDelay)
+}
+
+// watchDir adds dir to the watcher, with recursive also its subdirectories
+func watchDir(watcher *fsnotify.Watcher, dir string, recursive bool) error {
+	if !recursive {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch input directory '%s': %w", dir, err)
+		}
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path != dir {
+			// Removed in the meantime
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot watch input directory '%s': %w", path, err)
+		}
+		return nil
+	})
+}
+
+// watchCreatedDir adds path to the watcher if it is a directory created within an input
+// directory of a recursive set, so the files created in it are noticed as well
+func watchCreatedDir(watcher *fsnotify.Watcher, sets settings.BatchConvertSets, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		// Files and directories removed in the meantime are found by the next scan
+		return nil
+	}
+	for _, set := range sets {
+		if !set.GetEnabled() || !set.Recursive {
+			continue
+		}
+		for _, dir := range set.GetInputDirs() {
+			if inDir(dir, path) {
+				return watchDir(watcher, path, true)
+			}
+		}
+	}
+	return nil
+}
+
+// inDir reports whether path lies inside dir
+func inDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// statSetFiles returns the state of the input files of the set, due at the given time.
+// The age of the files is checked against now.
+func statSetFiles(set settings.BatchConvertSet, due time.Time, now time.Time) (map[string]fileState, error) {
+	// Not logged, the files are searched on each scan
+	files, err := findSetFiles(set, now, nil)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]fileState, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Renamed or removed in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states[file] = fileState{size: info.Size(), modTime: info.ModTime(), due: due}
+	}
+	return states, nil
+}
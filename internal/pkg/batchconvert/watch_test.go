@@ -0,0 +1,216 @@
+package batchconvert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/settings"
+)
+
+func TestWatch(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	src := filepath.Join("testfiles", "input", "volksbank", filename)
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	// Files existing at the start are left to the initial conversion
+	if err := copyFile(src, filepath.Join(inputDir, "existing.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:            "volksbank",
+				InputDir:        inputDir,
+				OutputDir:       outputDir,
+				FileGlobPattern: "*.csv",
+			},
+		},
+	}
+	var mu sync.Mutex
+	var converted []string
+	cb := func(s BatchStatus, userData interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, f := range s[0].Files {
+			if f.Status == ConversionSuccess {
+				converted = append(converted, filepath.Base(f.InputFile))
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, batchSettings, cb, nil, WithDebounce(50*time.Millisecond))
+	}()
+
+	// The download is written to a temporary file which is renamed afterwards
+	time.Sleep(30 * time.Millisecond)
+	download := filepath.Join(inputDir, filename+".part")
+	if err := copyFile(src, download); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(download, filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	outfile := filepath.Join(outputDir, filename)
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(outfile); err == nil {
+			break
+		}
+	}
+	// Gives Watch the chance to convert files again which it should not
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	expectedFile := filepath.Join("testfiles", "expected_output", "volksbank", filename)
+	if equal, err := areFilesEqual(expectedFile, outfile); err != nil || !equal {
+		t.Errorf("Output file '%s' differs from expected file (%v)", outfile, err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "existing.csv")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected existing file not to be converted, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(converted) != 1 || converted[0] != filename {
+		t.Errorf("Expected single conversion of '%s', got %v", filename, converted)
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	src := filepath.Join("testfiles", "input", "volksbank", filename)
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:            "volksbank",
+				InputDir:        inputDir,
+				OutputDir:       outputDir,
+				FileGlobPattern: "*.csv",
+				Recursive:       true,
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, batchSettings, nil, nil, WithDebounce(50*time.Millisecond))
+	}()
+
+	// A file in a subdirectory created after the start
+	time.Sleep(30 * time.Millisecond)
+	subDir := filepath.Join(inputDir, "sub")
+	if err := os.Mkdir(subDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := copyFile(src, filepath.Join(subDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	outfile := filepath.Join(outputDir, filename)
+	var statErr error
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if _, statErr = os.Stat(outfile); statErr == nil {
+			break
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if statErr != nil {
+		t.Errorf("Expected file in subdirectory to be converted, got %v", statErr)
+	}
+}
+
+func TestWatchFailedSet(t *testing.T) {
+	const filename = "Umsaetze_DE12345678901234567890_2023.10.04.csv"
+	src := filepath.Join("testfiles", "input", "volksbank", filename)
+	inputDir := t.TempDir()
+
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{
+			{
+				Name:            "volksbank",
+				InputDir:        inputDir,
+				OutputDir:       filepath.Join(t.TempDir(), "missing"),
+				FileGlobPattern: "*.csv",
+			},
+		},
+	}
+	var mu sync.Mutex
+	failures := 0
+	cb := func(s BatchStatus, userData interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s[0].Error != nil {
+			failures++
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, batchSettings, cb, nil, WithDebounce(10*time.Millisecond))
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	if err := copyFile(src, filepath.Join(inputDir, filename)); err != nil {
+		t.Fatal(err)
+	}
+	// The retry is delayed by at least a second
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if failures != 1 {
+		t.Errorf("Expected a single failure of the set, got %d", failures)
+	}
+}
+
+func TestFailureDelay(t *testing.T) {
+	testCases := []struct {
+		debounce time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{2 * time.Second, 1, 2 * time.Second},
+		{2 * time.Second, 2, 4 * time.Second},
+		{2 * time.Second, 4, 16 * time.Second},
+		{2 * time.Second, 100, maxFailureDelay},
+		{10 * time.Millisecond, 1, time.Second},
+		{10 * time.Millisecond, 3, 4 * time.Second},
+		{time.Hour, 1, maxFailureDelay},
+	}
+	for _, tc := range testCases {
+		if delay := failureDelay(tc.debounce, tc.failures); delay != tc.expected {
+			t.Errorf("failureDelay(%v, %d): expected %v, got %v", tc.debounce, tc.failures, tc.expected, delay)
+		}
+	}
+}
+
+func TestWatchInvalidSet(t *testing.T) {
+	batchSettings := settings.BatchConvertSettings{
+		Sets: []settings.BatchConvertSet{{Name: "invalid"}},
+	}
+	if err := Watch(context.Background(), batchSettings, nil, nil); err == nil {
+		t.Error("Expected error for invalid set")
+	}
+}
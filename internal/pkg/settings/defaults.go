@@ -0,0 +1,189 @@
+package settings
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	yamlparser "github.com/goccy/go-yaml/parser"
+)
+
+// keysWithoutDefault are the keys of a set which can't be given in batchconvert.defaults
+var keysWithoutDefault = []string{"name", "inputdir", "inputdirs", "outputdir"}
+
+// decodeWithDefaults decodes the YAML content into settings. Keys missing in a set are
+// taken from batchconvert.defaults before decoding, so an explicit zero value in a set
+// overrides the default.
+func decodeWithDefaults(content []byte, settings *Settings, options ...yaml.DecodeOption) error {
+	file, err := yamlparser.ParseBytes(content, 0)
+	if err != nil {
+		return err
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil
+	}
+	root := file.Docs[0].Body
+	if err := applyDefaults(root); err != nil {
+		return err
+	}
+	return yaml.NodeToValue(root, settings, options...)
+}
+
+// applyDefaults adds the key-value pairs of batchconvert.defaults to each set not
+// containing the key
+func applyDefaults(root ast.Node) error {
+	batchConvert := mappingValue(root, "batchconvert")
+	if batchConvert == nil {
+		return nil
+	}
+	defaults := mappingValue(batchConvert, "defaults")
+	if defaults == nil {
+		return nil
+	}
+	defaultValues, ok := mappingValues(defaults)
+	if !ok {
+		// The decoder reports the type mismatch
+		return nil
+	}
+	for _, value := range defaultValues {
+		if key := mappingKey(value); slices.Contains(keysWithoutDefault, key) {
+			position := value.Key.GetToken().Position
+			return fmt.Errorf("[%d:%d] '%s' can't be given in batchconvert.defaults", position.Line, position.Column, key)
+		}
+	}
+
+	sets, ok := mappingValue(batchConvert, "sets").(*ast.SequenceNode)
+	if !ok {
+		return nil
+	}
+	for i, set := range sets.Values {
+		setValues, ok := mappingValues(set)
+		if !ok {
+			continue
+		}
+		for _, value := range defaultValues {
+			if !slices.ContainsFunc(setValues, func(v *ast.MappingValueNode) bool {
+				return mappingKey(v) == mappingKey(value)
+			}) {
+				setValues = append(setValues, value)
+			}
+		}
+		sets.Values[i] = ast.Mapping(set.GetToken(), false, setValues...)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in the mapping node, nil if missing
+func mappingValue(node ast.Node, key string) ast.Node {
+	values, _ := mappingValues(node)
+	for _, value := range values {
+		if mappingKey(value) == key {
+			return value.Value
+		}
+	}
+	return nil
+}
+
+// mappingValues returns the key-value pairs of a mapping node, false if node is no
+// mapping. A mapping with a single pair may be a MappingValueNode.
+func mappingValues(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return slices.Clone(n.Values), true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	}
+	return nil, false
+}
+
+// mappingKey returns the key of a key-value pair
+func mappingKey(value *ast.MappingValueNode) string {
+	return value.Key.GetToken().Value
+}
+
+// marshal returns the settings as YAML. The settings of a set equal to the defaults are
+// left out, settings with the zero value are given explicitly if the defaults set them.
+// So loading the YAML results in the same settings.
+//
+// The version is always written as CurrentVersion.
+func (settings Settings) marshal(options ...yaml.EncodeOption) ([]byte, error) {
+	settings.Version = CurrentVersion
+	if reflect.ValueOf(settings.BatchConvert.Defaults).IsZero() {
+		return yaml.MarshalWithOptions(settings, options...)
+	}
+	content, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var tree yaml.MapSlice
+	if err := yaml.UnmarshalWithOptions(content, &tree, yaml.UseOrderedMap()); err != nil {
+		return nil, err
+	}
+	batchConvert, _ := mapSliceValue(tree, "batchconvert").(yaml.MapSlice)
+	defaults, _ := mapSliceValue(batchConvert, "defaults").(yaml.MapSlice)
+	sets, _ := mapSliceValue(batchConvert, "sets").([]interface{})
+	for i, set := range sets {
+		if set, ok := set.(yaml.MapSlice); ok {
+			sets[i] = overrideDefaults(set, defaults, settings.BatchConvert.Sets[i])
+		}
+	}
+	return yaml.MarshalWithOptions(tree, options...)
+}
+
+// overrideDefaults returns the marshalled set without the values equal to the defaults
+// and with the zero values of value for the keys of the defaults missing in set
+func overrideDefaults(set yaml.MapSlice, defaults yaml.MapSlice, value BatchConvertSet) yaml.MapSlice {
+	var result yaml.MapSlice
+	for _, item := range set {
+		if defaultValue, ok := mapSliceLookup(defaults, item.Key); ok && reflect.DeepEqual(defaultValue, item.Value) {
+			continue
+		}
+		result = append(result, item)
+	}
+	for _, item := range defaults {
+		if _, ok := mapSliceLookup(set, item.Key); !ok {
+			key, _ := item.Key.(string)
+			result = append(result, yaml.MapItem{Key: item.Key, Value: fieldValue(value, key)})
+		}
+	}
+	return result
+}
+
+// fieldValue returns the value of the field of set with the YAML key, nil for nil
+// slices, maps and pointers
+func fieldValue(set BatchConvertSet, key string) interface{} {
+	v := reflect.ValueOf(set)
+	for i := 0; i < v.NumField(); i++ {
+		if name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ","); name != key {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Pointer:
+			if field.IsNil() {
+				return nil
+			}
+		}
+		return field.Interface()
+	}
+	return nil
+}
+
+// mapSliceValue returns the value of key in m, nil if missing
+func mapSliceValue(m yaml.MapSlice, key string) interface{} {
+	value, _ := mapSliceLookup(m, key)
+	return value
+}
+
+// mapSliceLookup returns the value of key in m and whether it exists
+func mapSliceLookup(m yaml.MapSlice, key interface{}) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
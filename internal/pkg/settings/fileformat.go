@@ -0,0 +1,136 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+)
+
+// FileFormat is the format of a config file, detected by its extension
+type FileFormat int
+
+const (
+	FormatYAML FileFormat = iota // Extension ".yml" or ".yaml", also used for unknown extensions
+	FormatJSON                   // Extension ".json"
+	FormatTOML                   // Extension ".toml"
+)
+
+// fileFormats is the mapping between FileFormat and its textual representation
+var fileFormats = map[FileFormat]string{
+	FormatYAML: "YAML",
+	FormatJSON: "JSON",
+	FormatTOML: "TOML",
+}
+
+// Returns the textual representation of the file format
+func (f FileFormat) String() string {
+	if text, ok := fileFormats[f]; ok {
+		return text
+	}
+	return "unknown file format"
+}
+
+// FileFormatOf returns the format of the config file by its extension. Files with an
+// unknown extension are taken as YAML.
+func FileFormatOf(filePath string) FileFormat {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// toYAML returns the content of the given format as YAML. JSON is a subset of YAML and
+// returned unchanged, so all formats are decoded the same way.
+func toYAML(content []byte, format FileFormat) ([]byte, error) {
+	if format != FormatTOML {
+		return content, nil
+	}
+	var tree map[string]interface{}
+	if _, err := toml.Decode(string(content), &tree); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tomlToYAMLValue(tree))
+}
+
+// tomlToYAMLValue returns the decoded TOML value with the dates and times as strings,
+// as they are given unquoted in TOML but as strings in YAML
+func tomlToYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = tomlToYAMLValue(item)
+		}
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = tomlToYAMLValue(item)
+		}
+		return items
+	case []interface{}:
+		for i, item := range v {
+			v[i] = tomlToYAMLValue(item)
+		}
+	case time.Time:
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format(time.DateOnly)
+		case "time-local":
+			return v.Format(time.TimeOnly)
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// yamlPosition matches the position in the message of a YAML error, like "[3:5] "
+var yamlPosition = regexp.MustCompile(`^\[\d+:\d+\] `)
+
+// convertedError returns the error of decoding the YAML converted from a file of another
+// format. Its position and source refer to the converted YAML, so only the message is kept.
+func convertedError(err error, format FileFormat) error {
+	if format != FormatTOML {
+		return err
+	}
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	return errors.New(yamlPosition.ReplaceAllString(message, ""))
+}
+
+// fromYAML returns the YAML content in the given format
+func fromYAML(content []byte, format FileFormat) ([]byte, error) {
+	if format == FormatYAML {
+		return content, nil
+	}
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(content, &tree); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(tree); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		if err := toml.NewEncoder(&buf).Encode(tree); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot write %s", format)
+	}
+	return buf.Bytes(), nil
+}
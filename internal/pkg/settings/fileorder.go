@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileOrder decides in which order batchconvert converts the input files of a set
+type FileOrder int
+
+const (
+	OrderByName      FileOrder = iota // By path, the default
+	OrderByMtimeDesc                  // Newest modification time first
+	OrderByMtimeAsc                   // Oldest modification time first
+)
+
+// fileOrders is the mapping between FileOrder and its textual representation
+var fileOrders = map[FileOrder]string{
+	OrderByName:      "name",
+	OrderByMtimeDesc: "mtime-desc",
+	OrderByMtimeAsc:  "mtime-asc",
+}
+
+// Returns the textual representation of the file order
+func (o FileOrder) String() string {
+	if s, ok := fileOrders[o]; ok {
+		return s
+	}
+	return "unknown file order"
+}
+
+// MarshalText returns the textual representation of the file order, e.g. for YAML.
+// An error is returned for unsupported values.
+func (o FileOrder) MarshalText() ([]byte, error) {
+	if _, ok := fileOrders[o]; !ok {
+		return nil, fmt.Errorf("unsupported file order %d", int(o))
+	}
+	return []byte(fileOrders[o]), nil
+}
+
+// UnmarshalText sets the file order from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (o *FileOrder) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range fileOrders {
+		if strings.EqualFold(value, textString) {
+			*o = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported file order '%s', valid values are: name, mtime-desc, mtime-asc", string(text))
+}
@@ -0,0 +1,61 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+// FormatList are the source formats tried one after the other to parse an input file
+type FormatList []parser.SourceFormat
+
+// String returns the names of the formats separated by comma
+func (l FormatList) String() string {
+	names := make([]string, len(l))
+	for i, format := range l {
+		names[i] = format.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// UnmarshalYAML sets the formats from a list of format names or from a single name, null
+// for autodetect
+func (l *FormatList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	if _, isList := value.([]interface{}); isList {
+		var formats []parser.SourceFormat
+		if err := unmarshal(&formats); err != nil {
+			return err
+		}
+		*l = formats
+		return nil
+	}
+	var format parser.SourceFormat
+	if err := unmarshal(&format); err != nil {
+		return err
+	}
+	*l = FormatList{format}
+	return nil
+}
+
+// checkValidity returns an error if a format is unknown or listed more than once
+func (l FormatList) checkValidity() error {
+	for i, format := range l {
+		if !slices.Contains(parser.GetSourceFormats(), format) {
+			return fmt.Errorf("unknown format %d", format)
+		}
+		if slices.Contains(l[:i], format) {
+			return fmt.Errorf("duplicate format '%s'", format)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// day is the duration of a day in MaxAge
+const day = 24 * time.Hour
+
+// maxDays is the largest number of days which can be represented as MaxAge
+const maxDays = math.MaxInt64 / int64(day)
+
+// MaxAge is the maximum age of input files, no limit if 0. In YAML it is given either as
+// number of days like 14, or as duration string like "36h" or "14d".
+type MaxAge time.Duration
+
+// MaxAgeDays returns the maximum age of the given number of days
+func MaxAgeDays(days int) MaxAge {
+	return MaxAge(time.Duration(days) * day)
+}
+
+// String returns the maximum age as number of days like "14d" if it is a multiple of a
+// day, otherwise as duration like "36h0m0s"
+func (a MaxAge) String() string {
+	d := time.Duration(a)
+	if d != 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
+
+// UnmarshalYAML sets the maximum age from an integer number of days or from a duration
+// string. Besides the units of time.ParseDuration, the unit "d" for days is supported
+// without combining it with other units.
+func (a *MaxAge) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case string:
+		return a.parse(v)
+	case int64:
+		return a.setDays(v, value)
+	case uint64:
+		if v > uint64(maxDays) {
+			return fmt.Errorf("invalid maximum age '%v': more than %d days", value, maxDays)
+		}
+		return a.setDays(int64(v), value)
+	}
+	return fmt.Errorf("invalid maximum age '%v', expected number of days or duration like '36h' or '14d'", value)
+}
+
+// MarshalYAML returns the textual representation of the maximum age, see String
+func (a MaxAge) MarshalYAML() (interface{}, error) {
+	return a.String(), nil
+}
+
+// parse sets the maximum age from a duration string
+func (a *MaxAge) parse(text string) error {
+	text = strings.TrimSpace(text)
+	if days, found := strings.CutSuffix(text, "d"); found {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("invalid maximum age '%s': more than %d days", text, maxDays)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid maximum age '%s': number of days is not an integer", text)
+		}
+		return a.setDays(n, text)
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid maximum age '%s': %w", text, err)
+	}
+	*a = MaxAge(d)
+	return nil
+}
+
+// setDays sets the maximum age to the number of days given as value. More days than
+// maxDays, also negative ones, would overflow time.Duration.
+func (a *MaxAge) setDays(days int64, value interface{}) error {
+	if days > maxDays || days < -maxDays {
+		return fmt.Errorf("invalid maximum age '%v': more than %d days", value, maxDays)
+	}
+	*a = MaxAge(time.Duration(days) * day)
+	return nil
+}
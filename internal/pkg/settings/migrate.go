@@ -0,0 +1,122 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/goccy/go-yaml"
+)
+
+// CurrentVersion is the version of the config file format of this program. Config files
+// without version have version 0.
+const CurrentVersion = 1
+
+// migration converts the settings of a config file to the next version. It changes tree
+// in place and returns warnings about settings whose meaning changed.
+type migration func(tree yaml.MapSlice) ([]string, error)
+
+// migrations[v] migrates version v to version v+1
+var migrations = []migration{
+	migrateV0,
+}
+
+// migrateV0 migrates config files written before the version was introduced. They have
+// the keys of version 1, so only the version is set.
+func migrateV0(tree yaml.MapSlice) ([]string, error) {
+	return nil, nil
+}
+
+// Migrate loads the settings of an older config file, see LoadFromReader. The settings
+// are migrated to CurrentVersion and returned with the warnings of the migrations. A
+// config file of a version later than CurrentVersion is an error.
+func Migrate(old []byte, options ...LoadOption) (Settings, []string, error) {
+	var settings Settings
+	warnings, err := settings.loadMigrated(old, options)
+	if err != nil {
+		return Settings{}, nil, err
+	}
+	return settings, warnings, nil
+}
+
+// FileVersion returns the version of the config file content, 0 if not given
+func FileVersion(content []byte, options ...LoadOption) (int, error) {
+	o := newLoadOptions(options)
+	content, err := toYAML(content, o.format)
+	if err != nil {
+		return 0, err
+	}
+	tree, err := versionTree(content)
+	if err != nil {
+		return 0, err
+	}
+	return treeVersion(tree)
+}
+
+// migrate returns the YAML content migrated to CurrentVersion and the warnings of the
+// migrations. The content is returned unchanged if no migration changed it, so the
+// positions in error messages refer to the original content.
+func migrate(content []byte) ([]byte, []string, error) {
+	tree, err := versionTree(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := treeVersion(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > CurrentVersion {
+		return nil, nil, fmt.Errorf("config file has version %d, but this program only supports up to version %d, please upgrade go-homebank-csv",
+			version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return content, nil, nil
+	}
+
+	before, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	var warnings []string
+	for v := version; v < CurrentVersion; v++ {
+		w, err := migrations[v](tree)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot migrate config file from version %d to %d: %w", v, v+1, err)
+		}
+		warnings = append(warnings, w...)
+	}
+	after, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(before, after) {
+		return content, warnings, nil
+	}
+	return after, warnings, nil
+}
+
+// versionTree returns the YAML content as ordered map, nil for empty content. Content
+// which is no mapping is returned as nil as well, the decoder reports it.
+func versionTree(content []byte) (yaml.MapSlice, error) {
+	var tree interface{}
+	if err := yaml.UnmarshalWithOptions(content, &tree, yaml.UseOrderedMap()); err != nil {
+		return nil, err
+	}
+	mapSlice, _ := tree.(yaml.MapSlice)
+	return mapSlice, nil
+}
+
+// treeVersion returns the value of the key version in tree, 0 if missing
+func treeVersion(tree yaml.MapSlice) (int, error) {
+	switch v := mapSliceValue(tree, "version").(type) {
+	case nil:
+		return 0, nil
+	case uint64:
+		return int(min(v, math.MaxInt32)), nil
+	case int64:
+		if v >= 0 {
+			return int(min(v, math.MaxInt32)), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid config file version '%v', expected a positive integer", mapSliceValue(tree, "version"))
+}
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SuccessAction is what batchconvert does with an input file after its successful conversion
+type SuccessAction int
+
+const (
+	OnSuccessKeep   SuccessAction = iota // The input file is kept
+	OnSuccessMove                        // The input file is moved to the archive directory
+	OnSuccessDelete                      // The input file is deleted
+)
+
+// successActions is the mapping between SuccessAction and its textual representation
+var successActions = map[SuccessAction]string{
+	OnSuccessKeep:   "keep",
+	OnSuccessMove:   "move",
+	OnSuccessDelete: "delete",
+}
+
+// Returns the textual representation of the action
+func (a SuccessAction) String() string {
+	if s, ok := successActions[a]; ok {
+		return s
+	}
+	return "unknown action"
+}
+
+// MarshalText returns the textual representation of the action, e.g. for YAML.
+// An error is returned for unsupported values.
+func (a SuccessAction) MarshalText() ([]byte, error) {
+	if _, ok := successActions[a]; !ok {
+		return nil, fmt.Errorf("unsupported action %d", int(a))
+	}
+	return []byte(successActions[a]), nil
+}
+
+// UnmarshalText sets the action from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (a *SuccessAction) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range successActions {
+		if strings.EqualFold(value, textString) {
+			*a = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported action '%s', valid values are: keep, move, delete", string(text))
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OutputNameValues are the values of the placeholders of OutputNameTemplate
+type OutputNameValues struct {
+	BaseName  string    // Name of the input file without extension
+	Format    string    // Source format of the input file
+	FirstDate time.Time // Date of the oldest converted record, zero if there is none
+	LastDate  time.Time // Date of the newest converted record, zero if there is none
+	Today     time.Time // Current date
+}
+
+// placeholderRegexp matches the placeholders of OutputNameTemplate
+var placeholderRegexp = regexp.MustCompile(`\{[a-z]*\}`)
+
+// outputNamePlaceholders are the placeholders of OutputNameTemplate, those which depend on
+// the converted records are true
+var outputNamePlaceholders = map[string]bool{
+	"{basename}":  false,
+	"{set}":       false,
+	"{format}":    true,
+	"{firstdate}": true,
+	"{lastdate}":  true,
+	"{today}":     false,
+}
+
+// GetOutputName returns the name of the output file with the placeholders of
+// OutputNameTemplate replaced by the values. Without template it is the base name with
+// the extension ".csv". An error is returned if the name is empty or a path.
+func (s BatchConvertSet) GetOutputName(v OutputNameValues) (string, error) {
+	if s.OutputNameTemplate == "" {
+		return v.BaseName + ".csv", nil
+	}
+	formatDate := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.DateOnly)
+	}
+	name := strings.NewReplacer(
+		"{basename}", v.BaseName,
+		"{set}", s.Name,
+		"{format}", v.Format,
+		"{firstdate}", formatDate(v.FirstDate),
+		"{lastdate}", formatDate(v.LastDate),
+		"{today}", formatDate(v.Today),
+	).Replace(s.OutputNameTemplate)
+	switch {
+	case strings.TrimSpace(name) == "":
+		return "", fmt.Errorf("output file name of template '%s' is empty", s.OutputNameTemplate)
+	case name == "." || name == ".." || strings.ContainsAny(name, `/\`):
+		return "", fmt.Errorf("output file name '%s' must not be a path", name)
+	}
+	return name, nil
+}
+
+// OutputNameNeedsRecords reports whether OutputNameTemplate contains placeholders which
+// depend on the converted records, so the input file has to be parsed for the name.
+func (s BatchConvertSet) OutputNameNeedsRecords() bool {
+	for _, placeholder := range placeholderRegexp.FindAllString(s.OutputNameTemplate, -1) {
+		if outputNamePlaceholders[placeholder] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOutputNameTemplate returns an error if OutputNameTemplate contains unknown
+// placeholders or produces an empty name or a path
+func (s BatchConvertSet) checkOutputNameTemplate() error {
+	for _, placeholder := range placeholderRegexp.FindAllString(s.OutputNameTemplate, -1) {
+		if _, found := outputNamePlaceholders[placeholder]; !found {
+			return fmt.Errorf("unknown placeholder '%s'", placeholder)
+		}
+	}
+	if strings.TrimSpace(s.OutputNameTemplate) != s.OutputNameTemplate {
+		return errors.New("leading or trailing whitespace")
+	}
+	date := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	_, err := s.GetOutputName(OutputNameValues{BaseName: "Umsaetze", Format: "Volksbank", FirstDate: date, LastDate: date, Today: date})
+	return err
+}
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OverwritePolicy decides whether an existing output file of batchconvert is converted again
+type OverwritePolicy int
+
+const (
+	OverwriteNever   OverwritePolicy = iota // Existing output files are kept
+	OverwriteAlways                         // Existing output files are replaced
+	OverwriteIfNewer                        // Existing output files are replaced if the input file is newer
+)
+
+// overwritePolicies is the mapping between OverwritePolicy and its textual representation
+var overwritePolicies = map[OverwritePolicy]string{
+	OverwriteNever:   "never",
+	OverwriteAlways:  "always",
+	OverwriteIfNewer: "if-newer",
+}
+
+// Returns the textual representation of the overwrite policy
+func (o OverwritePolicy) String() string {
+	if s, ok := overwritePolicies[o]; ok {
+		return s
+	}
+	return "unknown overwrite policy"
+}
+
+// MarshalText returns the textual representation of the overwrite policy, e.g. for YAML.
+// An error is returned for unsupported values.
+func (o OverwritePolicy) MarshalText() ([]byte, error) {
+	if _, ok := overwritePolicies[o]; !ok {
+		return nil, fmt.Errorf("unsupported overwrite policy %d", int(o))
+	}
+	return []byte(overwritePolicies[o]), nil
+}
+
+// UnmarshalText sets the overwrite policy from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (o *OverwritePolicy) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range overwritePolicies {
+		if strings.EqualFold(value, textString) {
+			*o = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported overwrite policy '%s', valid values are: never, always, if-newer", string(text))
+}
@@ -0,0 +1,196 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// NormalizePaths expands the paths of all sets, see expandPath, and cleans them with
+// filepath.Clean. Aliases are resolved with PathAliases. Relative paths are resolved
+// against the directory of the loaded config file or the one given by WithBaseDir,
+// otherwise they are kept relative to the working directory. Empty paths are kept
+// empty. It is meant to be called after loading and before CheckValidity.
+func (settings *Settings) NormalizePaths() error {
+	for i := range settings.BatchConvert.Sets {
+		set := &settings.BatchConvert.Sets[i]
+		paths := []*string{&set.InputDir, &set.OutputDir, &set.ArchiveDir, &set.StateFile}
+		for j := range set.InputDirs {
+			paths = append(paths, &set.InputDirs[j])
+		}
+		for _, path := range paths {
+			expanded, err := normalizePath(*path, settings.baseDir, settings.PathAliases)
+			if err != nil {
+				return fmt.Errorf("set '%s': %w", set.Name, err)
+			}
+			*path = expanded
+		}
+	}
+	return nil
+}
+
+// normalizePath returns the expanded and cleaned path, "" for an empty path. A relative
+// path is joined to baseDir.
+func normalizePath(path string, baseDir string, aliases map[string]string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	expanded, err := expandPath(path, aliases, nil)
+	if err != nil {
+		return "", err
+	}
+	if baseDir != "" && !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(baseDir, expanded)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// expandPath replaces the environment variables in path, see expandEnv. Then a leading
+// "alias:name" is replaced by the expanded path of the alias, a leading "xdg:token" by
+// the user directory, see xdgDirForToken, and a leading "~" followed by a separator or
+// at the end of path by the home directory of the user. Other paths are returned
+// unchanged. resolving are the aliases being expanded, to detect recursive aliases.
+func expandPath(path string, aliases map[string]string, resolving []string) (string, error) {
+	path, err := expandEnv(path, runtime.GOOS == "windows")
+	if err != nil {
+		return "", err
+	}
+	if name, rest, found := cutToken(path, "alias:"); found {
+		if slices.Contains(resolving, name) {
+			return "", fmt.Errorf("recursive path alias '%s': %s", name, strings.Join(append(resolving, name), " -> "))
+		}
+		aliasPath, ok := aliases[name]
+		if !ok {
+			return "", fmt.Errorf("unknown path alias '%s' in path '%s'", name, path)
+		}
+		expanded, err := expandPath(aliasPath, aliases, append(resolving, name))
+		if err != nil {
+			return "", err
+		}
+		return expanded + rest, nil
+	}
+	if token, rest, found := cutToken(path, "xdg:"); found {
+		dir, err := xdgDirForToken(token)
+		if err != nil {
+			return "", fmt.Errorf("%w in path '%s'", err, path)
+		}
+		return dir + rest, nil
+	}
+	rest, found := strings.CutPrefix(path, "~")
+	if !found || (rest != "" && !os.IsPathSeparator(rest[0])) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot expand '~' in path '%s': %w", path, err)
+	}
+	return home + rest, nil
+}
+
+// cutToken returns the name after prefix at the start of path up to the first separator
+// and the rest of path starting with the separator. found is false if path does not
+// start with prefix.
+func cutToken(path string, prefix string) (name string, rest string, found bool) {
+	after, found := strings.CutPrefix(path, prefix)
+	if !found {
+		return "", "", false
+	}
+	end := strings.IndexFunc(after, func(r rune) bool {
+		return r < 128 && os.IsPathSeparator(uint8(r))
+	})
+	if end < 0 {
+		return after, "", true
+	}
+	return after[:end], after[end:], true
+}
+
+// xdgDirForToken returns the user directory of the token used as "xdg:token", e.g. the
+// download directory for "downloads"
+func xdgDirForToken(token string) (string, error) {
+	switch token {
+	case "desktop":
+		return xdg.UserDirs.Desktop, nil
+	case "documents":
+		return xdg.UserDirs.Documents, nil
+	case "downloads":
+		return xdg.UserDirs.Download, nil
+	case "music":
+		return xdg.UserDirs.Music, nil
+	case "pictures":
+		return xdg.UserDirs.Pictures, nil
+	case "publicshare":
+		return xdg.UserDirs.PublicShare, nil
+	case "templates":
+		return xdg.UserDirs.Templates, nil
+	case "videos":
+		return xdg.UserDirs.Videos, nil
+	}
+	return "", fmt.Errorf("unknown xdg directory '%s'", token)
+}
+
+// windowsEnvVar matches an environment variable in the Windows notation %VAR%
+var windowsEnvVar = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// expandEnv replaces ${VAR} and $VAR in path by the values of the environment variables,
+// with windows also %VAR%. An unset or empty variable is an error, it would result in an
+// empty path segment.
+func expandEnv(path string, windows bool) (string, error) {
+	var missing []string
+	lookup := func(name string) string {
+		value, _ := os.LookupEnv(name)
+		if value == "" {
+			missing = append(missing, name)
+		}
+		return value
+	}
+	expanded := os.Expand(path, lookup)
+	if windows {
+		expanded = windowsEnvVar.ReplaceAllStringFunc(expanded, func(match string) string {
+			return lookup(match[1 : len(match)-1])
+		})
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable '%s' in path '%s' is not set", missing[0], path)
+	}
+	return expanded, nil
+}
+
+// samePath reports whether the paths a and b denote the same file or directory. They are
+// compared after cleaning them, on Windows also ignoring case and the kind of separator.
+// If both exist, they are also the same if os.SameFile reports so, e.g. for symlinks or
+// on case-insensitive file systems.
+func samePath(a string, b string) bool {
+	windows := runtime.GOOS == "windows"
+	if pathKey(a, windows) == pathKey(b, windows) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// isSubPath reports whether path lies inside dir, compared like samePath without
+// resolving symlinks. A path is not inside itself.
+func isSubPath(dir string, path string) bool {
+	windows := runtime.GOOS == "windows"
+	rel, err := filepath.Rel(pathKey(dir, windows), pathKey(path, windows))
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// pathKey returns the cleaned path for comparing paths, with windows in lower case and
+// with slashes as separator
+func pathKey(path string, windows bool) string {
+	if windows {
+		path = strings.ReplaceAll(strings.ToLower(path), `\`, "/")
+	}
+	return filepath.Clean(path)
+}
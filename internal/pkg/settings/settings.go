@@ -4,39 +4,280 @@ package settings
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/adrg/xdg"
 	"github.com/goccy/go-yaml"
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/atomicfile"
 	"github.com/sercxanto/go-homebank-csv/pkg/parser"
 )
 
 const defaultConfigFilePath = "go-homebank-csv/config.yml"
 
+// defaultConfigFilePaths are the default config files in the supported formats, searched
+// in this order
+var defaultConfigFilePaths = []string{defaultConfigFilePath, "go-homebank-csv/config.json", "go-homebank-csv/config.toml"}
+
 type BatchConvertSet struct {
 	// Name of the batchconvert set, must be unique
-	Name string `yaml:"name"`
-	// Where to search for input files, must be non-empty
-	InputDir string `yaml:"inputdir"`
+	Name string `yaml:"name,omitempty"`
+	// Whether the set is converted, true if nil. A disabled set is kept in the config, but
+	// skipped by the conversion and its input directories may be used by other sets.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Where to search for input files, InputDir or InputDirs must be non-empty
+	InputDir string `yaml:"inputdir,omitempty"`
+	// Further directories to search for input files, e.g. if downloads land in different folders
+	InputDirs []string `yaml:"inputdirs,omitempty"`
 	// Where to place output files, must be non-empty and not equal to InputDir
-	OutputDir string `yaml:"outputdir"`
-	// Source format, nil to use format autodetect
-	Format *parser.SourceFormat `yaml:"format"`
-	// Glob pattern to search for input files
-	FileGlobPattern string `yaml:"fileglobpattern"`
-	// Maximum age of input files in days
-	FileMaxAgeDays int `yaml:"filemaxagedays"`
+	OutputDir string `yaml:"outputdir,omitempty"`
+	// Create OutputDir if it does not exist, by default the conversion fails then
+	CreateOutputDir bool `yaml:"createoutputdir,omitempty"`
+	// Source formats tried one after the other until one can parse the input file, given as
+	// a single format or as list. Empty to use format autodetect.
+	Format FormatList `yaml:"format,omitempty"`
+	// Glob pattern to search for input files, matched against the base name in recursive mode
+	FileGlobPattern string `yaml:"fileglobpattern,omitempty"`
+	// Glob patterns of files not to be converted, matched against the base name
+	ExcludeGlobPatterns []string `yaml:"excludeglobpatterns,omitempty"`
+	// Search for input files also in the subdirectories of InputDir
+	Recursive bool `yaml:"recursive,omitempty"`
+	// Whether symlinks to input files are converted, true if nil. With false symlinks are
+	// ignored. Dangling symlinks are always skipped.
+	FollowSymlinks *bool `yaml:"followsymlinks,omitempty"`
+	// Place the output files in the same subdirectories of OutputDir as the input files are in
+	// InputDir, only used in recursive mode. By default all output files are placed in OutputDir.
+	PreserveStructure bool `yaml:"preservestructure,omitempty"`
+	// Maximum age of input files, no limit if 0. Given in YAML as number of days or as duration like "36h".
+	FileMaxAge MaxAge `yaml:"filemaxagedays,omitempty"`
+	// Maximum number of input files converted per run, the others are left for the next run.
+	// Files skipped as their output file exists don't count. No limit if 0.
+	MaxFilesPerRun int `yaml:"maxfilesperrun,omitempty"`
+	// Order in which the input files are converted and MaxFilesPerRun picks them, by name by default
+	Order FileOrder `yaml:"order,omitempty"`
+	// Prefix prepended to the categories of converted records, e.g. "Import:DKB"
+	CategoryPrefix string `yaml:"categoryprefix,omitempty"`
+	// Set CategoryPrefix also as category for records without category
+	CategoryPrefixAlways bool `yaml:"categoryprefixalways,omitempty"`
+	// Move the content of the "info" field into the "memo" field
+	RouteInfoToMemo bool `yaml:"routeinfotomemo,omitempty"`
+	// Move the content of the "memo" field into the "info" field
+	RouteMemoToInfo bool `yaml:"routememotoinfo,omitempty"`
+	// Disable the normalization of text fields to Unicode NFC
+	NoUnicodeNormalization bool `yaml:"nounicodenormalization,omitempty"`
+	// Skip buying and selling of securities, only used by the TradeRepublic format
+	SkipSecurityTrades bool `yaml:"skipsecuritytrades,omitempty"`
+	// IBANs of own accounts, only used by the Bunq format
+	OwnAccounts []string `yaml:"ownaccounts,omitempty"`
+	// Full account name of the imported bank account, only used by the GnuCash format
+	GnuCashAccount string `yaml:"gnucashaccount,omitempty"`
+	// Only records of this account are converted, only used by the Outbank format
+	OutbankAccount string `yaml:"outbankaccount,omitempty"`
+	// Payment codes by transaction type, they override the defaults of the
+	// Comdirect, DKB and Volksbank formats
+	PaymentTypes parser.PaymentTypes `yaml:"paymenttypes,omitempty"`
+	// Skip data rows which can't be parsed instead of failing the whole file
+	Lenient bool `yaml:"lenient,omitempty"`
+	// Encoding of the input files, detected by default
+	Encoding parser.Encoding `yaml:"encoding,omitempty"`
+	// Take the date of unbooked rows from "Wertstellung (Valuta)", only used by the Comdirect format
+	ComdirectValutaFallback bool `yaml:"comdirectvalutafallback,omitempty"`
+	// Skip records already contained in previously converted files in OutputDir
+	Dedupe bool `yaml:"dedupe,omitempty"`
+	// Don't write an output file for input files without entries, e.g. exports containing
+	// only pending transactions. By default an output file with the header only is written.
+	SkipEmpty bool `yaml:"skipempty,omitempty"`
+	// Whether existing files in OutputDir are converted again, by default they are kept
+	Overwrite OverwritePolicy `yaml:"overwrite,omitempty"`
+	// How files skipped by Overwrite are detected as converted, by default by the name of the output file
+	SkipMode SkipMode `yaml:"skipmode,omitempty"`
+	// Remember the converted input files in a state file, so they are skipped even if their
+	// output files are renamed or moved. Changed input files are converted again, even if
+	// their output files exist.
+	State bool `yaml:"state,omitempty"`
+	// Path of the state file, DefaultStateFileName in OutputDir if empty. Setting it enables State.
+	StateFile string `yaml:"statefile,omitempty"`
+	// What to do with an input file after its successful conversion, by default it is kept
+	OnSuccess SuccessAction `yaml:"onsuccess,omitempty"`
+	// Where input files are moved to with OnSuccess "move", InputDir/processed if empty
+	ArchiveDir string `yaml:"archivedir,omitempty"`
+	// Command run after the successful conversion of each file, the program followed by its
+	// arguments. The output file, the set and the status are passed in the environment
+	// variables GHC_OUTPUT_FILE, GHC_SET and GHC_STATUS.
+	OnSuccessCommand []string `yaml:"onsuccesscommand,omitempty"`
+	// How often reading an input file or writing an output file is retried after transient
+	// I/O errors, e.g. of a network share. By default it is not retried.
+	Retries int `yaml:"retries,omitempty"`
+	// Time waited before each retry, e.g. "5s". One second if not set.
+	RetryDelay time.Duration `yaml:"retrydelay,omitempty"`
+	// Keep the extension of input files with the same output file, e.g. "Umsaetze.xlsx.csv",
+	// prepended by the subdirectory if flattened. By default these files fail.
+	DisambiguateOutputNames bool `yaml:"disambiguateoutputnames,omitempty"`
+	// Name of the output files, by default the name of the input file with the extension ".csv".
+	// The placeholders {basename}, {set}, {format}, {firstdate}, {lastdate} and {today} are
+	// replaced by the name of the input file without extension, the name of the set, the source
+	// format, the dates of the oldest and newest converted record and the current date (YYYY-MM-DD).
+	OutputNameTemplate string `yaml:"outputnametemplate,omitempty"`
+	// Convert all files of the set into the single output file MergedOutputName, sorted by date
+	Merge bool `yaml:"merge,omitempty"`
+	// Name of the merged output file in OutputDir, "{set}.csv" if empty. The placeholders
+	// {set} and {date} are replaced by the name of the set and the current date (YYYY-MM-DD).
+	MergedOutputName string `yaml:"mergedoutputname,omitempty"`
+	// Don't write the merged output file if one of the files fails. By default the
+	// records of the other files are written.
+	MergeFailFast bool `yaml:"mergefailfast,omitempty"`
+	// Order of the converted records, by default the order of the input file is kept
+	Sort parser.SortOrder `yaml:"sort,omitempty"`
+	// Field delimiter of the output files, a single character or "tab", ";" if empty
+	OutputDelimiter string `yaml:"outputdelimiter,omitempty"`
+	// Decimal separator of the amounts in the output files, "." or ",", "." if empty
+	OutputDecimalSeparator string `yaml:"outputdecimalseparator,omitempty"`
+	// Don't write the header line into the output files
+	OutputNoHeader bool `yaml:"outputnoheader,omitempty"`
+	// Layout of the dates in the output files as used by Go's time.Format, e.g. "02.01.2006"
+	// for day.month.year. "2006-01-02" if empty.
+	OutputDateFormat string `yaml:"outputdateformat,omitempty"`
+	// Mapping rules of this set, they take precedence over the global rules
+	Rules []parser.Rule `yaml:"rules,omitempty"`
+	// Only records on or after this date (YYYY-MM-DD) are converted
+	DateFrom string `yaml:"datefrom,omitempty"`
+	// Only records on or before this date (YYYY-MM-DD) are converted
+	DateTo string `yaml:"dateto,omitempty"`
+	// Only records within this date range are converted, set from the command line.
+	// It overrides DateFrom and DateTo.
+	DateRange parser.DateRange `yaml:"-"`
 }
 
 type BatchConvertSets []BatchConvertSet
 
 type BatchConvertSettings struct {
-	Sets BatchConvertSets `yaml:"sets"`
+	// Settings taken by all sets not giving them, Name, InputDir, InputDirs and OutputDir
+	// can't be given. They are applied when loading.
+	Defaults BatchConvertSet  `yaml:"defaults,omitempty"`
+	Sets     BatchConvertSets `yaml:"sets,omitempty"`
+	// Mapping rules applied to the records of all sets
+	Rules []parser.Rule `yaml:"rules,omitempty"`
+	// Number of files of a set converted at the same time, values below 1 mean 1
+	Parallelism int `yaml:"parallelism,omitempty"`
+	// Command run after all sets are converted, the program followed by its arguments.
+	// The overall status is passed in the environment variable GHC_STATUS.
+	OnFinishCommand []string `yaml:"onfinishcommand,omitempty"`
+	// Time after which OnSuccessCommand and OnFinishCommand are killed, e.g. "30s".
+	// A default is used if zero.
+	CommandTimeout time.Duration `yaml:"commandtimeout,omitempty"`
+	// Report what would be converted without writing files, set from the command line
+	DryRun bool `yaml:"-"`
+	// Ignore the existing state files of the sets and replace them, set from the command line
+	ResetState bool `yaml:"-"`
+}
+
+// rulesFile is the content of a file with mapping rules only
+type rulesFile struct {
+	Rules []parser.Rule `yaml:"rules,omitempty"`
 }
 
 type Settings struct {
-	BatchConvert BatchConvertSettings `yaml:"batchconvert"`
+	// Version of the config file format, see CurrentVersion. Older config files are
+	// migrated when loading.
+	Version int `yaml:"version,omitempty"`
+	// Paths by name, used as "alias:name" at the start of the paths of the sets
+	PathAliases  map[string]string    `yaml:"pathaliases,omitempty"`
+	BatchConvert BatchConvertSettings `yaml:"batchconvert,omitempty"`
+	// Directory relative paths are resolved against by NormalizePaths, see WithBaseDir
+	baseDir string
+}
+
+// LoadOption changes how settings are loaded
+type LoadOption func(*loadOptions)
+
+// loadOptions are the options of loading settings, set by LoadOption
+type loadOptions struct {
+	baseDir   string
+	nonStrict bool
+	format    FileFormat
+}
+
+// WithBaseDir resolves relative paths of the sets against dir in NormalizePaths. By
+// default they are resolved against the directory of the file loaded by LoadFromFile,
+// and against the working directory for the other load functions.
+func WithBaseDir(dir string) LoadOption {
+	return func(o *loadOptions) {
+		o.baseDir = dir
+	}
+}
+
+// WithFileFormat sets the format of the content, YAML by default. LoadFromFile detects
+// the format by the extension of the file, see FileFormatOf.
+func WithFileFormat(format FileFormat) LoadOption {
+	return func(o *loadOptions) {
+		o.format = format
+	}
+}
+
+// NonStrict ignores unknown keys, e.g. of settings added by later versions. By default
+// unknown keys are an error, as a misspelled key would be ignored silently otherwise.
+func NonStrict() LoadOption {
+	return func(o *loadOptions) {
+		o.nonStrict = true
+	}
+}
+
+// Option configures settings created by New
+type Option func(*Settings)
+
+// WithSet adds the batch convert sets
+func WithSet(sets ...BatchConvertSet) Option {
+	return func(settings *Settings) {
+		settings.BatchConvert.Sets = append(settings.BatchConvert.Sets, sets...)
+	}
+}
+
+// WithRules adds mapping rules applied to the records of all sets
+func WithRules(rules ...parser.Rule) Option {
+	return func(settings *Settings) {
+		settings.BatchConvert.Rules = append(settings.BatchConvert.Rules, rules...)
+	}
+}
+
+// WithParallelism sets the number of files of a set converted at the same time
+func WithParallelism(n int) Option {
+	return func(settings *Settings) {
+		settings.BatchConvert.Parallelism = n
+	}
+}
+
+// WithPathAliases sets the path aliases usable in the paths of the sets
+func WithPathAliases(aliases map[string]string) Option {
+	return func(settings *Settings) {
+		settings.PathAliases = aliases
+	}
+}
+
+// New returns the settings configured by opts, for settings created in code. Their paths
+// are normalized with NormalizePaths, relative paths are kept relative to the working
+// directory. An error is returned if they are invalid, see CheckValidity.
+func New(opts ...Option) (Settings, error) {
+	settings := Settings{Version: CurrentVersion}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	if err := settings.NormalizePaths(); err != nil {
+		return Settings{}, err
+	}
+	if err := settings.CheckValidity(); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// NewSettings returns validated settings with the given batch convert sets, see New.
+func NewSettings(sets ...BatchConvertSet) (Settings, error) {
+	return New(WithSet(sets...))
 }
 
 func (s *BatchConvertSet) LoadFromString(str string) error {
@@ -51,57 +292,252 @@ func (s *BatchConvertSet) LoadFromString(str string) error {
 	return nil
 }
 
-func (settings *Settings) LoadFromString(str string) error {
-	// Load settings from str
-	// Parse yaml contained in str into variable settings
-	*settings = Settings{}
-	err := yaml.Unmarshal([]byte(str), settings)
+// LoadFromString loads the settings from str like LoadFromReader
+func (settings *Settings) LoadFromString(str string, options ...LoadOption) error {
+	return settings.LoadFromReader(strings.NewReader(str), options...)
+}
+
+// LoadFromFile loads the settings from the file like LoadFromReader, in the format given
+// by its extension, see FileFormatOf. Relative paths of the sets are resolved against the
+// directory of the file, so they don't depend on the working directory.
+func (settings *Settings) LoadFromFile(filePath string, options ...LoadOption) error {
+	baseDir, err := filepath.Abs(filepath.Dir(filePath))
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func (settings *Settings) LoadFromFile(filePath string) error {
-
-	// Open file filePath for reading
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	// The options given take precedence
+	defaults := []LoadOption{WithFileFormat(FileFormatOf(filePath)), WithBaseDir(baseDir)}
+	return settings.LoadFromReader(file, append(defaults, options...)...)
+}
 
-	// Read file into a byte slice
-	content, err := os.ReadFile(filePath)
+// LoadFromReader loads the settings from r, YAML unless set by WithFileFormat. The sets
+// take the missing settings from the defaults. Unknown keys are an error unless the option
+// NonStrict is given.
+//
+// The settings are loaded as given, so they can be saved again unchanged. Before using them
+// call NormalizePaths and CheckValidity, as New does for settings created in code.
+func (settings *Settings) LoadFromReader(r io.Reader, options ...LoadOption) error {
+	content, err := io.ReadAll(r)
 	if err != nil {
 		return err
 	}
+	return settings.load(content, options)
+}
+
+// newLoadOptions returns the options set by options
+func newLoadOptions(options []LoadOption) loadOptions {
+	var o loadOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// load sets the settings from the content, see LoadFromReader
+func (settings *Settings) load(content []byte, options []LoadOption) error {
+	_, err := settings.loadMigrated(content, options)
+	return err
+}
+
+// loadMigrated sets the settings from the content migrated to CurrentVersion, see
+// LoadFromReader. It returns the warnings of the migrations.
+func (settings *Settings) loadMigrated(content []byte, options []LoadOption) ([]string, error) {
+	o := newLoadOptions(options)
+	content, err := toYAML(content, o.format)
+	if err != nil {
+		return nil, err
+	}
+	content, warnings, err := migrate(content)
+	if err != nil {
+		return nil, convertedError(err, o.format)
+	}
+	var decodeOptions []yaml.DecodeOption
+	if !o.nonStrict {
+		decodeOptions = append(decodeOptions, yaml.DisallowUnknownField())
+	}
 
 	*settings = Settings{}
-	err = yaml.Unmarshal(content, settings)
+	if err := decodeWithDefaults(content, settings, decodeOptions...); err != nil {
+		return nil, convertedError(err, o.format)
+	}
+	settings.Version = CurrentVersion
+	settings.baseDir = o.baseDir
+	return warnings, nil
+}
+
+// searchDefaultFile returns the first existing default config file, searching for
+// config.yml, config.json and config.toml in turn in the XDG config directories
+func searchDefaultFile() (string, error) {
+	var err error
+	for _, path := range defaultConfigFilePaths {
+		var configFilePath string
+		if configFilePath, err = xdg.SearchConfigFile(path); err == nil {
+			return configFilePath, nil
+		}
+	}
+	return "", err
+}
+
+// DefaultFilePath returns the path of the default config file. It is the first existing
+// config file in the XDG config directories, see searchDefaultFile, otherwise the path
+// of config.yml in the user's config directory where a new config file is expected.
+func DefaultFilePath() string {
+	if configFilePath, err := searchDefaultFile(); err == nil {
+		return configFilePath
+	}
+	return filepath.Join(xdg.ConfigHome, filepath.FromSlash(defaultConfigFilePath))
+}
+
+// LoadFromDefaultFile loads settings from default config file, the first one of
+// config.yml, config.json and config.toml found.
+func (settings *Settings) LoadFromDefaultFile(options ...LoadOption) (string, error) {
+	configFilePath, err := searchDefaultFile()
+	if err != nil {
+		return "", err
+	}
+	return configFilePath, settings.LoadFromFile(configFilePath, options...)
+}
+
+// Marshal returns the settings as YAML, fields with default values are left out
+func (settings Settings) Marshal() ([]byte, error) {
+	return settings.marshal()
+}
+
+// SaveToFile writes the settings to filePath in the format given by its extension, see
+// FileFormatOf. Fields with default values are left out. The comments of an existing
+// YAML file are kept as long as the commented fields still exist. The file is replaced
+// atomically and is only readable by the user.
+func (settings Settings) SaveToFile(filePath string) error {
+	format := FileFormatOf(filePath)
+	var options []yaml.EncodeOption
+	if format == FormatYAML {
+		if content, err := os.ReadFile(filePath); err == nil {
+			comments := yaml.CommentMap{}
+			var existing interface{}
+			if err := yaml.UnmarshalWithOptions(content, &existing, yaml.CommentToMap(comments)); err == nil {
+				options = append(options, yaml.WithComment(comments))
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	content, err := settings.marshal(options...)
 	if err != nil {
 		return err
 	}
-	return nil
+	if content, err = fromYAML(content, format); err != nil {
+		return err
+	}
+	return atomicfile.Write(filePath, 0o600, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
 }
 
-// LoadFromDefaultFile loads settings from default config file.
-func (settings *Settings) LoadFromDefaultFile() (string, error) {
-	configFilePath, err := xdg.SearchConfigFile(defaultConfigFilePath)
+// SaveToDefaultFile writes the settings to the existing default config file, otherwise
+// to config.yml in the user's config directory, creating the directory if missing. It
+// returns the path of the file.
+func (settings Settings) SaveToDefaultFile() (string, error) {
+	configFilePath, err := searchDefaultFile()
+	if err != nil {
+		configFilePath, err = xdg.ConfigFile(defaultConfigFilePath)
+	}
 	if err != nil {
 		return "", err
 	}
-	return configFilePath, settings.LoadFromFile(configFilePath)
+	return configFilePath, settings.SaveToFile(configFilePath)
+}
+
+// LoadRulesFromFile loads the mapping rules from the "rules" section of a YAML file
+// and checks that they can be compiled.
+func LoadRulesFromFile(filePath string) ([]parser.Rule, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var f rulesFile
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, err
+	}
+	if _, err := parser.NewRules(f.Rules); err != nil {
+		return nil, err
+	}
+	return f.Rules, nil
 }
 
 // CheckValidity reports whether a the whole settings are valid
 func (s Settings) CheckValidity() error {
+	if err := s.checkGlobalValidity(); err != nil {
+		return err
+	}
 	if len(s.BatchConvert.Sets) > 0 {
 		return s.BatchConvert.Sets.CheckValidity()
 	}
 	return nil
 }
 
+// CheckValidityDeep reports whether the whole settings are valid like CheckValidity, and
+// whether the directories of the enabled sets can be used, see BatchConvertSet.CheckDirs
+func (s Settings) CheckValidityDeep() error {
+	if err := s.checkGlobalValidity(); err != nil {
+		return err
+	}
+	return s.BatchConvert.Sets.CheckValidityDeep()
+}
+
+// Problems returns all problems of the settings, while CheckValidity returns only the
+// first one. The problems of a set are prefixed by its name. With checkDirs the missing
+// directories of the sets are reported as well, see BatchConvertSet.CheckDirs.
+func (s Settings) Problems(checkDirs bool) []error {
+	var problems []error
+	if err := s.checkGlobalValidity(); err != nil {
+		problems = append(problems, err)
+	}
+	for _, set := range s.BatchConvert.Sets {
+		var setProblems []error
+		if err := set.CheckValidity(); err != nil {
+			setProblems = append(setProblems, err)
+		}
+		if checkDirs {
+			setProblems = append(setProblems, set.CheckDirs()...)
+		}
+		for _, err := range setProblems {
+			problems = append(problems, fmt.Errorf("set '%s': %w", set.Name, err))
+		}
+	}
+	return append(problems, s.BatchConvert.Sets.checkDuplicates()...)
+}
+
+// checkGlobalValidity reports whether the settings outside of the sets are valid
+func (s Settings) checkGlobalValidity() error {
+	if _, err := parser.NewRules(s.BatchConvert.Rules); err != nil {
+		return fmt.Errorf("Rules are invalid: %w", err)
+	}
+	if s.BatchConvert.Parallelism < 0 {
+		return errors.New("Parallelism must not be negative")
+	}
+	if s.BatchConvert.CommandTimeout < 0 {
+		return errors.New("CommandTimeout must not be negative")
+	}
+	if err := checkCommand(s.BatchConvert.OnFinishCommand); err != nil {
+		return fmt.Errorf("OnFinishCommand is invalid: %w", err)
+	}
+	return nil
+}
+
+// checkCommand returns an error if a command is given without program
+func checkCommand(command []string) error {
+	if len(command) > 0 && strings.TrimSpace(command[0]) == "" {
+		return errors.New("program is empty")
+	}
+	return nil
+}
+
 // IsFileGlobPatternValid reports whether a file glob pattern is valid.
 //
 //   - pattern: the file glob pattern to be validated.
@@ -116,65 +552,356 @@ func IsFileGlobPatternValid(pattern string) bool {
 // Possible errors:
 //
 //   - Name is empty
-//   - InputDir is empty
+//   - InputDir and InputDirs are empty
+//   - duplicate input directory
 //   - OutputDir is empty
-//   - OutputDir == InputDir
-//   - FileMaxAgeDays < 0
+//   - OutputDir == InputDir or one of InputDirs
+//   - FileMaxAge < 0
+//   - MaxFilesPerRun < 0
+//   - Retries < 0 or RetryDelay < 0
+//   - Format contains an unknown or duplicate format
 //   - FileGlobPattern is invalid
+//   - DateFrom or DateTo is invalid or DateFrom is after DateTo
+//   - CategoryPrefix is invalid
+//   - RouteInfoToMemo and RouteMemoToInfo are both set
+//   - PaymentTypes are invalid
+//   - Rules are invalid
+//   - output options are invalid, e.g. OutputDelimiter == OutputDecimalSeparator
+//   - Dedupe with output files the homebank format can't read
 func (s BatchConvertSet) CheckValidity() error {
 	if s.Name == "" {
 		return errors.New("name is empty")
 	}
-	if s.InputDir == "" {
-		return errors.New("InputDir is empty")
+	inputDirs := s.GetInputDirs()
+	if len(inputDirs) == 0 {
+		return errors.New("InputDir and InputDirs are empty")
 	}
 	if s.OutputDir == "" {
 		return errors.New("OutputDir is empty")
 	}
-	if s.InputDir == s.OutputDir {
-		return errors.New("InputDir == OutputDir")
+	for i, dir := range inputDirs {
+		if dir == "" {
+			return errors.New("InputDirs contains an empty directory")
+		}
+		if samePath(dir, s.OutputDir) {
+			return errors.New("InputDir == OutputDir")
+		}
+		if slices.ContainsFunc(inputDirs[:i], func(other string) bool { return samePath(other, dir) }) {
+			return fmt.Errorf("duplicate input directory '%s'", dir)
+		}
+	}
+	if s.FileMaxAge < 0 {
+		return errors.New("FileMaxAge < 0")
 	}
-	if s.FileMaxAgeDays < 0 {
-		return errors.New("FileMaxAgeDays < 0")
+	if s.MaxFilesPerRun < 0 {
+		return errors.New("MaxFilesPerRun < 0")
+	}
+	if s.Retries < 0 {
+		return errors.New("Retries < 0")
+	}
+	if s.RetryDelay < 0 {
+		return errors.New("RetryDelay < 0")
+	}
+	if err := s.Format.checkValidity(); err != nil {
+		return fmt.Errorf("Format is invalid: %w", err)
 	}
 	if !IsFileGlobPatternValid(s.FileGlobPattern) {
 		return errors.New("FileGlobPattern is invalid")
 	}
+	for _, pattern := range s.ExcludeGlobPatterns {
+		if !IsFileGlobPatternValid(pattern) {
+			return fmt.Errorf("ExcludeGlobPatterns contains invalid pattern '%s'", pattern)
+		}
+	}
+	if s.SkipMode == SkipByHash && s.Dedupe {
+		return errors.New("SkipMode hash cannot be combined with Dedupe")
+	}
+	if s.PreserveStructure && !s.Recursive {
+		return errors.New("PreserveStructure requires Recursive")
+	}
+	if s.OnSuccess == OnSuccessMove && samePath(s.GetArchiveDir(), s.OutputDir) {
+		return errors.New("ArchiveDir == OutputDir")
+	}
+	if s.Merge && s.SkipMode == SkipByHash {
+		return errors.New("SkipMode hash cannot be combined with Merge")
+	}
+	if s.Merge && s.MaxFilesPerRun > 0 {
+		return errors.New("MaxFilesPerRun cannot be combined with Merge")
+	}
+	if s.Merge && s.GetStateFile() != "" {
+		return errors.New("State cannot be combined with Merge")
+	}
+	if s.Merge && s.PreserveStructure {
+		return errors.New("PreserveStructure cannot be combined with Merge")
+	}
+	if strings.ContainsAny(s.GetMergedOutputName(time.Time{}), `/\`) {
+		return errors.New("MergedOutputName must not contain a directory")
+	}
+	if s.OutputNameTemplate != "" {
+		if s.Merge {
+			return errors.New("OutputNameTemplate cannot be combined with Merge")
+		}
+		if s.DisambiguateOutputNames {
+			return errors.New("OutputNameTemplate cannot be combined with DisambiguateOutputNames")
+		}
+		if err := s.checkOutputNameTemplate(); err != nil {
+			return fmt.Errorf("OutputNameTemplate is invalid: %w", err)
+		}
+	}
+	if err := checkCommand(s.OnSuccessCommand); err != nil {
+		return fmt.Errorf("OnSuccessCommand is invalid: %w", err)
+	}
+	if _, err := parser.ParseDateRange(s.DateFrom, s.DateTo); err != nil {
+		return fmt.Errorf("DateFrom / DateTo is invalid: %w", err)
+	}
+	if err := parser.CheckCategoryPrefix(s.CategoryPrefix); err != nil {
+		return fmt.Errorf("CategoryPrefix is invalid: %w", err)
+	}
+	if err := parser.CheckFieldRouting(s.RouteInfoToMemo, s.RouteMemoToInfo); err != nil {
+		return err
+	}
+	if err := parser.CheckPaymentTypes(s.PaymentTypes); err != nil {
+		return fmt.Errorf("PaymentTypes are invalid: %w", err)
+	}
+	if _, err := parser.NewRules(s.Rules); err != nil {
+		return fmt.Errorf("Rules are invalid: %w", err)
+	}
+	output, err := s.GetOutputOptions()
+	if err != nil {
+		return fmt.Errorf("output options are invalid: %w", err)
+	}
+	if s.Dedupe && !homebankReadable(output) {
+		return errors.New("Dedupe requires output files readable as homebank format, with delimiter ';', header and date format '2006-01-02' or '02.01.2006'")
+	}
+	return nil
+}
+
+// homebankReadable reports whether output files written with the options can be read
+// by the homebank format, as needed to dedupe against previously converted files
+func homebankReadable(o parser.OutputOptions) bool {
+	return (o.Delimiter == 0 || o.Delimiter == ';') && !o.NoHeader &&
+		slices.Contains([]string{"", "2006-01-02", "02.01.2006"}, o.DateFormat)
+}
+
+// GetOutputOptions returns the options of the written output files given by
+// OutputDelimiter, OutputDecimalSeparator, OutputNoHeader and OutputDateFormat
+func (s BatchConvertSet) GetOutputOptions() (parser.OutputOptions, error) {
+	return parser.ParseOutputOptions(s.OutputDelimiter, s.OutputDecimalSeparator, s.OutputNoHeader, s.OutputDateFormat)
+}
+
+// CheckValidityDeep reports whether the set is valid like CheckValidity, and whether
+// its directories can be used, see CheckDirs. It returns the first problem found.
+func (s BatchConvertSet) CheckValidityDeep() error {
+	if err := s.CheckValidity(); err != nil {
+		return err
+	}
+	if problems := s.CheckDirs(); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// CheckDirs returns an error for each problem with the directories of the set:
+//
+//   - an input directory does not exist, is not a directory or is not readable
+//   - OutputDir does not exist, is not a directory or is not writable. A missing
+//     OutputDir is fine with CreateOutputDir.
+//   - with Recursive OutputDir and an input directory are nested, so converted files
+//     would be found as input files again
+//
+// Whether OutputDir is writable is checked by creating and removing a temporary file.
+func (s BatchConvertSet) CheckDirs() []error {
+	var problems []error
+	check := func(name string, dir string, mayBeMissing bool) bool {
+		info, err := os.Stat(dir)
+		if err != nil {
+			if !mayBeMissing || !errors.Is(err, fs.ErrNotExist) {
+				problems = append(problems, fmt.Errorf("%s: %w", name, err))
+			}
+			return false
+		}
+		if !info.IsDir() {
+			problems = append(problems, fmt.Errorf("%s '%s' is not a directory", name, dir))
+			return false
+		}
+		return true
+	}
+	for _, dir := range s.GetInputDirs() {
+		if check("InputDir", dir, false) {
+			if err := checkReadable(dir); err != nil {
+				problems = append(problems, fmt.Errorf("InputDir '%s' is not readable: %w", dir, err))
+			}
+		}
+		if s.Recursive && s.OutputDir != "" {
+			if isSubPath(dir, s.OutputDir) {
+				problems = append(problems, fmt.Errorf("OutputDir '%s' is inside InputDir '%s', its files would be converted again with Recursive", s.OutputDir, dir))
+			} else if isSubPath(s.OutputDir, dir) {
+				problems = append(problems, fmt.Errorf("InputDir '%s' is inside OutputDir '%s', it is searched for converted files with Recursive", dir, s.OutputDir))
+			}
+		}
+	}
+	if s.OutputDir != "" && check("OutputDir", s.OutputDir, s.CreateOutputDir) {
+		if err := checkWritable(s.OutputDir); err != nil {
+			problems = append(problems, fmt.Errorf("OutputDir '%s' is not writable: %w", s.OutputDir, err))
+		}
+	}
+	return problems
+}
+
+// checkReadable returns an error if the entries of the directory can't be listed
+func checkReadable(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
 	return nil
 }
 
+// checkWritable returns an error if no file can be created in the directory
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".go-homebank-csv-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// GetDateRange returns the range of dates to be converted. It is DateRange if set,
+// otherwise the range given by DateFrom and DateTo.
+func (s BatchConvertSet) GetDateRange() (parser.DateRange, error) {
+	if !s.DateRange.IsZero() {
+		return s.DateRange, nil
+	}
+	return parser.ParseDateRange(s.DateFrom, s.DateTo)
+}
+
+// GetMergedOutputName returns the name of the merged output file, with the placeholders
+// {set} and {date} replaced by the name of the set and the date of now.
+func (s BatchConvertSet) GetMergedOutputName(now time.Time) string {
+	name := s.MergedOutputName
+	if name == "" {
+		name = "{set}.csv"
+	}
+	return strings.NewReplacer("{set}", s.Name, "{date}", now.Format(time.DateOnly)).Replace(name)
+}
+
+// GetInputDirs returns the directories to search for input files, InputDir if set
+// followed by InputDirs
+func (s BatchConvertSet) GetInputDirs() []string {
+	if s.InputDir == "" {
+		return s.InputDirs
+	}
+	return append([]string{s.InputDir}, s.InputDirs...)
+}
+
+// DefaultStateFileName is the name of the state file in OutputDir if StateFile is not set
+const DefaultStateFileName = ".go-homebank-csv-state.json"
+
+// GetStateFile returns the path of the state file, "" if State is disabled
+func (s BatchConvertSet) GetStateFile() string {
+	if s.StateFile != "" {
+		return s.StateFile
+	}
+	if !s.State {
+		return ""
+	}
+	return filepath.Join(s.OutputDir, DefaultStateFileName)
+}
+
+// GetFollowSymlinks returns whether symlinks to input files are converted, by default true
+func (s BatchConvertSet) GetFollowSymlinks() bool {
+	return s.FollowSymlinks == nil || *s.FollowSymlinks
+}
+
+// GetEnabled returns whether the set is converted, by default true
+func (s BatchConvertSet) GetEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// GetArchiveDir returns the directory input files are moved to after their conversion.
+// It is ArchiveDir if set, otherwise the subdirectory "processed" of the first input directory.
+func (s BatchConvertSet) GetArchiveDir() string {
+	if s.ArchiveDir != "" {
+		return s.ArchiveDir
+	}
+	inputDirs := s.GetInputDirs()
+	if len(inputDirs) == 0 {
+		return "processed"
+	}
+	return filepath.Join(inputDirs[0], "processed")
+}
+
 // CheckValidity reports whether a BatchConvertSets are valid
 //
 // Possible errors:
 //
 //   - invalid CheckValidity() of entry
 //   - duplicate Name
-//   - duplicate InputDir / FileGlobPattern combination
+//   - duplicate input directory / FileGlobPattern combination of enabled sets
 func (s BatchConvertSets) CheckValidity() error {
-
-	names := make([]string, 0, len(s))
-	inputDirAndGlobPattern := make([]string, 0, len(s))
-
 	for _, entry := range s {
 		if err := entry.CheckValidity(); err != nil {
 			return err
 		}
-		for i := range names {
-			if names[i] == entry.Name {
-				return fmt.Errorf("duplicate Name '%s' detected", entry.Name)
-			}
+	}
+	if errs := s.checkDuplicates(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// CheckValidityDeep reports whether the BatchConvertSets are valid like CheckValidity,
+// and whether the directories of the enabled sets can be used, see BatchConvertSet.CheckDirs
+func (s BatchConvertSets) CheckValidityDeep() error {
+	if err := s.CheckValidity(); err != nil {
+		return err
+	}
+	for _, entry := range s {
+		if !entry.GetEnabled() {
+			continue
+		}
+		if problems := entry.CheckDirs(); len(problems) > 0 {
+			return fmt.Errorf("set '%s': %w", entry.Name, problems[0])
+		}
+	}
+	return nil
+}
+
+// checkDuplicates returns an error for each duplicate Name and each duplicate input
+// directory / FileGlobPattern combination. Disabled sets are only checked for their Name.
+// The input directories are compared with samePath.
+func (s BatchConvertSets) checkDuplicates() []error {
+	var problems []error
+	names := make([]string, 0, len(s))
+	type inputDirAndGlobPattern struct {
+		inputDir, globPattern string
+	}
+	var seen []inputDirAndGlobPattern
+
+	for _, entry := range s {
+		if slices.Contains(names, entry.Name) {
+			problems = append(problems, fmt.Errorf("duplicate Name '%s' detected", entry.Name))
 		}
 		names = append(names, entry.Name)
+		if !entry.GetEnabled() {
+			continue
+		}
 
-		value := entry.InputDir + entry.FileGlobPattern
-		for i := range inputDirAndGlobPattern {
-			if inputDirAndGlobPattern[i] == value {
-				return fmt.Errorf("duplicate InputDir / FileGlobPattern combination detected ('%s', '%s')",
-					entry.InputDir, entry.FileGlobPattern)
+		for _, inputDir := range entry.GetInputDirs() {
+			if slices.ContainsFunc(seen, func(other inputDirAndGlobPattern) bool {
+				return other.globPattern == entry.FileGlobPattern && samePath(other.inputDir, inputDir)
+			}) {
+				problems = append(problems, fmt.Errorf("duplicate InputDir / FileGlobPattern combination detected ('%s', '%s')",
+					inputDir, entry.FileGlobPattern))
 			}
+			seen = append(seen, inputDirAndGlobPattern{inputDir, entry.FileGlobPattern})
 		}
-		inputDirAndGlobPattern = append(inputDirAndGlobPattern, value)
 	}
 
-	return nil
+	return problems
 }
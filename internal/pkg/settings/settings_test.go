@@ -1,12 +1,22 @@
 package settings
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/goccy/go-yaml"
 
 	"github.com/sercxanto/go-homebank-csv/pkg/parser"
 )
@@ -96,23 +106,92 @@ func TestBatchConvertSetCheckValidity(t *testing.T) {
 	}
 
 	s.OutputDir = "/some/other/path"
-	s.FileMaxAgeDays = -1
+	s.FileMaxAge = MaxAgeDays(-1)
 	if s.CheckValidity() == nil {
-		t.Error("Expected FileMaxAgeDays error")
+		t.Error("Expected FileMaxAge error")
 	}
 
-	s.FileMaxAgeDays = 0
+	s.FileMaxAge = MaxAgeDays(0)
 	s.FileGlobPattern = "["
 	if err := s.CheckValidity(); err == nil {
 		t.Error("Expected FileGlobPattern error")
 	}
 
 	s.FileGlobPattern = "*"
+	s.ExcludeGlobPatterns = []string{"*.pdf", "["}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected ExcludeGlobPatterns error")
+	}
+
+	s.ExcludeGlobPatterns = []string{"*.pdf", "*_old.csv"}
+	s.PreserveStructure = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected PreserveStructure without Recursive error")
+	}
+
+	s.Recursive = true
+	s.CategoryPrefix = "Import;DKB"
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected CategoryPrefix error")
+	}
+
+	s.CategoryPrefix = "Import:DKB"
+	s.RouteInfoToMemo = true
+	s.RouteMemoToInfo = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected RouteInfoToMemo/RouteMemoToInfo error")
+	}
+
+	s.RouteMemoToInfo = false
+	s.DateFrom = "01.05.2024"
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected DateFrom error")
+	}
+
+	s.DateFrom = "2024-05-01"
+	s.DateTo = "2024-04-30"
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected DateFrom after DateTo error")
+	}
+
+	s.DateTo = "2024-05-31"
 	if err := s.CheckValidity(); err != nil {
 		t.Errorf("No error expected, got '%s' instead", err)
 	}
 }
 
+func TestBatchConvertSetGetDateRange(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("datefrom: 2024-05-01\ndateto: \"2024-05-31\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.GetDateRange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "2024-05-01 to 2024-05-31" {
+		t.Errorf("Unexpected date range '%s'", r)
+	}
+
+	// The date range from the command line takes precedence
+	s.DateRange, err = parser.MonthRange("2024-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = s.GetDateRange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "2024-06-01 to 2024-06-30" {
+		t.Errorf("Unexpected date range '%s'", r)
+	}
+
+	s = BatchConvertSet{DateTo: "2024-13-01"}
+	if _, err := s.GetDateRange(); err == nil {
+		t.Error("Expected error for invalid DateTo")
+	}
+}
+
 func TestBatchConvertSetsCheckValidity(t *testing.T) {
 
 	s := BatchConvertSets{
@@ -184,6 +263,123 @@ func TestBatchConvertSetsCheckValidity(t *testing.T) {
 		t.Error("Did not expect error")
 	}
 
+	// The input directories of different sets may not overlap
+	s = BatchConvertSets{
+		BatchConvertSet{
+			Name:      "name1",
+			InputDirs: []string{"/my/path1", "/my/path3"},
+			OutputDir: "/my/path2",
+		},
+		BatchConvertSet{
+			Name:      "name2",
+			InputDir:  "/my/path3",
+			OutputDir: "/my/path4",
+		},
+	}
+
+	if s.CheckValidity() == nil {
+		t.Error("Expected duplicate InputDirs/FileGlobPattern error")
+	}
+
+	// A disabled set may share its input directory with the set replacing it
+	disabled := false
+	s = BatchConvertSets{
+		BatchConvertSet{
+			Name:      "name1",
+			Enabled:   &disabled,
+			InputDir:  "/my/path1",
+			OutputDir: "/my/path2",
+		},
+		BatchConvertSet{
+			Name:      "name2",
+			InputDir:  "/my/path1",
+			OutputDir: "/my/path4",
+		},
+	}
+
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("Did not expect error for disabled set, got %v", err)
+	}
+
+	// but not its name
+	s[1].Name = "name1"
+	if s.CheckValidity() == nil {
+		t.Error("Expected duplicate name error for disabled set")
+	}
+}
+
+func TestCheckValidityPathVariants(t *testing.T) {
+	existing := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(existing, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		inputDir  string
+		outputDir string
+		otherDir  string // Input directory of a second set
+	}{
+		{"trailing slash", "/my/path1", "/my/path1/", "/my/path3"},
+		{"double slash", "/my//path1", "/my/path1", "/my/path3"},
+		{"dot segment", "/my/./path1", "/my/other/../path1", "/my/path3"},
+		{"symlink", existing, link, "/my/path3"},
+		{"duplicate trailing slash", "/my/path1", "/my/path2", "/my/path1/"},
+		{"duplicate dot segment", "/my/path1", "/my/path2", "/my/sub/../path1"},
+		{"duplicate symlink", existing, "/my/path2", link},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := BatchConvertSets{
+				{Name: "name1", InputDir: test.inputDir, OutputDir: test.outputDir},
+				{Name: "name2", InputDir: test.otherDir, OutputDir: "/my/path4"},
+			}
+			if err := s.CheckValidity(); err == nil {
+				t.Errorf("Expected error for %s and %s / %s", test.inputDir, test.outputDir, test.otherDir)
+			}
+		})
+	}
+}
+
+func TestPathKey(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		windows bool
+		same    bool
+	}{
+		{"/my/path1", "/my/path1/", false, true},
+		{"/my/path1", "/My/Path1", false, false},
+		{`C:\Data`, `c:\data`, true, true},
+		{`C:\Data\`, `c:/data`, true, true},
+		{`C:\Data\.\sub`, `c:\data\sub\`, true, true},
+		{`C:\Data`, `C:\Data2`, true, false},
+	}
+	for _, test := range tests {
+		if same := pathKey(test.a, test.windows) == pathKey(test.b, test.windows); same != test.same {
+			t.Errorf("Expected same %v for '%s' and '%s' (windows %v)", test.same, test.a, test.b, test.windows)
+		}
+	}
+}
+
+func TestIsSubPath(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		inside    bool
+	}{
+		{"/my/input", "/my/input/converted", true},
+		{"/my/input/", "/my/input/a/b/", true},
+		{"/my/input", "/my/input", false},
+		{"/my/input", "/my/input2", false},
+		{"/my/input", "/my", false},
+		{"/my/input", "/my/input/../output", false},
+		{"/my/input", "relative", false},
+	}
+	for _, test := range tests {
+		if inside := isSubPath(filepath.FromSlash(test.dir), filepath.FromSlash(test.path)); inside != test.inside {
+			t.Errorf("Expected inside %v for '%s' in '%s'", test.inside, test.path, test.dir)
+		}
+	}
 }
 
 func TestSettingsCheckValidity(t *testing.T) {
@@ -191,6 +387,83 @@ func TestSettingsCheckValidity(t *testing.T) {
 	if s.CheckValidity() != nil {
 		t.Error("Expected nil error")
 	}
+	s.BatchConvert.Parallelism = -1
+	if s.CheckValidity() == nil {
+		t.Error("Expected error for negative parallelism")
+	}
+}
+
+func TestNewSettings(t *testing.T) {
+	s, err := NewSettings()
+	if err != nil {
+		t.Errorf("Did not expect error: %v", err)
+	}
+	if len(s.BatchConvert.Sets) != 0 {
+		t.Error("Expected no sets")
+	}
+
+	set := BatchConvertSet{Name: "My name", InputDir: "/some/path", OutputDir: "/some/other/path"}
+	s, err = NewSettings(set)
+	if err != nil {
+		t.Errorf("Did not expect error: %v", err)
+	}
+	if len(s.BatchConvert.Sets) != 1 || !reflect.DeepEqual(s.BatchConvert.Sets[0], set) {
+		t.Errorf("Unexpected sets %+v", s.BatchConvert.Sets)
+	}
+
+	if _, err := NewSettings(set, set); err == nil {
+		t.Error("Expected duplicate name error")
+	}
+}
+
+func TestNew(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	rule := parser.Rule{Match: parser.RuleMatch{PayeeRegex: "REWE"}, Set: parser.RuleSet{Category: "Food"}}
+	s, err := New(
+		WithPathAliases(map[string]string{"bankroot": "~/exports"}),
+		WithSet(BatchConvertSet{Name: "dkb", InputDir: "alias:bankroot/dkb", OutputDir: "~/converted/"}),
+		WithSet(BatchConvertSet{Name: "amex", InputDir: "/my/amex", OutputDir: "/my/amex-converted"}),
+		WithRules(rule),
+		WithParallelism(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.BatchConvert.Sets) != 2 || s.BatchConvert.Parallelism != 2 || !reflect.DeepEqual(s.BatchConvert.Rules, []parser.Rule{rule}) {
+		t.Errorf("Unexpected settings %+v", s)
+	}
+	// The paths are normalized as after loading
+	set := s.BatchConvert.Sets[0]
+	if expected := filepath.Join(home, "exports", "dkb"); set.InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+	}
+	if expected := filepath.Join(home, "converted"); set.OutputDir != expected {
+		t.Errorf("Expected OutputDir '%s', got '%s'", expected, set.OutputDir)
+	}
+
+	if _, err := New(WithSet(BatchConvertSet{Name: "dkb", InputDir: "/my/path", OutputDir: "/my/path/"})); err == nil {
+		t.Error("Expected InputDir == OutputDir error")
+	}
+	if _, err := New(WithSet(BatchConvertSet{Name: "dkb", InputDir: "alias:missing", OutputDir: "/my/path"})); err == nil {
+		t.Error("Expected unknown alias error")
+	}
+}
+
+func TestSettingsLoadFromReader(t *testing.T) {
+	var s Settings
+	if err := s.LoadFromReader(strings.NewReader("batchconvert:\n  parallelism: 2\n")); err != nil || s.BatchConvert.Parallelism != 2 {
+		t.Errorf("Expected parallelism 2, got %d, %v", s.BatchConvert.Parallelism, err)
+	}
+	if err := s.LoadFromReader(strings.NewReader(`{"batchconvert": {"parallelism": 3}}`), WithFileFormat(FormatJSON)); err != nil || s.BatchConvert.Parallelism != 3 {
+		t.Errorf("Expected parallelism 3, got %d, %v", s.BatchConvert.Parallelism, err)
+	}
+	readErr := errors.New("read failed")
+	if err := s.LoadFromReader(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("Expected read error, got %v", err)
+	}
 }
 
 func TestBatchConvertLoadFromString(t *testing.T) {
@@ -228,8 +501,8 @@ filemaxagedays: 10`
 	if s.FileGlobPattern != "" {
 		t.Errorf("Expected '', got '%s' instead", s.FileGlobPattern)
 	}
-	if s.FileMaxAgeDays != 10 {
-		t.Errorf("Expected '10', got '%d' instead", s.FileMaxAgeDays)
+	if s.FileMaxAge != MaxAgeDays(10) {
+		t.Errorf("Expected '10d', got '%s' instead", s.FileMaxAge)
 	}
 
 	text2 := `
@@ -252,14 +525,14 @@ fileglobpattern: some glob pattern`
 	if s.OutputDir != "/my/path2" {
 		t.Errorf("Expected '/my/path2', got '%s' instead", s.OutputDir)
 	}
-	if *(s.Format) != parser.Barclaycard {
-		t.Errorf("Expected 'Barclaycard', got '%s' instead", (*s.Format))
+	if len(s.Format) != 1 || s.Format[0] != parser.Barclaycard {
+		t.Errorf("Expected 'Barclaycard', got '%s' instead", s.Format)
 	}
 	if s.FileGlobPattern != "some glob pattern" {
 		t.Errorf("Expected 'some glob pattern', got '%s' instead", s.FileGlobPattern)
 	}
-	if s.FileMaxAgeDays != 0 {
-		t.Errorf("Expected '0', got '%d' instead", s.FileMaxAgeDays)
+	if s.FileMaxAge != MaxAgeDays(0) {
+		t.Errorf("Expected '0s', got '%s' instead", s.FileMaxAge)
 	}
 
 	err = s.LoadFromString(text1)
@@ -267,7 +540,7 @@ fileglobpattern: some glob pattern`
 		t.Errorf("Expected nil error, got '%s' instead", err)
 	}
 	if s.Format != nil {
-		t.Errorf("Expected 'nil', got '%s' instead", *(s.Format))
+		t.Errorf("Expected 'nil', got '%s' instead", s.Format)
 	}
 	if s.FileGlobPattern != "" {
 		t.Errorf("Expected '', got '%s' instead", s.FileGlobPattern)
@@ -311,13 +584,13 @@ batchconvert:
 		t.Errorf("Expected '/my/path12', got '%s' instead", s.BatchConvert.Sets[0].OutputDir)
 	}
 	if s.BatchConvert.Sets[0].Format != nil {
-		t.Errorf("Expected 'nil', got '%s' instead", *(s.BatchConvert.Sets[0].Format))
+		t.Errorf("Expected 'nil', got '%s' instead", s.BatchConvert.Sets[0].Format)
 	}
 	if s.BatchConvert.Sets[0].FileGlobPattern != "" {
 		t.Errorf("Expected '', got '%s' instead", s.BatchConvert.Sets[0].FileGlobPattern)
 	}
-	if s.BatchConvert.Sets[0].FileMaxAgeDays != 0 {
-		t.Errorf("Expected '0', got '%d' instead", s.BatchConvert.Sets[0].FileMaxAgeDays)
+	if s.BatchConvert.Sets[0].FileMaxAge != MaxAgeDays(0) {
+		t.Errorf("Expected '0s', got '%s' instead", s.BatchConvert.Sets[0].FileMaxAge)
 	}
 	if s.BatchConvert.Sets[1].Name != "name2" {
 		t.Errorf("Expected 'name2', got '%s' instead", s.BatchConvert.Sets[1].Name)
@@ -328,17 +601,17 @@ batchconvert:
 	if s.BatchConvert.Sets[1].OutputDir != "/my/path22" {
 		t.Errorf("Expected '/my/path22', got '%s' instead", s.BatchConvert.Sets[1].OutputDir)
 	}
-	if s.BatchConvert.Sets[1].Format == nil {
-		t.Errorf("Expected 'non-nil', got '%s' instead", *(s.BatchConvert.Sets[1].Format))
+	if len(s.BatchConvert.Sets[1].Format) != 1 {
+		t.Fatalf("Expected a single format, got '%s' instead", s.BatchConvert.Sets[1].Format)
 	}
-	if *(s.BatchConvert.Sets[1].Format) != parser.Barclaycard {
-		t.Errorf("Expected 'Barclaycard', got '%s' instead", *(s.BatchConvert.Sets[1].Format))
+	if s.BatchConvert.Sets[1].Format[0] != parser.Barclaycard {
+		t.Errorf("Expected 'Barclaycard', got '%s' instead", s.BatchConvert.Sets[1].Format)
 	}
 	if s.BatchConvert.Sets[1].FileGlobPattern != "*.*" {
 		t.Errorf("Expected '*.*', got '%s' instead", s.BatchConvert.Sets[1].FileGlobPattern)
 	}
-	if s.BatchConvert.Sets[1].FileMaxAgeDays != 0 {
-		t.Errorf("Expected '0', got '%d' instead", s.BatchConvert.Sets[1].FileMaxAgeDays)
+	if s.BatchConvert.Sets[1].FileMaxAge != MaxAgeDays(0) {
+		t.Errorf("Expected '0s', got '%s' instead", s.BatchConvert.Sets[1].FileMaxAge)
 	}
 }
 
@@ -375,17 +648,17 @@ func TestSettingsLoadFromFile(t *testing.T) {
 	if s.BatchConvert.Sets[0].OutputDir != "/my/path12" {
 		t.Errorf("Expected '/my/path12', got '%s' instead", s.BatchConvert.Sets[0].OutputDir)
 	}
-	if s.BatchConvert.Sets[0].Format == nil {
-		t.Fatalf("Expected 'non-nil', got 'non-nil' instead")
+	if len(s.BatchConvert.Sets[0].Format) != 1 {
+		t.Fatalf("Expected a single format, got '%s' instead", s.BatchConvert.Sets[0].Format)
 	}
-	if *(s.BatchConvert.Sets[0].Format) != parser.Barclaycard {
-		t.Errorf("Expected 'Barclaycard', got '%s' instead", *(s.BatchConvert.Sets[0].Format))
+	if s.BatchConvert.Sets[0].Format[0] != parser.Barclaycard {
+		t.Errorf("Expected 'Barclaycard', got '%s' instead", s.BatchConvert.Sets[0].Format)
 	}
 	if s.BatchConvert.Sets[0].FileGlobPattern != "*.csv" {
 		t.Errorf("Expected '*.csv', got '%s' instead", s.BatchConvert.Sets[0].FileGlobPattern)
 	}
-	if s.BatchConvert.Sets[0].FileMaxAgeDays != 5 {
-		t.Errorf("Expected '5', got '%d' instead", s.BatchConvert.Sets[0].FileMaxAgeDays)
+	if s.BatchConvert.Sets[0].FileMaxAge != MaxAgeDays(5) {
+		t.Errorf("Expected '5d', got '%s' instead", s.BatchConvert.Sets[0].FileMaxAge)
 	}
 	if s.BatchConvert.Sets[1].Name != "name2" {
 		t.Errorf("Expected 'name2', got '%s' instead", s.BatchConvert.Sets[1].Name)
@@ -402,8 +675,8 @@ func TestSettingsLoadFromFile(t *testing.T) {
 	if s.BatchConvert.Sets[1].FileGlobPattern != "" {
 		t.Errorf("Expected '', got '%s' instead", s.BatchConvert.Sets[0].FileGlobPattern)
 	}
-	if s.BatchConvert.Sets[1].FileMaxAgeDays != 0 {
-		t.Errorf("Expected '0', got '%d' instead", s.BatchConvert.Sets[0].FileMaxAgeDays)
+	if s.BatchConvert.Sets[1].FileMaxAge != MaxAgeDays(0) {
+		t.Errorf("Expected '0s', got '%s' instead", s.BatchConvert.Sets[0].FileMaxAge)
 	}
 
 	err = s.CheckValidity()
@@ -412,3 +685,1744 @@ func TestSettingsLoadFromFile(t *testing.T) {
 	}
 
 }
+
+func TestSettingsLoadRules(t *testing.T) {
+	var s Settings
+	err := s.LoadFromString(`
+batchconvert:
+  rules:
+    - match:
+        payee_regex: "REWE.*"
+        amount: negative
+      set:
+        category: "Groceries:Food"
+        payee: "REWE"
+  sets:
+    - name: "name1"
+      inputdir: "/my/path11"
+      outputdir: "/my/path12"
+      rules:
+        - match:
+            format: dkb
+            memo_regex: "Miete"
+          set:
+            category: "Housing"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("Expected no error, got '%s' instead", err)
+	}
+	expected := []parser.Rule{{
+		Match: parser.RuleMatch{PayeeRegex: "REWE.*", Amount: "negative"},
+		Set:   parser.RuleSet{Category: "Groceries:Food", Payee: "REWE"},
+	}}
+	if !reflect.DeepEqual(s.BatchConvert.Rules, expected) {
+		t.Errorf("Unexpected rules %+v", s.BatchConvert.Rules)
+	}
+	setRules := s.BatchConvert.Sets[0].Rules
+	if len(setRules) != 1 || setRules[0].Match.Format == nil || *setRules[0].Match.Format != parser.DKB {
+		t.Errorf("Unexpected set rules %+v", setRules)
+	}
+
+	s.BatchConvert.Rules[0].Match.PayeeRegex = "REWE("
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for invalid global rule")
+	}
+	s.BatchConvert.Rules = nil
+	s.BatchConvert.Sets[0].Rules[0].Match.MemoRegex = "["
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for invalid set rule")
+	}
+}
+
+func TestSettingsRulesSchema(t *testing.T) {
+	config := `batchconvert:
+  rules:
+    - name: large
+      match:
+        amount_max: -100
+      set:
+        tags: "large review"
+    - name: small cash
+      match:
+        payee_regex: "^ATM"
+        amount_min: -50.5
+        amount_max: 0
+        format: DKB
+      set:
+        category: Cash
+        payment: 3
+  sets:
+    - name: giro
+      inputdir: /input
+      outputdir: /output
+      rules:
+        - name: large
+          match:
+            memo_regex: "(?i)miete"
+          set:
+            payee: Landlord
+`
+	var s Settings
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("Expected no error, got '%s'", err)
+	}
+	amountMax, amountMin, zero := -100.0, -50.5, 0.0
+	payment := int8(3)
+	dkb := parser.DKB
+	expected := []parser.Rule{
+		{Name: "large", Match: parser.RuleMatch{AmountMax: &amountMax}, Set: parser.RuleSet{Tags: "large review"}},
+		{
+			Name:  "small cash",
+			Match: parser.RuleMatch{PayeeRegex: "^ATM", AmountMin: &amountMin, AmountMax: &zero, Format: &dkb},
+			Set:   parser.RuleSet{Category: "Cash", Payment: &payment},
+		},
+	}
+	if !reflect.DeepEqual(s.BatchConvert.Rules, expected) {
+		t.Errorf("Expected rules %+v, got %+v", expected, s.BatchConvert.Rules)
+	}
+
+	// The rule names are unique per list, a set may reuse the name of a global rule
+	content, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Settings
+	if err := loaded.LoadFromString(string(content)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withoutBaseDir(loaded), withoutBaseDir(s)) {
+		t.Errorf("Expected round trip to keep the rules, got:\n%s", content)
+	}
+
+	invalid := []struct {
+		name   string
+		change func(s *Settings)
+		err    string
+	}{
+		{"empty match", func(s *Settings) { s.BatchConvert.Rules[0].Match = parser.RuleMatch{} }, "rule 'large': match is empty"},
+		{"regex", func(s *Settings) { s.BatchConvert.Rules[1].Match.PayeeRegex = "(" }, "rule 'small cash': invalid payee_regex"},
+		{"amount range", func(s *Settings) { s.BatchConvert.Rules[1].Match.AmountMax = &amountMax }, "rule 'small cash': amount_min -50.5 is greater than amount_max -100"},
+		{"duplicate name", func(s *Settings) { s.BatchConvert.Rules[1].Name = "large" }, "rule 'large': duplicate rule name"},
+		{"set rule", func(s *Settings) { s.BatchConvert.Sets[0].Rules[0].Match.MemoRegex = "" }, "rule 'large': match is empty"},
+	}
+	for _, tc := range invalid {
+		var s Settings
+		if err := s.LoadFromString(config); err != nil {
+			t.Fatal(err)
+		}
+		tc.change(&s)
+		if err := s.CheckValidity(); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error containing '%s', got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestLoadRulesFromFile(t *testing.T) {
+	if _, err := LoadRulesFromFile(filepath.Join("testfiles", "non_existing_rules.yml")); err == nil {
+		t.Error("Expected error for file not existing")
+	}
+	if _, err := LoadRulesFromFile(filepath.Join("testfiles", "invalid_yaml.yml")); err == nil {
+		t.Error("Expected error for invalid yaml")
+	}
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid_rules.yml")
+	if err := os.WriteFile(invalid, []byte("rules:\n  - match:\n      info_regex: \"*\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRulesFromFile(invalid); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+
+	rules, err := LoadRulesFromFile(filepath.Join("testfiles", "rules.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Match.PayeeRegex != "REWE.*" || rules[0].Set.Category != "Groceries:Food" {
+		t.Errorf("Unexpected first rule %+v", rules[0])
+	}
+	if rules[1].Match.Amount != "positive" || rules[1].Set.Category != "Income" {
+		t.Errorf("Unexpected second rule %+v", rules[1])
+	}
+}
+
+func TestBatchConvertSetPaymentTypes(t *testing.T) {
+	var s BatchConvertSet
+	err := s.LoadFromString(`
+name: "name1"
+inputdir: "/my/path11"
+outputdir: "/my/path12"
+paymenttypes:
+  Lastschrift: 11
+  Kartenzahlung: 6
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parser.PaymentTypes{"Lastschrift": 11, "Kartenzahlung": 6}
+	if !reflect.DeepEqual(s.PaymentTypes, expected) {
+		t.Errorf("Expected %v, got %v", expected, s.PaymentTypes)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("Expected no error, got '%s' instead", err)
+	}
+	s.PaymentTypes["Lastschrift"] = 12
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for invalid payment code")
+	}
+}
+
+func TestBatchConvertSetSort(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Sort != parser.SortNone {
+		t.Errorf("Expected default sort order none, got %s", s.Sort)
+	}
+	if err := s.LoadFromString("sort: date-desc\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Sort != parser.SortDateDesc {
+		t.Errorf("Expected sort order date-desc, got %s", s.Sort)
+	}
+	if err := s.LoadFromString("sort: random\n"); err == nil {
+		t.Error("Expected error for invalid sort order")
+	}
+}
+
+func TestBatchConvertSetEncoding(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Encoding != parser.EncodingAuto {
+		t.Errorf("Expected default encoding auto, got %s", s.Encoding)
+	}
+	if err := s.LoadFromString("encoding: windows-1252\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Encoding != parser.EncodingWindows1252 {
+		t.Errorf("Expected encoding windows-1252, got %s", s.Encoding)
+	}
+	if err := s.LoadFromString("encoding: ebcdic\n"); err == nil {
+		t.Error("Expected error for invalid encoding")
+	}
+}
+
+func TestBatchConvertSetOverwrite(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Overwrite != OverwriteNever {
+		t.Errorf("Expected default overwrite policy never, got %s", s.Overwrite)
+	}
+	for text, expected := range map[string]OverwritePolicy{"never": OverwriteNever, "Always": OverwriteAlways, "if-newer": OverwriteIfNewer} {
+		if err := s.LoadFromString("overwrite: " + text + "\n"); err != nil {
+			t.Fatal(err)
+		}
+		if s.Overwrite != expected {
+			t.Errorf("Expected overwrite policy %s, got %s", expected, s.Overwrite)
+		}
+	}
+	if err := s.LoadFromString("overwrite: sometimes\n"); err == nil {
+		t.Error("Expected error for invalid overwrite policy")
+	}
+}
+
+func TestBatchConvertSetOnSuccess(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\ninputdir: /input\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.OnSuccess != OnSuccessKeep {
+		t.Errorf("Expected default action keep, got %s", s.OnSuccess)
+	}
+	if s.GetArchiveDir() != filepath.Join("/input", "processed") {
+		t.Errorf("Expected default archive directory '/input/processed', got '%s'", s.GetArchiveDir())
+	}
+	if err := s.LoadFromString("inputdir: /input\nonsuccess: Move\narchivedir: /archive\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.OnSuccess != OnSuccessMove || s.GetArchiveDir() != "/archive" {
+		t.Errorf("Expected action move to '/archive', got %s to '%s'", s.OnSuccess, s.GetArchiveDir())
+	}
+	if err := s.LoadFromString("onsuccess: rename\n"); err == nil {
+		t.Error("Expected error for invalid action")
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/archive", OnSuccess: OnSuccessMove, ArchiveDir: "/archive"}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected ArchiveDir == OutputDir error")
+	}
+}
+
+func TestBatchConvertSetSkipMode(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.SkipMode != SkipByName {
+		t.Errorf("Expected default skip mode name, got %s", s.SkipMode)
+	}
+	if err := s.LoadFromString("skipmode: Hash\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.SkipMode != SkipByHash {
+		t.Errorf("Expected skip mode hash, got %s", s.SkipMode)
+	}
+	if err := s.LoadFromString("skipmode: size\n"); err == nil {
+		t.Error("Expected error for invalid skip mode")
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", SkipMode: SkipByHash, Dedupe: true}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for SkipMode hash with Dedupe")
+	}
+}
+
+func TestBatchConvertSetSkipEmpty(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.SkipEmpty {
+		t.Error("Expected empty files to be converted by default")
+	}
+	if err := s.LoadFromString("name: name1\nskipempty: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.SkipEmpty {
+		t.Error("Expected empty files to be skipped")
+	}
+}
+
+func TestBatchConvertSetOrder(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Order != OrderByName || s.MaxFilesPerRun != 0 {
+		t.Errorf("Expected order by name without limit, got %s and %d", s.Order, s.MaxFilesPerRun)
+	}
+	if err := s.LoadFromString("name: name1\norder: MTime-Desc\nmaxfilesperrun: 10\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Order != OrderByMtimeDesc || s.MaxFilesPerRun != 10 {
+		t.Errorf("Expected order mtime-desc with limit 10, got %s and %d", s.Order, s.MaxFilesPerRun)
+	}
+	if err := s.LoadFromString("order: size\n"); err == nil {
+		t.Error("Expected error for invalid order")
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", MaxFilesPerRun: -1}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for negative MaxFilesPerRun")
+	}
+	s.MaxFilesPerRun = 5
+	s.Merge = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for MaxFilesPerRun with Merge")
+	}
+}
+
+func TestBatchConvertSetFollowSymlinks(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.GetFollowSymlinks() {
+		t.Error("Expected symlinks to be followed by default")
+	}
+	if err := s.LoadFromString("name: name1\nfollowsymlinks: false\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetFollowSymlinks() {
+		t.Error("Expected symlinks to be ignored")
+	}
+	if err := s.LoadFromString("name: name1\nfollowsymlinks: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.GetFollowSymlinks() {
+		t.Error("Expected symlinks to be followed")
+	}
+}
+
+func TestBatchConvertSetEnabled(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.GetEnabled() {
+		t.Error("Expected set to be enabled by default")
+	}
+	if err := s.LoadFromString("name: name1\nenabled: false\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetEnabled() {
+		t.Error("Expected set to be disabled")
+	}
+	if err := s.LoadFromString("name: name1\nenabled: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.GetEnabled() {
+		t.Error("Expected set to be enabled")
+	}
+}
+
+func TestBatchConvertSetCreateOutputDir(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.CreateOutputDir {
+		t.Error("Expected output directory not to be created by default")
+	}
+	if err := s.LoadFromString("name: name1\ncreateoutputdir: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.CreateOutputDir {
+		t.Error("Expected output directory to be created")
+	}
+}
+
+func TestBatchConvertSetFormatList(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\nformat: dkb\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.Format, FormatList{parser.DKB}) {
+		t.Errorf("Expected single format DKB, got '%s'", s.Format)
+	}
+	if err := s.LoadFromString("name: name1\nformat: [DKBVisa, DKB]\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.Format, FormatList{parser.DKBVisa, parser.DKB}) {
+		t.Errorf("Expected formats DKBVisa, DKB, got '%s'", s.Format)
+	}
+	if err := s.LoadFromString("name: name1\nformat: [DKB, Unknown]\n"); err == nil || !strings.Contains(err.Error(), "Unknown") {
+		t.Errorf("Expected error for unknown format in list, got %v", err)
+	}
+	if err := s.LoadFromString("name: name1\nformat: Unknown\n"); err == nil || !strings.Contains(err.Error(), "Unknown") {
+		t.Errorf("Expected error for unknown format, got %v", err)
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", Format: FormatList{parser.DKB, parser.DKBVisa}}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("Expected valid formats, got %v", err)
+	}
+	s.Format = FormatList{parser.DKB, parser.DKB}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for duplicate format")
+	}
+	s.Format = FormatList{parser.DKB, parser.SourceFormat(-1)}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestBatchConvertSetFileMaxAge(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected MaxAge
+		text     string
+	}{
+		{"14", MaxAgeDays(14), "14d"},
+		{"0", 0, "0s"},
+		{"14d", MaxAgeDays(14), "14d"},
+		{"\"2d\"", MaxAgeDays(2), "2d"},
+		{"36h", MaxAge(36 * time.Hour), "36h0m0s"},
+		{"48h", MaxAgeDays(2), "2d"},
+		{"90m", MaxAge(90 * time.Minute), "1h30m0s"},
+		{"-1", MaxAgeDays(-1), "-1d"},
+		{"106751", MaxAgeDays(106751), "106751d"},
+		{"106751d", MaxAgeDays(106751), "106751d"},
+	}
+	for _, tc := range tests {
+		var s BatchConvertSet
+		if err := s.LoadFromString("name: name1\nfilemaxagedays: " + tc.yaml + "\n"); err != nil {
+			t.Errorf("Unexpected error for '%s': %v", tc.yaml, err)
+			continue
+		}
+		if s.FileMaxAge != tc.expected || s.FileMaxAge.String() != tc.text {
+			t.Errorf("Expected '%s' for '%s', got '%s'", tc.text, tc.yaml, s.FileMaxAge)
+		}
+
+		// The maximum age is the same after writing and reading it again
+		type maxAgeOnly struct {
+			FileMaxAge MaxAge `yaml:"filemaxagedays"`
+		}
+		data, err := yaml.Marshal(maxAgeOnly{s.FileMaxAge})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again maxAgeOnly
+		if err := yaml.Unmarshal(data, &again); err != nil {
+			t.Errorf("Unexpected error for '%s': %v", data, err)
+			continue
+		}
+		if again.FileMaxAge != tc.expected {
+			t.Errorf("Expected '%s' after round trip of '%s', got '%s'", tc.text, tc.yaml, again.FileMaxAge)
+		}
+	}
+
+	for _, invalid := range []string{"14x", "1.5d", "d", "[1, 2]", "1.5"} {
+		var s BatchConvertSet
+		if err := s.LoadFromString("name: name1\nfilemaxagedays: " + invalid + "\n"); err == nil {
+			t.Errorf("Expected error for '%s', got '%s'", invalid, s.FileMaxAge)
+		}
+	}
+
+	// Overflowing the duration
+	for _, tooLarge := range []string{"106752", "106752d", "-106752", "9223372036854775807", "18446744073709551615", "99999999999999999999d"} {
+		var s BatchConvertSet
+		err := s.LoadFromString("name: name1\nfilemaxagedays: " + tooLarge + "\n")
+		if err == nil || !strings.Contains(err.Error(), "more than 106751 days") {
+			t.Errorf("Expected error for too many days '%s', got '%s', %v", tooLarge, s.FileMaxAge, err)
+		}
+	}
+
+	s := BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", FileMaxAge: MaxAge(-time.Hour)}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for negative FileMaxAge")
+	}
+}
+
+func TestBatchConvertSetStateFile(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\noutputdir: /output\n"); err != nil {
+		t.Fatal(err)
+	}
+	if stateFile := s.GetStateFile(); stateFile != "" {
+		t.Errorf("Expected no state file by default, got '%s'", stateFile)
+	}
+	if err := s.LoadFromString("name: name1\noutputdir: /output\nstate: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if stateFile, expected := s.GetStateFile(), filepath.Join("/output", DefaultStateFileName); stateFile != expected {
+		t.Errorf("Expected state file '%s', got '%s'", expected, stateFile)
+	}
+	if err := s.LoadFromString("name: name1\noutputdir: /output\nstatefile: /state/giro.json\n"); err != nil {
+		t.Fatal(err)
+	}
+	if stateFile := s.GetStateFile(); stateFile != "/state/giro.json" {
+		t.Errorf("Expected explicit state file, got '%s'", stateFile)
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", State: true, Merge: true}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for State with Merge")
+	}
+}
+
+func TestBatchConvertSetRetries(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\nretries: 3\nretrydelay: 5s\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Retries != 3 || s.RetryDelay != 5*time.Second {
+		t.Errorf("Expected 3 retries after 5s, got %d after %s", s.Retries, s.RetryDelay)
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/input", OutputDir: "/output", Retries: -1}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for negative Retries")
+	}
+	s.Retries = 1
+	s.RetryDelay = -time.Second
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for negative RetryDelay")
+	}
+}
+
+func TestBatchConvertSetMerge(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: giro\nmerge: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	if !s.Merge || s.GetMergedOutputName(now) != "giro.csv" {
+		t.Errorf("Expected merge into default 'giro.csv', got %v into '%s'", s.Merge, s.GetMergedOutputName(now))
+	}
+	if err := s.LoadFromString("name: giro\nmergedoutputname: \"{set}_{date}.csv\"\nmergefailfast: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.MergeFailFast || s.GetMergedOutputName(now) != "giro_2024-01-31.csv" {
+		t.Errorf("Expected fail fast merge into 'giro_2024-01-31.csv', got %v into '%s'", s.MergeFailFast, s.GetMergedOutputName(now))
+	}
+
+	s = BatchConvertSet{Name: "giro", InputDir: "/input", OutputDir: "/output", Merge: true, MergedOutputName: "2024/{set}.csv"}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for MergedOutputName with directory")
+	}
+	s.MergedOutputName = ""
+	s.SkipMode = SkipByHash
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for SkipMode hash with Merge")
+	}
+	s.SkipMode = SkipByName
+	s.Recursive = true
+	s.PreserveStructure = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for PreserveStructure with Merge")
+	}
+}
+
+func TestBatchConvertSetInputDirs(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: name1\ninputdir: /downloads\ninputdirs: [/sync, /archive]\noutputdir: /output\n"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/downloads", "/sync", "/archive"}; !reflect.DeepEqual(s.GetInputDirs(), expected) {
+		t.Errorf("Expected input directories %v, got %v", expected, s.GetInputDirs())
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if s.GetArchiveDir() != filepath.Join("/downloads", "processed") {
+		t.Errorf("Expected archive directory in first input directory, got '%s'", s.GetArchiveDir())
+	}
+
+	// inputdir may be left out
+	if err := s.LoadFromString("name: name1\ninputdirs: [/sync]\noutputdir: /output\n"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/sync"}; !reflect.DeepEqual(s.GetInputDirs(), expected) || s.CheckValidity() != nil {
+		t.Errorf("Expected valid input directories %v, got %v", expected, s.GetInputDirs())
+	}
+
+	s = BatchConvertSet{Name: "name1", InputDir: "/downloads", InputDirs: []string{"/downloads"}, OutputDir: "/output"}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected duplicate input directory error")
+	}
+	s.InputDirs = []string{"/output"}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected InputDirs == OutputDir error")
+	}
+	s.InputDirs = []string{""}
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected empty input directory error")
+	}
+}
+
+func TestBatchConvertSetOutputNameTemplate(t *testing.T) {
+	var s BatchConvertSet
+	if err := s.LoadFromString("name: giro\ninputdir: /input\noutputdir: /output\noutputnametemplate: \"{set}_{firstdate}_{lastdate}.csv\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if !s.OutputNameNeedsRecords() {
+		t.Error("Expected name depending on the records")
+	}
+	values := OutputNameValues{
+		BaseName:  "Umsaetze",
+		FirstDate: time.Date(2023, 9, 29, 0, 0, 0, 0, time.UTC),
+		LastDate:  time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC),
+	}
+	name, err := s.GetOutputName(values)
+	if err != nil || name != "giro_2023-09-29_2023-10-04.csv" {
+		t.Errorf("Expected 'giro_2023-09-29_2023-10-04.csv', got '%s' (%v)", name, err)
+	}
+
+	s.OutputNameTemplate = "{basename}_{today}.csv"
+	if s.OutputNameNeedsRecords() {
+		t.Error("Expected name not depending on the records")
+	}
+	s.OutputNameTemplate = ""
+	if name, err := s.GetOutputName(values); err != nil || name != "Umsaetze.csv" {
+		t.Errorf("Expected default 'Umsaetze.csv', got '%s' (%v)", name, err)
+	}
+
+	// An empty name is only detected with the values of the records
+	s.OutputNameTemplate = "{firstdate}"
+	if _, err := s.GetOutputName(OutputNameValues{}); err == nil {
+		t.Error("Expected error for empty output name")
+	}
+
+	for _, template := range []string{"{format}/{basename}.csv", "..", "{set}\\x.csv", "{name}.csv", " {set}.csv", "   "} {
+		s.OutputNameTemplate = template
+		if err := s.CheckValidity(); err == nil {
+			t.Errorf("Expected error for template '%s'", template)
+		}
+	}
+	s.OutputNameTemplate = "{basename}.csv"
+	s.DisambiguateOutputNames = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for OutputNameTemplate with DisambiguateOutputNames")
+	}
+	s.DisambiguateOutputNames = false
+	s.Merge = true
+	if err := s.CheckValidity(); err == nil {
+		t.Error("Expected error for OutputNameTemplate with Merge")
+	}
+}
+
+func TestBatchConvertSetOutputOptions(t *testing.T) {
+	var s Settings
+	config := `batchconvert:
+  defaults:
+    outputdecimalseparator: ","
+    outputdateformat: "02.01.2006"
+  sets:
+    - name: giro
+      inputdir: /input
+      outputdir: /output
+      outputdelimiter: tab
+    - name: visa
+      inputdir: /visa
+      outputdir: /output-visa
+      outputdecimalseparator: "."
+      outputnoheader: true
+`
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	expected := []parser.OutputOptions{
+		{Delimiter: '\t', DecimalSeparator: ',', DateFormat: "02.01.2006"},
+		{DecimalSeparator: '.', NoHeader: true, DateFormat: "02.01.2006"},
+	}
+	for i, set := range s.BatchConvert.Sets {
+		o, err := set.GetOutputOptions()
+		if err != nil || o != expected[i] {
+			t.Errorf("%s: expected %+v, got %+v (%v)", set.Name, expected[i], o, err)
+		}
+	}
+
+	set := s.BatchConvert.Sets[0]
+	set.OutputDelimiter = ","
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for delimiter equal to the decimal separator")
+	}
+	set.OutputDelimiter = ";;"
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for delimiter with two characters")
+	}
+	set.OutputDelimiter = ""
+	set.OutputDecimalSeparator = "'"
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for invalid decimal separator")
+	}
+	set.OutputDecimalSeparator = ""
+	set.OutputDateFormat = "2006"
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for date format without month and day")
+	}
+
+	// Dedupe reads the previous output files with the homebank format
+	set.OutputDateFormat = "02.01.2006"
+	set.OutputDecimalSeparator = ","
+	set.Dedupe = true
+	if err := set.CheckValidity(); err != nil {
+		t.Errorf("No error expected for dedupe with readable output, got '%s'", err)
+	}
+	set.OutputNoHeader = true
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for dedupe without header")
+	}
+	set.OutputNoHeader = false
+	set.OutputDelimiter = "tab"
+	if err := set.CheckValidity(); err == nil {
+		t.Error("Expected error for dedupe with tab delimiter")
+	}
+}
+
+func TestSettingsCommands(t *testing.T) {
+	var s Settings
+	config := `batchconvert:
+  onfinishcommand: [notify-send, "Conversion finished"]
+  commandtimeout: 30s
+  sets:
+    - name: giro
+      inputdir: /input
+      outputdir: /output
+      onsuccesscommand: [cp, --backup]
+`
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if s.BatchConvert.CommandTimeout != 30*time.Second {
+		t.Errorf("Expected timeout 30s, got %s", s.BatchConvert.CommandTimeout)
+	}
+	if expected := []string{"notify-send", "Conversion finished"}; !reflect.DeepEqual(s.BatchConvert.OnFinishCommand, expected) {
+		t.Errorf("Expected finish command %v, got %v", expected, s.BatchConvert.OnFinishCommand)
+	}
+	if expected := []string{"cp", "--backup"}; !reflect.DeepEqual(s.BatchConvert.Sets[0].OnSuccessCommand, expected) {
+		t.Errorf("Expected success command %v, got %v", expected, s.BatchConvert.Sets[0].OnSuccessCommand)
+	}
+
+	s.BatchConvert.Sets[0].OnSuccessCommand = []string{""}
+	if s.CheckValidity() == nil {
+		t.Error("Expected error for success command without program")
+	}
+	s.BatchConvert.Sets[0].OnSuccessCommand = nil
+	s.BatchConvert.OnFinishCommand = []string{" ", "x"}
+	if s.CheckValidity() == nil {
+		t.Error("Expected error for finish command without program")
+	}
+	s.BatchConvert.OnFinishCommand = nil
+	s.BatchConvert.CommandTimeout = -time.Second
+	if s.CheckValidity() == nil {
+		t.Error("Expected error for negative command timeout")
+	}
+}
+
+func TestSettingsSaveToFile(t *testing.T) {
+	var loaded Settings
+	if err := loaded.LoadFromFile(filepath.Join("testfiles", "config_full.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.CheckValidity(); err != nil {
+		t.Fatalf("No error expected, got '%s'", err)
+	}
+
+	fpath := filepath.Join(t.TempDir(), "config.yml")
+	if err := loaded.SaveToFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	var saved Settings
+	if err := saved.LoadFromFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withoutBaseDir(loaded), withoutBaseDir(saved)) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", loaded, saved)
+	}
+
+	content, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enums are written by name, fields with default values are left out
+	for _, expected := range []string{
+		"- DKB\n", "- DKBLegacy\n", "format: DKB\n", "order: mtime-desc\n", "order: mtime-asc\n",
+		"overwrite: if-newer\n", "overwrite: always\n", "skipmode: hash\n", "onsuccess: move\n",
+		"onsuccess: delete\n", "sort: date-desc\n", "sort: date-asc\n", "encoding: windows-1252\n",
+		"encoding: utf-8\n", "filemaxagedays: 14d\n", "filemaxagedays: 36h0m0s\n",
+		"retrydelay: 5s\n", "commandtimeout: 30s\n", "followsymlinks: false\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in saved file:\n%s", strings.TrimSpace(expected), content)
+		}
+	}
+	for _, unexpected := range []string{"skipmode: name", "routememotoinfo", "memo_regex", "daterange"} {
+		if strings.Contains(string(content), unexpected) {
+			t.Errorf("Expected no '%s' in saved file:\n%s", unexpected, content)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(fpath); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected permissions 0600, got %v (%v)", info.Mode().Perm(), err)
+		}
+	}
+}
+
+func TestSettingsSaveToFileKeepsComments(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "config.yml")
+	if err := copyFile(filepath.Join("testfiles", "config_full.yml"), fpath); err != nil {
+		t.Fatal(err)
+	}
+	var s Settings
+	if err := s.LoadFromFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	s.BatchConvert.Parallelism = 2
+	if err := s.SaveToFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"# Sets are converted one after the other", "# Applied to the records of all sets"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected comment '%s' in saved file:\n%s", expected, content)
+		}
+	}
+	var saved Settings
+	if err := saved.LoadFromFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, saved) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", s, saved)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(fpath)); len(files) != 1 {
+		t.Errorf("Expected no temporary files, got %v", files)
+	}
+}
+
+func TestSaveToDefaultFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	xdg.Reload()
+
+	s, err := NewSettings(BatchConvertSet{Name: "giro", InputDir: "/input", OutputDir: "/output"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := s.SaveToDefaultFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(tmpDir, filepath.FromSlash(defaultConfigFilePath)); path != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, path)
+	}
+	var loaded Settings
+	if _, err := loaded.LoadFromDefaultFile(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withoutBaseDir(s), withoutBaseDir(loaded)) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", s, loaded)
+	}
+}
+
+func TestDefaultFilePath(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", t.TempDir())
+	xdg.Reload()
+
+	expected := filepath.Join(configHome, filepath.FromSlash(defaultConfigFilePath))
+	if path := DefaultFilePath(); path != expected {
+		t.Errorf("Expected '%s' for missing config file, got '%s'", expected, path)
+	}
+	if _, err := os.Stat(filepath.Dir(expected)); err == nil {
+		t.Error("Expected the config directory not to be created")
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "go-homebank-csv", "config.yml")
+	if err := WriteTemplate(fpath, false); err != nil {
+		t.Fatal(err)
+	}
+	var s Settings
+	if err := s.LoadFromFile(fpath); err != nil {
+		t.Fatalf("Expected template to load, got '%s'", err)
+	}
+	if problems := s.Problems(true); len(problems) != 0 {
+		t.Errorf("Expected valid template, got %v", problems)
+	}
+
+	if err := WriteTemplate(filepath.Join(t.TempDir(), "config.toml"), false); err == nil {
+		t.Error("Expected error for TOML file")
+	}
+
+	if err := os.WriteFile(fpath, []byte("changed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTemplate(fpath, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist for existing file, got '%v'", err)
+	}
+	if content, _ := os.ReadFile(fpath); string(content) != "changed" {
+		t.Errorf("Expected existing file to be kept, got '%s'", content)
+	}
+	if err := WriteTemplate(fpath, true); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(fpath); string(content) != Template {
+		t.Errorf("Expected template with force, got '%s'", content)
+	}
+}
+
+func TestNormalizePaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	s := Settings{BatchConvert: BatchConvertSettings{Sets: BatchConvertSets{{
+		Name:       "giro",
+		InputDir:   "~/Downloads/",
+		InputDirs:  []string{"/my//path", "~other/path"},
+		OutputDir:  "~",
+		ArchiveDir: "",
+		StateFile:  "./state.json",
+	}}}}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	set := s.BatchConvert.Sets[0]
+	if expected := filepath.Join(home, "Downloads"); set.InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+	}
+	if expected := []string{filepath.Clean("/my//path"), filepath.Clean("~other/path")}; !reflect.DeepEqual(set.InputDirs, expected) {
+		t.Errorf("Expected InputDirs %v, got %v", expected, set.InputDirs)
+	}
+	if set.OutputDir != filepath.Clean(home) {
+		t.Errorf("Expected OutputDir '%s', got '%s'", home, set.OutputDir)
+	}
+	if set.ArchiveDir != "" {
+		t.Errorf("Expected empty ArchiveDir, got '%s'", set.ArchiveDir)
+	}
+	if set.StateFile != "state.json" {
+		t.Errorf("Expected StateFile 'state.json', got '%s'", set.StateFile)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("BANK_DOWNLOADS", "/data/downloads")
+	t.Setenv("USERPROFILE", `C:\Users\me`)
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		path     string
+		windows  bool
+		expected string
+		missing  string
+	}{
+		{"${BANK_DOWNLOADS}/dkb", false, "/data/downloads/dkb", ""},
+		{"$BANK_DOWNLOADS/dkb", false, "/data/downloads/dkb", ""},
+		{`${USERPROFILE}\Downloads`, true, `C:\Users\me\Downloads`, ""},
+		{`%USERPROFILE%\Downloads`, true, `C:\Users\me\Downloads`, ""},
+		{"%USERPROFILE%/Downloads", false, "%USERPROFILE%/Downloads", ""},
+		{"/no/variables", false, "/no/variables", ""},
+		{"${UNSET_BANK_DIR}/dkb", false, "", "UNSET_BANK_DIR"},
+		{`$UNSET_BANK_DIR\dkb`, true, "", "UNSET_BANK_DIR"},
+		{`%UNSET_BANK_DIR%\dkb`, true, "", "UNSET_BANK_DIR"},
+		{"${EMPTY}/dkb", false, "", "EMPTY"},
+	}
+	for _, test := range tests {
+		expanded, err := expandEnv(test.path, test.windows)
+		if test.missing != "" {
+			if err == nil || !strings.Contains(err.Error(), "'"+test.missing+"'") {
+				t.Errorf("%s: expected error naming %s, got '%s', %v", test.path, test.missing, expanded, err)
+			}
+			continue
+		}
+		if err != nil || expanded != test.expected {
+			t.Errorf("%s: expected '%s', got '%s', %v", test.path, test.expected, expanded, err)
+		}
+	}
+}
+
+func TestNormalizePathsEnv(t *testing.T) {
+	downloads := t.TempDir()
+	t.Setenv("BANK_DOWNLOADS", downloads)
+
+	s := Settings{BatchConvert: BatchConvertSettings{Sets: BatchConvertSets{{
+		Name:      "giro",
+		InputDir:  "${BANK_DOWNLOADS}/dkb",
+		OutputDir: "$BANK_DOWNLOADS/converted",
+	}}}}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	set := s.BatchConvert.Sets[0]
+	if expected := filepath.Join(downloads, "dkb"); set.InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+	}
+	if expected := filepath.Join(downloads, "converted"); set.OutputDir != expected {
+		t.Errorf("Expected OutputDir '%s', got '%s'", expected, set.OutputDir)
+	}
+
+	s.BatchConvert.Sets[0].InputDir = "${UNSET_BANK_DIR}/dkb"
+	err := s.NormalizePaths()
+	if err == nil || !strings.Contains(err.Error(), "set 'giro'") || !strings.Contains(err.Error(), "UNSET_BANK_DIR") {
+		t.Errorf("Expected error naming set and variable, got %v", err)
+	}
+}
+
+func TestXdgDirForToken(t *testing.T) {
+	tests := map[string]string{
+		"desktop":     xdg.UserDirs.Desktop,
+		"documents":   xdg.UserDirs.Documents,
+		"downloads":   xdg.UserDirs.Download,
+		"music":       xdg.UserDirs.Music,
+		"pictures":    xdg.UserDirs.Pictures,
+		"publicshare": xdg.UserDirs.PublicShare,
+		"templates":   xdg.UserDirs.Templates,
+		"videos":      xdg.UserDirs.Videos,
+	}
+	for token, expected := range tests {
+		dir, err := xdgDirForToken(token)
+		if err != nil || dir != expected || dir == "" {
+			t.Errorf("%s: expected '%s', got '%s', %v", token, expected, dir, err)
+		}
+		expanded, err := expandPath("xdg:"+token+"/dkb", nil, nil)
+		if err != nil || expanded != expected+"/dkb" {
+			t.Errorf("%s: expected '%s/dkb', got '%s', %v", token, expected, expanded, err)
+		}
+	}
+	if _, err := xdgDirForToken("trash"); err == nil {
+		t.Error("Expected error for unknown token")
+	}
+}
+
+func TestNormalizePathsAliases(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	var s Settings
+	err := s.LoadFromString(`pathaliases:
+  bankroot: ~/Finanzen/exports
+  dkbroot: alias:bankroot/dkb
+batchconvert:
+  sets:
+  - name: giro
+    inputdir: alias:bankroot/dkb
+    outputdir: alias:dkbroot/converted
+    archivedir: alias:bankroot
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	set := s.BatchConvert.Sets[0]
+	exports := filepath.Join(home, "Finanzen", "exports")
+	if expected := filepath.Join(exports, "dkb"); set.InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+	}
+	if expected := filepath.Join(exports, "dkb", "converted"); set.OutputDir != expected {
+		t.Errorf("Expected OutputDir '%s', got '%s'", expected, set.OutputDir)
+	}
+	if set.ArchiveDir != exports {
+		t.Errorf("Expected ArchiveDir '%s', got '%s'", exports, set.ArchiveDir)
+	}
+
+	tests := []struct {
+		aliases  map[string]string
+		inputDir string
+		expected string
+	}{
+		{map[string]string{"a": "alias:a/x"}, "alias:a", "recursive path alias 'a': a -> a"},
+		{map[string]string{"a": "alias:b", "b": "alias:a"}, "alias:a/dkb", "recursive path alias 'a': a -> b -> a"},
+		{nil, "alias:missing/dkb", "unknown path alias 'missing'"},
+		{nil, "xdg:trash/dkb", "unknown xdg directory 'trash'"},
+	}
+	for _, test := range tests {
+		s := Settings{PathAliases: test.aliases, BatchConvert: BatchConvertSettings{Sets: BatchConvertSets{{
+			Name:      "giro",
+			InputDir:  test.inputDir,
+			OutputDir: "/my/output",
+		}}}}
+		err := s.NormalizePaths()
+		if err == nil || !strings.Contains(err.Error(), "set 'giro'") || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error '%s' naming the set, got %v", test.inputDir, test.expected, err)
+		}
+	}
+}
+
+func TestSettingsProblems(t *testing.T) {
+	existing := t.TempDir()
+	file := filepath.Join(existing, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(existing, "missing")
+
+	s := Settings{BatchConvert: BatchConvertSettings{
+		Parallelism: -1,
+		Sets: BatchConvertSets{
+			{Name: "valid", InputDir: existing, OutputDir: missing, CreateOutputDir: true},
+			{Name: "invalid", InputDir: missing, OutputDir: file, FileMaxAge: -1},
+			{Name: "valid", InputDir: existing, OutputDir: existing + "2", CreateOutputDir: true},
+		},
+	}}
+	if s.CheckValidity() == nil {
+		t.Fatal("Expected error")
+	}
+	problems := s.Problems(false)
+	if len(problems) != 4 {
+		t.Errorf("Expected 4 problems without directories, got %v", problems)
+	}
+	problems = s.Problems(true)
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	expected := []string{
+		"Parallelism must not be negative",
+		"set 'invalid': FileMaxAge < 0",
+		"set 'invalid': InputDir: ",
+		"set 'invalid': OutputDir '" + file + "' is not a directory",
+		"duplicate Name 'valid' detected",
+		"duplicate InputDir / FileGlobPattern combination detected",
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), messages)
+	}
+	for i := range expected {
+		if !strings.HasPrefix(messages[i], expected[i]) {
+			t.Errorf("Expected problem '%s', got '%s'", expected[i], messages[i])
+		}
+	}
+
+	if problems := (Settings{}).Problems(true); len(problems) != 0 {
+		t.Errorf("Expected no problems for empty settings, got %v", problems)
+	}
+}
+
+func TestCheckValidityDeep(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	set := BatchConvertSet{Name: "giro", InputDir: input, OutputDir: output}
+	if err := set.CheckValidityDeep(); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if entries, _ := os.ReadDir(output); len(entries) != 0 {
+		t.Errorf("Expected no files left in the output directory, got %v", entries)
+	}
+
+	// Only detected by the deep check
+	missing := filepath.Join(output, "missing")
+	set.OutputDir = missing
+	if err := set.CheckValidity(); err != nil {
+		t.Errorf("No error expected for missing directory, got '%s'", err)
+	}
+	if err := set.CheckValidityDeep(); err == nil {
+		t.Error("Expected error for missing output directory")
+	}
+	set.CreateOutputDir = true
+	if err := set.CheckValidityDeep(); err != nil {
+		t.Errorf("No error expected with CreateOutputDir, got '%s'", err)
+	}
+	set.CreateOutputDir = false
+
+	// The converted files would be found in the subdirectory in recursive mode
+	set.OutputDir = filepath.Join(input, "converted")
+	if err := os.Mkdir(set.OutputDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.CheckValidityDeep(); err != nil {
+		t.Errorf("No error expected for output directory inside input directory, got '%s'", err)
+	}
+	set.Recursive = true
+	if err := set.CheckValidityDeep(); err == nil || !strings.Contains(err.Error(), "is inside InputDir") {
+		t.Errorf("Expected error for output directory inside input directory, got %v", err)
+	}
+	set.InputDir, set.OutputDir = set.OutputDir, input
+	if err := set.CheckValidityDeep(); err == nil || !strings.Contains(err.Error(), "is inside OutputDir") {
+		t.Errorf("Expected error for input directory inside output directory, got %v", err)
+	}
+
+	// Disabled sets are not checked
+	sets := BatchConvertSets{
+		{Name: "giro", InputDir: input, OutputDir: output},
+		{Name: "visa", InputDir: missing, OutputDir: output},
+	}
+	if err := sets.CheckValidityDeep(); err == nil || !strings.HasPrefix(err.Error(), "set 'visa': InputDir") {
+		t.Errorf("Expected error for missing input directory, got %v", err)
+	}
+	disabled := false
+	sets[1].Enabled = &disabled
+	s := Settings{BatchConvert: BatchConvertSettings{Sets: sets}}
+	if err := s.CheckValidityDeep(); err != nil {
+		t.Errorf("No error expected for disabled set, got '%s'", err)
+	}
+	s.BatchConvert.Parallelism = -1
+	if err := s.CheckValidityDeep(); err == nil {
+		t.Error("Expected error for negative parallelism")
+	}
+}
+
+func TestCheckValidityDeepPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Permissions can't be restricted with chmod on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("Permissions are not enforced for root")
+	}
+	input := t.TempDir()
+	output := t.TempDir()
+	set := BatchConvertSet{Name: "giro", InputDir: input, OutputDir: output}
+
+	if err := os.Chmod(output, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(output, 0o700) })
+	if err := set.CheckValidityDeep(); err == nil || !strings.Contains(err.Error(), "is not writable") {
+		t.Errorf("Expected error for read-only output directory, got %v", err)
+	}
+	if err := os.Chmod(output, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(input, 0o300); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(input, 0o700) })
+	if err := set.CheckValidityDeep(); err == nil || !strings.Contains(err.Error(), "is not readable") {
+		t.Errorf("Expected error for unreadable input directory, got %v", err)
+	}
+}
+
+func TestNormalizePathsWithBaseDir(t *testing.T) {
+	baseDir := t.TempDir()
+	absolute := filepath.Join(t.TempDir(), "output")
+	config := fmt.Sprintf(`batchconvert:
+  sets:
+  - name: giro
+    inputdir: input
+    inputdirs: [../other]
+    outputdir: %q
+`, absolute)
+
+	var s Settings
+	if err := s.LoadFromString(config, WithBaseDir(baseDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	set := s.BatchConvert.Sets[0]
+	if expected := filepath.Join(baseDir, "input"); set.InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+	}
+	if expected := filepath.Join(filepath.Dir(baseDir), "other"); set.InputDirs[0] != expected {
+		t.Errorf("Expected InputDirs[0] '%s', got '%s'", expected, set.InputDirs[0])
+	}
+	if set.OutputDir != absolute {
+		t.Errorf("Expected OutputDir '%s', got '%s'", absolute, set.OutputDir)
+	}
+
+	// Without base directory relative paths are kept
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	if set := s.BatchConvert.Sets[0]; set.InputDir != "input" {
+		t.Errorf("Expected InputDir 'input', got '%s'", set.InputDir)
+	}
+}
+
+func TestSettingsLoadUnknownKeys(t *testing.T) {
+	configs := map[string]string{
+		"set": `batchconvert:
+  sets:
+  - name: giro
+    inputdir: /input
+    outputdir: /output
+    fileglobpatern: "*.csv"
+`,
+		"top": `batchconvert:
+  sets:
+  - name: giro
+    inputdir: /input
+    outputdir: /output
+  paralellism: 2
+`,
+		"root": `batchconvert:
+  sets:
+  - name: giro
+    inputdir: /input
+    outputdir: /output
+batchconverts:
+`,
+	}
+	expectedKeys := map[string]string{"set": "fileglobpatern", "top": "paralellism", "root": "batchconverts"}
+	expectedLines := map[string]string{"set": "[6:", "top": "[6:", "root": "[6:"}
+
+	for name, config := range configs {
+		var s Settings
+		err := s.LoadFromString(config)
+		if err == nil {
+			t.Errorf("%s: expected error for unknown key", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), expectedKeys[name]) || !strings.Contains(err.Error(), expectedLines[name]) {
+			t.Errorf("%s: expected key '%s' and line in error, got '%s'", name, expectedKeys[name], err)
+		}
+		if err := s.LoadFromString(config, NonStrict()); err != nil {
+			t.Errorf("%s: expected no error with NonStrict, got '%s'", name, err)
+		}
+		if len(s.BatchConvert.Sets) != 1 || s.BatchConvert.Sets[0].Name != "giro" {
+			t.Errorf("%s: expected set 'giro' with NonStrict, got %v", name, s.BatchConvert.Sets)
+		}
+	}
+
+	var s Settings
+	fpath := filepath.Join("testfiles", "unknown_key.yml")
+	err := s.LoadFromFile(fpath)
+	if err == nil || !strings.Contains(err.Error(), "fileglobpatern") {
+		t.Errorf("Expected error naming the unknown key, got '%v'", err)
+	}
+	if err := s.LoadFromFile(fpath, NonStrict()); err != nil {
+		t.Errorf("Expected no error with NonStrict, got '%s'", err)
+	}
+}
+
+func TestSettingsDefaults(t *testing.T) {
+	config := `batchconvert:
+  defaults:
+    fileglobpattern: "*.csv"
+    filemaxagedays: 14
+    overwrite: always
+    recursive: true
+    format: [DKB, Volksbank]
+  sets:
+  - name: inherits
+    inputdir: /input1
+    outputdir: /output1
+  - name: overrides
+    inputdir: /input2
+    outputdir: /output2
+    filemaxagedays: 0
+    recursive: false
+    format: Comdirect
+  - {name: flow, inputdir: /input3, outputdir: /output3, overwrite: never}
+`
+	var s Settings
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckValidity(); err != nil {
+		t.Fatalf("No error expected, got '%s'", err)
+	}
+	sets := s.BatchConvert.Sets
+	if len(sets) != 3 {
+		t.Fatalf("Expected 3 sets, got %d", len(sets))
+	}
+	for _, set := range sets {
+		if set.FileGlobPattern != "*.csv" {
+			t.Errorf("%s: expected inherited FileGlobPattern '*.csv', got '%s'", set.Name, set.FileGlobPattern)
+		}
+	}
+	inherits, overrides, flow := sets[0], sets[1], sets[2]
+	if inherits.FileMaxAge != MaxAgeDays(14) || !inherits.Recursive || inherits.Overwrite != OverwriteAlways {
+		t.Errorf("Expected inherited defaults, got %+v", inherits)
+	}
+	if !reflect.DeepEqual(inherits.Format, FormatList{parser.DKB, parser.Volksbank}) {
+		t.Errorf("Expected inherited formats, got %v", inherits.Format)
+	}
+	// Explicit zero values override the defaults
+	if overrides.FileMaxAge != 0 || overrides.Recursive || overrides.Overwrite != OverwriteAlways {
+		t.Errorf("Expected overridden FileMaxAge and Recursive, got %+v", overrides)
+	}
+	if !reflect.DeepEqual(overrides.Format, FormatList{parser.Comdirect}) {
+		t.Errorf("Expected overridden format, got %v", overrides.Format)
+	}
+	if flow.Overwrite != OverwriteNever || flow.FileMaxAge != MaxAgeDays(14) {
+		t.Errorf("Expected overridden Overwrite in flow style set, got %+v", flow)
+	}
+
+	// Saving keeps the defaults
+	fpath := filepath.Join(t.TempDir(), "config.yml")
+	if err := s.SaveToFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	var saved Settings
+	if err := saved.LoadFromFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withoutBaseDir(s), withoutBaseDir(saved)) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", s, saved)
+	}
+	content, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the overridden settings are saved in the sets
+	if n := strings.Count(string(content), "fileglobpattern:"); n != 1 {
+		t.Errorf("Expected fileglobpattern only in the defaults, got %d times:\n%s", n, content)
+	}
+	for _, expected := range []string{"filemaxagedays: 0s\n", "recursive: false\n", "overwrite: never\n"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in saved file:\n%s", strings.TrimSpace(expected), content)
+		}
+	}
+}
+
+func TestSettingsDefaultsInvalid(t *testing.T) {
+	for _, key := range []string{"name: giro", "inputdir: /input", "inputdirs: [/input]", "outputdir: /output"} {
+		config := `batchconvert:
+  defaults:
+    recursive: true
+    ` + key + `
+  sets:
+  - name: giro
+    inputdir: /input
+    outputdir: /output
+`
+		var s Settings
+		err := s.LoadFromString(config)
+		if err == nil || !strings.Contains(err.Error(), "[4:5]") {
+			t.Errorf("Expected error with position for '%s' in defaults, got '%v'", key, err)
+		}
+	}
+
+	var s Settings
+	err := s.LoadFromString("batchconvert:\n  defaults:\n    fileglobpatern: \"*.csv\"\n")
+	if err == nil || !strings.Contains(err.Error(), "fileglobpatern") {
+		t.Errorf("Expected error for unknown key in defaults, got '%v'", err)
+	}
+	if err := s.LoadFromString(""); err != nil {
+		t.Errorf("Expected no error for empty settings, got '%s'", err)
+	}
+	if err := s.LoadFromString("batchconvert:\n  defaults: 1\n"); err == nil {
+		t.Error("Expected error for defaults which are no mapping")
+	}
+}
+
+func TestSettingsFileFormats(t *testing.T) {
+	var expected Settings
+	if err := expected.LoadFromFile(filepath.Join("testfiles", "config_full.yml")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"config_full.json", "config_full.toml"} {
+		var s Settings
+		if err := s.LoadFromFile(filepath.Join("testfiles", name)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(expected, s) {
+			t.Errorf("%s: expected\n%+v\ngot\n%+v", name, expected, s)
+		}
+
+		// Saved in the format of the file
+		fpath := filepath.Join(t.TempDir(), name)
+		if err := s.SaveToFile(fpath); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var saved Settings
+		if err := saved.LoadFromFile(fpath); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(withoutBaseDir(expected), withoutBaseDir(saved)) {
+			t.Errorf("%s: expected saved\n%+v\ngot\n%+v", name, expected, saved)
+		}
+	}
+}
+
+func TestSettingsFileFormatErrors(t *testing.T) {
+	var s Settings
+	err := s.LoadFromString("[batchconvert]\nparalellism = 2\n", WithFileFormat(FormatTOML))
+	if err == nil || !strings.Contains(err.Error(), "paralellism") || strings.Contains(err.Error(), "[2:") {
+		t.Errorf("Expected unknown key error without YAML position, got %v", err)
+	}
+	if err := s.LoadFromString("[batchconvert\n", WithFileFormat(FormatTOML)); err == nil {
+		t.Error("Expected TOML syntax error")
+	}
+	if err := s.LoadFromString(`{"batchconvert": {"parallelism": 2}}`, WithFileFormat(FormatJSON)); err != nil || s.BatchConvert.Parallelism != 2 {
+		t.Errorf("Expected parallelism 2 from JSON, got %d, %v", s.BatchConvert.Parallelism, err)
+	}
+}
+
+func TestLoadFromDefaultFileFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	xdg.Reload()
+	configDir := filepath.Join(tmpDir, "go-homebank-csv")
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// config.toml is only used without config.yml and config.json
+	for _, name := range []string{"config.toml", "config.json", "config.yml"} {
+		ext := filepath.Ext(name)
+		if err := copyFile(filepath.Join("testfiles", "config_full"+ext), filepath.Join(configDir, name)); err != nil {
+			t.Fatal(err)
+		}
+		var s Settings
+		path, err := s.LoadFromDefaultFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := filepath.Join(configDir, name); path != expected || DefaultFilePath() != expected {
+			t.Errorf("Expected '%s', got '%s' and '%s'", expected, path, DefaultFilePath())
+		}
+		if len(s.BatchConvert.Sets) != 3 {
+			t.Errorf("%s: expected 3 sets, got %d", name, len(s.BatchConvert.Sets))
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testfiles", "config_v0.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := FileVersion(content); err != nil || version != 0 {
+		t.Errorf("Expected version 0, got %d, %v", version, err)
+	}
+	s, _, err := Migrate(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Settings{Version: CurrentVersion, BatchConvert: BatchConvertSettings{Sets: BatchConvertSets{{
+		Name:       "dkb",
+		InputDir:   "/my/downloads",
+		OutputDir:  "/my/converted",
+		Format:     FormatList{parser.DKB},
+		FileMaxAge: MaxAgeDays(14),
+	}}}}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", expected, s)
+	}
+
+	// Saved with the current version, loaded without migration
+	fpath := filepath.Join(t.TempDir(), "config.yml")
+	if err := s.SaveToFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := FileVersion(saved); err != nil || version != CurrentVersion {
+		t.Errorf("Expected version %d, got %d, %v", CurrentVersion, version, err)
+	}
+	if _, warnings, err := Migrate(saved); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v, %v", warnings, err)
+	}
+}
+
+func TestMigrateFutureVersion(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testfiles", "config_future.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := FileVersion(content); err != nil || version != 99 {
+		t.Errorf("Expected version 99, got %d, %v", version, err)
+	}
+	// The version is checked before the unknown keys
+	if _, _, err := Migrate(content); err == nil || !strings.Contains(err.Error(), "please upgrade") {
+		t.Errorf("Expected upgrade error, got %v", err)
+	}
+	var s Settings
+	if err := s.LoadFromFile(filepath.Join("testfiles", "config_future.yml")); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("Expected upgrade error, got %v", err)
+	}
+
+	for _, config := range []string{"version: -1\n", "version: one\n", "version: 1.5\n"} {
+		if err := s.LoadFromString(config); err == nil || !strings.Contains(err.Error(), "invalid config file version") {
+			t.Errorf("%s: expected invalid version error, got %v", config, err)
+		}
+	}
+}
+
+func TestMigrateChangingContent(t *testing.T) {
+	defer func(m []migration) { migrations = m }(migrations)
+	// A migration renaming a key of version 0
+	migrations = []migration{func(tree yaml.MapSlice) ([]string, error) {
+		batchConvert, _ := mapSliceValue(tree, "batchconvert").(yaml.MapSlice)
+		for i, item := range batchConvert {
+			if item.Key == "workers" {
+				batchConvert[i].Key = "parallelism"
+				return []string{"'workers' is renamed to 'parallelism'"}, nil
+			}
+		}
+		return nil, nil
+	}}
+
+	s, warnings, err := Migrate([]byte("batchconvert:\n  workers: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.BatchConvert.Parallelism != 3 || !reflect.DeepEqual(warnings, []string{"'workers' is renamed to 'parallelism'"}) {
+		t.Errorf("Expected parallelism 3 and warning, got %d, %v", s.BatchConvert.Parallelism, warnings)
+	}
+	// Files of the current version are not migrated
+	if _, _, err := Migrate([]byte("version: 1\nbatchconvert:\n  workers: 3\n")); err == nil {
+		t.Error("Expected unknown key error")
+	}
+}
+
+// withoutBaseDir returns the settings without the directory of the loaded file, to compare
+// settings saved to another directory
+func withoutBaseDir(s Settings) Settings {
+	s.baseDir = ""
+	return s
+}
+
+func TestLoadFromFileBaseDir(t *testing.T) {
+	// Without symlinks, the working directory is reported without them
+	configDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(configDir, "config.yml")
+	config := `batchconvert:
+  sets:
+  - name: giro
+    inputdir: downloads
+    outputdir: ../converted
+    statefile: state/giro.json
+`
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.Chdir(workingDir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// The same config file loaded from different working directories with a relative path
+	var results []BatchConvertSet
+	for _, dir := range []string{configDir, filepath.Dir(configDir)} {
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		relative, err := filepath.Rel(dir, configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Settings
+		if err := s.LoadFromFile(relative); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.NormalizePaths(); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, s.BatchConvert.Sets[0])
+	}
+	for _, set := range results {
+		if expected := filepath.Join(configDir, "downloads"); set.InputDir != expected {
+			t.Errorf("Expected InputDir '%s', got '%s'", expected, set.InputDir)
+		}
+		if expected := filepath.Join(filepath.Dir(configDir), "converted"); set.OutputDir != expected {
+			t.Errorf("Expected OutputDir '%s', got '%s'", expected, set.OutputDir)
+		}
+		if expected := filepath.Join(configDir, "state", "giro.json"); set.StateFile != expected {
+			t.Errorf("Expected StateFile '%s', got '%s'", expected, set.StateFile)
+		}
+	}
+
+	// A string is resolved against the working directory
+	var s Settings
+	if err := s.LoadFromString(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	if set := s.BatchConvert.Sets[0]; set.InputDir != "downloads" || set.OutputDir != filepath.Join("..", "converted") {
+		t.Errorf("Expected paths relative to the working directory, got '%s' and '%s'", set.InputDir, set.OutputDir)
+	}
+
+	// The base directory given takes precedence
+	baseDir := t.TempDir()
+	if err := s.LoadFromFile(configFile, WithBaseDir(baseDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NormalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(baseDir, "downloads"); s.BatchConvert.Sets[0].InputDir != expected {
+		t.Errorf("Expected InputDir '%s', got '%s'", expected, s.BatchConvert.Sets[0].InputDir)
+	}
+}
@@ -0,0 +1,50 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SkipMode decides how batchconvert detects that an input file has already been converted
+type SkipMode int
+
+const (
+	SkipByName SkipMode = iota // An existing output file with the same name is sufficient
+	SkipByHash                 // The existing output file must have the same content as the converted file
+)
+
+// skipModes is the mapping between SkipMode and its textual representation
+var skipModes = map[SkipMode]string{
+	SkipByName: "name",
+	SkipByHash: "hash",
+}
+
+// Returns the textual representation of the skip mode
+func (m SkipMode) String() string {
+	if s, ok := skipModes[m]; ok {
+		return s
+	}
+	return "unknown skip mode"
+}
+
+// MarshalText returns the textual representation of the skip mode, e.g. for YAML.
+// An error is returned for unsupported values.
+func (m SkipMode) MarshalText() ([]byte, error) {
+	if _, ok := skipModes[m]; !ok {
+		return nil, fmt.Errorf("unsupported skip mode %d", int(m))
+	}
+	return []byte(skipModes[m]), nil
+}
+
+// UnmarshalText sets the skip mode from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (m *SkipMode) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range skipModes {
+		if strings.EqualFold(value, textString) {
+			*m = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported skip mode '%s', valid values are: name, hash", string(text))
+}
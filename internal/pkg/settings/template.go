@@ -0,0 +1,61 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/sercxanto/go-homebank-csv/internal/pkg/atomicfile"
+)
+
+// Template is the content of a new config file. It contains no sets, the example set is
+// commented out.
+const Template = `# Config file of go-homebank-csv, see the README for all settings.
+# Version of the config file format
+version: 1
+batchconvert:
+  # Number of files of a set converted at the same time
+  # parallelism: 1
+  sets:
+  # Each set converts the files of an input directory into an output directory.
+  # - name: Bank 1
+  #   # Where to search for the exported files, "~" is the home directory
+  #   inputdir: ~/Downloads
+  #   # Where to place the converted files, must differ from inputdir
+  #   outputdir: ~/finance/homebankcsv
+  #   # Only files matching this pattern are converted
+  #   fileglobpattern: "*.csv"
+  #   # Only files modified within this number of days are converted
+  #   filemaxagedays: 14
+  #   # Format of the files, detected if not given, see "go-homebank-csv list-formats"
+  #   format: DKB
+  #   # Prefix of the categories of the converted entries
+  #   categoryprefix: "Import:DKB"
+`
+
+// WriteTemplate writes Template to filePath, creating its directory if missing. An
+// existing file is only replaced with force, otherwise an error wrapping fs.ErrExist
+// is returned. The template is only available as YAML, filePath must not have the
+// extension of another format.
+func WriteTemplate(filePath string, force bool) error {
+	if format := FileFormatOf(filePath); format != FormatYAML {
+		return fmt.Errorf("config file '%s' is %s, the template is only available as YAML", filePath, format)
+	}
+	if !force {
+		if _, err := os.Lstat(filePath); err == nil {
+			return fmt.Errorf("config file '%s' exists: %w", filePath, fs.ErrExist)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+		return err
+	}
+	return atomicfile.Write(filePath, 0o600, func(w io.Writer) error {
+		_, err := io.WriteString(w, Template)
+		return err
+	})
+}
@@ -0,0 +1,164 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the "Umsätze" export of American Express Germany, comma separated and UTF-8 encoded
+- Quoted fields, especially "Erweiterte Details", may contain line breaks. They are replaced
+  by spaces as homebank expects one record per line
+- Homebanks "date" is "Datum" in the format dd/mm/yyyy
+- Homebanks "amount" is "Betrag" in German notation. Charges are positive in the export,
+  so the sign is inverted
+- Homebanks "payee" is "Beschreibung", "memo" is "Erweiterte Details" and "category" is "Kategorie"
+*/
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// Single record of amex data
+type amexRecord struct {
+	datum             time.Time
+	beschreibung      string
+	betrag            float64
+	erweiterteDetails string
+	kategorie         string
+}
+
+type amexParser struct {
+	converter
+	entries []amexRecord
+}
+
+// amexColumns are the columns needed from the Amex CSV
+var amexColumns = []string{
+	"Datum",
+	"Beschreibung",
+	"Betrag",
+	"Erweiterte Details",
+	"Erscheint auf Ihrer Abrechnung als",
+	"Adresse",
+	"Ort",
+	"PLZ",
+	"Land",
+	"Referenz",
+	"Kategorie",
+}
+
+func (a *amexParser) ParseFile(filepath string) error {
+	a.entries = make([]amexRecord, 0)
+	return parseFile(filepath, a.Parse)
+}
+
+func (a *amexParser) Parse(in io.Reader) error {
+	a.entries = make([]amexRecord, 0)
+	a.rowErrors = nil
+	csvReader := a.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], amexColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]amexRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		datum, err := time.Parse("02/01/2006", row[columns["Datum"]])
+		if err != nil {
+			if a.skipRow(lineNr, "Datum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Datum",
+				Err:       err,
+			}
+		}
+		betrag, err := parseGermanAmount(row[columns["Betrag"]])
+		if err != nil {
+			if a.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag",
+				Err:       err,
+			}
+		}
+		entries = append(entries, amexRecord{
+			datum:             datum,
+			beschreibung:      row[columns["Beschreibung"]],
+			betrag:            betrag,
+			erweiterteDetails: row[columns["Erweiterte Details"]],
+			kategorie:         row[columns["Kategorie"]],
+		})
+	}
+
+	a.entries = entries
+	return nil
+}
+
+func (a *amexParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, a.Parse)
+}
+
+func (a *amexParser) GetFormat() SourceFormat {
+	return Amex
+}
+
+func (a *amexParser) GetNumberOfEntries() int {
+	return len(a.entries)
+}
+
+func (a *amexParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, a.WriteHomebank)
+}
+
+func (a *amexParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, a.GetEntries(), WithOutputOptions(a.outputOptions))
+}
+
+func (a *amexParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(a.entries))
+	for _, aRecord := range a.entries {
+		hRecord := aRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(a.processRecords(hRecords, a.GetFormat()))
+}
+
+// convertRecord converts a single record from amex to homebank format
+func (a *amexRecord) convertRecord() (h homebankRecord) {
+	h.payment = 1 // Credit card
+	h.date = a.datum.Format("2006-01-02")
+	h.amount = -a.betrag
+	h.payee = singleLine(a.beschreibung)
+	h.memo = singleLine(a.erweiterteDetails)
+	h.category = a.kategorie
+	return
+}
+
+// singleLine replaces line breaks and the surrounding whitespace by a single space
+func singleLine(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r", ""), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return joinNonEmpty(lines...)
+}
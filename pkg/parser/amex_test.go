@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAmexName(t *testing.T) {
+	p := &amexParser{}
+	if p.GetFormat() != Amex {
+		t.Error("Wrong format")
+	}
+}
+
+func TestAmexParseFileNonExisting(t *testing.T) {
+	p := &amexParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestAmexParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "amex", "Umsaetze_nok_noheader.csv")
+	p := &amexParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestAmexParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		// The first record spans two lines
+		{"Umsaetze_nok_wrongdatum.csv", 4, "Datum"},
+		{"Umsaetze_nok_wrongbetrag.csv", 3, "Betrag"},
+	}
+	for _, tc := range testCases {
+		p := &amexParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "amex", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestAmexParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "amex", "Umsaetze_onlyheader.csv")
+	p := &amexParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestAmexParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "amex", "Umsaetze.csv")
+	p := &amexParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestAmexConvertRecord(t *testing.T) {
+	a := amexRecord{
+		datum:             time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		beschreibung:      "BÄCKEREI",
+		betrag:            12.34,
+		erweiterteDetails: "Bäckerei\nBrötchen",
+		kategorie:         "Lebensmittel",
+	}
+	h := a.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 1 {
+		t.Errorf("Expected payment to be 1, got %d", h.payment)
+	}
+	if h.amount != -12.34 {
+		t.Errorf("Expected amount to be -12.34, got %f", h.amount)
+	}
+	if h.payee != a.beschreibung {
+		t.Errorf("Expected payee to be '%s', got '%s'", a.beschreibung, h.payee)
+	}
+	if h.memo != "Bäckerei Brötchen" {
+		t.Errorf("Expected memo to be 'Bäckerei Brötchen', got '%s'", h.memo)
+	}
+	if h.category != a.kategorie {
+		t.Errorf("Expected category to be '%s', got '%s'", a.kategorie, h.category)
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	testCases := map[string]string{
+		"":                        "",
+		"one line":                "one line",
+		"first\nsecond":           "first second",
+		"first \r\n  second\n\n":  "first second",
+		"\n  indented\nlast line": "indented last line",
+	}
+	for s, expected := range testCases {
+		if result := singleLine(s); result != expected {
+			t.Errorf("%q: expected '%s', got '%s'", s, expected, result)
+		}
+	}
+}
+
+func TestAmexConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "amex", "Umsaetze.csv")
+	p := &amexParser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = p.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "amex", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
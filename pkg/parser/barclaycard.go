@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
@@ -19,9 +20,14 @@ type barclaycardRecord struct {
 }
 
 type barclaycardParser struct {
+	converter
 	entries []barclaycardRecord
 }
 
+func (b *barclaycardParser) SniffHeader(head []byte) bool {
+	return isXlsx(head)
+}
+
 func (b *barclaycardParser) GetFormat() SourceFormat {
 	return Barclaycard
 }
@@ -30,33 +36,70 @@ func (b *barclaycardParser) GetNumberOfEntries() int {
 	return len(b.entries)
 }
 
+// barclaycardHeader is the header of current exports. The first "Buchungsdatum"
+// is actually the date of the transaction.
+var barclaycardHeader = []string{
+	"Referenznummer",
+	"Buchungsdatum",
+	"Buchungsdatum",
+	"Betrag",
+	"Beschreibung",
+	"Typ",
+	"Status",
+	"Kartennummer",
+	"Originalbetrag",
+	"Mögliche Zahlpläne",
+	"Land",
+	"Name des Karteninhabers",
+	"Kartennetzwerk",
+	"Kontaktlose Bezahlung",
+	"Händlerdetails",
+}
+
+// barclaycardHeaderLegacy is the header of exports until 2023 without
+// "Kontaktlose Bezahlung" and "Händlerdetails"
+var barclaycardHeaderLegacy = barclaycardHeader[:13]
+
 func isValidBarclaycardHeader(record []string) bool {
-	expected := []string{
-		"Referenznummer",
-		"Buchungsdatum", // eigentlich: Transaktionsdatum
-		"Buchungsdatum",
-		"Betrag",
-		"Beschreibung",
-		"Typ",
-		"Status",
-		"Kartennummer",
-		"Originalbetrag",
-		"Mögliche Zahlpläne",
-		"Land",
-		"Name des Karteninhabers",
-		"Kartennetzwerk",
-		"Kontaktlose Bezahlung",
-		"Händlerdetails",
+	return reflect.DeepEqual(record, barclaycardHeader) || reflect.DeepEqual(record, barclaycardHeaderLegacy)
+}
+
+// getBarclaycardColumns returns the index of each column by its name.
+// The first "Buchungsdatum" is named "Transaktionsdatum" to tell both apart.
+func getBarclaycardColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, found := columns[name]; found && name == "Buchungsdatum" {
+			columns["Transaktionsdatum"] = columns[name]
+		}
+		columns[name] = i
+	}
+	return columns
+}
+
+// barclaycardField returns the field of the row in the column with the given name.
+// Missing columns and cells, e.g. trailing empty cells trimmed by excelize, are empty.
+func barclaycardField(row []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(row) {
+		return ""
 	}
-	return reflect.DeepEqual(record, expected)
+	return row[i]
 }
 
 func (b *barclaycardParser) ParseFile(filepath string) error {
 	b.entries = make([]barclaycardRecord, 0)
-	f, err := excelize.OpenFile(filepath)
+	return parseFile(filepath, b.Parse)
+}
+
+func (b *barclaycardParser) Parse(in io.Reader) error {
+	b.entries = make([]barclaycardRecord, 0)
+	b.rowErrors = nil
+	f, err := excelize.OpenReader(in)
 	if err != nil {
-		return &ParserError{ErrorType: IOError}
+		return &ParserError{ErrorType: IOError, Err: err}
 	}
+	defer f.Close()
 	rows, err := f.GetRows("Sheet1")
 	if err != nil {
 		return &ParserError{
@@ -64,46 +107,61 @@ func (b *barclaycardParser) ParseFile(filepath string) error {
 		}
 	}
 
-	inDataSection := false
-	dataSectionFound := false
+	var columns map[string]int // Set when the header has been found
 
 	for lineNr, row := range rows {
-		if inDataSection {
+		if columns != nil {
+			if len(row) == 0 {
+				continue
+			}
 
-			tDate, err := time.Parse("02.01.2006", row[1])
+			tDate, err := time.Parse("02.01.2006", barclaycardField(row, columns, "Transaktionsdatum"))
 			if err != nil {
+				if b.skipRow(lineNr+1, "Buchungsdatum(1)/Transaktionsdatum", err) {
+					continue
+				}
 				return &ParserError{
 					ErrorType: DataParsingError,
 					Line:      lineNr + 1,
 					Field:     "Buchungsdatum(1)/Transaktionsdatum",
+					Err:       err,
 				}
 			}
 
 			// Entries with an empty "Buchungsdatum" are "vorgemerkt", not "Berechnet"
 			// and need to be skipped
-			if len(row[2]) == 0 {
+			bookingDate := barclaycardField(row, columns, "Buchungsdatum")
+			if len(bookingDate) == 0 {
 				continue
 			}
 
-			bDate, err := time.Parse("02.01.2006", row[2])
+			bDate, err := time.Parse("02.01.2006", bookingDate)
 			if err != nil {
+				if b.skipRow(lineNr+1, "Buchungsdatum", err) {
+					continue
+				}
 				return &ParserError{
 					ErrorType: DataParsingError,
 					Line:      lineNr + 1,
 					Field:     "Buchungsdatum",
+					Err:       err,
 				}
 			}
 
 			var value float64
 			// Format in excel export is "3,14 €"
-			valueString := strings.Replace(row[3], ",", ".", -1)
+			valueString := strings.Replace(barclaycardField(row, columns, "Betrag"), ",", ".", -1)
 			valueString = strings.TrimRight(valueString, "€")
 			value, err = strconv.ParseFloat(strings.TrimSpace(valueString), 64)
 			if err != nil {
+				if b.skipRow(lineNr+1, "Betrag", err) {
+					continue
+				}
 				return &ParserError{
 					ErrorType: DataParsingError,
 					Line:      lineNr + 1,
 					Field:     "Betrag",
+					Err:       err,
 				}
 			}
 
@@ -111,18 +169,15 @@ func (b *barclaycardParser) ParseFile(filepath string) error {
 				transactionDate: tDate,
 				bookingDate:     bDate,
 				value:           value,
-				description:     row[4],
-				payee:           row[14],
+				description:     barclaycardField(row, columns, "Beschreibung"),
+				payee:           barclaycardField(row, columns, "Händlerdetails"),
 			}
 			b.entries = append(b.entries, bRecord)
-		} else {
-			if isValidBarclaycardHeader(row) {
-				inDataSection = true
-				dataSectionFound = true
-			}
+		} else if isValidBarclaycardHeader(row) {
+			columns = getBarclaycardColumns(row)
 		}
 	}
-	if !dataSectionFound {
+	if columns == nil {
 		return &ParserError{
 			ErrorType: HeaderError,
 		}
@@ -144,14 +199,18 @@ func (b *barclaycardRecord) convertRecord() homebankRecord {
 }
 
 func (b *barclaycardParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, b.WriteHomebank)
+}
+
+func (b *barclaycardParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, b.GetEntries(), WithOutputOptions(b.outputOptions))
+}
+
+func (b *barclaycardParser) GetEntries() []Transaction {
 	hRecords := make([]homebankRecord, 0, len(b.entries))
 	for _, bRecord := range b.entries {
 		hRecord := bRecord.convertRecord()
 		hRecords = append(hRecords, hRecord)
 	}
-	err := writeHomeBankRecords(hRecords, filepath)
-	if err != nil {
-		return err
-	}
-	return nil
+	return toTransactions(b.processRecords(hRecords, b.GetFormat()))
 }
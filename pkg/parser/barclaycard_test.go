@@ -216,3 +216,37 @@ func TestBarclaycardConvertToHomebank(t *testing.T) {
 		t.Error("Files are not equal")
 	}
 }
+
+func TestBarclaycardConvertToHomebankLegacy(t *testing.T) {
+	// Exports without the columns "Kontaktlose Bezahlung" and "Händlerdetails"
+	fpath := filepath.Join("testfiles", "barclaycard", "Umsaetze_legacy.xlsx")
+	b := &barclaycardParser{}
+	if err := b.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := b.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join("testfiles", "barclaycard", "Umsaetze_legacy.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Error("Files are not equal")
+	}
+}
+
+func TestBarclaycardConvertToHomebankTrailingEmpty(t *testing.T) {
+	// Rows with empty trailing cells are shorter than the header
+	fpath := filepath.Join("testfiles", "barclaycard", "Umsaetze_trailingempty.xlsx")
+	b := &barclaycardParser{}
+	if err := b.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := b.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join("testfiles", "barclaycard", "Umsaetze_trailingempty.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Error("Files are not equal")
+	}
+}
@@ -0,0 +1,166 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the statement export of bunq, semicolon separated
+- Homebanks "date" is "Date" in the format yyyy-mm-dd, "amount" is the signed "Amount"
+  with a dot as decimal separator
+- Homebanks "payee" is "Name", "memo" is "Description"
+- Transfers to or from own accounts get the payment "bank transfer". An account is own if
+  "Counterparty" is one of the IBANs in FormatOptions.OwnAccounts
+- "Interest Date" and "Account" are ignored
+*/
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of bunq data
+type bunqRecord struct {
+	date         time.Time
+	amount       float64
+	counterparty string
+	name         string
+	description  string
+}
+
+type bunqParser struct {
+	converter
+	entries []bunqRecord
+}
+
+func (p *bunqParser) ParseFile(filepath string) error {
+	p.entries = make([]bunqRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *bunqParser) Parse(in io.Reader) error {
+	p.entries = make([]bunqRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidBunqHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]bunqRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Date",
+				Err:       err,
+			}
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err != nil {
+			if p.skipRow(lineNr, "Amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Amount",
+				Err:       err,
+			}
+		}
+		entries = append(entries, bunqRecord{
+			date:         date,
+			amount:       amount,
+			counterparty: row[4],
+			name:         row[5],
+			description:  row[6],
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *bunqParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *bunqParser) GetFormat() SourceFormat {
+	return Bunq
+}
+
+func (p *bunqParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *bunqParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *bunqParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *bunqParser) GetEntries() []Transaction {
+	ownAccounts := make(map[string]bool, len(p.formatOptions.OwnAccounts))
+	for _, iban := range p.formatOptions.OwnAccounts {
+		ownAccounts[normalizeIBAN(iban)] = true
+	}
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, bRecord := range p.entries {
+		hRecord := bRecord.convertRecord()
+		if ownAccounts[normalizeIBAN(bRecord.counterparty)] {
+			hRecord.payment = 4 // Bank transfer
+		}
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecord converts a single record from bunq to homebank format
+func (b *bunqRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = b.date.Format("2006-01-02")
+	h.amount = b.amount
+	h.payee = b.name
+	h.memo = b.description
+	return
+}
+
+// normalizeIBAN removes the spaces of the printed form and converts to upper case
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+func isValidBunqHeader(record []string) bool {
+	expected := []string{
+		"Date",
+		"Interest Date",
+		"Amount",
+		"Account",
+		"Counterparty",
+		"Name",
+		"Description",
+	}
+	return reflect.DeepEqual(record, expected)
+}
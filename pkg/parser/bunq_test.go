@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBunqName(t *testing.T) {
+	p := &bunqParser{}
+	if p.GetFormat() != Bunq {
+		t.Error("Wrong format")
+	}
+}
+
+func TestBunqParseFileNonExisting(t *testing.T) {
+	p := &bunqParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestBunqParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "bunq", "bunq_nok_noheader.csv")
+	p := &bunqParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestBunqParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"bunq_nok_wrongdate.csv", 3, "Date"},
+		{"bunq_nok_wrongamount.csv", 4, "Amount"},
+	}
+	for _, tc := range testCases {
+		p := &bunqParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "bunq", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestBunqParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "bunq", "bunq_onlyheader.csv")
+	p := &bunqParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestBunqParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "bunq", "bunq.csv")
+	p := &bunqParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestBunqConvertRecord(t *testing.T) {
+	b := bunqRecord{
+		date:         time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		amount:       -12.34,
+		counterparty: "DE02120300000000202051",
+		name:         "Bäckerei",
+		description:  "Brötchen",
+	}
+	h := b.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != b.amount {
+		t.Errorf("Expected amount to be %f, got %f", b.amount, h.amount)
+	}
+	if h.payee != b.name {
+		t.Errorf("Expected payee to be '%s', got '%s'", b.name, h.payee)
+	}
+	if h.memo != b.description {
+		t.Errorf("Expected memo to be '%s', got '%s'", b.description, h.memo)
+	}
+}
+
+func TestNormalizeIBAN(t *testing.T) {
+	testCases := map[string]string{
+		"NL34BUNQ2025654321":       "NL34BUNQ2025654321",
+		"nl34 bunq 2025 6543 21":   "NL34BUNQ2025654321",
+		" NL34 BUNQ 2025 6543 21 ": "NL34BUNQ2025654321",
+		"":                         "",
+	}
+	for iban, expected := range testCases {
+		if result := normalizeIBAN(iban); result != expected {
+			t.Errorf("'%s': expected '%s', got '%s'", iban, expected, result)
+		}
+	}
+}
+
+func TestBunqConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		ownAccounts []string
+		expected    string
+	}{
+		{nil, "homebank.csv"},
+		{[]string{"NL12 BUNQ 2025 1234 56", "nl34bunq2025654321"}, "homebank_ownaccounts.csv"},
+	}
+	for _, tc := range testCases {
+		p := &bunqParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "bunq", "bunq.csv")); err != nil {
+			t.Error(err)
+		}
+		p.SetFormatOptions(FormatOptions{OwnAccounts: tc.ownAccounts})
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "bunq", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
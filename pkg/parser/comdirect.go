@@ -1,22 +1,21 @@
 package parser
 
 import (
-	"encoding/csv"
-	"os"
+	"io"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
-
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
 )
 
 // Single record of comdirect data, all data is stored as quoted string in the CSV file
 type comdirectRecord struct {
+	creditCard       bool // Record from a credit card section
 	buchungstag      time.Time
+	umsatztag        time.Time // Only set for credit card records
 	vorgang          string
+	referenz         string // Only set for credit card records
 	fullBuchungstext string // Contains all fields
 	auftraggeber     string // parsed from fullBuchungstext
 	buchungstext     string // parsed from fullBuchungstext
@@ -27,25 +26,35 @@ type comdirectRecord struct {
 }
 
 type comdirectParser struct {
+	converter
 	entries []comdirectRecord
 }
 
+// comdirectSection is the type of account of a section in the export.
+// A single export can contain several sections back to back.
+type comdirectSection int
+
+const (
+	comdirectNoSection comdirectSection = iota
+	comdirectGiroSection
+	comdirectCreditCardSection
+)
+
 func (m *comdirectParser) ParseFile(filepath string) error {
-	const headerInRecordNr int = 2 // csvReader skips empty lines, so the header is in the third line
 	m.entries = make([]comdirectRecord, 0)
-	infile, err := os.Open(filepath)
-	if err != nil {
-		return &ParserError{ErrorType: IOError}
-	}
-	defer infile.Close()
+	return parseFile(filepath, m.Parse)
+}
 
-	reader := transform.NewReader(infile, charmap.ISO8859_1.NewDecoder())
-	csvReader := csv.NewReader(reader)
+func (m *comdirectParser) Parse(in io.Reader) error {
+	const headerInRecordNr int = 2 // csvReader skips empty lines, so the first header is in the third line
+	m.entries = make([]comdirectRecord, 0)
+	m.rowErrors = nil
+	csvReader := m.newCSVReader(in)
 	csvReader.Comma = ';'
 	csvReader.FieldsPerRecord = -1 // Enable variable length records
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		return &ParserError{ErrorType: IOError}
+		return err
 	}
 	if len(records) < headerInRecordNr+1 {
 		return &ParserError{ErrorType: HeaderError}
@@ -54,67 +63,160 @@ func (m *comdirectParser) ParseFile(filepath string) error {
 	if !isValidComdirectHeader(records[headerInRecordNr]) {
 		return &ParserError{
 			ErrorType: HeaderError,
-			Line:      headerInRecordNr + 2,
+			Line:      csvReader.recordLine(headerInRecordNr),
 		}
 	}
 
-	for lineNr, row := range records[headerInRecordNr+1:] {
-		if len(row) != 6 {
+	entries := make([]comdirectRecord, 0, len(records)-headerInRecordNr-1)
+	section := comdirectNoSection
+	for i := headerInRecordNr; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if s := getComdirectSection(row); s != comdirectNoSection {
+			section = s
 			continue
 		}
-		if row[0] == "offen" {
+		if isComdirectUnbooked(row[0]) {
 			continue
 		}
-		date, err := time.Parse("02.01.2006", row[0])
-		if err != nil {
-			return &ParserError{
-				ErrorType: DataParsingError,
-				Line:      lineNr + 6,
-				Field:     "Buchungstag",
-			}
+		var cRecord comdirectRecord
+		switch {
+		case section == comdirectGiroSection && len(row) == 6:
+			cRecord, err = parseComdirectGiroRow(row, lineNr, m.parseOptions.ComdirectValutaFallback)
+		case section == comdirectCreditCardSection && len(row) == 7:
+			cRecord, err = parseComdirectCreditCardRow(row, lineNr)
+		default:
+			continue
 		}
-		umsatzString := strings.Replace(row[4], ".", "", -1)
-		umsatzString = strings.Replace(umsatzString, ",", ".", -1)
-		var umsatz float64
-		umsatz, err = strconv.ParseFloat(umsatzString, 64)
 		if err != nil {
-			return &ParserError{
-				ErrorType: DataParsingError,
-				Line:      lineNr + 6,
-				Field:     "Umsatz in EUR",
+			if m.skipRowError(err) {
+				continue
 			}
+			return err
 		}
+		entries = append(entries, cRecord)
+	}
 
-		cRecord := comdirectRecord{
-			buchungstag:      date,
-			vorgang:          row[2],
-			fullBuchungstext: row[3],
-			umsatz_eur:       umsatz,
-		}
+	m.entries = entries
+	return nil
+}
 
-		listOfFields := []string{"Auftraggeber", "Buchungstext", "Empfänger", "Kto/IBAN", "BLZ/BIC"}
-		splitInfo := splitComdirectBuchungstext(listOfFields, row[3])
+// comdirectUnbookedMarkers are the values of "Buchungstag" of transactions which
+// are not booked yet
+var comdirectUnbookedMarkers = []string{"offen", "neu"}
+
+// isComdirectUnbooked reports whether the "Buchungstag" marks an unbooked transaction
+func isComdirectUnbooked(buchungstag string) bool {
+	for _, marker := range comdirectUnbookedMarkers {
+		if strings.EqualFold(strings.TrimSpace(buchungstag), marker) {
+			return true
+		}
+	}
+	return false
+}
 
-		if val, ok := splitInfo["Auftraggeber"]; ok {
-			cRecord.auftraggeber = val
+// parseComdirectGiroRow parses a row of the giro account section.
+// With valutaFallback the date of a row with an empty "Buchungstag" is taken
+// from "Wertstellung (Valuta)".
+func parseComdirectGiroRow(row []string, lineNr int, valutaFallback bool) (comdirectRecord, error) {
+	dateString, dateField := row[0], "Buchungstag"
+	if valutaFallback && strings.TrimSpace(dateString) == "" {
+		dateString, dateField = row[1], "Wertstellung (Valuta)"
+	}
+	date, err := time.Parse("02.01.2006", dateString)
+	if err != nil {
+		return comdirectRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     dateField,
+			Err:       err,
 		}
-		if val, ok := splitInfo["Buchungstext"]; ok {
-			cRecord.buchungstext = val
+	}
+	umsatzString := strings.Replace(row[4], ".", "", -1)
+	umsatzString = strings.Replace(umsatzString, ",", ".", -1)
+	var umsatz float64
+	umsatz, err = strconv.ParseFloat(umsatzString, 64)
+	if err != nil {
+		return comdirectRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "Umsatz in EUR",
+			Err:       err,
 		}
-		if val, ok := splitInfo["Empfänger"]; ok {
-			cRecord.empfaenger = val
+	}
+
+	cRecord := comdirectRecord{
+		buchungstag:      date,
+		vorgang:          row[2],
+		fullBuchungstext: row[3],
+		umsatz_eur:       umsatz,
+	}
+
+	listOfFields := []string{"Auftraggeber", "Buchungstext", "Empfänger", "Kto/IBAN", "BLZ/BIC"}
+	splitInfo := splitComdirectBuchungstext(listOfFields, row[3])
+
+	if val, ok := splitInfo["Auftraggeber"]; ok {
+		cRecord.auftraggeber = val
+	}
+	if val, ok := splitInfo["Buchungstext"]; ok {
+		cRecord.buchungstext = val
+	}
+	if val, ok := splitInfo["Empfänger"]; ok {
+		cRecord.empfaenger = val
+	}
+	if val, ok := splitInfo["Kto/IBAN"]; ok {
+		cRecord.ktoIBAN = val
+	}
+	if val, ok := splitInfo["BLZ/BIC"]; ok {
+		cRecord.blzBic = val
+	}
+
+	return cRecord, nil
+}
+
+// parseComdirectCreditCardRow parses a row of the credit card section
+func parseComdirectCreditCardRow(row []string, lineNr int) (comdirectRecord, error) {
+	buchungstag, err := time.Parse("02.01.2006", row[0])
+	if err != nil {
+		return comdirectRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "Buchungstag",
+			Err:       err,
 		}
-		if val, ok := splitInfo["Kto/IBAN"]; ok {
-			cRecord.ktoIBAN = val
+	}
+	umsatztag, err := time.Parse("02.01.2006", row[1])
+	if err != nil {
+		return comdirectRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "Umsatztag",
+			Err:       err,
 		}
-		if val, ok := splitInfo["BLZ/BIC"]; ok {
-			cRecord.blzBic = val
+	}
+	umsatz, err := parseGermanAmount(row[5])
+	if err != nil {
+		return comdirectRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "Umsatz in EUR",
+			Err:       err,
 		}
-
-		m.entries = append(m.entries, cRecord)
 	}
+	return comdirectRecord{
+		creditCard:       true,
+		buchungstag:      buchungstag,
+		umsatztag:        umsatztag,
+		vorgang:          row[2],
+		referenz:         row[3],
+		fullBuchungstext: row[4],
+		buchungstext:     row[4],
+		umsatz_eur:       umsatz,
+	}, nil
+}
 
-	return nil
+func (m *comdirectParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, m.Parse)
 }
 
 func (m *comdirectParser) GetFormat() SourceFormat {
@@ -126,18 +228,21 @@ func (m *comdirectParser) GetNumberOfEntries() int {
 }
 
 func (v *comdirectParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, v.WriteHomebank)
+}
+
+func (v *comdirectParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, v.GetEntries(), WithOutputOptions(v.outputOptions))
+}
+
+func (v *comdirectParser) GetEntries() []Transaction {
 	hRecords := make([]homebankRecord, 0, len(v.entries))
+	paymentTypes := v.paymentTypes(comdirectPaymentTypes)
 	for _, mRecord := range v.entries {
-		hRecord := mRecord.convertRecord()
+		hRecord := mRecord.convertRecord(paymentTypes)
 		hRecords = append(hRecords, hRecord)
 	}
-
-	err := writeHomeBankRecords(hRecords, filepath)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return toTransactions(v.processRecords(hRecords, v.GetFormat()))
 }
 
 /*
@@ -212,9 +317,10 @@ func splitComdirectBuchungstext(fields []string, buchungstext string) map[string
 	return result
 }
 
-func isValidComdirectHeader(record []string) bool {
-
-	expected := []string{
+// getComdirectSection returns the section started by the given header record.
+// It returns comdirectNoSection if the record is not a section header.
+func getComdirectSection(record []string) comdirectSection {
+	giroHeader := []string{
 		"Buchungstag",
 		"Wertstellung (Valuta)",
 		"Vorgang",
@@ -222,7 +328,28 @@ func isValidComdirectHeader(record []string) bool {
 		"Umsatz in EUR",
 		"", // yes, there is an empty field
 	}
-	return reflect.DeepEqual(record, expected)
+	creditCardHeader := []string{
+		"Buchungstag",
+		"Umsatztag",
+		"Vorgang",
+		"Referenz",
+		"Buchungstext",
+		"Umsatz in EUR",
+		"",
+	}
+	switch {
+	case reflect.DeepEqual(record, giroHeader):
+		return comdirectGiroSection
+	case reflect.DeepEqual(record, creditCardHeader):
+		return comdirectCreditCardSection
+	default:
+		return comdirectNoSection
+	}
+}
+
+// isValidComdirectHeader checks whether the record is the header of a giro account or credit card section
+func isValidComdirectHeader(record []string) bool {
+	return getComdirectSection(record) != comdirectNoSection
 }
 
 /*
@@ -247,9 +374,14 @@ Example:
 		"memo": "the full buchungstext",
 		"amount": 12.34,
 	}
+
+The payment is looked up by "vorgang" in paymentTypes.
 */
-func (c *comdirectRecord) convertRecord() (h homebankRecord) {
-	h.payment = 0
+func (c *comdirectRecord) convertRecord(paymentTypes PaymentTypes) (h homebankRecord) {
+	if c.creditCard {
+		return c.convertCreditCardRecord()
+	}
+	h.payment = paymentTypes.lookup(c.vorgang)
 	h.date = c.buchungstag.Format("2006-01-02")
 	h.amount = c.umsatz_eur
 	h.memo = c.fullBuchungstext
@@ -274,6 +406,19 @@ func (c *comdirectRecord) convertRecord() (h homebankRecord) {
 	return
 }
 
+// convertCreditCardRecord converts a record of the credit card section.
+// The date of the transaction is the "Umsatztag", the "Buchungstag" is the
+// date of the booking on the credit card account.
+func (c *comdirectRecord) convertCreditCardRecord() (h homebankRecord) {
+	h.payment = 1 // Credit card
+	h.date = c.umsatztag.Format("2006-01-02")
+	h.amount = c.umsatz_eur
+	h.info = c.vorgang
+	h.payee = c.buchungstext
+	h.memo = c.referenz
+	return
+}
+
 func getFirstNWords(n uint, s string) string {
 	if n == 0 {
 		return ""
@@ -66,8 +66,8 @@ func TestComdirectParseFileNokInvalidHeader(t *testing.T) {
 		if pError.ErrorType != HeaderError {
 			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
 		}
-		if pError.Line != 4 {
-			t.Errorf("Expected error on line 4, got %d", pError.Line)
+		if pError.Line != 5 {
+			t.Errorf("Expected error on line 5, got %d", pError.Line)
 		}
 	} else {
 		t.Error("ParserError expected")
@@ -145,14 +145,14 @@ func TestComdirectConvertRecord(t *testing.T) {
 
 	c := comdirectRecord{
 		buchungstag:      time.Date(2019, 8, 5, 0, 0, 0, 0, time.UTC),
-		vorgang:          "Übertrag/Überweisung",
+		vorgang:          "Übertrag / Überweisung",
 		fullBuchungstext: "Auftraggeber:auftragnameBuchungstext: Der Buchungstext 123 456Kto/IBAN: DE123 BLZ/BIC: ABC123",
 		buchungstext:     "Der Buchungstext 123 456",
 		auftraggeber:     "auftragname",
 		empfaenger:       "",
 		umsatz_eur:       -139.40,
 	}
-	h := c.convertRecord()
+	h := c.convertRecord(comdirectPaymentTypes.merge(nil))
 	if h.amount != c.umsatz_eur {
 		t.Error("Amount does not match")
 	}
@@ -162,8 +162,8 @@ func TestComdirectConvertRecord(t *testing.T) {
 	if h.info != "Der Buchungstext 123" {
 		t.Error("Info does not match")
 	}
-	if h.payment != 0 {
-		t.Error("Payment does not match")
+	if h.payment != 4 {
+		t.Errorf("Payment does not match. Got %d", h.payment)
 	}
 	if h.payee != "auftragname" {
 		t.Errorf("Payee does not match. Got '%s'", h.payee)
@@ -355,4 +355,162 @@ func TestIsValidComdirectHeader(t *testing.T) {
 	if isValidComdirectHeader(headerWrongLength) {
 		t.Error("Header should be NOK (wrong length)")
 	}
+
+	headerCreditCard := []string{
+		"Buchungstag",
+		"Umsatztag",
+		"Vorgang",
+		"Referenz",
+		"Buchungstext",
+		"Umsatz in EUR",
+		"",
+	}
+	if !isValidComdirectHeader(headerCreditCard) {
+		t.Error("Credit card header should be OK")
+	}
+	if getComdirectSection(headerCreditCard) != comdirectCreditCardSection {
+		t.Error("Expected credit card section")
+	}
+	if getComdirectSection(headerOk) != comdirectGiroSection {
+		t.Error("Expected giro section")
+	}
+	if getComdirectSection(headerNok) != comdirectNoSection {
+		t.Error("Expected no section")
+	}
+}
+
+func TestComdirectConvertCreditCardRecord(t *testing.T) {
+	c := comdirectRecord{
+		creditCard:       true,
+		buchungstag:      time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC),
+		umsatztag:        time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC),
+		vorgang:          "Visa-Umsatz",
+		referenz:         "74185296307418529",
+		fullBuchungstext: "Bäckerei Schön München DE",
+		buchungstext:     "Bäckerei Schön München DE",
+		umsatz_eur:       -12.34,
+	}
+	h := c.convertRecord(nil)
+	if h.date != "2023-10-03" {
+		t.Errorf("Expected date to be the Umsatztag 2023-10-03, got '%s'", h.date)
+	}
+	if h.payment != 1 {
+		t.Errorf("Expected payment to be 1, got %d", h.payment)
+	}
+	if h.amount != c.umsatz_eur {
+		t.Errorf("Expected amount to be %f, got %f", c.umsatz_eur, h.amount)
+	}
+	if h.info != c.vorgang {
+		t.Errorf("Expected info to be '%s', got '%s'", c.vorgang, h.info)
+	}
+	if h.payee != c.buchungstext {
+		t.Errorf("Expected payee to be '%s', got '%s'", c.buchungstext, h.payee)
+	}
+	if h.memo != c.referenz {
+		t.Errorf("Expected memo to be '%s', got '%s'", c.referenz, h.memo)
+	}
+}
+
+func TestComdirectParseFileCreditCardOnly(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirect", "umsaetze_visa.csv")
+	c := &comdirectParser{}
+	if err := c.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	// The pending "offen" record is skipped
+	if c.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", c.GetNumberOfEntries())
+	}
+}
+
+func TestComdirectParseFileNokWrongUmsatztag(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirect", "umsaetze_nok_wrongumsatztag.csv")
+	c := &comdirectParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 17 {
+			t.Errorf("Expected error on line 17, got %d", pError.Line)
+		}
+		if pError.Field != "Umsatztag" {
+			t.Errorf("Expected error on field 'Umsatztag', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestComdirectConvertToHomebankMixedSections(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirect", "umsaetze_mixed.csv")
+	c := &comdirectParser{}
+	if err := c.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetNumberOfEntries() != 5 {
+		t.Errorf("Expected 5 entries, got %d", c.GetNumberOfEntries())
+	}
+
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := c.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join("testfiles", "comdirect", "homebank_mixed.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
+
+func TestComdirectParseFileUnbooked(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirect", "umsaetze_unbooked.csv")
+
+	// By default the row with an empty "Buchungstag" fails after the "offen" and "neu" rows are skipped
+	c := &comdirectParser{}
+	err := c.ParseFile(fpath)
+	var pError *ParserError
+	if !errors.As(err, &pError) {
+		t.Fatalf("ParserError expected, got %v", err)
+	}
+	if pError.ErrorType != DataParsingError || pError.Line != 9 || pError.Field != "Buchungstag" {
+		t.Errorf("Expected DataParsingError on line 9, field 'Buchungstag', got %v", pError)
+	}
+
+	c = &comdirectParser{}
+	c.SetParseOptions(ParseOptions{ComdirectValutaFallback: true})
+	if err := c.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetNumberOfEntries() != 5 {
+		t.Fatalf("Expected 5 entries, got %d", c.GetNumberOfEntries())
+	}
+	entries := c.GetEntries()
+	if entries[0].Amount != -40.01 {
+		t.Errorf("Expected the first entry to be the first booked row, got %v", entries[0])
+	}
+	expectedDate := time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC)
+	if !entries[1].Date.Equal(expectedDate) || entries[1].Amount != -9.99 {
+		t.Errorf("Expected entry dated by Wertstellung %v, got %v", expectedDate, entries[1])
+	}
+}
+
+func TestIsComdirectUnbooked(t *testing.T) {
+	for _, s := range []string{"offen", "neu", " Neu "} {
+		if !isComdirectUnbooked(s) {
+			t.Errorf("'%s' should be unbooked", s)
+		}
+	}
+	for _, s := range []string{"", "06.10.2023", "neuer"} {
+		if isComdirectUnbooked(s) {
+			t.Errorf("'%s' should not be unbooked", s)
+		}
+	}
 }
@@ -0,0 +1,163 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the export of the comdirect securities account ("Depotumsätze"), semicolon separated
+  and ISO-8859-1 encoded. It is a separate format so that the detection of the giro account
+  and credit card export is not affected
+- The first lines contain the date range, they are skipped until the header line is found
+- Homebanks "date" is "Geschäftstag" in the format dd.mm.yyyy
+- Homebanks "amount" is "Umsatz in EUR" in German notation, buys are negative, sells and dividends positive
+- Homebanks "info" is "Geschäftsart" like "Kauf", "Verkauf" or "Ertrag"
+- Homebanks "memo" is "Bezeichnung" followed by the "WKN"
+*/
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+// Single record of comdirect securities account data
+type comdirectDepotRecord struct {
+	geschaeftstag time.Time
+	geschaeftsart string
+	bezeichnung   string
+	wkn           string
+	umsatz_eur    float64
+}
+
+type comdirectDepotParser struct {
+	converter
+	entries []comdirectDepotRecord
+}
+
+// comdirectDepotHeader is the header of the securities account export
+var comdirectDepotHeader = []string{
+	"Geschäftstag",
+	"Geschäftsart",
+	"Stück/Nom.",
+	"Bezeichnung",
+	"WKN",
+	"Währung",
+	"Ausführungskurs",
+	"Umsatz in EUR",
+	"", // like in the giro account export there is an empty field
+}
+
+func (p *comdirectDepotParser) ParseFile(filepath string) error {
+	p.entries = make([]comdirectDepotRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *comdirectDepotParser) Parse(in io.Reader) error {
+	p.entries = make([]comdirectDepotRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Enable variable length records
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	headerIndex := -1
+	for i, record := range records {
+		if isValidComdirectDepotHeader(record) {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	entries := make([]comdirectDepotRecord, 0, len(records)-headerIndex-1)
+	for i := headerIndex + 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		// Skip trailing lines like the total
+		if len(row) != len(comdirectDepotHeader) {
+			continue
+		}
+		date, err := time.Parse("02.01.2006", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Geschäftstag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Geschäftstag",
+				Err:       err,
+			}
+		}
+		umsatz, err := parseGermanAmount(row[7])
+		if err != nil {
+			if p.skipRow(lineNr, "Umsatz in EUR", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Umsatz in EUR",
+				Err:       err,
+			}
+		}
+		entries = append(entries, comdirectDepotRecord{
+			geschaeftstag: date,
+			geschaeftsart: row[1],
+			bezeichnung:   row[3],
+			wkn:           row[4],
+			umsatz_eur:    umsatz,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *comdirectDepotParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *comdirectDepotParser) GetFormat() SourceFormat {
+	return ComdirectDepot
+}
+
+func (p *comdirectDepotParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *comdirectDepotParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *comdirectDepotParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *comdirectDepotParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, cRecord := range p.entries {
+		hRecord := cRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidComdirectDepotHeader(record []string) bool {
+	return reflect.DeepEqual(record, comdirectDepotHeader)
+}
+
+// convertRecord converts a single record from the comdirect securities account to homebank format
+func (c *comdirectDepotRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = c.geschaeftstag.Format("2006-01-02")
+	h.amount = c.umsatz_eur
+	h.info = c.geschaeftsart
+	h.memo = joinNonEmpty(c.bezeichnung, c.wkn)
+	return
+}
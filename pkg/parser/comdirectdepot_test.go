@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComdirectDepotName(t *testing.T) {
+	p := &comdirectDepotParser{}
+	if p.GetFormat() != ComdirectDepot {
+		t.Error("Wrong format")
+	}
+}
+
+func TestComdirectDepotParseFileNonExisting(t *testing.T) {
+	p := &comdirectDepotParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestComdirectDepotParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirectdepot", "depotumsaetze_nok_noheader.csv")
+	p := &comdirectDepotParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestComdirectDepotParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"depotumsaetze_nok_wronggeschaeftstag.csv", 6, "Geschäftstag"},
+		{"depotumsaetze_nok_wrongumsatz.csv", 7, "Umsatz in EUR"},
+	}
+	for _, tc := range testCases {
+		p := &comdirectDepotParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "comdirectdepot", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestComdirectDepotParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirectdepot", "depotumsaetze_onlyheader.csv")
+	p := &comdirectDepotParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestComdirectDepotParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "comdirectdepot", "depotumsaetze.csv")
+	p := &comdirectDepotParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestComdirectDepotConvertRecord(t *testing.T) {
+	c := comdirectDepotRecord{
+		geschaeftstag: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC),
+		geschaeftsart: "Kauf",
+		bezeichnung:   "ISHSIII-CORE MSCI WORLD U.ETF",
+		wkn:           "A0RPWH",
+		umsatz_eur:    -988.98,
+	}
+	h := c.convertRecord()
+	if h.date != "2023-10-05" {
+		t.Errorf("Expected date to be 2023-10-05, got '%s'", h.date)
+	}
+	if h.amount != c.umsatz_eur {
+		t.Errorf("Expected amount to be %f, got %f", c.umsatz_eur, h.amount)
+	}
+	if h.info != c.geschaeftsart {
+		t.Errorf("Expected info to be '%s', got '%s'", c.geschaeftsart, h.info)
+	}
+	if h.memo != "ISHSIII-CORE MSCI WORLD U.ETF A0RPWH" {
+		t.Errorf("Expected memo to be 'ISHSIII-CORE MSCI WORLD U.ETF A0RPWH', got '%s'", h.memo)
+	}
+}
+
+// The giro account parser must not detect the securities account export
+func TestComdirectDepotNotComdirect(t *testing.T) {
+	p := &comdirectParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "comdirectdepot", "depotumsaetze.csv")); err == nil {
+		t.Error("Should fail")
+	}
+}
+
+func TestComdirectDepotConvertToHomebank(t *testing.T) {
+	p := &comdirectDepotParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "comdirectdepot", "depotumsaetze.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "comdirectdepot", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
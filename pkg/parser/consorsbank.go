@@ -0,0 +1,181 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the giro account export of Consorsbank, semicolon separated and UTF-8 encoded
+- Homebanks "date" is "Buchung" in the format dd.mm.yyyy, "amount" is "Betrag in EUR" in German notation
+- Homebanks "payee" is "Sender / Empfänger", "memo" is "Verwendungszweck", "info" is "Buchungstext"
+- Homebanks "category" is "Kategorie"
+- Homebanks "tags" are the "Stichwörter". As homebank separates tags by space,
+  keywords separated by "," are joined with a space
+- "Umsatz geprüft" is ignored
+*/
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Single record of consorsbank data
+type consorsbankRecord struct {
+	buchung          time.Time
+	valuta           time.Time
+	senderEmpfaenger string
+	buchungstext     string
+	verwendungszweck string
+	kategorie        string
+	stichwoerter     string
+	betrag           float64
+}
+
+type consorsbankParser struct {
+	converter
+	entries []consorsbankRecord
+}
+
+func (p *consorsbankParser) ParseFile(filepath string) error {
+	p.entries = make([]consorsbankRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *consorsbankParser) Parse(in io.Reader) error {
+	p.entries = make([]consorsbankRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidConsorsbankHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]consorsbankRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		buchung, err := time.Parse("02.01.2006", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Buchung", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Buchung",
+				Err:       err,
+			}
+		}
+		valuta, err := time.Parse("02.01.2006", row[1])
+		if err != nil {
+			if p.skipRow(lineNr, "Valuta", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Valuta",
+				Err:       err,
+			}
+		}
+		betrag, err := parseGermanAmount(row[10])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag in EUR", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag in EUR",
+				Err:       err,
+			}
+		}
+		entries = append(entries, consorsbankRecord{
+			buchung:          buchung,
+			valuta:           valuta,
+			senderEmpfaenger: row[2],
+			buchungstext:     row[5],
+			verwendungszweck: row[6],
+			kategorie:        row[7],
+			stichwoerter:     row[8],
+			betrag:           betrag,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *consorsbankParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *consorsbankParser) GetFormat() SourceFormat {
+	return Consorsbank
+}
+
+func (p *consorsbankParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *consorsbankParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *consorsbankParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *consorsbankParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, cRecord := range p.entries {
+		hRecord := cRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecord converts a single record from consorsbank to homebank format
+func (c *consorsbankRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = c.buchung.Format("2006-01-02")
+	h.amount = c.betrag
+	h.info = c.buchungstext
+	h.payee = c.senderEmpfaenger
+	h.memo = c.verwendungszweck
+	h.category = c.kategorie
+	h.tags = strings.Join(strings.FieldsFunc(c.stichwoerter, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}), " ")
+	return
+}
+
+func isValidConsorsbankHeader(record []string) bool {
+	expected := []string{
+		"Buchung",
+		"Valuta",
+		"Sender / Empfänger",
+		"IBAN / Konto-Nr.",
+		"BIC / BLZ",
+		"Buchungstext",
+		"Verwendungszweck",
+		"Kategorie",
+		"Stichwörter",
+		"Umsatz geprüft",
+		"Betrag in EUR",
+	}
+	return reflect.DeepEqual(record, expected)
+}
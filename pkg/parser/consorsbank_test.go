@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConsorsbankName(t *testing.T) {
+	p := &consorsbankParser{}
+	if p.GetFormat() != Consorsbank {
+		t.Error("Wrong format")
+	}
+}
+
+func TestConsorsbankParseFileNonExisting(t *testing.T) {
+	p := &consorsbankParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestConsorsbankParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "consorsbank", "Umsaetze_nok_noheader.csv")
+	p := &consorsbankParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestConsorsbankParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Umsaetze_nok_wrongbuchung.csv", 3, "Buchung"},
+		{"Umsaetze_nok_wrongbetrag.csv", 4, "Betrag in EUR"},
+	}
+	for _, tc := range testCases {
+		p := &consorsbankParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "consorsbank", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestConsorsbankParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "consorsbank", "Umsaetze_onlyheader.csv")
+	p := &consorsbankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestConsorsbankParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "consorsbank", "Umsaetze.csv")
+	p := &consorsbankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestConsorsbankConvertRecord(t *testing.T) {
+	c := consorsbankRecord{
+		buchung:          time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC),
+		valuta:           time.Date(2023, 10, 6, 0, 0, 0, 0, time.UTC),
+		senderEmpfaenger: "Arbeitgeber GmbH",
+		buchungstext:     "Gutschrift",
+		verwendungszweck: "Gehalt",
+		kategorie:        "Gehalt",
+		stichwoerter:     "arbeit, monatlich  steuer",
+		betrag:           2500,
+	}
+	h := c.convertRecord()
+	if h.date != "2023-10-05" {
+		t.Errorf("Expected date to be 2023-10-05, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != c.betrag {
+		t.Errorf("Expected amount to be %f, got %f", c.betrag, h.amount)
+	}
+	if h.info != c.buchungstext {
+		t.Errorf("Expected info to be '%s', got '%s'", c.buchungstext, h.info)
+	}
+	if h.payee != c.senderEmpfaenger {
+		t.Errorf("Expected payee to be '%s', got '%s'", c.senderEmpfaenger, h.payee)
+	}
+	if h.memo != c.verwendungszweck {
+		t.Errorf("Expected memo to be '%s', got '%s'", c.verwendungszweck, h.memo)
+	}
+	if h.category != c.kategorie {
+		t.Errorf("Expected category to be '%s', got '%s'", c.kategorie, h.category)
+	}
+	if h.tags != "arbeit monatlich steuer" {
+		t.Errorf("Expected tags to be 'arbeit monatlich steuer', got '%s'", h.tags)
+	}
+}
+
+func TestConsorsbankConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "consorsbank", "Umsaetze.csv")
+	p := &consorsbankParser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = p.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "consorsbank", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
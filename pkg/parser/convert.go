@@ -0,0 +1,79 @@
+package parser
+
+// ConvertResult describes the conversion of a file by Convert
+type ConvertResult struct {
+	Format    SourceFormat  // Given or detected format of the input file
+	Entries   int           // Number of parsed entries
+	Written   int           // Number of records written to the output file
+	Dropped   int           // Number of entries outside of the date range, see Parser.SetDateRange
+	RowErrors []ParserError // Errors of the data rows skipped in lenient mode
+}
+
+// ConvertOption changes the conversion of Convert
+type ConvertOption func(*convertOptions)
+
+type convertOptions struct {
+	parseOptions ParseOptions
+	configure    []func(Parser)
+}
+
+// WithParseOptions sets the limits, the lenient mode and the encoding used for parsing
+// the input file
+func WithParseOptions(o ParseOptions) ConvertOption {
+	return func(c *convertOptions) {
+		c.parseOptions = o
+	}
+}
+
+// WithConfigure calls configure with the parser after parsing the input file and before
+// writing the output file, e.g. to set the date range, the rules or the output options
+func WithConfigure(configure func(Parser)) ConvertOption {
+	return func(c *convertOptions) {
+		c.configure = append(c.configure, configure)
+	}
+}
+
+// ParseFileAs parses the file with the parser of format. With nil the format is guessed,
+// see GuessParser. The parser is returned also on error if the format is known, e.g. to
+// report the format of a file failing with a DataParsingError.
+func ParseFileAs(inPath string, format *SourceFormat, o ParseOptions) (Parser, error) {
+	if format == nil {
+		return GuessParser(inPath, o)
+	}
+	p := GetParser(*format)
+	p.SetParseOptions(o)
+	return p, p.ParseFile(inPath)
+}
+
+// Convert converts the input file inPath into the homebank CSV file outPath. The input
+// file is parsed as format, with nil the format is guessed, see ParseFileAs.
+//
+// The result is filled as far as the conversion got, e.g. Format is set if the parsing
+// fails with a known format. Errors of reading inPath are ParserError with File set to
+// inPath, a file of unknown format fails with ErrUnknownFormat. Errors of writing
+// outPath are ParserError of type IOError with File set to outPath.
+func Convert(inPath, outPath string, format *SourceFormat, opts ...ConvertOption) (ConvertResult, error) {
+	var o convertOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var result ConvertResult
+	p, err := ParseFileAs(inPath, format, o.parseOptions)
+	if p != nil {
+		result.Format = p.GetFormat()
+		result.Entries = p.GetNumberOfEntries()
+		result.RowErrors = p.GetRowErrors()
+	}
+	if err != nil {
+		return result, err
+	}
+	for _, configure := range o.configure {
+		configure(p)
+	}
+	if err := p.ConvertToHomebank(outPath); err != nil {
+		return result, &ParserError{ErrorType: IOError, File: outPath, Err: err}
+	}
+	result.Written = p.GetNumberOfWrittenEntries()
+	result.Dropped = p.GetNumberOfDroppedEntries()
+	return result, nil
+}
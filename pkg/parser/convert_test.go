@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConvertExplicitFormat(t *testing.T) {
+	infile := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	format := Volksbank
+	result, err := Convert(infile, outfile, &format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ConvertResult{Format: Volksbank, Entries: 4, Written: 4}
+	if result.Format != expected.Format || result.Entries != expected.Entries || result.Written != expected.Written || result.Dropped != 0 {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+	if !areFilesEqual(filepath.Join("testfiles", "volksbank", "homebank.csv"), outfile) {
+		t.Errorf("Unexpected content of '%s'", outfile)
+	}
+
+	// The input file does not match the explicit format
+	format = DKB
+	_, err = Convert(infile, outfile, &format)
+	var parserErr *ParserError
+	if !errors.As(err, &parserErr) || parserErr.File != infile {
+		t.Errorf("Expected ParserError with file '%s', got %v", infile, err)
+	}
+}
+
+func TestConvertGuessedFormat(t *testing.T) {
+	infile := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	outfile := filepath.Join(t.TempDir(), "output.csv")
+	dateRange := DateRange{From: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)}
+	result, err := Convert(infile, outfile, nil, WithConfigure(func(p Parser) {
+		p.SetDateRange(dateRange)
+	}), WithConfigure(func(p Parser) {
+		p.SetOutputOptions(OutputOptions{Delimiter: '\t', DecimalSeparator: ','})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Format != Volksbank || result.Entries != 4 || result.Written != 2 || result.Dropped != 2 {
+		t.Errorf("Expected 2 of 4 Volksbank entries written, got %+v", result)
+	}
+	content, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "date\tpayment\tinfo\tpayee\tmemo\tamount\tcategory\ttags\n" +
+		"2023-10-04\t11\t\tName des Zahlungsbeteiligten\tVerwendungszweck abc\t-6,00\tSonstiges\t\n" +
+		"2023-10-02\t7\t\tUmlaute äöß\tVerwendungszweck xyz\t600,00\tSonstiges\t\n"
+	if string(content) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, content)
+	}
+
+	// A DataParsingError of a guessed format is returned with the format
+	infile = filepath.Join("testfiles", "volksbank", "Umsaetze_nok_wrongbetrag.csv")
+	result, err = Convert(infile, outfile, nil)
+	var parserErr *ParserError
+	if !errors.As(err, &parserErr) || parserErr.ErrorType != DataParsingError || parserErr.File != infile {
+		t.Errorf("Expected DataParsingError with file '%s', got %v", infile, err)
+	}
+	if result.Format != Volksbank {
+		t.Errorf("Expected format Volksbank, got %s", result.Format)
+	}
+
+	// Lenient mode skips the invalid row
+	result, err = Convert(infile, outfile, nil, WithParseOptions(ParseOptions{Lenient: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Format != Volksbank || len(result.RowErrors) != 1 || result.Written != result.Entries {
+		t.Errorf("Expected one skipped row, got %+v", result)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.csv")
+	if err := os.WriteFile(unknown, []byte("a;b;c\n1;2;3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Convert(unknown, filepath.Join(dir, "output.csv"), nil)
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+	if result.Entries != 0 || result.RowErrors != nil {
+		t.Errorf("Expected empty result, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "output.csv")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no output file, got %v", err)
+	}
+
+	missing := filepath.Join(dir, "missing.csv")
+	_, err = Convert(missing, filepath.Join(dir, "output.csv"), nil)
+	var parserErr *ParserError
+	if !errors.As(err, &parserErr) || parserErr.ErrorType != IOError || parserErr.File != missing {
+		t.Errorf("Expected IOError with file '%s', got %v", missing, err)
+	}
+
+	infile := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	outfile := filepath.Join(dir, "missing", "output.csv")
+	result, err = Convert(infile, outfile, nil)
+	if !errors.As(err, &parserErr) || parserErr.ErrorType != IOError || parserErr.File != outfile {
+		t.Errorf("Expected IOError with file '%s', got %v", outfile, err)
+	}
+	if result.Format != Volksbank || result.Entries != 4 || result.Written != 0 {
+		t.Errorf("Expected the parsed entries without written records, got %+v", result)
+	}
+}
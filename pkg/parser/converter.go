@@ -0,0 +1,316 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// converter holds the settings for the conversion to homebank format which
+// are common to all formats. It is embedded by all parsers.
+type converter struct {
+	dateRange            DateRange
+	categoryPrefix       string
+	categoryPrefixAlways bool
+	routeInfoToMemo      bool
+	routeMemoToInfo      bool
+	noNormalization      bool // Unicode normalization is enabled by default
+	parseOptions         ParseOptions
+	formatOptions        FormatOptions
+	rules                *Rules
+	sortOrder            SortOrder
+	outputOptions        OutputOptions
+	rowErrors            []ParserError // Rows skipped by the last parse in lenient mode
+	droppedRecords       int           // Records outside of the date range in the last conversion
+	writtenRecords       int           // Records written by the last conversion
+}
+
+// FormatOptions are conversion options which only apply to some source formats.
+// Parsers of other formats ignore them.
+type FormatOptions struct {
+	SkipSecurityTrades bool     // TradeRepublic: skip buying and selling of securities
+	OwnAccounts        []string // Bunq: IBANs of own accounts, transfers between them get the payment "bank transfer"
+	GnuCashAccount     string   // GnuCash: "Full Account Name" of the imported bank account
+	OutbankAccount     string   // Outbank: only records of this "Account" are converted, all if empty
+
+	// Comdirect, DKB, Volksbank: payment codes by transaction type, they override the
+	// defaults returned by GetDefaultPaymentTypes
+	PaymentTypes PaymentTypes
+}
+
+// SortOrder is the order of the converted records
+type SortOrder int
+
+const (
+	SortNone     SortOrder = iota // Keep the order of the source file
+	SortDateAsc                   // Oldest record first
+	SortDateDesc                  // Newest record first
+)
+
+// sortOrders is the mapping between SortOrder and its textual representation
+var sortOrders = map[SortOrder]string{
+	SortNone:     "none",
+	SortDateAsc:  "date-asc",
+	SortDateDesc: "date-desc",
+}
+
+// Returns the textual representation of the sort order
+func (o SortOrder) String() string {
+	if s, ok := sortOrders[o]; ok {
+		return s
+	}
+	return "unknown sort order"
+}
+
+// MarshalText returns the textual representation of the sort order, e.g. for YAML.
+// An error is returned for unsupported values.
+func (o SortOrder) MarshalText() ([]byte, error) {
+	if _, ok := sortOrders[o]; !ok {
+		return nil, fmt.Errorf("unsupported sort order %d", int(o))
+	}
+	return []byte(sortOrders[o]), nil
+}
+
+// UnmarshalText sets the sort order from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (o *SortOrder) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range sortOrders {
+		if strings.EqualFold(value, textString) {
+			*o = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported sort order '%s', valid values are: none, date-asc, date-desc", string(text))
+}
+
+// SetDateRange sets the range of dates to be converted.
+func (c *converter) SetDateRange(r DateRange) {
+	c.dateRange = r
+}
+
+// SetCategoryPrefix sets a prefix which is prepended to the categories of the
+// converted records, separated by ":". If always is set, records without a
+// category get the prefix as category.
+func (c *converter) SetCategoryPrefix(prefix string, always bool) {
+	c.categoryPrefix = prefix
+	c.categoryPrefixAlways = always
+}
+
+// SetFieldRouting sets whether the content of the "info" field is moved into
+// the "memo" field or vice versa. Both options together are rejected by
+// CheckFieldRouting.
+func (c *converter) SetFieldRouting(infoToMemo, memoToInfo bool) {
+	c.routeInfoToMemo = infoToMemo
+	c.routeMemoToInfo = memoToInfo
+}
+
+// SetUnicodeNormalization sets whether the text fields of the converted records
+// are normalized to the Unicode normalization form NFC. It is enabled by default.
+func (c *converter) SetUnicodeNormalization(enabled bool) {
+	c.noNormalization = !enabled
+}
+
+// SetParseOptions sets the limits and the lenient mode used for parsing the input file.
+func (c *converter) SetParseOptions(o ParseOptions) {
+	c.parseOptions = o
+}
+
+// GetRowErrors returns the errors of the data rows skipped in lenient mode.
+func (c *converter) GetRowErrors() []ParserError {
+	return c.rowErrors
+}
+
+// skipRow returns true if the data row at line with an invalid field is to be
+// skipped. err is the optional underlying error. In lenient mode the error is
+// recorded, otherwise the caller has to fail with the error.
+func (c *converter) skipRow(line int, field string, err error) bool {
+	return c.skipRowError(&ParserError{
+		ErrorType: DataParsingError,
+		Line:      line,
+		Field:     field,
+		Err:       err,
+	})
+}
+
+// skipRowError is like skipRow for an error returned by parsing a row.
+// Only data parsing errors are skipped.
+func (c *converter) skipRowError(err error) bool {
+	var parserErr *ParserError
+	if !c.parseOptions.Lenient || !errors.As(err, &parserErr) || parserErr.ErrorType != DataParsingError {
+		return false
+	}
+	c.rowErrors = append(c.rowErrors, *parserErr)
+	if logger := c.parseOptions.Logger; logger != nil {
+		logger.Debug("skipped row", "line", parserErr.Line, "field", parserErr.Field, "error", parserErr.Err)
+	}
+	return true
+}
+
+// SetFormatOptions sets the options which only apply to some source formats.
+func (c *converter) SetFormatOptions(o FormatOptions) {
+	c.formatOptions = o
+}
+
+// SetSortOrder sets the order of the converted records. The sorting is stable,
+// records of the same date keep their order of the source file.
+func (c *converter) SetSortOrder(o SortOrder) {
+	c.sortOrder = o
+}
+
+// SetOutputOptions sets the delimiter, decimal separator, header and date format of
+// the written homebank CSV file.
+func (c *converter) SetOutputOptions(o OutputOptions) {
+	c.outputOptions = o
+}
+
+// SetRules sets the rules which map the converted records to new field values.
+// nil disables the mapping.
+func (c *converter) SetRules(r *Rules) {
+	c.rules = r
+}
+
+// processRecords applies the common conversion steps to the records of a parser
+// of the given format before they are written.
+//
+// The rules are applied to the filtered and sorted records before the fields are
+// routed, so they match the fields as read from the file. The values they set are
+// prefixed and normalized like the converted ones.
+func (c *converter) processRecords(records []homebankRecord, format SourceFormat) []homebankRecord {
+	records = c.filterRecords(records)
+	c.sortRecords(records)
+	c.rules.apply(records, format)
+	c.routeFields(records)
+	c.prefixCategories(records)
+	c.normalizeRecords(records)
+	c.writtenRecords = len(records)
+	return records
+}
+
+// filterRecords returns the records whose date lies within the date range.
+// The number of the other records is kept for GetNumberOfDroppedEntries.
+func (c *converter) filterRecords(records []homebankRecord) []homebankRecord {
+	c.droppedRecords = 0
+	if c.dateRange.IsZero() {
+		return records
+	}
+	filtered := make([]homebankRecord, 0, len(records))
+	for _, rec := range records {
+		if c.dateRange.containsDate(rec.date) {
+			filtered = append(filtered, rec)
+		}
+	}
+	c.droppedRecords = len(records) - len(filtered)
+	return filtered
+}
+
+// GetNumberOfDroppedEntries returns the number of entries of the last conversion
+// which were not written because their date lies outside of the date range.
+func (c *converter) GetNumberOfDroppedEntries() int {
+	return c.droppedRecords
+}
+
+// GetNumberOfWrittenEntries returns the number of entries written by the last conversion.
+func (c *converter) GetNumberOfWrittenEntries() int {
+	return c.writtenRecords
+}
+
+// sortRecords sorts the records by date according to the sort order.
+// As the date is an ISO 8601 string, a string comparison is sufficient.
+func (c *converter) sortRecords(records []homebankRecord) {
+	switch c.sortOrder {
+	case SortDateAsc:
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].date < records[j].date
+		})
+	case SortDateDesc:
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].date > records[j].date
+		})
+	}
+}
+
+// routeFields moves the content of "info" into "memo" or vice versa.
+// If the target field is not empty, the moved content is prepended.
+func (c *converter) routeFields(records []homebankRecord) {
+	for i := range records {
+		switch {
+		case c.routeInfoToMemo:
+			records[i].memo = joinNonEmpty(records[i].info, records[i].memo)
+			records[i].info = ""
+		case c.routeMemoToInfo:
+			records[i].info = joinNonEmpty(records[i].memo, records[i].info)
+			records[i].memo = ""
+		}
+	}
+}
+
+// joinNonEmpty joins the non-empty strings separated by a space
+func joinNonEmpty(elems ...string) string {
+	nonEmpty := make([]string, 0, len(elems))
+	for _, e := range elems {
+		if e != "" {
+			nonEmpty = append(nonEmpty, e)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+// prefixCategories prepends the category prefix to the category of each record.
+func (c *converter) prefixCategories(records []homebankRecord) {
+	if c.categoryPrefix == "" {
+		return
+	}
+	for i := range records {
+		if records[i].category != "" {
+			records[i].category = c.categoryPrefix + ":" + records[i].category
+		} else if c.categoryPrefixAlways {
+			records[i].category = c.categoryPrefix
+		}
+	}
+}
+
+// normalizeRecords normalizes all text fields to NFC.
+//
+// Some banks deliver decomposed umlauts like "u" followed by a combining
+// diaeresis. Homebank compares the bytes, so the same payee would show up twice.
+func (c *converter) normalizeRecords(records []homebankRecord) {
+	if c.noNormalization {
+		return
+	}
+	for i := range records {
+		r := &records[i]
+		r.info = norm.NFC.String(r.info)
+		r.payee = norm.NFC.String(r.payee)
+		r.memo = norm.NFC.String(r.memo)
+		r.category = norm.NFC.String(r.category)
+		r.tags = norm.NFC.String(r.tags)
+	}
+}
+
+// CheckCategoryPrefix reports whether a category prefix can be used.
+//
+// Possible errors:
+//
+//   - prefix contains the delimiter ";" of the homebank CSV file
+func CheckCategoryPrefix(prefix string) error {
+	if strings.Contains(prefix, ";") {
+		return errors.New("category prefix must not contain ';'")
+	}
+	return nil
+}
+
+// CheckFieldRouting reports whether the field routing options can be used together.
+//
+// Possible errors:
+//
+//   - both infoToMemo and memoToInfo are set
+func CheckFieldRouting(infoToMemo, memoToInfo bool) error {
+	if infoToMemo && memoToInfo {
+		return errors.New("routing info to memo and memo to info cannot be combined")
+	}
+	return nil
+}
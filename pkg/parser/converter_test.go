@@ -0,0 +1,383 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrefixCategories(t *testing.T) {
+	records := []homebankRecord{
+		{category: "Essen"},
+		{category: ""},
+	}
+
+	var c converter
+	c.prefixCategories(records)
+	if records[0].category != "Essen" || records[1].category != "" {
+		t.Errorf("Empty prefix should not change categories, got %+v", records)
+	}
+
+	c.SetCategoryPrefix("Import:Cash", false)
+	c.prefixCategories(records)
+	if records[0].category != "Import:Cash:Essen" {
+		t.Errorf("Expected 'Import:Cash:Essen', got '%s'", records[0].category)
+	}
+	if records[1].category != "" {
+		t.Errorf("Expected empty category, got '%s'", records[1].category)
+	}
+
+	records[0].category = "Essen"
+	c.SetCategoryPrefix("Import:Cash", true)
+	c.prefixCategories(records)
+	if records[0].category != "Import:Cash:Essen" {
+		t.Errorf("Expected 'Import:Cash:Essen', got '%s'", records[0].category)
+	}
+	if records[1].category != "Import:Cash" {
+		t.Errorf("Expected 'Import:Cash', got '%s'", records[1].category)
+	}
+}
+
+func TestCheckCategoryPrefix(t *testing.T) {
+	for _, prefix := range []string{"", "Import", "Import:DKB", "Bank Import"} {
+		if err := CheckCategoryPrefix(prefix); err != nil {
+			t.Errorf("Prefix '%s' should be valid, got '%s'", prefix, err)
+		}
+	}
+	if err := CheckCategoryPrefix("Import;DKB"); err == nil {
+		t.Error("Prefix containing ';' should be invalid")
+	}
+}
+
+func TestConvertToHomebankWithCategoryPrefix(t *testing.T) {
+	fpath := filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_1.csv")
+	p := &moneywalletParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	p.SetCategoryPrefix("Import:MoneyWallet", false)
+
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(tmpFilepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected header and 6 records, got %d lines", len(lines))
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ";")
+		if !strings.HasPrefix(fields[6], "Import:MoneyWallet:") {
+			t.Errorf("Expected prefixed category, got '%s'", fields[6])
+		}
+	}
+	if !strings.Contains(lines[1], ";Import:MoneyWallet:Einkäufe;") {
+		t.Errorf("Expected category 'Import:MoneyWallet:Einkäufe' in '%s'", lines[1])
+	}
+}
+
+func TestRouteFields(t *testing.T) {
+	newRecords := func() []homebankRecord {
+		return []homebankRecord{
+			{info: "Info", memo: "Memo"},
+			{info: "Info"},
+			{memo: "Memo"},
+		}
+	}
+
+	var c converter
+	records := newRecords()
+	c.routeFields(records)
+	if !reflect.DeepEqual(records, newRecords()) {
+		t.Errorf("Records should be unchanged by default, got %+v", records)
+	}
+
+	c.SetFieldRouting(true, false)
+	records = newRecords()
+	c.routeFields(records)
+	expected := []homebankRecord{
+		{memo: "Info Memo"},
+		{memo: "Info"},
+		{memo: "Memo"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, records)
+	}
+
+	c.SetFieldRouting(false, true)
+	records = newRecords()
+	c.routeFields(records)
+	expected = []homebankRecord{
+		{info: "Memo Info"},
+		{info: "Info"},
+		{info: "Memo"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, records)
+	}
+}
+
+func TestCheckFieldRouting(t *testing.T) {
+	if err := CheckFieldRouting(false, false); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if err := CheckFieldRouting(true, false); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if err := CheckFieldRouting(false, true); err != nil {
+		t.Errorf("No error expected, got '%s'", err)
+	}
+	if err := CheckFieldRouting(true, true); err == nil {
+		t.Error("Expected error when both options are set")
+	}
+}
+
+func TestConvertToHomebankWithFieldRouting(t *testing.T) {
+	testCases := []struct {
+		infoToMemo bool
+		expected   string
+	}{
+		{false, "converted_1.csv"},
+		{true, "converted_1_infotomemo.csv"},
+	}
+
+	for _, tc := range testCases {
+		fpath := filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_1.csv")
+		p := &moneywalletParser{}
+		if err := p.ParseFile(fpath); err != nil {
+			t.Fatal(err)
+		}
+		p.SetFieldRouting(tc.infoToMemo, false)
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := filepath.Join("testfiles", "moneywallet", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
+
+func TestNormalizeRecords(t *testing.T) {
+	nfd := "Mu\u0308ller"
+	nfc := "M\u00fcller"
+	newRecords := func() []homebankRecord {
+		return []homebankRecord{{info: nfd, payee: nfd, memo: nfd, category: nfd, tags: nfd}}
+	}
+
+	var c converter
+	records := newRecords()
+	c.normalizeRecords(records)
+	expected := []homebankRecord{{info: nfc, payee: nfc, memo: nfc, category: nfc, tags: nfc}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, records)
+	}
+
+	c.SetUnicodeNormalization(false)
+	records = newRecords()
+	c.normalizeRecords(records)
+	if !reflect.DeepEqual(records, newRecords()) {
+		t.Errorf("Records should be unchanged with normalization disabled, got %+v", records)
+	}
+}
+
+func TestConvertToHomebankUnicodeNormalization(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_nfd.csv")
+	p := &wiseParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payees only differ in their Unicode representation
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, wRecord := range p.entries {
+		hRecords = append(hRecords, wRecord.convertRecord())
+	}
+	if len(hRecords) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(hRecords))
+	}
+	if hRecords[0].recordID() == hRecords[1].recordID() {
+		t.Error("Records should differ before normalization")
+	}
+	hRecords = p.processRecords(hRecords, p.GetFormat())
+	if hRecords[0].recordID() != hRecords[1].recordID() {
+		t.Errorf("Expected same record ID after normalization, got %+v and %+v", hRecords[0], hRecords[1])
+	}
+
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join("testfiles", "wise", "homebank_nfd.csv")
+	content, err := os.ReadFile(tmpFilepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedContent, err := os.ReadFile(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Compared byte by byte, areFilesEqual would hide differences in line endings only
+	if !bytes.Equal(content, expectedContent) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
+
+func TestSkipRow(t *testing.T) {
+	var c converter
+	if c.skipRow(2, "Date", nil) {
+		t.Error("Expected no skipping in strict mode")
+	}
+	if len(c.GetRowErrors()) != 0 {
+		t.Errorf("Expected no row errors, got %v", c.GetRowErrors())
+	}
+
+	c.SetParseOptions(ParseOptions{Lenient: true})
+	if !c.skipRow(2, "Date", nil) {
+		t.Error("Expected skipping in lenient mode")
+	}
+	if c.skipRowError(&ParserError{ErrorType: HeaderError, Line: 1}) {
+		t.Error("Expected header errors not to be skipped")
+	}
+	if c.skipRowError(errors.New("other")) {
+		t.Error("Expected other errors not to be skipped")
+	}
+	expected := []ParserError{{ErrorType: DataParsingError, Line: 2, Field: "Date"}}
+	if !reflect.DeepEqual(c.GetRowErrors(), expected) {
+		t.Errorf("Expected %v, got %v", expected, c.GetRowErrors())
+	}
+}
+
+func TestParseLenientKeepsValidRows(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Invalid amount in the third line
+	content = bytes.Replace(content, []byte(";600;EUR;"), []byte(";6x0;EUR;"), 1)
+
+	p := &volksbankParser{}
+	if err := p.Parse(bytes.NewReader(content)); err == nil {
+		t.Error("Expected error in strict mode")
+	}
+
+	p.SetParseOptions(ParseOptions{Lenient: true})
+	if err := p.Parse(bytes.NewReader(content)); err != nil {
+		t.Fatalf("Expected no error in lenient mode, got %v", err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+	rowErrors := p.GetRowErrors()
+	if len(rowErrors) != 1 || rowErrors[0].Line != 3 || rowErrors[0].Field != "Betrag" || rowErrors[0].Err == nil {
+		t.Errorf("Expected error in line 3 and field 'Betrag', got %v", rowErrors)
+	}
+
+	// Errors of a previous run are reset
+	p.SetParseOptions(ParseOptions{})
+	if err := p.ParseFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.GetRowErrors()) != 0 {
+		t.Errorf("Expected no row errors, got %v", p.GetRowErrors())
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	newRecords := func() []homebankRecord {
+		return []homebankRecord{
+			{date: "2023-10-02", memo: "a"},
+			{date: "2023-10-01", memo: "b"},
+			{date: "2023-10-02", memo: "c"},
+			{date: "2023-10-03", memo: "d"},
+			{date: "2023-10-01", memo: "e"},
+			{date: "2023-10-02", memo: "f"},
+		}
+	}
+	// Records of the same date keep their relative order
+	testCases := []struct {
+		order    SortOrder
+		expected string
+	}{
+		{SortNone, "abcdef"},
+		{SortDateAsc, "beacfd"},
+		{SortDateDesc, "dacfbe"},
+	}
+	for _, tc := range testCases {
+		c := converter{sortOrder: tc.order}
+		records := newRecords()
+		c.sortRecords(records)
+		var memos strings.Builder
+		for _, r := range records {
+			memos.WriteString(r.memo)
+		}
+		if memos.String() != tc.expected {
+			t.Errorf("%s: expected order %s, got %s", tc.order, tc.expected, memos.String())
+		}
+	}
+}
+
+func TestSortOrderUnmarshalText(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected SortOrder
+		isValid  bool
+	}{
+		{"none", SortNone, true},
+		{"date-asc", SortDateAsc, true},
+		{" Date-Desc ", SortDateDesc, true},
+		{"date", SortNone, false},
+		{"", SortNone, false},
+	}
+	for _, tc := range testCases {
+		var o SortOrder
+		err := o.UnmarshalText([]byte(tc.text))
+		if tc.isValid {
+			if err != nil {
+				t.Errorf("%q: expected no error, got %v", tc.text, err)
+			}
+			if o != tc.expected {
+				t.Errorf("%q: expected %s, got %s", tc.text, tc.expected, o)
+			}
+		} else if err == nil {
+			t.Errorf("%q: expected error", tc.text)
+		}
+	}
+	if s := SortOrder(42).String(); s != "unknown sort order" {
+		t.Errorf("Unexpected string '%s'", s)
+	}
+}
+
+func TestGetEntriesSorted(t *testing.T) {
+	p := GetParser(Volksbank)
+	if err := p.ParseFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")); err != nil {
+		t.Fatal(err)
+	}
+	p.SetSortOrder(SortDateAsc)
+	var memos []string
+	for _, e := range p.GetEntries() {
+		memos = append(memos, e.Memo)
+	}
+	// Both records of 2023-09-29 keep their order of the source file
+	expected := []string{
+		"Verwendungszweck ghijkl mnop, ,x",
+		"Abschluss per 30.09.2023",
+		"Verwendungszweck xyz",
+		"Verwendungszweck abc",
+	}
+	if !reflect.DeepEqual(memos, expected) {
+		t.Errorf("Expected %v, got %v", expected, memos)
+	}
+}
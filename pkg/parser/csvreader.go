@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Default limits for parsing a single file, see ParseOptions
+const (
+	DefaultMaxFieldsPerRecord = 1000
+	DefaultMaxLineBytes       = 64 * 1024
+)
+
+// ParseOptions limits the resources used for parsing a single file.
+//
+// A corrupted file may contain a single line with an enormous number of fields.
+// The limits make the parser fail early instead of allocating memory for it.
+// Zero or negative values are replaced by the defaults.
+//
+// In lenient mode data rows which can't be parsed, e.g. because of an invalid date
+// or amount, are skipped instead of failing the whole file. The errors of the
+// skipped rows are returned by GetRowErrors. Errors in the header or violations of
+// the limits still fail the file.
+type ParseOptions struct {
+	MaxFieldsPerRecord int  // Maximum number of fields in a single record
+	MaxLineBytes       int  // Maximum number of bytes in a single line
+	Lenient            bool // Skip data rows which can't be parsed
+
+	// Encoding of the input file, by default it is detected. Not used by xlsx files.
+	Encoding Encoding
+
+	// Comdirect: take the date of rows with an empty "Buchungstag" from "Wertstellung (Valuta)"
+	ComdirectValutaFallback bool
+
+	// Logger for debug messages, e.g. about the rows skipped in lenient mode and the
+	// formats tried by GuessParser. Nil disables logging.
+	Logger *slog.Logger
+}
+
+// withDefaults returns the options with unset limits replaced by the defaults
+func (o ParseOptions) withDefaults() ParseOptions {
+	if o.MaxFieldsPerRecord <= 0 {
+		o.MaxFieldsPerRecord = DefaultMaxFieldsPerRecord
+	}
+	if o.MaxLineBytes <= 0 {
+		o.MaxLineBytes = DefaultMaxLineBytes
+	}
+	return o
+}
+
+// errLineTooLong is returned by lineLimitReader when a line exceeds the limit
+var errLineTooLong = errors.New("line too long")
+
+// errLimitExceeded is wrapped by the DataParsingError of a violation of the limits
+var errLimitExceeded = errors.New("limit exceeded")
+
+// isLimitError reports whether e is the DataParsingError of a violation of the limits of
+// the parse options. It has the field "record" like the error of a row with too few fields.
+func isLimitError(e *ParserError) bool {
+	return e.ErrorType == DataParsingError && e.Field == "record" && errors.Is(e.Err, errLimitExceeded)
+}
+
+// lineLimitReader fails with errLineTooLong as soon as a line exceeds maxLineBytes.
+// Once the limit is exceeded all further reads fail.
+type lineLimitReader struct {
+	r            io.Reader
+	maxLineBytes int
+	line         int // Current line number, 1 based
+	lineBytes    int // Number of bytes read in the current line
+	exceeded     bool
+}
+
+func (l *lineLimitReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errLineTooLong
+	}
+	n, err := l.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.line++
+			l.lineBytes = 0
+			continue
+		}
+		l.lineBytes++
+		if l.lineBytes > l.maxLineBytes {
+			l.exceeded = true
+			return i, errLineTooLong
+		}
+	}
+	return n, err
+}
+
+// limitedCSVReader is a csv.Reader which enforces the limits of ParseOptions.
+// The embedded csv.Reader can be configured as usual.
+type limitedCSVReader struct {
+	*csv.Reader
+	limiter            *lineLimitReader
+	maxFieldsPerRecord int
+	lines              []int // Line numbers of the records returned by ReadAll
+}
+
+// newCSVReader returns a limitedCSVReader reading from r with the limits of the parse options.
+// The input is decoded to UTF-8 according to the encoding of the parse options.
+func (c *converter) newCSVReader(r io.Reader) *limitedCSVReader {
+	opts := c.parseOptions.withDefaults()
+	limiter := &lineLimitReader{r: newDecodingReader(r, opts.Encoding), maxLineBytes: opts.MaxLineBytes, line: 1}
+	return &limitedCSVReader{
+		Reader:             csv.NewReader(limiter),
+		limiter:            limiter,
+		maxFieldsPerRecord: opts.MaxFieldsPerRecord,
+	}
+}
+
+// ReadAll reads all remaining records like csv.Reader.ReadAll.
+//
+// Violations of the limits result in a ParserError of type DataParsingError
+// with field "record", see isLimitError. Malformed CSV, e.g. an unbalanced quote,
+// results in a ParserError of type FormatError with the position of the error, all
+// other errors in a ParserError of type IOError.
+func (r *limitedCSVReader) ReadAll() ([][]string, error) {
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if r.limiter.exceeded {
+			return nil, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      r.limiter.line,
+				Field:     "record",
+				Err:       fmt.Errorf("%w: line longer than %d bytes", errLimitExceeded, r.limiter.maxLineBytes),
+			}
+		}
+		// Checked before err as the record is returned together with csv.ErrFieldCount
+		if len(record) > r.maxFieldsPerRecord {
+			line, _ := r.FieldPos(0)
+			return nil, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      line,
+				Field:     "record",
+				Err:       fmt.Errorf("%w: more than %d fields", errLimitExceeded, r.maxFieldsPerRecord),
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &ParserError{
+				ErrorType: FormatError,
+				Line:      parseErr.Line,
+				Column:    parseErr.Column,
+				Err:       parseErr,
+			}
+		}
+		if err != nil {
+			return nil, &ParserError{ErrorType: IOError, Err: err}
+		}
+		line, _ := r.FieldPos(0)
+		r.lines = append(r.lines, line)
+		records = append(records, record)
+	}
+}
+
+// recordLine returns the line number where the record with the given index
+// returned by ReadAll starts. Empty lines skipped by the csv reader are counted.
+func (r *limitedCSVReader) recordLine(index int) int {
+	return r.lines[index]
+}
+
+// checkFieldCount returns a DataParsingError for the field "record" if the row at
+// line has fewer than n fields, e.g. a summary line or the last line of a truncated file.
+func checkFieldCount(row []string, n int, line int) error {
+	if len(row) >= n {
+		return nil
+	}
+	return &ParserError{
+		ErrorType: DataParsingError,
+		Line:      line,
+		Field:     "record",
+		Err:       fmt.Errorf("expected %d fields, got %d", n, len(row)),
+	}
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePathologicalFile writes a volksbank like file whose second line consists of n semicolons
+func writePathologicalFile(t *testing.T, n int) string {
+	t.Helper()
+	header := "Bezeichnung Auftragskonto;IBAN Auftragskonto;BIC Auftragskonto;Bankname Auftragskonto;" +
+		"Buchungstag;Valutadatum;Name Zahlungsbeteiligter;IBAN Zahlungsbeteiligter;BIC (SWIFT-Code) Zahlungsbeteiligter;" +
+		"Buchungstext;Verwendungszweck;Betrag;Waehrung;Saldo nach Buchung;Bemerkung;Kategorie;Steuerrelevant;" +
+		"Glaeubiger ID;Mandatsreferenz\n"
+	fpath := filepath.Join(t.TempDir(), "pathological.csv")
+	content := header + strings.Repeat(";", n) + "\n"
+	if err := os.WriteFile(fpath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return fpath
+}
+
+func assertRecordError(t *testing.T, err error, line int) {
+	t.Helper()
+	var pError *ParserError
+	if !errors.As(err, &pError) {
+		t.Fatalf("ParserError expected, got '%v'", err)
+	}
+	if pError.ErrorType != DataParsingError {
+		t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+	}
+	if pError.Field != "record" {
+		t.Errorf("Expected field 'record', got '%s' instead", pError.Field)
+	}
+	if pError.Line != line {
+		t.Errorf("Expected line %d, got %d", line, pError.Line)
+	}
+}
+
+// assertLimitError checks for the DataParsingError of a violation of the limits
+func assertLimitError(t *testing.T, err error, line int) {
+	t.Helper()
+	assertRecordError(t, err, line)
+	var pError *ParserError
+	if errors.As(err, &pError) && !isLimitError(pError) {
+		t.Errorf("Expected violation of the limits, got '%v'", err)
+	}
+}
+
+func TestParseFileTooManyFields(t *testing.T) {
+	fpath := writePathologicalFile(t, 2*DefaultMaxFieldsPerRecord)
+	p := &volksbankParser{}
+	assertLimitError(t, p.ParseFile(fpath), 2)
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestParseFileLineTooLong(t *testing.T) {
+	fpath := writePathologicalFile(t, 10*DefaultMaxLineBytes)
+	p := &volksbankParser{}
+	assertLimitError(t, p.ParseFile(fpath), 2)
+}
+
+func TestParseFileLineTooLongBoundedMemory(t *testing.T) {
+	fpath := writePathologicalFile(t, 2_000_000)
+	p := &volksbankParser{}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	err := p.ParseFile(fpath)
+	runtime.ReadMemStats(&after)
+
+	assertLimitError(t, err, 2)
+	// Without limit csv.Reader allocates several hundred MiB for this line,
+	// with limit the allocation only depends on DefaultMaxLineBytes
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 32*1024*1024 {
+		t.Errorf("Expected less than 32 MiB allocated, got %d bytes", allocated)
+	}
+}
+
+func TestParseFileCustomParseOptions(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+
+	p := &volksbankParser{}
+	p.SetParseOptions(ParseOptions{MaxFieldsPerRecord: 5})
+	assertLimitError(t, p.ParseFile(fpath), 1)
+
+	p.SetParseOptions(ParseOptions{MaxLineBytes: 100})
+	assertLimitError(t, p.ParseFile(fpath), 1)
+
+	// Generous limits do not change the result
+	p.SetParseOptions(ParseOptions{MaxFieldsPerRecord: 20, MaxLineBytes: 1024})
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestParseOptionsWithDefaults(t *testing.T) {
+	o := ParseOptions{}.withDefaults()
+	if o.MaxFieldsPerRecord != DefaultMaxFieldsPerRecord || o.MaxLineBytes != DefaultMaxLineBytes {
+		t.Errorf("Expected defaults, got %+v", o)
+	}
+	o = ParseOptions{MaxFieldsPerRecord: 3, MaxLineBytes: -1}.withDefaults()
+	if o.MaxFieldsPerRecord != 3 || o.MaxLineBytes != DefaultMaxLineBytes {
+		t.Errorf("Unexpected options %+v", o)
+	}
+}
+
+func TestParseFileShortRow(t *testing.T) {
+	testCases := []struct {
+		format  SourceFormat
+		input   string
+		line    int
+		entries int // Number of entries in lenient mode
+	}{
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_nok_shortrow.csv"), 6, 4},
+		{MoneyWallet, filepath.Join("moneywallet", "MoneyWallet_nok_shortrow.csv"), 8, 6},
+		{DKB, filepath.Join("dkb", "dkb_nok_shortrow.csv"), 9, 2},
+	}
+	for _, tc := range testCases {
+		fpath := filepath.Join("testfiles", tc.input)
+		p := GetParser(tc.format)
+		err := p.ParseFile(fpath)
+		assertRecordError(t, err, tc.line)
+		var pError *ParserError
+		if errors.As(err, &pError) && isLimitError(pError) {
+			t.Errorf("%s: expected no violation of the limits, got %v", tc.input, err)
+		}
+
+		p.SetParseOptions(ParseOptions{Lenient: true})
+		if err := p.ParseFile(fpath); err != nil {
+			t.Errorf("%s: %v", tc.input, err)
+			continue
+		}
+		if p.GetNumberOfEntries() != tc.entries {
+			t.Errorf("%s: expected %d entries, got %d", tc.input, tc.entries, p.GetNumberOfEntries())
+		}
+		if len(p.GetRowErrors()) != 1 {
+			t.Errorf("%s: expected 1 row error, got %v", tc.input, p.GetRowErrors())
+		}
+	}
+}
+
+func TestCheckFieldCount(t *testing.T) {
+	if err := checkFieldCount([]string{"a", "b"}, 2, 3); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	assertRecordError(t, checkFieldCount([]string{"a"}, 2, 3), 3)
+}
+
+func TestParseFileMalformedCSV(t *testing.T) {
+	fpath := filepath.Join("testfiles", "moneywallet", "MoneyWallet_nok_unbalancedquote.csv")
+	err := GetParser(MoneyWallet).ParseFile(fpath)
+	var pError *ParserError
+	if !errors.As(err, &pError) {
+		t.Fatalf("ParserError expected, got '%v'", err)
+	}
+	if pError.ErrorType != FormatError {
+		t.Errorf("FormatError expected, got '%s' instead", pError.ErrorType)
+	}
+	if pError.Line != 3 || pError.Column != 61 {
+		t.Errorf("Expected line 3, column 61, got line %d, column %d", pError.Line, pError.Column)
+	}
+	if !errors.Is(err, csv.ErrBareQuote) {
+		t.Errorf("Expected wrapped csv.ErrBareQuote, got %v", pError.Err)
+	}
+	if !strings.Contains(err.Error(), "FormatError") || !strings.Contains(err.Error(), "in line 3 in column 61") {
+		t.Errorf("Unexpected error message '%s'", err.Error())
+	}
+}
@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OutputOptions are the options of the written homebank CSV file. The zero value writes
+// the format homebank expects by default.
+type OutputOptions struct {
+	Delimiter        rune   // Field delimiter, ';' if zero
+	DecimalSeparator rune   // Decimal separator of the amount, '.' or ',', '.' if zero
+	NoHeader         bool   // Omit the header line
+	DateFormat       string // Layout of the date as used by time.Format, "2006-01-02" if empty
+}
+
+// ParseOutputOptions returns the output options given as text, e.g. in the config file
+// or on the command line. delimiter is a single character or "tab", decimalSeparator is
+// "." or ",", empty strings select the defaults. The options are checked with
+// CheckOutputOptions.
+func ParseOutputOptions(delimiter, decimalSeparator string, noHeader bool, dateFormat string) (OutputOptions, error) {
+	o := OutputOptions{NoHeader: noHeader, DateFormat: dateFormat}
+	var err error
+	if o.Delimiter, err = parseSeparator(delimiter); err != nil {
+		return OutputOptions{}, fmt.Errorf("delimiter %w", err)
+	}
+	if o.DecimalSeparator, err = parseSeparator(decimalSeparator); err != nil {
+		return OutputOptions{}, fmt.Errorf("decimal separator %w", err)
+	}
+	return o, CheckOutputOptions(o)
+}
+
+// parseSeparator returns the single character of s, a tab for "tab" and 0 for ""
+func parseSeparator(s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if strings.EqualFold(s, "tab") {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return 0, fmt.Errorf("'%s' is not a single character", s)
+	}
+	return r, nil
+}
+
+// CheckOutputOptions returns an error if the output options can't be written or would
+// result in an ambiguous file:
+//
+//   - the delimiter is a quote, a line break or the decimal separator
+//   - the decimal separator is neither '.' nor ','
+//   - the date format does not contain the year, month and day
+func CheckOutputOptions(o OutputOptions) error {
+	o = o.withDefaults()
+	if o.Delimiter == '"' || o.Delimiter == '\r' || o.Delimiter == '\n' ||
+		!utf8.ValidRune(o.Delimiter) || o.Delimiter == utf8.RuneError {
+		return fmt.Errorf("invalid delimiter %q", o.Delimiter)
+	}
+	if o.DecimalSeparator != '.' && o.DecimalSeparator != ',' {
+		return fmt.Errorf("invalid decimal separator %q, valid values are '.' and ','", o.DecimalSeparator)
+	}
+	if o.Delimiter == o.DecimalSeparator {
+		return errors.New("delimiter and decimal separator must differ")
+	}
+	// A date with a day above 12 detects a layout mixing up day and month
+	date := time.Date(2006, 1, 22, 0, 0, 0, 0, time.UTC)
+	if parsed, err := time.Parse(o.DateFormat, date.Format(o.DateFormat)); err != nil || !parsed.Equal(date) {
+		return fmt.Errorf("date format '%s' must contain the year, month and day, e.g. '02.01.2006'", o.DateFormat)
+	}
+	return nil
+}
+
+// withDefaults returns the options with the defaults for the unset fields
+func (o OutputOptions) withDefaults() OutputOptions {
+	if o.Delimiter == 0 {
+		o.Delimiter = ';'
+	}
+	if o.DecimalSeparator == 0 {
+		o.DecimalSeparator = '.'
+	}
+	if o.DateFormat == "" {
+		o.DateFormat = isoDate
+	}
+	return o
+}
+
+// WriteOption changes the output of WriteHomebankCSV
+type WriteOption func(*OutputOptions)
+
+// WithDelimiter sets the field delimiter, the default is ';'.
+// Homebank lets choose the delimiter on import.
+func WithDelimiter(delimiter rune) WriteOption {
+	return func(o *OutputOptions) {
+		o.Delimiter = delimiter
+	}
+}
+
+// WithoutHeader omits the header line, e.g. to append to an existing file
+func WithoutHeader() WriteOption {
+	return func(o *OutputOptions) {
+		o.NoHeader = true
+	}
+}
+
+// WithDecimalSeparator sets the decimal separator of the amount, '.' or ','. The
+// default is '.'.
+func WithDecimalSeparator(separator rune) WriteOption {
+	return func(o *OutputOptions) {
+		o.DecimalSeparator = separator
+	}
+}
+
+// WithDateFormat sets the layout of the date as used by time.Format, the default is
+// "2006-01-02". Homebank lets choose between year-month-day, month-day-year and
+// day-month-year on import.
+func WithDateFormat(layout string) WriteOption {
+	return func(o *OutputOptions) {
+		o.DateFormat = layout
+	}
+}
+
+// WithOutputOptions sets all output options at once
+func WithOutputOptions(options OutputOptions) WriteOption {
+	return func(o *OutputOptions) {
+		*o = options
+	}
+}
+
+// WriteHomebankCSV writes the transactions in the given order as homebank CSV.
+// Fields containing the delimiter, quotes or newlines are quoted.
+// See "Transaction import CSV format" under http://homebank.free.fr/help/misc-csvformat.html
+func WriteHomebankCSV(w io.Writer, records []Transaction, opts ...WriteOption) error {
+	var o OutputOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := CheckOutputOptions(o); err != nil {
+		return err
+	}
+	o = o.withDefaults()
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = o.Delimiter
+	if !o.NoHeader {
+		if err := csvWriter.Write(homebankHeader); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range records {
+		rec := t.homebankRecord()
+		amount := strconv.FormatFloat(roundCents(rec.amount), 'f', 2, 64)
+		if o.DecimalSeparator != '.' {
+			amount = strings.Replace(amount, ".", string(o.DecimalSeparator), 1)
+		}
+		err := csvWriter.Write([]string{
+			formatDate(rec.date, o.DateFormat),
+			strconv.Itoa(int(rec.payment)),
+			rec.info,
+			rec.payee,
+			rec.memo,
+			amount,
+			rec.category,
+			rec.tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// formatDate returns the ISO 8601 date in the layout, dates which can't be parsed
+// unchanged
+func formatDate(date string, layout string) string {
+	if layout == isoDate {
+		return date
+	}
+	t, err := time.Parse(isoDate, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(layout)
+}
+
+// writeHomeBankRecords writes a slice of homebankRecord as homebank CSV
+func writeHomeBankRecords(records []homebankRecord, w io.Writer) error {
+	return WriteHomebankCSV(w, toTransactions(records))
+}
+
+// roundCents rounds the amount half away from zero to whole cents.
+// A negative amount rounded to zero is returned as 0 to avoid "-0.00".
+func roundCents(amount float64) float64 {
+	rounded := math.Round(amount*100) / 100
+	if rounded == 0 {
+		return 0
+	}
+	return rounded
+}
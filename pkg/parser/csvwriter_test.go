@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteHomeBankRecords(t *testing.T) {
+	records := []homebankRecord{
+		{date: "2024-01-02", payment: 4, info: "Info", payee: "Payee", memo: "Memo", amount: -1.5, category: "Cat:Sub", tags: "a b"},
+		{date: "2024-01-03", info: "a;b", payee: "Payee \"Nickname\"", memo: "Miete Jan; Feb\nMärz", amount: 700},
+	}
+	var buf bytes.Buffer
+	if err := writeHomeBankRecords(records, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "date;payment;info;payee;memo;amount;category;tags\n" +
+		"2024-01-02;4;Info;Payee;Memo;-1.50;Cat:Sub;a b\n" +
+		"2024-01-03;0;\"a;b\";\"Payee \"\"Nickname\"\"\";\"Miete Jan; Feb\nMärz\";700.00;;\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	var h homebankParser
+	if err := h.Parse(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h.entries, records) {
+		t.Errorf("Expected %v, got %v", records, h.entries)
+	}
+}
+
+func TestRoundCents(t *testing.T) {
+	testCases := map[float64]float64{
+		0.1 + 0.2:  0.3,
+		10.299999:  10.3,
+		-139.4:     -139.4,
+		0.005:      0.01,
+		-0.005:     -0.01,
+		-0.001:     0,
+		1234.5678:  1234.57,
+		-1234.5678: -1234.57,
+	}
+	for amount, expected := range testCases {
+		if rounded := roundCents(amount); rounded != expected {
+			t.Errorf("%v: expected %v, got %v", amount, expected, rounded)
+		}
+	}
+	if s := strconv.FormatFloat(roundCents(-0.001), 'f', 2, 64); s != "0.00" {
+		t.Errorf("Expected '0.00', got '%s'", s)
+	}
+}
+
+func TestWriteHomebankCSV(t *testing.T) {
+	records := []Transaction{
+		{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Payee: "B", Amount: 700, Tags: []string{"x", "y"}},
+		{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Payment: 4, Info: "a,b", Memo: "Miete; Jan", Amount: -1.005},
+	}
+	testCases := []struct {
+		name     string
+		opts     []WriteOption
+		expected string
+	}{
+		{
+			"default",
+			nil,
+			"date;payment;info;payee;memo;amount;category;tags\n" +
+				"2024-01-03;0;;B;;700.00;;x y\n" +
+				"2024-01-02;4;a,b;;\"Miete; Jan\";-1.00;;\n",
+		},
+		{
+			"delimiter",
+			[]WriteOption{WithDelimiter(',')},
+			"date,payment,info,payee,memo,amount,category,tags\n" +
+				"2024-01-03,0,,B,,700.00,,x y\n" +
+				"2024-01-02,4,\"a,b\",,Miete; Jan,-1.00,,\n",
+		},
+		{
+			"without header",
+			[]WriteOption{WithoutHeader(), WithDelimiter('\t')},
+			"2024-01-03\t0\t\tB\t\t700.00\t\tx y\n" +
+				"2024-01-02\t4\ta,b\t\tMiete; Jan\t-1.00\t\t\n",
+		},
+		{
+			"decimal separator and date format",
+			[]WriteOption{WithDecimalSeparator(','), WithDateFormat("01/02/2006")},
+			"date;payment;info;payee;memo;amount;category;tags\n" +
+				"01/03/2024;0;;B;;700,00;;x y\n" +
+				"01/02/2024;4;a,b;;\"Miete; Jan\";-1,00;;\n",
+		},
+		{
+			"output options",
+			[]WriteOption{WithOutputOptions(OutputOptions{Delimiter: '\t', DecimalSeparator: ',', DateFormat: "02.01.2006"})},
+			"date\tpayment\tinfo\tpayee\tmemo\tamount\tcategory\ttags\n" +
+				"03.01.2024\t0\t\tB\t\t700,00\t\tx y\n" +
+				"02.01.2024\t4\ta,b\t\tMiete; Jan\t-1,00\t\t\n",
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := WriteHomebankCSV(&buf, records, tc.opts...); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tc.name, tc.expected, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHomebankCSV(&buf, records, WithDelimiter('"')); err == nil {
+		t.Error("Expected error for invalid delimiter")
+	}
+	if err := WriteHomebankCSV(&buf, records, WithDelimiter(','), WithDecimalSeparator(',')); err == nil {
+		t.Error("Expected error for delimiter equal to the decimal separator")
+	}
+	if err := WriteHomebankCSV(&buf, nil, WithoutHeader()); err != nil || buf.Len() != 0 {
+		t.Errorf("Expected empty output, got '%s' (%v)", buf.String(), err)
+	}
+}
+
+func TestParseOutputOptions(t *testing.T) {
+	validCases := []struct {
+		delimiter, decimalSeparator, dateFormat string
+		expected                                OutputOptions
+	}{
+		{"", "", "", OutputOptions{}},
+		{"tab", ",", "02.01.2006", OutputOptions{Delimiter: '\t', DecimalSeparator: ',', DateFormat: "02.01.2006"}},
+		{"TAB", ".", "", OutputOptions{Delimiter: '\t', DecimalSeparator: '.'}},
+		{"\t", "", "01/02/2006", OutputOptions{Delimiter: '\t', DateFormat: "01/02/2006"}},
+		{",", "", "2006-01-02", OutputOptions{Delimiter: ',', DateFormat: "2006-01-02"}},
+		{"|", ",", "", OutputOptions{Delimiter: '|', DecimalSeparator: ','}},
+	}
+	for _, tc := range validCases {
+		o, err := ParseOutputOptions(tc.delimiter, tc.decimalSeparator, false, tc.dateFormat)
+		if err != nil {
+			t.Errorf("%q %q %q: unexpected error: %v", tc.delimiter, tc.decimalSeparator, tc.dateFormat, err)
+		} else if o != tc.expected {
+			t.Errorf("%q %q %q: expected %+v, got %+v", tc.delimiter, tc.decimalSeparator, tc.dateFormat, tc.expected, o)
+		}
+	}
+
+	invalidCases := []struct {
+		delimiter, decimalSeparator, dateFormat string
+	}{
+		{";;", "", ""},
+		{"\"", "", ""},
+		{"\n", "", ""},
+		{"", ";", ""},
+		{"", "..", ""},
+		{",", ",", ""},
+		{".", "", ""},
+		{"", "", "2006-01"},
+		{"", "", "02.01."},
+		{"", "", "date"},
+	}
+	for _, tc := range invalidCases {
+		if _, err := ParseOutputOptions(tc.delimiter, tc.decimalSeparator, false, tc.dateFormat); err == nil {
+			t.Errorf("%q %q %q: expected error", tc.delimiter, tc.decimalSeparator, tc.dateFormat)
+		}
+	}
+
+	if o, err := ParseOutputOptions("", "", true, ""); err != nil || !o.NoHeader {
+		t.Errorf("Expected NoHeader, got %+v (%v)", o, err)
+	}
+}
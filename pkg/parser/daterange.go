@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"time"
+)
+
+// isoDate is the date layout used in the homebank CSV file
+const isoDate = "2006-01-02"
+
+// DateRange is an inclusive range of dates used to filter the converted records.
+//
+// Only the date part of From and To is taken into account. A zero From or To
+// leaves the respective end of the range open.
+type DateRange struct {
+	From time.Time // First day included in the range
+	To   time.Time // Last day included in the range
+}
+
+// IsZero reports whether the range is open on both ends, i.e. it does not filter anything.
+func (r DateRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains reports whether the date of t lies within the range.
+func (r DateRange) Contains(t time.Time) bool {
+	return r.containsDate(t.Format(isoDate))
+}
+
+// containsDate reports whether the given ISO 8601 date string lies within the range.
+// As the format has a fixed width, a string comparison is sufficient.
+func (r DateRange) containsDate(date string) bool {
+	if !r.From.IsZero() && date < r.From.Format(isoDate) {
+		return false
+	}
+	if !r.To.IsZero() && date > r.To.Format(isoDate) {
+		return false
+	}
+	return true
+}
+
+// Returns the textual representation of the date range
+func (r DateRange) String() string {
+	switch {
+	case r.IsZero():
+		return "all dates"
+	case r.To.IsZero():
+		return fmt.Sprintf("from %s", r.From.Format(isoDate))
+	case r.From.IsZero():
+		return fmt.Sprintf("until %s", r.To.Format(isoDate))
+	default:
+		return fmt.Sprintf("%s to %s", r.From.Format(isoDate), r.To.Format(isoDate))
+	}
+}
+
+// ParseDateRange returns the date range between the dates given as "YYYY-MM-DD".
+// An empty from or to leaves the respective end of the range open.
+func ParseDateRange(from, to string) (DateRange, error) {
+	var r DateRange
+	var err error
+	if from != "" {
+		if r.From, err = time.Parse(isoDate, from); err != nil {
+			return DateRange{}, fmt.Errorf("invalid date '%s', expected format YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		if r.To, err = time.Parse(isoDate, to); err != nil {
+			return DateRange{}, fmt.Errorf("invalid date '%s', expected format YYYY-MM-DD", to)
+		}
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return DateRange{}, fmt.Errorf("start date %s is after end date %s", from, to)
+	}
+	return r, nil
+}
+
+// MonthRange returns the date range covering the whole month given as "YYYY-MM".
+func MonthRange(month string) (DateRange, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return DateRange{}, fmt.Errorf("invalid month '%s', expected format YYYY-MM", month)
+	}
+	// Day 0 of the following month is the last day of the given month,
+	// time.Date normalizes leap years and the December rollover.
+	end := time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	return DateRange{From: start, To: end}, nil
+}
+
+// YearRange returns the date range covering the whole year given as "YYYY".
+func YearRange(year string) (DateRange, error) {
+	start, err := time.Parse("2006", year)
+	if err != nil {
+		return DateRange{}, fmt.Errorf("invalid year '%s', expected format YYYY", year)
+	}
+	end := time.Date(start.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+	return DateRange{From: start, To: end}, nil
+}
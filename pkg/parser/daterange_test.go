@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMonthRange(t *testing.T) {
+	testCases := []struct {
+		month    string
+		expected DateRange
+		isValid  bool
+	}{
+		{"2024-05", DateRange{From: date(2024, 5, 1), To: date(2024, 5, 31)}, true},
+		{"2024-04", DateRange{From: date(2024, 4, 1), To: date(2024, 4, 30)}, true},
+		{"2024-02", DateRange{From: date(2024, 2, 1), To: date(2024, 2, 29)}, true}, // leap year
+		{"2023-02", DateRange{From: date(2023, 2, 1), To: date(2023, 2, 28)}, true},
+		{"2000-02", DateRange{From: date(2000, 2, 1), To: date(2000, 2, 29)}, true}, // leap year, divisible by 400
+		{"1900-02", DateRange{From: date(1900, 2, 1), To: date(1900, 2, 28)}, true}, // no leap year, divisible by 100
+		{"2023-12", DateRange{From: date(2023, 12, 1), To: date(2023, 12, 31)}, true},
+		{"2024-01", DateRange{From: date(2024, 1, 1), To: date(2024, 1, 31)}, true},
+		{"2024-13", DateRange{}, false},
+		{"2024-00", DateRange{}, false},
+		{"2024-5", DateRange{}, false},
+		{"05-2024", DateRange{}, false},
+		{"2024-05-01", DateRange{}, false},
+		{"", DateRange{}, false},
+	}
+
+	for _, tc := range testCases {
+		r, err := MonthRange(tc.month)
+		if tc.isValid {
+			if err != nil {
+				t.Errorf("%s: expected no error, got: %v", tc.month, err)
+			}
+			if !r.From.Equal(tc.expected.From) || !r.To.Equal(tc.expected.To) {
+				t.Errorf("%s: expected %s, got %s", tc.month, tc.expected, r)
+			}
+		} else if err == nil {
+			t.Errorf("%s: expected error", tc.month)
+		}
+	}
+}
+
+func TestYearRange(t *testing.T) {
+	testCases := []struct {
+		year     string
+		expected DateRange
+		isValid  bool
+	}{
+		{"2024", DateRange{From: date(2024, 1, 1), To: date(2024, 12, 31)}, true},
+		{"1999", DateRange{From: date(1999, 1, 1), To: date(1999, 12, 31)}, true},
+		{"24", DateRange{}, false},
+		{"20245", DateRange{}, false},
+		{"2024-01", DateRange{}, false},
+		{"abcd", DateRange{}, false},
+		{"", DateRange{}, false},
+	}
+
+	for _, tc := range testCases {
+		r, err := YearRange(tc.year)
+		if tc.isValid {
+			if err != nil {
+				t.Errorf("%s: expected no error, got: %v", tc.year, err)
+			}
+			if !r.From.Equal(tc.expected.From) || !r.To.Equal(tc.expected.To) {
+				t.Errorf("%s: expected %s, got %s", tc.year, tc.expected, r)
+			}
+		} else if err == nil {
+			t.Errorf("%s: expected error", tc.year)
+		}
+	}
+}
+
+func TestDateRangeContains(t *testing.T) {
+	r := DateRange{From: date(2024, 5, 1), To: date(2024, 5, 31)}
+	testCases := []struct {
+		date     time.Time
+		expected bool
+	}{
+		{date(2024, 4, 30), false},
+		{date(2024, 5, 1), true},
+		{time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC), true},
+		{date(2024, 6, 1), false},
+	}
+	for _, tc := range testCases {
+		if r.Contains(tc.date) != tc.expected {
+			t.Errorf("%s: expected %t", tc.date, tc.expected)
+		}
+	}
+
+	if !(DateRange{}).Contains(date(1, 1, 1)) {
+		t.Error("Empty range should contain every date")
+	}
+	if (DateRange{From: date(2024, 5, 1)}).Contains(date(2024, 4, 30)) {
+		t.Error("Date before From should not be contained")
+	}
+	if !(DateRange{From: date(2024, 5, 1)}).Contains(date(2099, 1, 1)) {
+		t.Error("Range without To should be open ended")
+	}
+	if (DateRange{To: date(2024, 5, 31)}).Contains(date(2024, 6, 1)) {
+		t.Error("Date after To should not be contained")
+	}
+}
+
+func TestDateRangeString(t *testing.T) {
+	testCases := []struct {
+		r        DateRange
+		expected string
+	}{
+		{DateRange{}, "all dates"},
+		{DateRange{From: date(2024, 5, 1)}, "from 2024-05-01"},
+		{DateRange{To: date(2024, 5, 31)}, "until 2024-05-31"},
+		{DateRange{From: date(2024, 5, 1), To: date(2024, 5, 31)}, "2024-05-01 to 2024-05-31"},
+	}
+	for _, tc := range testCases {
+		if tc.r.String() != tc.expected {
+			t.Errorf("Expected '%s', got '%s'", tc.expected, tc.r.String())
+		}
+	}
+}
+
+func TestConvertToHomebankWithDateRange(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	v := &volksbankParser{}
+	if err := v.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	r, err := MonthRange("2023-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SetDateRange(r)
+
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := v.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(tmpFilepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	// header plus the two records from October
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	for _, line := range lines[1:] {
+		if !strings.HasPrefix(line, "2023-10-") {
+			t.Errorf("Unexpected record '%s'", line)
+		}
+	}
+	// The two records from September are dropped
+	if v.GetNumberOfDroppedEntries() != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", v.GetNumberOfDroppedEntries())
+	}
+
+	v.SetDateRange(DateRange{})
+	if err := v.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+	if v.GetNumberOfDroppedEntries() != 0 {
+		t.Errorf("Expected no dropped entries without date range, got %d", v.GetNumberOfDroppedEntries())
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	testCases := []struct {
+		from     string
+		to       string
+		expected DateRange
+		isValid  bool
+	}{
+		{"", "", DateRange{}, true},
+		{"2024-05-01", "", DateRange{From: date(2024, 5, 1)}, true},
+		{"", "2024-05-31", DateRange{To: date(2024, 5, 31)}, true},
+		{"2024-05-01", "2024-05-31", DateRange{From: date(2024, 5, 1), To: date(2024, 5, 31)}, true},
+		{"2024-05-01", "2024-05-01", DateRange{From: date(2024, 5, 1), To: date(2024, 5, 1)}, true},
+		{"2024-05-02", "2024-05-01", DateRange{}, false},
+		{"2024-5-1", "", DateRange{}, false},
+		{"", "31.05.2024", DateRange{}, false},
+		{"2024-02-30", "", DateRange{}, false},
+	}
+
+	for _, tc := range testCases {
+		r, err := ParseDateRange(tc.from, tc.to)
+		if tc.isValid {
+			if err != nil {
+				t.Errorf("%s - %s: expected no error, got: %v", tc.from, tc.to, err)
+			}
+			if !r.From.Equal(tc.expected.From) || !r.To.Equal(tc.expected.To) {
+				t.Errorf("%s - %s: expected %s, got %s", tc.from, tc.to, tc.expected, r)
+			}
+		} else if err == nil {
+			t.Errorf("%s - %s: expected error", tc.from, tc.to)
+		}
+	}
+}
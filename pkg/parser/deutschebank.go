@@ -0,0 +1,196 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the giro account export of Deutsche Bank, semicolon separated.
+  The file is Windows-1252 encoded unless it is valid UTF-8
+- The first lines contain the account and the date range. They are skipped until the
+  header line starting with "Buchungstag" is found
+- The last line contains the balance ("Kontostand") and is skipped
+- Homebanks "date" is "Buchungstag" in the format dd.mm.yyyy
+- Homebanks "amount" is either "Soll" (debit) or "Haben" (credit), whichever is set.
+  Debits are always negative, credits always positive
+- Homebanks "info" is "Umsatzart", "payee" is "Begünstigter / Auftraggeber" and "memo" is "Verwendungszweck"
+*/
+
+import (
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Single record of Deutsche Bank data
+type deutscheBankRecord struct {
+	buchungstag               time.Time
+	umsatzart                 string
+	beguenstigterAuftraggeber string
+	verwendungszweck          string
+	betrag                    float64
+}
+
+type deutscheBankParser struct {
+	converter
+	entries []deutscheBankRecord
+}
+
+// deutscheBankColumns are the columns needed from the Deutsche Bank CSV
+var deutscheBankColumns = []string{
+	"Buchungstag",
+	"Wert",
+	"Umsatzart",
+	"Begünstigter / Auftraggeber",
+	"Verwendungszweck",
+	"Soll",
+	"Haben",
+	"Währung",
+}
+
+func (p *deutscheBankParser) ParseFile(filepath string) error {
+	p.entries = make([]deutscheBankRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *deutscheBankParser) Parse(in io.Reader) error {
+	p.entries = make([]deutscheBankRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Enable variable length records
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	headerIndex := -1
+	for i, record := range records {
+		if record[0] == "Buchungstag" {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+	columns, ok := getColumns(records[headerIndex], deutscheBankColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      csvReader.recordLine(headerIndex),
+		}
+	}
+
+	entries := make([]deutscheBankRecord, 0, len(records)-headerIndex-1)
+	for i := headerIndex + 1; i < len(records); i++ {
+		row := records[i]
+		if row[0] == "Kontostand" {
+			continue
+		}
+		record, err := parseDeutscheBankRow(row, columns, csvReader.recordLine(i))
+		if err != nil {
+			if p.skipRowError(err) {
+				continue
+			}
+			return err
+		}
+		entries = append(entries, record)
+	}
+
+	p.entries = entries
+	return nil
+}
+
+// parseDeutscheBankRow parses a single transaction row
+func parseDeutscheBankRow(row []string, columns map[string]int, lineNr int) (deutscheBankRecord, error) {
+	for _, index := range columns {
+		if index >= len(row) {
+			return deutscheBankRecord{}, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "record",
+			}
+		}
+	}
+	buchungstag, err := time.Parse("02.01.2006", row[columns["Buchungstag"]])
+	if err != nil {
+		return deutscheBankRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "Buchungstag",
+			Err:       err,
+		}
+	}
+	var betrag float64
+	if soll := strings.TrimSpace(row[columns["Soll"]]); soll != "" {
+		betrag, err = parseGermanAmount(soll)
+		if err != nil {
+			return deutscheBankRecord{}, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Soll",
+				Err:       err,
+			}
+		}
+		betrag = -math.Abs(betrag)
+	} else {
+		betrag, err = parseGermanAmount(row[columns["Haben"]])
+		if err != nil {
+			return deutscheBankRecord{}, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Haben",
+				Err:       err,
+			}
+		}
+		betrag = math.Abs(betrag)
+	}
+	return deutscheBankRecord{
+		buchungstag:               buchungstag,
+		umsatzart:                 row[columns["Umsatzart"]],
+		beguenstigterAuftraggeber: row[columns["Begünstigter / Auftraggeber"]],
+		verwendungszweck:          row[columns["Verwendungszweck"]],
+		betrag:                    betrag,
+	}, nil
+}
+
+func (p *deutscheBankParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *deutscheBankParser) GetFormat() SourceFormat {
+	return DeutscheBank
+}
+
+func (p *deutscheBankParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *deutscheBankParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *deutscheBankParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *deutscheBankParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, dRecord := range p.entries {
+		hRecord := dRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecord converts a single record from Deutsche Bank to homebank format
+func (d *deutscheBankRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = d.buchungstag.Format("2006-01-02")
+	h.amount = d.betrag
+	h.info = d.umsatzart
+	h.payee = d.beguenstigterAuftraggeber
+	h.memo = d.verwendungszweck
+	return
+}
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeutscheBankName(t *testing.T) {
+	p := &deutscheBankParser{}
+	if p.GetFormat() != DeutscheBank {
+		t.Error("Wrong format")
+	}
+}
+
+func TestDeutscheBankParseFileNonExisting(t *testing.T) {
+	p := &deutscheBankParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDeutscheBankParseFileNokHeader(t *testing.T) {
+	testCases := []struct {
+		file string
+		line int
+	}{
+		// The header is searched, so no line is given if it is not found
+		{"Kontoumsaetze_nok_noheader.csv", 0},
+		{"Kontoumsaetze_nok_invalidheader.csv", 4},
+	}
+	for _, tc := range testCases {
+		p := &deutscheBankParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "deutschebank", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != HeaderError {
+			t.Errorf("%s: HeaderError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestDeutscheBankParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Kontoumsaetze_nok_wrongbuchungstag.csv", 6, "Buchungstag"},
+		{"Kontoumsaetze_nok_wrongsoll.csv", 7, "Soll"},
+		{"Kontoumsaetze_nok_wronghaben.csv", 6, "Haben"},
+	}
+	for _, tc := range testCases {
+		p := &deutscheBankParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "deutschebank", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestDeutscheBankParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "deutschebank", "Kontoumsaetze_onlyheader.csv")
+	p := &deutscheBankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDeutscheBankParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "deutschebank", "Kontoumsaetze.csv")
+	p := &deutscheBankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestParseDeutscheBankRow(t *testing.T) {
+	columns := map[string]int{
+		"Buchungstag":                 0,
+		"Wert":                        1,
+		"Umsatzart":                   2,
+		"Begünstigter / Auftraggeber": 3,
+		"Verwendungszweck":            4,
+		"Soll":                        5,
+		"Haben":                       6,
+		"Währung":                     7,
+	}
+	testCases := []struct {
+		soll   string
+		haben  string
+		betrag float64
+	}{
+		{"-12,34", "", -12.34},
+		{"12,34", "", -12.34},
+		{"", "2.500,00", 2500},
+		{" ", "-1,00", 1},
+	}
+	for _, tc := range testCases {
+		row := []string{"02.10.2023", "02.10.2023", "Umsatzart", "Name", "Zweck", tc.soll, tc.haben, "EUR"}
+		d, err := parseDeutscheBankRow(row, columns, 5)
+		if err != nil {
+			t.Errorf("%s/%s: unexpected error %v", tc.soll, tc.haben, err)
+			continue
+		}
+		if d.betrag != tc.betrag {
+			t.Errorf("%s/%s: expected %f, got %f", tc.soll, tc.haben, tc.betrag, d.betrag)
+		}
+	}
+
+	_, err := parseDeutscheBankRow([]string{"02.10.2023", "02.10.2023"}, columns, 5)
+	var pError *ParserError
+	if !errors.As(err, &pError) || pError.Field != "record" || pError.Line != 5 {
+		t.Errorf("Expected DataParsingError for field 'record' on line 5, got %v", err)
+	}
+}
+
+func TestDeutscheBankConvertRecord(t *testing.T) {
+	d := deutscheBankRecord{
+		buchungstag:               time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		umsatzart:                 "Kartenzahlung",
+		beguenstigterAuftraggeber: "Bäckerei",
+		verwendungszweck:          "Brötchen",
+		betrag:                    -12.34,
+	}
+	h := d.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != d.betrag {
+		t.Errorf("Expected amount to be %f, got %f", d.betrag, h.amount)
+	}
+	if h.info != d.umsatzart {
+		t.Errorf("Expected info to be '%s', got '%s'", d.umsatzart, h.info)
+	}
+	if h.payee != d.beguenstigterAuftraggeber {
+		t.Errorf("Expected payee to be '%s', got '%s'", d.beguenstigterAuftraggeber, h.payee)
+	}
+	if h.memo != d.verwendungszweck {
+		t.Errorf("Expected memo to be '%s', got '%s'", d.verwendungszweck, h.memo)
+	}
+}
+
+func TestDeutscheBankConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "deutschebank", "Kontoumsaetze.csv")
+	p := &deutscheBankParser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = p.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "deutschebank", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -9,14 +9,13 @@ Parsing rules:
 - DKBs "Umsatztyp" depicts incoming ("Eingang") or outgoing ("Ausgang") transactions
 - There is a special record for "Abrechnung". It is skipped and not transferred to Homebank. It can be identified by the following values:
   "Umsatztyp"=Eingang, "Betrag"=0, both Fields "Zahlungspflichtige*r Name" are set to "DKB AG"
+- Homebanks "payment" is derived from the transaction type, which is "Lastschrift" for outgoing
+  records with a "Mandatsreferenz" and "Umsatztyp" otherwise, see dkbPaymentTypes
 */
 
 import (
-	"encoding/csv"
-	"os"
+	"io"
 	"reflect"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -36,28 +35,25 @@ type dkbRecord struct {
 }
 
 type dkbParser struct {
+	converter
 	entries []dkbRecord
 }
 
 func (p *dkbParser) ParseFile(filepath string) error {
-	const headerInRecordNr int = 3 // csvReader skips completely empty lines, so the header is in the third line
-	const lineNrOffset int = 6     // line number offset for error messages
 	p.entries = make([]dkbRecord, 0)
-	infile, err := os.Open(filepath)
-	if err != nil {
-		return &ParserError{ErrorType: IOError}
-	}
-	defer infile.Close()
+	return parseFile(filepath, p.Parse)
+}
 
-	csvReader := csv.NewReader(infile)
+func (p *dkbParser) Parse(in io.Reader) error {
+	const headerInRecordNr int = 3 // csvReader skips completely empty lines, so the header is in the third line
+	p.entries = make([]dkbRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
 	csvReader.Comma = ';'
 	csvReader.FieldsPerRecord = -1 // Enable variable length records
-	// Workaround for UTF-8 Byte Order Mark (BOM) not supported by csv reader
-	// see https://github.com/golang/go/issues/33887
-	csvReader.LazyQuotes = true
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		return &ParserError{ErrorType: IOError}
+		return err
 	}
 	if len(records) < headerInRecordNr+1 {
 		return &ParserError{ErrorType: HeaderError}
@@ -66,42 +62,56 @@ func (p *dkbParser) ParseFile(filepath string) error {
 	if !isValidDkbHeader(records[headerInRecordNr]) {
 		return &ParserError{
 			ErrorType: HeaderError,
-			Line:      headerInRecordNr + 2,
+			Line:      csvReader.recordLine(headerInRecordNr),
 		}
 	}
 
-	for lineNr, row := range records[headerInRecordNr+1:] {
-		if len(row) != 12 {
-			continue
+	for i := headerInRecordNr + 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if err := checkFieldCount(row, 12, lineNr); err != nil {
+			if p.skipRowError(err) {
+				continue
+			}
+			return err
 		}
 		if row[2] != "Gebucht" {
 			continue
 		}
 		parsedBuchungsdatum, err := time.Parse("02.01.06", row[0])
 		if err != nil {
+			if p.skipRow(lineNr, "Buchungsdatum", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNrOffset + lineNr,
+				Line:      lineNr,
 				Field:     "Buchungsdatum",
+				Err:       err,
 			}
 		}
 		parsedWertstellung, err := time.Parse("02.01.06", row[1])
 		if err != nil {
+			if p.skipRow(lineNr, "Wertstellung", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNrOffset + lineNr,
+				Line:      lineNr,
 				Field:     "Wertstellung",
+				Err:       err,
 			}
 		}
-		amountString := strings.Replace(row[8], ".", "", -1)
-		amountString = strings.Replace(amountString, ",", ".", -1)
-		var amount float64
-		amount, err = strconv.ParseFloat(amountString, 64)
+		amount, err := parseGermanAmount(row[8])
 		if err != nil {
+			if p.skipRow(lineNr, "Betrag (€)", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNrOffset + lineNr,
+				Line:      lineNr,
 				Field:     "Betrag (€)",
+				Err:       err,
 			}
 		}
 		dRecord := dkbRecord{
@@ -126,6 +136,10 @@ func (p *dkbParser) ParseFile(filepath string) error {
 	return nil
 }
 
+func (d *dkbParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, d.Parse)
+}
+
 func (d *dkbParser) GetFormat() SourceFormat {
 	return DKB
 }
@@ -135,22 +149,27 @@ func (d *dkbParser) GetNumberOfEntries() int {
 }
 
 func (v *dkbParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, v.WriteHomebank)
+}
+
+func (v *dkbParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, v.GetEntries(), WithOutputOptions(v.outputOptions))
+}
+
+func (v *dkbParser) GetEntries() []Transaction {
 	hRecords := make([]homebankRecord, 0, len(v.entries))
+	paymentTypes := v.paymentTypes(dkbPaymentTypes)
 	for _, mRecord := range v.entries {
-		hRecord := mRecord.convertRecord()
+		hRecord := mRecord.convertRecord(paymentTypes)
 		hRecords = append(hRecords, hRecord)
 	}
-
-	err := writeHomeBankRecords(hRecords, filepath)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return toTransactions(v.processRecords(hRecords, v.GetFormat()))
 }
 
-func (d *dkbRecord) convertRecord() (h homebankRecord) {
-	h.payment = 0
+// convertRecord converts a single record from DKB to homebank format.
+// The payment is looked up by the transaction type in paymentTypes.
+func (d *dkbRecord) convertRecord(paymentTypes PaymentTypes) (h homebankRecord) {
+	h.payment = paymentTypes.lookup(d.transactionType())
 	h.date = d.buchungsdatum.Format("2006-01-02")
 	if d.betrag_eur < 0 {
 		h.payee = d.zahlungsempfaenger
@@ -160,6 +179,15 @@ func (d *dkbRecord) convertRecord() (h homebankRecord) {
 	return
 }
 
+// transactionType returns "Lastschrift" for outgoing direct debits, which are
+// identified by the "Mandatsreferenz", and "Umsatztyp" otherwise
+func (d *dkbRecord) transactionType() string {
+	if d.mandatsreferenz != "" && d.betrag_eur < 0 {
+		return "Lastschrift"
+	}
+	return d.umsatztyp
+}
+
 func isValidDkbHeader(record []string) bool {
 	expected := []string{
 		"Buchungsdatum",
@@ -181,15 +181,15 @@ func TestDkbConvertRecord(t *testing.T) {
 		mandatsreferenz:     "Mandatsreferenz",
 		kundenreferenz:      "Kundenreferenz",
 	}
-	h := d.convertRecord()
+	h := d.convertRecord(dkbPaymentTypes.merge(nil))
 	if h.amount != d.betrag_eur {
 		t.Errorf("Expected amount to be %f, got %f", d.betrag_eur, h.amount)
 	}
 	if h.date != "2024-12-13" {
 		t.Errorf("Expected date to be 2024-12-13, got '%s'", h.date)
 	}
-	if h.payment != 0 {
-		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	if h.payment != 11 {
+		t.Errorf("Expected payment to be 11, got %d", h.payment)
 	}
 	if h.payee != d.zahlungsempfaenger {
 		t.Errorf("Expected payee to be '%s', got '%s'", d.zahlungsempfaenger, h.payee)
@@ -278,3 +278,19 @@ func TestIsValidDkbHeader(t *testing.T) {
 		t.Errorf("Expected invalid header to be invalid")
 	}
 }
+
+func TestDkbConvertToHomebankNoBOM(t *testing.T) {
+	// Same content as dkb.csv without the UTF-8 BOM
+	d := &dkbParser{}
+	if err := d.ParseFile(filepath.Join("testfiles", "dkb", "dkb_nobom.csv")); err != nil {
+		t.Fatal(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := d.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join("testfiles", "dkb", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,188 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- DKBs CSV export before the web banking relaunch in 2023 is ISO-8859-1 encoded
+- The first lines contain the account number, the date range and the balance. They can be
+  skipped until the header line with the field names is found
+- Homebanks "date" field is equivalent to DKBs "Buchungstag" in the format dd.mm.yyyy
+- Homebanks "payee" is "Auftraggeber / Begünstigter", which is the other party for both
+  incoming and outgoing transactions
+- Homebanks "memo" is "Verwendungszweck", "info" is "Buchungstext"
+*/
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+type dkbLegacyRecord struct {
+	buchungstag               time.Time
+	wertstellung              time.Time
+	buchungstext              string
+	auftraggeberBeguenstigter string
+	verwendungszweck          string
+	kontonummer               string
+	blz                       string
+	betrag_eur                float64
+	glaeubigerId              string
+	mandatsreferenz           string
+	kundenreferenz            string
+}
+
+type dkbLegacyParser struct {
+	converter
+	entries []dkbLegacyRecord
+}
+
+func (p *dkbLegacyParser) ParseFile(filepath string) error {
+	p.entries = make([]dkbLegacyRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *dkbLegacyParser) Parse(in io.Reader) error {
+	const headerInRecordNr int = 4 // csvReader skips completely empty lines, so the header is in the fifth record
+	p.entries = make([]dkbLegacyRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Enable variable length records
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < headerInRecordNr+1 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidDkbLegacyHeader(records[headerInRecordNr]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      csvReader.recordLine(headerInRecordNr),
+		}
+	}
+
+	entries := make([]dkbLegacyRecord, 0, len(records)-headerInRecordNr-1)
+	for i := headerInRecordNr + 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if len(row) < 11 {
+			continue
+		}
+		buchungstag, err := time.Parse("02.01.2006", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Buchungstag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Buchungstag",
+				Err:       err,
+			}
+		}
+		wertstellung, err := time.Parse("02.01.2006", row[1])
+		if err != nil {
+			if p.skipRow(lineNr, "Wertstellung", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Wertstellung",
+				Err:       err,
+			}
+		}
+		amount, err := parseGermanAmount(row[7])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag (EUR)", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag (EUR)",
+				Err:       err,
+			}
+		}
+		entries = append(entries, dkbLegacyRecord{
+			buchungstag:               buchungstag,
+			wertstellung:              wertstellung,
+			buchungstext:              row[2],
+			auftraggeberBeguenstigter: row[3],
+			verwendungszweck:          row[4],
+			kontonummer:               row[5],
+			blz:                       row[6],
+			betrag_eur:                amount,
+			glaeubigerId:              row[8],
+			mandatsreferenz:           row[9],
+			kundenreferenz:            row[10],
+		})
+	}
+	p.entries = entries
+	return nil
+}
+
+func (p *dkbLegacyParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *dkbLegacyParser) GetFormat() SourceFormat {
+	return DKBLegacy
+}
+
+func (p *dkbLegacyParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *dkbLegacyParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *dkbLegacyParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *dkbLegacyParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, dRecord := range p.entries {
+		hRecord := dRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func (d *dkbLegacyRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = d.buchungstag.Format("2006-01-02")
+	h.info = d.buchungstext
+	h.payee = d.auftraggeberBeguenstigter
+	h.memo = d.verwendungszweck
+	h.amount = d.betrag_eur
+	return
+}
+
+// isValidDkbLegacyHeader checks the header, each line of the legacy export
+// ends with a separator, so there is an additional empty field.
+func isValidDkbLegacyHeader(record []string) bool {
+	expected := []string{
+		"Buchungstag",
+		"Wertstellung",
+		"Buchungstext",
+		"Auftraggeber / Begünstigter",
+		"Verwendungszweck",
+		"Kontonummer",
+		"BLZ",
+		"Betrag (EUR)",
+		"Gläubiger-ID",
+		"Mandatsreferenz",
+		"Kundenreferenz",
+	}
+	if len(record) == len(expected)+1 && record[len(expected)] == "" {
+		record = record[:len(expected)]
+	}
+	return reflect.DeepEqual(record, expected)
+}
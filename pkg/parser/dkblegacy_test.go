@@ -0,0 +1,284 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDkbLegacyName(t *testing.T) {
+	d := &dkbLegacyParser{}
+	if d.GetFormat() != DKBLegacy {
+		t.Error("Wrong format")
+	}
+}
+
+func TestDkbLegacyParseFileNonExisting(t *testing.T) {
+	d := &dkbLegacyParser{}
+	err := d.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if d.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileNok(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_nok_noheader.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileNokInvalidHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_nok_invalidheader.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 7 {
+			t.Errorf("Expected error on line 7, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileNokWrongBuchungstag(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_nok_wrongbuchungstag.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 9 {
+			t.Errorf("Expected error on line 9, got %d", pError.Line)
+		}
+		if pError.Field != "Buchungstag" {
+			t.Errorf("Expected error on field 'Buchungstag', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileNokWrongWertstellung(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_nok_wrongwertstellung.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 9 {
+			t.Errorf("Expected error on line 9, got %d", pError.Line)
+		}
+		if pError.Field != "Wertstellung" {
+			t.Errorf("Expected error on field 'Wertstellung', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileNokWrongBetrag(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_nok_wrongbetrag.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 9 {
+			t.Errorf("Expected error on line 9, got %d", pError.Line)
+		}
+		if pError.Field != "Betrag (EUR)" {
+			t.Errorf("Expected error on field 'Betrag (EUR)', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy_onlyheader.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(d.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbLegacyConvertRecord(t *testing.T) {
+	d := dkbLegacyRecord{
+		buchungstag:               time.Date(2022, 12, 30, 0, 0, 0, 0, time.UTC),
+		wertstellung:              time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+		buchungstext:              "Lastschrift",
+		auftraggeberBeguenstigter: "Stadtwerke",
+		verwendungszweck:          "Abschlag Strom",
+		kontonummer:               "DE98765432109876543210",
+		blz:                       "SSKMDEMMXXX",
+		betrag_eur:                -45.0,
+		glaeubigerId:              "DE12ZZZ00000012345",
+		mandatsreferenz:           "M-4711",
+	}
+	h := d.convertRecord()
+	if h.amount != d.betrag_eur {
+		t.Errorf("Expected amount to be %f, got %f", d.betrag_eur, h.amount)
+	}
+	if h.date != "2022-12-30" {
+		t.Errorf("Expected date to be 2022-12-30, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.info != d.buchungstext {
+		t.Errorf("Expected info to be '%s', got '%s'", d.buchungstext, h.info)
+	}
+	if h.payee != d.auftraggeberBeguenstigter {
+		t.Errorf("Expected payee to be '%s', got '%s'", d.auftraggeberBeguenstigter, h.payee)
+	}
+	if h.memo != d.verwendungszweck {
+		t.Errorf("Expected memo to be '%s', got '%s'", d.verwendungszweck, h.memo)
+	}
+	if h.category != "" {
+		t.Errorf("Expected category to be empty, got '%s'", h.category)
+	}
+	if h.tags != "" {
+		t.Errorf("Expected tags to be empty, got '%s'", h.tags)
+	}
+}
+
+func TestDkbLegacyParseFileCurrentFormat(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkb", "dkb.csv")
+	d := &dkbLegacyParser{}
+	if err := d.ParseFile(fpath); err == nil {
+		t.Error("Current DKB file should not be parsed as legacy file")
+	}
+
+	fpath = filepath.Join("testfiles", "dkblegacy", "dkblegacy.csv")
+	c := &dkbParser{}
+	if err := c.ParseFile(fpath); err == nil {
+		t.Error("Legacy DKB file should not be parsed as current file")
+	}
+}
+
+func TestIsValidDkbLegacyHeader(t *testing.T) {
+	validHeader := []string{
+		"Buchungstag",
+		"Wertstellung",
+		"Buchungstext",
+		"Auftraggeber / Begünstigter",
+		"Verwendungszweck",
+		"Kontonummer",
+		"BLZ",
+		"Betrag (EUR)",
+		"Gläubiger-ID",
+		"Mandatsreferenz",
+		"Kundenreferenz",
+	}
+
+	if !isValidDkbLegacyHeader(validHeader) {
+		t.Errorf("Expected valid header to be valid")
+	}
+
+	if !isValidDkbLegacyHeader(append(validHeader, "")) {
+		t.Errorf("Expected valid header with trailing separator to be valid")
+	}
+
+	invalidHeader := append([]string{"Datum"}, validHeader[1:]...)
+	if isValidDkbLegacyHeader(invalidHeader) {
+		t.Errorf("Expected invalid header to be invalid")
+	}
+}
+
+func TestDkbLegacyParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy.csv")
+	d := &dkbLegacyParser{}
+	if err := d.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if d.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", d.GetNumberOfEntries())
+	}
+}
+
+func TestDkbLegacyConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkblegacy", "dkblegacy.csv")
+	d := &dkbLegacyParser{}
+	err := d.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = d.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "dkblegacy", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
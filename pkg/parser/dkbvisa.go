@@ -0,0 +1,173 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The first lines of DKBs credit card CSV contain the card number and the balance. They can be
+  skipped until the header line with the field names is found
+- Homebanks "date" field is equivalent to DKBs "Belegdatum"
+- Homebanks "payee" and "info" are taken from "Beschreibung"
+- A foreign currency amount in "Fremdwährungsbetrag" is kept in the "memo"
+- Only records with "Status"=Gebucht are converted, "Vorgemerkt" records are skipped
+*/
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+type dkbVisaRecord struct {
+	belegdatum          time.Time
+	wertstellung        time.Time
+	status              string
+	beschreibung        string
+	umsatztyp           string
+	betrag_eur          float64
+	fremdwaehrungBetrag string
+}
+
+type dkbVisaParser struct {
+	converter
+	entries []dkbVisaRecord
+}
+
+func (p *dkbVisaParser) ParseFile(filepath string) error {
+	p.entries = make([]dkbVisaRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *dkbVisaParser) Parse(in io.Reader) error {
+	const headerInRecordNr int = 3 // csvReader skips completely empty lines, so the header is in the third line
+	p.entries = make([]dkbVisaRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Enable variable length records
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < headerInRecordNr+1 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidDkbVisaHeader(records[headerInRecordNr]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      csvReader.recordLine(headerInRecordNr),
+		}
+	}
+
+	entries := make([]dkbVisaRecord, 0, len(records)-headerInRecordNr-1)
+	for i := headerInRecordNr + 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if len(row) != 7 {
+			continue
+		}
+		if row[2] != "Gebucht" {
+			continue
+		}
+		belegdatum, err := time.Parse("02.01.06", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Belegdatum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Belegdatum",
+				Err:       err,
+			}
+		}
+		wertstellung, err := time.Parse("02.01.06", row[1])
+		if err != nil {
+			if p.skipRow(lineNr, "Wertstellung", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Wertstellung",
+				Err:       err,
+			}
+		}
+		amount, err := parseGermanAmount(row[5])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag (€)", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag (€)",
+				Err:       err,
+			}
+		}
+		entries = append(entries, dkbVisaRecord{
+			belegdatum:          belegdatum,
+			wertstellung:        wertstellung,
+			status:              row[2],
+			beschreibung:        row[3],
+			umsatztyp:           row[4],
+			betrag_eur:          amount,
+			fremdwaehrungBetrag: row[6],
+		})
+	}
+	p.entries = entries
+	return nil
+}
+
+func (p *dkbVisaParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *dkbVisaParser) GetFormat() SourceFormat {
+	return DKBVisa
+}
+
+func (p *dkbVisaParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *dkbVisaParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *dkbVisaParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *dkbVisaParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, dRecord := range p.entries {
+		hRecord := dRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func (d *dkbVisaRecord) convertRecord() (h homebankRecord) {
+	h.payment = 1 // Credit card
+	h.date = d.belegdatum.Format("2006-01-02")
+	h.info = d.beschreibung
+	h.payee = d.beschreibung
+	h.memo = d.fremdwaehrungBetrag
+	h.amount = d.betrag_eur
+	return
+}
+
+func isValidDkbVisaHeader(record []string) bool {
+	expected := []string{
+		"Belegdatum",
+		"Wertstellung",
+		"Status",
+		"Beschreibung",
+		"Umsatztyp",
+		"Betrag (€)",
+		"Fremdwährungsbetrag",
+	}
+	return reflect.DeepEqual(record, expected)
+}
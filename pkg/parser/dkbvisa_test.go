@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDkbVisaName(t *testing.T) {
+	c := &dkbVisaParser{}
+	if c.GetFormat() != DKBVisa {
+		t.Error("Wrong format")
+	}
+}
+
+func TestDkbVisaParseFileNonExisting(t *testing.T) {
+	v := &dkbVisaParser{}
+	err := v.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if v.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileNok(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_nok_noheader.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileNokInvalidHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_nok_invalidheader.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 5 {
+			t.Errorf("Expected error on line 5, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileNokWrongBelegdatum(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_nok_wrongbelegdatum.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 6 {
+			t.Errorf("Expected error on line 6, got %d", pError.Line)
+		}
+		if pError.Field != "Belegdatum" {
+			t.Errorf("Expected error on field 'Belegdatum', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileNokWrongWertstellung(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_nok_wrongwertstellung.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 6 {
+			t.Errorf("Expected error on line 6, got %d", pError.Line)
+		}
+		if pError.Field != "Wertstellung" {
+			t.Errorf("Expected error on field 'Wertstellung', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileNokWrongBetrag(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_nok_wrongbetrag.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 6 {
+			t.Errorf("Expected error on line 6, got %d", pError.Line)
+		}
+		if pError.Field != "Betrag (€)" {
+			t.Errorf("Expected error on field 'Betrag (€)', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa_onlyheader.csv")
+	c := &dkbVisaParser{}
+	err := c.ParseFile(fpath)
+	if err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(c.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestDkbVisaConvertRecord(t *testing.T) {
+	d := dkbVisaRecord{
+		belegdatum:          time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC),
+		wertstellung:        time.Date(2024, 12, 23, 0, 0, 0, 0, time.UTC),
+		status:              "Gebucht",
+		beschreibung:        "AMAZON.COM",
+		umsatztyp:           "Online",
+		betrag_eur:          -10.99,
+		fremdwaehrungBetrag: "-11,50 USD",
+	}
+	h := d.convertRecord()
+	if h.amount != d.betrag_eur {
+		t.Errorf("Expected amount to be %f, got %f", d.betrag_eur, h.amount)
+	}
+	if h.date != "2024-12-20" {
+		t.Errorf("Expected date to be 2024-12-20, got '%s'", h.date)
+	}
+	if h.payment != 1 {
+		t.Errorf("Expected payment to be 1, got %d", h.payment)
+	}
+	if h.payee != d.beschreibung {
+		t.Errorf("Expected payee to be '%s', got '%s'", d.beschreibung, h.payee)
+	}
+	if h.info != d.beschreibung {
+		t.Errorf("Expected info to be '%s', got '%s'", d.beschreibung, h.info)
+	}
+	if h.memo != d.fremdwaehrungBetrag {
+		t.Errorf("Expected memo to be '%s', got '%s'", d.fremdwaehrungBetrag, h.memo)
+	}
+	if h.category != "" {
+		t.Errorf("Expected category to be empty, got '%s'", h.category)
+	}
+	if h.tags != "" {
+		t.Errorf("Expected tags to be empty, got '%s'", h.tags)
+	}
+}
+
+func TestDkbVisaParseFileGiroFormat(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkb", "dkb.csv")
+	d := &dkbVisaParser{}
+	if err := d.ParseFile(fpath); err == nil {
+		t.Error("DKB giro account file should not be parsed as credit card file")
+	}
+
+	fpath = filepath.Join("testfiles", "dkbvisa", "dkbvisa.csv")
+	g := &dkbParser{}
+	if err := g.ParseFile(fpath); err == nil {
+		t.Error("DKB credit card file should not be parsed as giro account file")
+	}
+}
+
+func TestIsValidDkbVisaHeader(t *testing.T) {
+	validHeader := []string{
+		"Belegdatum",
+		"Wertstellung",
+		"Status",
+		"Beschreibung",
+		"Umsatztyp",
+		"Betrag (€)",
+		"Fremdwährungsbetrag",
+	}
+
+	invalidHeader := []string{
+		"Datum",
+		"Wertstellung",
+		"Status",
+		"Beschreibung",
+		"Umsatztyp",
+		"Betrag (€)",
+		"Fremdwährungsbetrag",
+	}
+
+	if !isValidDkbVisaHeader(validHeader) {
+		t.Errorf("Expected valid header to be valid")
+	}
+
+	if isValidDkbVisaHeader(invalidHeader) {
+		t.Errorf("Expected invalid header to be invalid")
+	}
+}
+
+func TestDkbVisaParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa.csv")
+	d := &dkbVisaParser{}
+	if err := d.ParseFile(fpath); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDkbVisaConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "dkbvisa", "dkbvisa.csv")
+	d := &dkbVisaParser{}
+	err := d.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = d.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "dkbvisa", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
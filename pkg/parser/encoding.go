@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encoding is the character encoding of an input file
+type Encoding int
+
+const (
+	EncodingAuto        Encoding = iota // Detect the encoding, see detectEncoding
+	EncodingUTF8                        // UTF-8 with or without byte order mark (BOM)
+	EncodingUTF16LE                     // UTF-16 little endian
+	EncodingUTF16BE                     // UTF-16 big endian
+	EncodingISO88591                    // ISO 8859-1 (Latin-1)
+	EncodingWindows1252                 // Windows-1252, like ISO 8859-1 with e.g. "€" at 0x80
+)
+
+// encodings is the mapping between Encoding and its textual representation
+var encodings = map[Encoding]string{
+	EncodingAuto:        "auto",
+	EncodingUTF8:        "utf-8",
+	EncodingUTF16LE:     "utf-16le",
+	EncodingUTF16BE:     "utf-16be",
+	EncodingISO88591:    "iso-8859-1",
+	EncodingWindows1252: "windows-1252",
+}
+
+// Returns the textual representation of the encoding
+func (e Encoding) String() string {
+	if s, ok := encodings[e]; ok {
+		return s
+	}
+	return "unknown encoding"
+}
+
+// MarshalText returns the textual representation of the encoding, e.g. for YAML.
+// An error is returned for unsupported values.
+func (e Encoding) MarshalText() ([]byte, error) {
+	if _, ok := encodings[e]; !ok {
+		return nil, fmt.Errorf("unsupported encoding %d", int(e))
+	}
+	return []byte(encodings[e]), nil
+}
+
+// UnmarshalText sets the encoding from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
+func (e *Encoding) UnmarshalText(text []byte) error {
+	textString := strings.TrimSpace(string(text))
+	for key, value := range encodings {
+		if strings.EqualFold(value, textString) {
+			*e = key
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported encoding '%s', valid values are: auto, utf-8, utf-16le, utf-16be, iso-8859-1, windows-1252", string(text))
+}
+
+// decoder returns the transformer decoding the encoding to UTF-8.
+// A leading BOM of UTF-8 and UTF-16 is removed.
+func (e Encoding) decoder() transform.Transformer {
+	switch e {
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()
+	case EncodingISO88591:
+		return charmap.ISO8859_1.NewDecoder()
+	case EncodingWindows1252:
+		return charmap.Windows1252.NewDecoder()
+	default:
+		return unicode.UTF8BOM.NewDecoder()
+	}
+}
+
+// detectEncoding returns the encoding of content. A BOM determines the encoding,
+// otherwise it is UTF-8 if content is valid UTF-8. As last resort it is Windows-1252
+// which also covers the printable characters of ISO 8859-1.
+func detectEncoding(content []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xEF\xBB\xBF")):
+		return EncodingUTF8
+	case bytes.HasPrefix(content, []byte("\xFF\xFE")):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, []byte("\xFE\xFF")):
+		return EncodingUTF16BE
+	case utf8.Valid(content):
+		return EncodingUTF8
+	default:
+		return EncodingWindows1252
+	}
+}
+
+// newDecodingReader returns a reader which decodes r from the given encoding to UTF-8.
+// With EncodingAuto the whole input is read on the first call of Read to detect
+// its encoding with detectEncoding.
+// The csv reader does not handle the BOM, see https://github.com/golang/go/issues/33887
+func newDecodingReader(r io.Reader, e Encoding) io.Reader {
+	if e == EncodingAuto {
+		return &autoDecodingReader{r: r}
+	}
+	return transform.NewReader(r, e.decoder())
+}
+
+// autoDecodingReader decodes the input according to its detected encoding
+type autoDecodingReader struct {
+	r       io.Reader
+	decoded io.Reader // Set by the first call of Read
+}
+
+func (a *autoDecodingReader) Read(p []byte) (int, error) {
+	if a.decoded == nil {
+		content, err := io.ReadAll(a.r)
+		if err != nil {
+			return 0, err
+		}
+		a.decoded = transform.NewReader(bytes.NewReader(content), detectEncoding(content).decoder())
+	}
+	return a.decoded.Read(p)
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodingUnmarshalText(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected Encoding
+	}{
+		{"auto", EncodingAuto},
+		{"UTF-8", EncodingUTF8},
+		{" utf-16le ", EncodingUTF16LE},
+		{"utf-16be", EncodingUTF16BE},
+		{"ISO-8859-1", EncodingISO88591},
+		{"windows-1252", EncodingWindows1252},
+	}
+	for _, tc := range testCases {
+		var e Encoding
+		if err := e.UnmarshalText([]byte(tc.text)); err != nil {
+			t.Errorf("%s: %v", tc.text, err)
+			continue
+		}
+		if e != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.text, tc.expected, e)
+		}
+	}
+
+	var e Encoding
+	if err := e.UnmarshalText([]byte("latin-9")); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  []byte
+		expected Encoding
+	}{
+		{"ASCII", []byte("Datum;Betrag\n"), EncodingUTF8},
+		{"UTF-8", []byte("Straße\n"), EncodingUTF8},
+		{"UTF-8 BOM", []byte("\xEF\xBB\xBFDatum\n"), EncodingUTF8},
+		{"UTF-16 LE BOM", []byte("\xFF\xFED\x00"), EncodingUTF16LE},
+		{"UTF-16 BE BOM", []byte("\xFE\xFF\x00D"), EncodingUTF16BE},
+		{"ISO 8859-1", []byte("Stra\xDFe\n"), EncodingWindows1252},
+		{"Windows-1252", []byte("10 \x80\n"), EncodingWindows1252},
+	}
+	for _, tc := range testCases {
+		if e := detectEncoding(tc.content); e != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, e)
+		}
+	}
+}
+
+func TestNewDecodingReader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    []byte
+		encoding Encoding
+	}{
+		{"auto no BOM", []byte("Straße;10 €\n"), EncodingAuto},
+		{"auto UTF-8 BOM", []byte("\xEF\xBB\xBFStraße;10 €\n"), EncodingAuto},
+		{"auto UTF-16 LE", []byte("\xFF\xFES\x00t\x00r\x00a\x00\xDF\x00e\x00;\x001\x000\x00 \x00\xAC\x20\n\x00"), EncodingAuto},
+		{"auto UTF-16 BE", []byte("\xFE\xFF\x00S\x00t\x00r\x00a\x00\xDF\x00e\x00;\x001\x000\x00 \x20\xAC\x00\n"), EncodingAuto},
+		{"auto Windows-1252", []byte("Stra\xDFe;10 \x80\n"), EncodingAuto},
+		{"UTF-8 BOM", []byte("\xEF\xBB\xBFStraße;10 €\n"), EncodingUTF8},
+		{"UTF-16 LE without BOM", []byte("S\x00t\x00r\x00a\x00\xDF\x00e\x00;\x001\x000\x00 \x00\xAC\x20\n\x00"), EncodingUTF16LE},
+		{"Windows-1252", []byte("Stra\xDFe;10 \x80\n"), EncodingWindows1252},
+	}
+	for _, tc := range testCases {
+		content, err := io.ReadAll(newDecodingReader(bytes.NewReader(tc.input), tc.encoding))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if string(content) != "Straße;10 €\n" {
+			t.Errorf("%s: got %q", tc.name, content)
+		}
+	}
+
+	// ISO 8859-1 has control characters where Windows-1252 has e.g. "€"
+	content, err := io.ReadAll(newDecodingReader(bytes.NewReader([]byte("Stra\xDFe")), EncodingISO88591))
+	if err != nil || string(content) != "Straße" {
+		t.Errorf("Expected 'Straße', got %q, %v", content, err)
+	}
+}
+
+// TestParseEncodings checks that the payees of files exported in different encodings are decoded correctly
+func TestParseEncodings(t *testing.T) {
+	testCases := []struct {
+		format   SourceFormat
+		input    string
+		encoding Encoding
+		payee    string
+	}{
+		{Comdirect, filepath.Join("comdirect", "umsaetze_encoding_utf8bom.csv"), EncodingAuto, "Bäckerei Müller"},
+		{Comdirect, filepath.Join("comdirect", "umsaetze_encoding_windows1252.csv"), EncodingAuto, "Bäckerei Müller"},
+		{Comdirect, filepath.Join("comdirect", "umsaetze_encoding_windows1252.csv"), EncodingWindows1252, "Bäckerei Müller"},
+		{Comdirect, filepath.Join("comdirect", "umsaetze_1234567890_20231006_1804.csv"), EncodingISO88591, "Auftraggeber Text"},
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), EncodingAuto, "Umlaute äöß"},
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_iso88591.csv"), EncodingAuto, "Umlaute äöß"},
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_iso88591.csv"), EncodingISO88591, "Umlaute äöß"},
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_windows1252.csv"), EncodingAuto, "Umlaute äöß €"},
+		{Volksbank, filepath.Join("volksbank", "Umsaetze_utf16be.csv"), EncodingAuto, "Umlaute äöß"},
+	}
+	for _, tc := range testCases {
+		p := GetParser(tc.format)
+		p.SetParseOptions(ParseOptions{Encoding: tc.encoding})
+		if err := p.ParseFile(filepath.Join("testfiles", tc.input)); err != nil {
+			t.Errorf("%s (%s): %v", tc.input, tc.encoding, err)
+			continue
+		}
+		found := false
+		for _, e := range p.GetEntries() {
+			if e.Payee == tc.payee {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s (%s): no entry with payee '%s'", tc.input, tc.encoding, tc.payee)
+		}
+	}
+}
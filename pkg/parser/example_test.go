@@ -0,0 +1,64 @@
+package parser_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sercxanto/go-homebank-csv/pkg/parser"
+)
+
+func ExampleGetParser() {
+	p := parser.GetParser(parser.Volksbank)
+	err := p.ParseFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Found %d entries in format '%s'\n", p.GetNumberOfEntries(), p.GetFormat())
+	// Output: Found 4 entries in format 'Volksbank'
+}
+
+func ExampleGetGuessedParser() {
+	p := parser.GetGuessedParser(filepath.Join("testfiles", "dkb", "dkb.csv"))
+	if p == nil {
+		fmt.Println("Cannot deduce format")
+		return
+	}
+	fmt.Printf("Detected format '%s'\n", p.GetFormat())
+	// Output: Detected format 'DKB'
+}
+
+func ExampleSourceFormat_UnmarshalText() {
+	var format parser.SourceFormat
+	if err := format.UnmarshalText([]byte("moneywallet")); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(format)
+	// Output: MoneyWallet
+}
+
+// Convert data from a reader and write the homebank CSV to a writer
+// without any intermediate files.
+func Example_convertReaderToWriter() {
+	input := strings.NewReader(`"wallet","currency","category","datetime","money","description"
+"Bargeld","EUR","Essen","2020-12-25 09:23:06","-20,00","Pizza"
+"Bargeld","EUR","Friseur","2020-12-08 14:55:43","-20,00","Haare schneiden"
+`)
+
+	p := parser.GetParser(parser.MoneyWallet)
+	if err := p.Parse(input); err != nil {
+		fmt.Println(err)
+		return
+	}
+	p.SetCategoryPrefix("Bargeld", false)
+	if err := p.WriteHomebank(os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// date;payment;info;payee;memo;amount;category;tags
+	// 2020-12-25;0;Pizza;;;-20.00;Bargeld:Essen;
+	// 2020-12-08;0;Haare schneiden;;;-20.00;Bargeld:Friseur;
+}
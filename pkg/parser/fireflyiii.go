@@ -0,0 +1,192 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the transaction export of Firefly III, comma separated
+- The columns are looked up by their name in the header as the export contains many more columns
+- Homebanks "date" is the date part of the ISO 8601 timestamp "date", e.g. "2023-10-02T00:00:00+02:00"
+- The sign of "amount" is set by "type": withdrawals are negative, deposits are positive.
+  Other types like transfers keep the exported sign
+- Transfers get the payment "bank transfer"
+- Homebanks "payee" is "destination_name" for outgoing and "source_name" for incoming transactions
+- Homebanks "memo" is "description" followed by "notes", "category" is "category"
+- The comma separated "tags" are converted into space separated homebank tags, spaces within
+  a tag are replaced by "_"
+*/
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of Firefly III data
+type fireflyIIIRecord struct {
+	typ             string
+	amount          float64
+	description     string
+	date            time.Time
+	sourceName      string
+	destinationName string
+	category        string
+	tags            string
+	notes           string
+}
+
+type fireflyIIIParser struct {
+	converter
+	entries []fireflyIIIRecord
+}
+
+// fireflyIIIColumns are the columns needed from the Firefly III CSV
+var fireflyIIIColumns = []string{
+	"type",
+	"amount",
+	"currency_code",
+	"description",
+	"date",
+	"source_name",
+	"destination_name",
+	"category",
+	"tags",
+	"notes",
+}
+
+func (p *fireflyIIIParser) ParseFile(filepath string) error {
+	p.entries = make([]fireflyIIIRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *fireflyIIIParser) Parse(in io.Reader) error {
+	p.entries = make([]fireflyIIIRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], fireflyIIIColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]fireflyIIIRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		dateField := row[columns["date"]]
+		if len(dateField) > 10 {
+			dateField = dateField[:10]
+		}
+		date, err := time.Parse("2006-01-02", dateField)
+		if err != nil {
+			if p.skipRow(lineNr, "date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "date",
+				Err:       err,
+			}
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[columns["amount"]]), 64)
+		if err != nil {
+			if p.skipRow(lineNr, "amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "amount",
+				Err:       err,
+			}
+		}
+		entries = append(entries, fireflyIIIRecord{
+			typ:             strings.ToLower(row[columns["type"]]),
+			amount:          amount,
+			description:     row[columns["description"]],
+			date:            date,
+			sourceName:      row[columns["source_name"]],
+			destinationName: row[columns["destination_name"]],
+			category:        row[columns["category"]],
+			tags:            row[columns["tags"]],
+			notes:           row[columns["notes"]],
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *fireflyIIIParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *fireflyIIIParser) GetFormat() SourceFormat {
+	return FireflyIII
+}
+
+func (p *fireflyIIIParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *fireflyIIIParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *fireflyIIIParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *fireflyIIIParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, fRecord := range p.entries {
+		hRecord := fRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecord converts a single record from Firefly III to homebank format
+func (f *fireflyIIIRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = f.date.Format("2006-01-02")
+	h.amount = f.amount
+	switch f.typ {
+	case "withdrawal":
+		h.amount = -math.Abs(f.amount)
+	case "deposit":
+		h.amount = math.Abs(f.amount)
+	case "transfer":
+		h.payment = 4 // Bank transfer
+	}
+	if h.amount < 0 {
+		h.payee = f.destinationName
+	} else {
+		h.payee = f.sourceName
+	}
+	h.memo = joinNonEmpty(f.description, singleLine(f.notes))
+	h.category = f.category
+
+	// Homebank separates tags by space, Firefly III tags may contain spaces
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(f.tags, ",") {
+		if tag = strings.Join(strings.Fields(tag), "_"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	h.tags = strings.Join(tags, " ")
+	return
+}
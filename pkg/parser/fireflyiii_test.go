@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFireflyIIIName(t *testing.T) {
+	p := &fireflyIIIParser{}
+	if p.GetFormat() != FireflyIII {
+		t.Error("Wrong format")
+	}
+}
+
+func TestFireflyIIIParseFileNonExisting(t *testing.T) {
+	p := &fireflyIIIParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestFireflyIIIParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "fireflyiii", "export_nok_noheader.csv")
+	p := &fireflyIIIParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestFireflyIIIParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"export_nok_wrongdate.csv", 3, "date"},
+		{"export_nok_wrongamount.csv", 6, "amount"},
+	}
+	for _, tc := range testCases {
+		p := &fireflyIIIParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "fireflyiii", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestFireflyIIIParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "fireflyiii", "export_onlyheader.csv")
+	p := &fireflyIIIParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestFireflyIIIParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "fireflyiii", "export.csv")
+	p := &fireflyIIIParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestFireflyIIIConvertRecord(t *testing.T) {
+	testCases := []struct {
+		typ     string
+		amount  float64
+		payment int8
+		result  float64
+		payee   string
+	}{
+		{"withdrawal", 12.5, 0, -12.5, "Destination"},
+		{"withdrawal", -12.5, 0, -12.5, "Destination"},
+		{"deposit", -12.5, 0, 12.5, "Source"},
+		{"transfer", -12.5, 4, -12.5, "Destination"},
+		{"transfer", 12.5, 4, 12.5, "Source"},
+		{"opening balance", 100, 0, 100, "Source"},
+	}
+	for _, tc := range testCases {
+		f := fireflyIIIRecord{
+			typ:             tc.typ,
+			amount:          tc.amount,
+			description:     "Description",
+			date:            time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+			sourceName:      "Source",
+			destinationName: "Destination",
+			category:        "Category",
+			tags:            " food, weekly shop,,",
+			notes:           "Line 1\nLine 2",
+		}
+		h := f.convertRecord()
+		if h.date != "2023-10-02" {
+			t.Errorf("%s: expected date to be 2023-10-02, got '%s'", tc.typ, h.date)
+		}
+		if h.payment != tc.payment {
+			t.Errorf("%s: expected payment to be %d, got %d", tc.typ, tc.payment, h.payment)
+		}
+		if h.amount != tc.result {
+			t.Errorf("%s: expected amount to be %f, got %f", tc.typ, tc.result, h.amount)
+		}
+		if h.payee != tc.payee {
+			t.Errorf("%s: expected payee to be '%s', got '%s'", tc.typ, tc.payee, h.payee)
+		}
+		if h.memo != "Description Line 1 Line 2" {
+			t.Errorf("%s: expected memo to be 'Description Line 1 Line 2', got '%s'", tc.typ, h.memo)
+		}
+		if h.category != f.category {
+			t.Errorf("%s: expected category to be '%s', got '%s'", tc.typ, f.category, h.category)
+		}
+		if h.tags != "food weekly_shop" {
+			t.Errorf("%s: expected tags to be 'food weekly_shop', got '%s'", tc.typ, h.tags)
+		}
+	}
+}
+
+func TestFireflyIIIConvertToHomebank(t *testing.T) {
+	p := &fireflyIIIParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "fireflyiii", "export.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "fireflyiii", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,238 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the transaction export of GnuCash with one row per split, comma separated
+- The columns are looked up by their name in the header
+- The rows are grouped into transactions by "Transaction ID". Rows with an empty
+  "Transaction ID" belong to the preceding transaction
+- The split of the imported bank account is selected by its "Full Account Name" given in
+  FormatOptions.GnuCashAccount. Without it the first split of each transaction is taken.
+  Transactions without a split of the bank account are skipped
+- Each other split results in one homebank record with the inverted split amount
+  "Amount Num.", i.e. the amount as seen from the bank account
+- The date format depends on the locale, it is detected like in the YNAB format
+- Homebanks "payee" is "Description", "info" is "Number" and "category" is "Account Name"
+- Homebanks "memo" is the "Memo" of the split, "Notes" of the transaction if the memo is empty
+*/
+
+import (
+	"io"
+	"time"
+)
+
+// Single transaction of gnucash data with all of its splits
+type gnuCashTransaction struct {
+	date        time.Time
+	number      string
+	description string
+	notes       string
+	splits      []gnuCashSplit
+}
+
+// gnuCashSplit is a single split of a gnucash transaction
+type gnuCashSplit struct {
+	memo            string
+	fullAccountName string
+	accountName     string
+	amount          float64
+}
+
+type gnuCashParser struct {
+	converter
+	entries []gnuCashTransaction
+}
+
+// gnuCashColumns are the columns needed from the GnuCash CSV
+var gnuCashColumns = []string{
+	"Date",
+	"Transaction ID",
+	"Number",
+	"Description",
+	"Notes",
+	"Memo",
+	"Full Account Name",
+	"Account Name",
+	"Amount Num.",
+}
+
+func (p *gnuCashParser) ParseFile(filepath string) error {
+	p.entries = make([]gnuCashTransaction, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *gnuCashParser) Parse(in io.Reader) error {
+	p.entries = make([]gnuCashTransaction, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], gnuCashColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]gnuCashTransaction, 0)
+	transactionIndex := make(map[string]int) // Index into entries by "Transaction ID"
+	current := -1
+	skipped := false // The current transaction is skipped in lenient mode
+	dateLayout := ""
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		id := row[columns["Transaction ID"]]
+		if id != "" {
+			skipped = false
+		} else if skipped {
+			continue
+		}
+		if id == "" && current < 0 {
+			if p.skipRow(lineNr, "Transaction ID", nil) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Transaction ID",
+			}
+		}
+		if id != "" {
+			index, found := transactionIndex[id]
+			if !found {
+				if dateLayout == "" {
+					dateLayout, err = getYnabDateLayout(row[columns["Date"]])
+					if err != nil {
+						if p.skipRow(lineNr, "Date", err) {
+							skipped = true
+							continue
+						}
+						return &ParserError{
+							ErrorType: DataParsingError,
+							Line:      lineNr,
+							Field:     "Date",
+							Err:       err,
+						}
+					}
+				}
+				date, err := time.Parse(dateLayout, row[columns["Date"]])
+				if err != nil {
+					if p.skipRow(lineNr, "Date", err) {
+						skipped = true
+						continue
+					}
+					return &ParserError{
+						ErrorType: DataParsingError,
+						Line:      lineNr,
+						Field:     "Date",
+						Err:       err,
+					}
+				}
+				entries = append(entries, gnuCashTransaction{
+					date:        date,
+					number:      row[columns["Number"]],
+					description: row[columns["Description"]],
+					notes:       row[columns["Notes"]],
+				})
+				index = len(entries) - 1
+				transactionIndex[id] = index
+			}
+			current = index
+		}
+		amount, err := parseYnabAmount(row[columns["Amount Num."]])
+		if err != nil {
+			if p.skipRow(lineNr, "Amount Num.", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Amount Num.",
+				Err:       err,
+			}
+		}
+		entries[current].splits = append(entries[current].splits, gnuCashSplit{
+			memo:            row[columns["Memo"]],
+			fullAccountName: row[columns["Full Account Name"]],
+			accountName:     row[columns["Account Name"]],
+			amount:          amount,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *gnuCashParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *gnuCashParser) GetFormat() SourceFormat {
+	return GnuCash
+}
+
+func (p *gnuCashParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *gnuCashParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *gnuCashParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *gnuCashParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, gTransaction := range p.entries {
+		hRecords = append(hRecords, gTransaction.convertRecords(p.formatOptions.GnuCashAccount)...)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecords converts the splits of a transaction into homebank records as seen
+// from the bank account with the given full account name. An empty account name
+// selects the first split.
+func (g *gnuCashTransaction) convertRecords(account string) []homebankRecord {
+	source := -1
+	for i, split := range g.splits {
+		if account == "" || split.fullAccountName == account {
+			source = i
+			break
+		}
+	}
+	if source < 0 {
+		return nil
+	}
+
+	records := make([]homebankRecord, 0, len(g.splits)-1)
+	for i, split := range g.splits {
+		if i == source {
+			continue
+		}
+		var h homebankRecord
+		h.payment = 0
+		h.date = g.date.Format("2006-01-02")
+		h.amount = -split.amount
+		h.info = g.number
+		h.payee = g.description
+		h.memo = split.memo
+		if h.memo == "" {
+			h.memo = g.notes
+		}
+		h.category = split.accountName
+		records = append(records, h)
+	}
+	return records
+}
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGnuCashName(t *testing.T) {
+	p := &gnuCashParser{}
+	if p.GetFormat() != GnuCash {
+		t.Error("Wrong format")
+	}
+}
+
+func TestGnuCashParseFileNonExisting(t *testing.T) {
+	p := &gnuCashParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestGnuCashParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "gnucash", "transactions_nok_noheader.csv")
+	p := &gnuCashParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestGnuCashParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"transactions_nok_wrongdate.csv", 4, "Date"},
+		{"transactions_nok_wrongamount.csv", 7, "Amount Num."},
+		{"transactions_nok_notransactionid.csv", 2, "Transaction ID"},
+	}
+	for _, tc := range testCases {
+		p := &gnuCashParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "gnucash", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestGnuCashParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "gnucash", "transactions_onlyheader.csv")
+	p := &gnuCashParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestGnuCashParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "gnucash", "transactions.csv")
+	p := &gnuCashParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestGnuCashConvertRecords(t *testing.T) {
+	g := gnuCashTransaction{
+		date:        time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC),
+		number:      "1042",
+		description: "Hardware Store",
+		notes:       "Garden and kitchen",
+		splits: []gnuCashSplit{
+			{"", "Assets:Checking Account", "Checking Account", -80},
+			{"Plants", "Expenses:Garden", "Garden", 50},
+			{"", "Expenses:Household", "Household", 30},
+		},
+	}
+	records := g.convertRecords("Assets:Checking Account")
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	h := records[0]
+	if h.date != "2023-10-07" {
+		t.Errorf("Expected date to be 2023-10-07, got '%s'", h.date)
+	}
+	if h.amount != -50 {
+		t.Errorf("Expected amount to be -50, got %f", h.amount)
+	}
+	if h.info != g.number {
+		t.Errorf("Expected info to be '%s', got '%s'", g.number, h.info)
+	}
+	if h.payee != g.description {
+		t.Errorf("Expected payee to be '%s', got '%s'", g.description, h.payee)
+	}
+	if h.memo != "Plants" {
+		t.Errorf("Expected memo to be 'Plants', got '%s'", h.memo)
+	}
+	if h.category != "Garden" {
+		t.Errorf("Expected category to be 'Garden', got '%s'", h.category)
+	}
+	if records[1].memo != g.notes {
+		t.Errorf("Expected memo to be '%s', got '%s'", g.notes, records[1].memo)
+	}
+
+	if records := g.convertRecords("Liabilities:Credit Card"); len(records) != 0 {
+		t.Errorf("Expected no records for other account, got %d", len(records))
+	}
+}
+
+func TestGnuCashConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		account  string
+		expected string
+	}{
+		{"Assets:Current Assets:Checking Account", "homebank.csv"},
+		{"", "homebank_noaccount.csv"},
+	}
+	for _, tc := range testCases {
+		p := &gnuCashParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "gnucash", "transactions.csv")); err != nil {
+			t.Error(err)
+		}
+		p.SetFormatOptions(FormatOptions{GnuCashAccount: tc.account})
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "gnucash", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is already in the homebank import format, see http://homebank.free.fr/help/misc-csvformat.html
+- The first line must be exactly the homebank header "date;payment;info;payee;memo;amount;category;tags"
+- "date" is accepted as yyyy-mm-dd and dd.mm.yyyy and written as yyyy-mm-dd
+- "payment" must be a number between 0 and 11, an empty "payment" is taken as 0
+- "amount" is accepted with "." or "," as decimal separator. If a "," is found,
+  "." is taken as thousands separator
+- The remaining fields are taken over unchanged
+*/
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// homebankHeader is the header of the homebank CSV file, split into fields
+var homebankHeader = []string{"date", "payment", "info", "payee", "memo", "amount", "category", "tags"}
+
+// homebankMaxPayment is the highest payment code known to homebank
+const homebankMaxPayment = 11
+
+type homebankParser struct {
+	converter
+	entries []homebankRecord
+}
+
+func (p *homebankParser) ParseFile(filepath string) error {
+	p.entries = make([]homebankRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *homebankParser) Parse(in io.Reader) error {
+	p.entries = make([]homebankRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Wrong number of fields is reported as DataParsingError
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+	if !reflect.DeepEqual(records[0], homebankHeader) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]homebankRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		record, err := parseHomebankRow(records[i], csvReader.recordLine(i))
+		if err != nil {
+			if p.skipRowError(err) {
+				continue
+			}
+			return err
+		}
+		entries = append(entries, record)
+	}
+
+	p.entries = entries
+	return nil
+}
+
+// parseHomebankRow validates and normalizes a single row of the homebank CSV
+func parseHomebankRow(row []string, lineNr int) (homebankRecord, error) {
+	if len(row) != len(homebankHeader) {
+		return homebankRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "record",
+		}
+	}
+	date, err := parseHomebankDate(row[0])
+	if err != nil {
+		return homebankRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "date",
+			Err:       err,
+		}
+	}
+	payment := 0
+	if s := strings.TrimSpace(row[1]); s != "" {
+		payment, err = strconv.Atoi(s)
+		if err != nil || payment < 0 || payment > homebankMaxPayment {
+			return homebankRecord{}, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "payment",
+			}
+		}
+	}
+	amount, err := parseHomebankAmount(row[5])
+	if err != nil {
+		return homebankRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      lineNr,
+			Field:     "amount",
+			Err:       err,
+		}
+	}
+	return homebankRecord{
+		date:     date.Format("2006-01-02"),
+		payment:  int8(payment),
+		info:     row[2],
+		payee:    row[3],
+		memo:     row[4],
+		amount:   amount,
+		category: row[6],
+		tags:     row[7],
+	}, nil
+}
+
+// parseHomebankDate parses a date in the format yyyy-mm-dd or dd.mm.yyyy
+func parseHomebankDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	date, err := time.Parse("2006-01-02", s)
+	if err == nil {
+		return date, nil
+	}
+	return time.Parse("02.01.2006", s)
+}
+
+// parseHomebankAmount parses an amount with "." or "," as decimal separator
+func parseHomebankAmount(s string) (float64, error) {
+	if strings.Contains(s, ",") {
+		return parseGermanAmount(s)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+func (p *homebankParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *homebankParser) GetFormat() SourceFormat {
+	return Homebank
+}
+
+func (p *homebankParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *homebankParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *homebankParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *homebankParser) GetEntries() []Transaction {
+	// processRecords works in place, so the parsed entries are copied
+	// to allow converting several times with different settings
+	hRecords := make([]homebankRecord, len(p.entries))
+	copy(hRecords, p.entries)
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomebankName(t *testing.T) {
+	p := &homebankParser{}
+	if p.GetFormat() != Homebank {
+		t.Error("Wrong format")
+	}
+}
+
+func TestHomebankParseFileNonExisting(t *testing.T) {
+	p := &homebankParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestHomebankParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "homebank", "homebank_nok_noheader.csv")
+	p := &homebankParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestHomebankParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"homebank_nok_wrongdate.csv", 4, "date"},
+		{"homebank_nok_wrongpayment.csv", 3, "payment"},
+		{"homebank_nok_wrongamount.csv", 3, "amount"},
+		{"homebank_nok_wrongfields.csv", 3, "record"},
+	}
+	for _, tc := range testCases {
+		p := &homebankParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "homebank", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestHomebankParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "homebank", "homebank_onlyheader.csv")
+	p := &homebankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestHomebankParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "homebank", "homebank_malformed.csv")
+	p := &homebankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestParseHomebankAmount(t *testing.T) {
+	testCases := []struct {
+		amount   string
+		expected float64
+	}{
+		{"-12.340000", -12.34},
+		{"-12,34", -12.34},
+		{"2.500,00", 2500},
+		{" 7 ", 7},
+	}
+	for _, tc := range testCases {
+		amount, err := parseHomebankAmount(tc.amount)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.amount, err)
+		}
+		if amount != tc.expected {
+			t.Errorf("%s: expected %f, got %f", tc.amount, tc.expected, amount)
+		}
+	}
+
+	for _, amount := range []string{"", "1,2,3", "12 EUR"} {
+		if _, err := parseHomebankAmount(amount); err == nil {
+			t.Errorf("%s: expected error", amount)
+		}
+	}
+}
+
+func TestParseHomebankDate(t *testing.T) {
+	for _, date := range []string{"2023-10-02", "02.10.2023", " 2023-10-02 "} {
+		d, err := parseHomebankDate(date)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", date, err)
+			continue
+		}
+		if d.Format("2006-01-02") != "2023-10-02" {
+			t.Errorf("%s: expected 2023-10-02, got %s", date, d.Format("2006-01-02"))
+		}
+	}
+
+	for _, date := range []string{"", "10/02/2023", "2023-02-30", "02.10.23"} {
+		if _, err := parseHomebankDate(date); err == nil {
+			t.Errorf("%s: expected error", date)
+		}
+	}
+}
+
+func TestHomebankConvertToHomebank(t *testing.T) {
+	for _, file := range []string{"homebank_malformed.csv", "homebank.csv"} {
+		p := &homebankParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "homebank", file)); err != nil {
+			t.Error(err)
+		}
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "homebank", "homebank.csv")
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("%s: files are not equal %s, %s", file, expected, tmpFilepath)
+		}
+	}
+}
+
+func TestHomebankWriteHomebankTwice(t *testing.T) {
+	p := &homebankParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "homebank", "homebank.csv")); err != nil {
+		t.Fatal(err)
+	}
+	p.SetCategoryPrefix("Import", false)
+	if err := p.WriteHomebank(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if p.entries[0].category != "Lebensmittel" {
+		t.Errorf("Parsed entries should not be modified, got category '%s'", p.entries[0].category)
+	}
+}
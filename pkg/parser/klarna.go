@@ -0,0 +1,221 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the purchase history export of Klarna, comma separated
+- Only purchases with "Status"=Captured are converted, others like "Cancelled" or "Pending" are skipped
+- Homebanks "date" is "Order date" in the format yyyy-mm-dd
+- Homebanks "amount" is "Order amount" with inverted sign as purchases are expenses
+- Homebanks "payee" is "Merchant", "info" is "Payment method"
+- "Installments" either is empty, the number of installments or a schedule like
+  "2023-10-02:25.00;2023-11-02:25.00". A schedule results in one homebank record per
+  installment date with the installment amount, the memo contains the installment number
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of klarna data
+type klarnaRecord struct {
+	orderDate     time.Time
+	merchant      string
+	orderAmount   float64
+	paymentMethod string
+	installments  []klarnaInstallment
+}
+
+// klarnaInstallment is a single entry of an installment schedule
+type klarnaInstallment struct {
+	date   time.Time
+	amount float64
+}
+
+type klarnaParser struct {
+	converter
+	entries []klarnaRecord
+}
+
+func (p *klarnaParser) ParseFile(filepath string) error {
+	p.entries = make([]klarnaRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *klarnaParser) Parse(in io.Reader) error {
+	p.entries = make([]klarnaRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidKlarnaHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]klarnaRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if row[4] != "Captured" {
+			continue
+		}
+		orderDate, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Order date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Order date",
+				Err:       err,
+			}
+		}
+		orderAmount, err := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err != nil {
+			if p.skipRow(lineNr, "Order amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Order amount",
+				Err:       err,
+			}
+		}
+		installments, err := parseKlarnaInstallments(row[5])
+		if err != nil {
+			if p.skipRow(lineNr, "Installments", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Installments",
+				Err:       err,
+			}
+		}
+		entries = append(entries, klarnaRecord{
+			orderDate:     orderDate,
+			merchant:      row[1],
+			orderAmount:   orderAmount,
+			paymentMethod: row[3],
+			installments:  installments,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+// parseKlarnaInstallments parses an installment schedule like "2023-10-02:25.00;2023-11-02:25.00".
+// An empty field or the plain number of installments is no schedule and returns nil.
+func parseKlarnaInstallments(s string) ([]klarnaInstallment, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return nil, nil
+	}
+	var installments []klarnaInstallment
+	for _, part := range strings.Split(s, ";") {
+		date, amount, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found {
+			return nil, errors.New("missing amount in installment schedule")
+		}
+		installmentDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, err
+		}
+		installmentAmount, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil {
+			return nil, err
+		}
+		installments = append(installments, klarnaInstallment{
+			date:   installmentDate,
+			amount: installmentAmount,
+		})
+	}
+	return installments, nil
+}
+
+func (p *klarnaParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *klarnaParser) GetFormat() SourceFormat {
+	return Klarna
+}
+
+func (p *klarnaParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *klarnaParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *klarnaParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *klarnaParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, kRecord := range p.entries {
+		hRecords = append(hRecords, kRecord.convertRecords()...)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidKlarnaHeader(record []string) bool {
+	expected := []string{
+		"Order date",
+		"Merchant",
+		"Order amount",
+		"Payment method",
+		"Status",
+		"Installments",
+	}
+	return reflect.DeepEqual(record, expected)
+}
+
+// convertRecords converts a single record from klarna to homebank format.
+// A record with installment schedule results in one homebank record per installment.
+func (k *klarnaRecord) convertRecords() []homebankRecord {
+	var h homebankRecord
+	h.payment = 0
+	h.date = k.orderDate.Format("2006-01-02")
+	h.amount = -k.orderAmount
+	h.payee = k.merchant
+	h.info = k.paymentMethod
+	if len(k.installments) == 0 {
+		return []homebankRecord{h}
+	}
+
+	records := make([]homebankRecord, 0, len(k.installments))
+	for i, installment := range k.installments {
+		r := h
+		r.date = installment.date.Format("2006-01-02")
+		r.amount = -installment.amount
+		r.memo = fmt.Sprintf("Installment %d of %d", i+1, len(k.installments))
+		records = append(records, r)
+	}
+	return records
+}
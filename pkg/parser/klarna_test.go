@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKlarnaName(t *testing.T) {
+	p := &klarnaParser{}
+	if p.GetFormat() != Klarna {
+		t.Error("Wrong format")
+	}
+}
+
+func TestKlarnaParseFileNonExisting(t *testing.T) {
+	p := &klarnaParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestKlarnaParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "klarna", "klarna_nok_noheader.csv")
+	p := &klarnaParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestKlarnaParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"klarna_nok_wrongorderdate.csv", 2, "Order date"},
+		{"klarna_nok_wrongorderamount.csv", 5, "Order amount"},
+		{"klarna_nok_wronginstallments.csv", 4, "Installments"},
+	}
+	for _, tc := range testCases {
+		p := &klarnaParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "klarna", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestKlarnaParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "klarna", "klarna_onlyheader.csv")
+	p := &klarnaParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestKlarnaParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "klarna", "klarna.csv")
+	p := &klarnaParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestKlarnaConvertRecords(t *testing.T) {
+	k := klarnaRecord{
+		orderDate:     time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		merchant:      "Zalando",
+		orderAmount:   89.95,
+		paymentMethod: "Pay in 30 days",
+	}
+	records := k.convertRecords()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	h := records[0]
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.amount != -k.orderAmount {
+		t.Errorf("Expected amount to be %f, got %f", -k.orderAmount, h.amount)
+	}
+	if h.payee != k.merchant {
+		t.Errorf("Expected payee to be '%s', got '%s'", k.merchant, h.payee)
+	}
+	if h.info != k.paymentMethod {
+		t.Errorf("Expected info to be '%s', got '%s'", k.paymentMethod, h.info)
+	}
+
+	k.installments = []klarnaInstallment{
+		{time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC), 44.95},
+		{time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), 45},
+	}
+	records = k.convertRecords()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[1].date != "2023-11-01" {
+		t.Errorf("Expected date to be 2023-11-01, got '%s'", records[1].date)
+	}
+	if records[1].amount != -45 {
+		t.Errorf("Expected amount to be -45, got %f", records[1].amount)
+	}
+	if records[1].memo != "Installment 2 of 2" {
+		t.Errorf("Expected memo to be 'Installment 2 of 2', got '%s'", records[1].memo)
+	}
+}
+
+func TestParseKlarnaInstallments(t *testing.T) {
+	for _, s := range []string{"", " ", "4"} {
+		if installments, err := parseKlarnaInstallments(s); installments != nil || err != nil {
+			t.Errorf("'%s' should be no schedule", s)
+		}
+	}
+	installments, err := parseKlarnaInstallments("2023-10-02:25.00; 2023-11-01:25.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []klarnaInstallment{
+		{time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC), 25},
+		{time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), 25.5},
+	}
+	if !reflect.DeepEqual(installments, expected) {
+		t.Errorf("Expected %v, got %v", expected, installments)
+	}
+	for _, s := range []string{"2023-10-02", "02.10.2023:25.00", "2023-10-02:abc"} {
+		if _, err := parseKlarnaInstallments(s); err == nil {
+			t.Errorf("'%s' should fail", s)
+		}
+	}
+}
+
+func TestKlarnaConvertToHomebank(t *testing.T) {
+	p := &klarnaParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "klarna", "klarna.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "klarna", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,197 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The Miles & More credit card portal only offers an excel (XLSX) download
+- The name of the sheet is not fixed ("Umsätze"), so all sheets are searched for the header row.
+  Lines above the header are skipped
+- Entries without "Buchungsdatum" are not yet booked and are skipped
+- Homebanks "date" is "Umsatzdatum", "amount" is "Betrag" in German notation
+- Homebanks "payee" is "Beschreibung"
+- The earned "Meilen" are written into the tags like "Meilen_12"
+- All records get the payment "credit card"
+*/
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Single record of Miles & More credit card data
+type milesAndMoreRecord struct {
+	umsatzdatum  time.Time
+	beschreibung string
+	betrag       float64
+	meilen       int
+}
+
+type milesAndMoreParser struct {
+	converter
+	entries []milesAndMoreRecord
+}
+
+// milesAndMoreHeader is the header row of the excel sheet
+var milesAndMoreHeader = []string{
+	"Buchungsdatum",
+	"Umsatzdatum",
+	"Beschreibung",
+	"Betrag",
+	"Währung",
+	"Meilen",
+}
+
+func (p *milesAndMoreParser) SniffHeader(head []byte) bool {
+	return isXlsx(head)
+}
+
+func (p *milesAndMoreParser) GetFormat() SourceFormat {
+	return MilesAndMore
+}
+
+func (p *milesAndMoreParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func isValidMilesAndMoreHeader(record []string) bool {
+	return reflect.DeepEqual(record, milesAndMoreHeader)
+}
+
+func (p *milesAndMoreParser) ParseFile(filepath string) error {
+	p.entries = make([]milesAndMoreRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *milesAndMoreParser) Parse(in io.Reader) error {
+	p.entries = make([]milesAndMoreRecord, 0)
+	p.rowErrors = nil
+	f, err := excelize.OpenReader(in)
+	if err != nil {
+		return &ParserError{ErrorType: IOError, Err: err}
+	}
+	defer f.Close()
+
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			continue
+		}
+		for headerIndex, row := range rows {
+			if isValidMilesAndMoreHeader(row) {
+				return p.parseRows(rows, headerIndex)
+			}
+		}
+	}
+	return &ParserError{ErrorType: HeaderError}
+}
+
+// parseRows parses the data rows following the header row at headerIndex
+func (p *milesAndMoreParser) parseRows(rows [][]string, headerIndex int) error {
+	entries := make([]milesAndMoreRecord, 0, len(rows)-headerIndex-1)
+	for i := headerIndex + 1; i < len(rows); i++ {
+		row := rows[i]
+		lineNr := i + 1
+		// Trailing empty cells are not returned
+		for len(row) < len(milesAndMoreHeader) {
+			row = append(row, "")
+		}
+
+		// Entries with an empty "Buchungsdatum" are not yet booked
+		if strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if _, err := time.Parse("02.01.2006", row[0]); err != nil {
+			if p.skipRow(lineNr, "Buchungsdatum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Buchungsdatum",
+				Err:       err,
+			}
+		}
+		umsatzdatum, err := time.Parse("02.01.2006", row[1])
+		if err != nil {
+			if p.skipRow(lineNr, "Umsatzdatum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Umsatzdatum",
+				Err:       err,
+			}
+		}
+		betrag, err := parseGermanAmount(row[3])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag",
+				Err:       err,
+			}
+		}
+		meilen := 0
+		if strings.TrimSpace(row[5]) != "" {
+			meilen, err = strconv.Atoi(strings.TrimSpace(row[5]))
+			if err != nil {
+				if p.skipRow(lineNr, "Meilen", err) {
+					continue
+				}
+				return &ParserError{
+					ErrorType: DataParsingError,
+					Line:      lineNr,
+					Field:     "Meilen",
+					Err:       err,
+				}
+			}
+		}
+		entries = append(entries, milesAndMoreRecord{
+			umsatzdatum:  umsatzdatum,
+			beschreibung: row[2],
+			betrag:       betrag,
+			meilen:       meilen,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (m *milesAndMoreRecord) convertRecord() (h homebankRecord) {
+	h.payment = 1 // Credit card
+	h.date = m.umsatzdatum.Format("2006-01-02")
+	h.payee = m.beschreibung
+	h.amount = m.betrag
+	if m.meilen != 0 {
+		h.tags = "Meilen_" + strconv.Itoa(m.meilen)
+	}
+	return
+}
+
+func (p *milesAndMoreParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *milesAndMoreParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *milesAndMoreParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, mRecord := range p.entries {
+		hRecord := mRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
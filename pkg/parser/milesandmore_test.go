@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMilesAndMoreName(t *testing.T) {
+	p := &milesAndMoreParser{}
+	if p.GetFormat() != MilesAndMore {
+		t.Error("Wrong format")
+	}
+}
+
+func TestMilesAndMoreParseFileNonExisting(t *testing.T) {
+	p := &milesAndMoreParser{}
+	err := p.ParseFile("non_existing_file.xlsx")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMilesAndMoreParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "milesandmore", "Umsaetze_nok_noheader.xlsx")
+	p := &milesAndMoreParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMilesAndMoreParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Umsaetze_nok_wrongbuchungsdatum.xlsx", 6, "Buchungsdatum"},
+		{"Umsaetze_nok_wrongumsatzdatum.xlsx", 7, "Umsatzdatum"},
+		{"Umsaetze_nok_wrongbetrag.xlsx", 7, "Betrag"},
+		{"Umsaetze_nok_wrongmeilen.xlsx", 6, "Meilen"},
+	}
+	for _, tc := range testCases {
+		p := &milesAndMoreParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "milesandmore", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestMilesAndMoreParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "milesandmore", "Umsaetze_onlyheader.xlsx")
+	p := &milesAndMoreParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMilesAndMoreParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "milesandmore", "Umsaetze.xlsx")
+	p := &milesAndMoreParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestMilesAndMoreConvertRecord(t *testing.T) {
+	m := milesAndMoreRecord{
+		umsatzdatum:  time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		beschreibung: "REWE SAGT DANKE",
+		betrag:       -23.45,
+		meilen:       11,
+	}
+	h := m.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 1 {
+		t.Errorf("Expected payment to be 1, got %d", h.payment)
+	}
+	if h.amount != m.betrag {
+		t.Errorf("Expected amount to be %f, got %f", m.betrag, h.amount)
+	}
+	if h.payee != m.beschreibung {
+		t.Errorf("Expected payee to be '%s', got '%s'", m.beschreibung, h.payee)
+	}
+	if h.tags != "Meilen_11" {
+		t.Errorf("Expected tags to be 'Meilen_11', got '%s'", h.tags)
+	}
+	m.meilen = 0
+	if h := m.convertRecord(); h.tags != "" {
+		t.Errorf("Expected no tags, got '%s'", h.tags)
+	}
+}
+
+func TestMilesAndMoreConvertToHomebank(t *testing.T) {
+	p := &milesAndMoreParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "milesandmore", "Umsaetze.xlsx")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "milesandmore", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
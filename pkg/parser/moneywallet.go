@@ -1,8 +1,7 @@
 package parser
 
 import (
-	"encoding/csv"
-	"os"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
@@ -20,20 +19,23 @@ type moneywalletRecord struct {
 }
 
 type moneywalletParser struct {
+	converter
 	entries []moneywalletRecord
 }
 
 func (m *moneywalletParser) ParseFile(filepath string) error {
 	m.entries = make([]moneywalletRecord, 0)
-	infile, err := os.Open(filepath)
-	if err != nil {
-		return &ParserError{ErrorType: IOError}
-	}
-	defer infile.Close()
-	csvReader := csv.NewReader(infile)
+	return parseFile(filepath, m.Parse)
+}
+
+func (m *moneywalletParser) Parse(in io.Reader) error {
+	m.entries = make([]moneywalletRecord, 0)
+	m.rowErrors = nil
+	csvReader := m.newCSVReader(in)
+	csvReader.FieldsPerRecord = -1 // Short rows are reported by checkFieldCount
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		return &ParserError{ErrorType: IOError}
+		return err
 	}
 	if len(records) == 0 {
 		return &ParserError{ErrorType: HeaderError}
@@ -49,13 +51,25 @@ func (m *moneywalletParser) ParseFile(filepath string) error {
 		return nil
 	}
 
-	for lineNr, row := range records[1:] {
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if err := checkFieldCount(row, len(records[0]), lineNr); err != nil {
+			if m.skipRowError(err) {
+				continue
+			}
+			return err
+		}
 		date, err := time.Parse("2006-01-02 15:04:05", row[3])
 		if err != nil {
+			if m.skipRow(lineNr, "datetime", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNr + 1,
+				Line:      lineNr,
 				Field:     "datetime",
+				Err:       err,
 			}
 		}
 
@@ -63,10 +77,14 @@ func (m *moneywalletParser) ParseFile(filepath string) error {
 		var money float64
 		money, err = strconv.ParseFloat(strings.TrimSpace(moneyString), 64)
 		if err != nil {
+			if m.skipRow(lineNr, "money", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNr + 1,
+				Line:      lineNr,
 				Field:     "money",
+				Err:       err,
 			}
 		}
 
@@ -84,6 +102,10 @@ func (m *moneywalletParser) ParseFile(filepath string) error {
 	return nil
 }
 
+func (m *moneywalletParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, m.Parse)
+}
+
 func (m *moneywalletParser) GetFormat() SourceFormat {
 	return MoneyWallet
 }
@@ -93,18 +115,20 @@ func (m *moneywalletParser) GetNumberOfEntries() int {
 }
 
 func (m *moneywalletParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, m.WriteHomebank)
+}
+
+func (m *moneywalletParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, m.GetEntries(), WithOutputOptions(m.outputOptions))
+}
+
+func (m *moneywalletParser) GetEntries() []Transaction {
 	hRecords := make([]homebankRecord, 0, len(m.entries))
 	for _, mRecord := range m.entries {
 		hRecord := mRecord.convertRecord()
 		hRecords = append(hRecords, hRecord)
 	}
-
-	err := writeHomeBankRecords(hRecords, filepath)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return toTransactions(m.processRecords(hRecords, m.GetFormat()))
 }
 
 func isValidMoneyWalletHeader(record []string) bool {
@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -95,8 +96,8 @@ func TestMoneywalletParseFileNokWrongDatetime(t *testing.T) {
 		if pError.ErrorType != DataParsingError {
 			t.Error("Expected DataParsingError")
 		}
-		if pError.Line != 1 {
-			t.Errorf("Expected DataParsingError on line 1, got %d", pError.Line)
+		if pError.Line != 2 {
+			t.Errorf("Expected DataParsingError on line 2, got %d", pError.Line)
 		}
 		if pError.Field != "datetime" {
 			t.Errorf("Expected field 'datetime', got '%s' instead", pError.Field)
@@ -122,8 +123,8 @@ func TestMoneywalletParseFileNokWrongMoney(t *testing.T) {
 		if pError.ErrorType != DataParsingError {
 			t.Error("Expected DataParsingError")
 		}
-		if pError.Line != 1 {
-			t.Errorf("Expected DataParsingError on line 1, got %d", pError.Line)
+		if pError.Line != 2 {
+			t.Errorf("Expected DataParsingError on line 2, got %d", pError.Line)
 		}
 		if pError.Field != "money" {
 			t.Errorf("Expected field 'money', got '%s' instead", pError.Field)
@@ -257,3 +258,43 @@ func TestIsValidMoneyWalletHeader(t *testing.T) {
 		t.Error("Header should be NOK (wrong length)")
 	}
 }
+
+func TestMoneywalletParseErrorPhysicalLine(t *testing.T) {
+	// The error is reported on the physical line of the file, counting the
+	// empty line and the line break within the quoted description
+	content := `"wallet","currency","category","datetime","money","description"
+"Bargeld","EUR","Essen","2020-12-25 09:23:06","-20,00","first line
+second line"
+
+"Bargeld","EUR","Essen","2020-12-xx 09:23:06","-20,00","essen"
+`
+	mw := &moneywalletParser{}
+	err := mw.Parse(strings.NewReader(content))
+	var pError *ParserError
+	if !errors.As(err, &pError) {
+		t.Fatalf("ParserError expected, got %v", err)
+	}
+	if pError.Line != 5 {
+		t.Errorf("Expected error on line 5, got %d", pError.Line)
+	}
+}
+
+func TestMoneywalletConvertToHomebankBOM(t *testing.T) {
+	// Same content as MoneyWallet_export_1.csv, with UTF-8 BOM and encoded as UTF-16 LE
+	for _, input := range []string{"MoneyWallet_export_bom.csv", "MoneyWallet_export_utf16le.csv"} {
+		mw := &moneywalletParser{}
+		if err := mw.ParseFile(filepath.Join("testfiles", "moneywallet", input)); err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := mw.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		expected := filepath.Join("testfiles", "moneywallet", "converted_1.csv")
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("%s: files are not equal %s, %s", input, expected, tmpFilepath)
+		}
+	}
+}
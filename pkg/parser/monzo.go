@@ -0,0 +1,258 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the transaction export of Monzo, comma separated
+- The columns are looked up by their name in the header as newer exports append further columns
+- Homebanks "date" is "Date" in the format dd/mm/yyyy, validated together with "Time" as hh:mm:ss
+- Homebanks "amount" is the signed "Amount" with a dot as decimal separator
+- Homebanks "info" is "Type", "payee" is "Name", "category" is "Category"
+- Homebanks "memo" is "Notes and #tags" without the hashtags. The hashtags are converted into
+  homebank tags without the leading "#"
+- A "Category split" like "Groceries:10.00,Eating out:5.00" expands the transaction into one
+  record per category. The amount is distributed proportionally to the values of the split
+*/
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of monzo data
+type monzoRecord struct {
+	date          time.Time
+	typ           string
+	name          string
+	category      string
+	amount        float64
+	notes         string
+	categorySplit []monzoCategorySplit
+}
+
+// monzoCategorySplit is a single part of a "Category split"
+type monzoCategorySplit struct {
+	category string
+	value    float64
+}
+
+type monzoParser struct {
+	converter
+	entries []monzoRecord
+}
+
+// monzoColumns are the columns needed from the Monzo CSV
+var monzoColumns = []string{
+	"Transaction ID",
+	"Date",
+	"Time",
+	"Type",
+	"Name",
+	"Category",
+	"Amount",
+	"Notes and #tags",
+	"Category split",
+}
+
+func (m *monzoParser) ParseFile(filepath string) error {
+	m.entries = make([]monzoRecord, 0)
+	return parseFile(filepath, m.Parse)
+}
+
+func (m *monzoParser) Parse(in io.Reader) error {
+	m.entries = make([]monzoRecord, 0)
+	m.rowErrors = nil
+	csvReader := m.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], monzoColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]monzoRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("02/01/2006", row[columns["Date"]])
+		if err != nil {
+			if m.skipRow(lineNr, "Date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Date",
+				Err:       err,
+			}
+		}
+		if _, err := time.Parse("15:04:05", row[columns["Time"]]); err != nil {
+			if m.skipRow(lineNr, "Time", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Time",
+				Err:       err,
+			}
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[columns["Amount"]]), 64)
+		if err != nil {
+			if m.skipRow(lineNr, "Amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Amount",
+				Err:       err,
+			}
+		}
+		categorySplit, err := parseMonzoCategorySplit(row[columns["Category split"]])
+		if err != nil {
+			if m.skipRow(lineNr, "Category split", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Category split",
+				Err:       err,
+			}
+		}
+		entries = append(entries, monzoRecord{
+			date:          date,
+			typ:           row[columns["Type"]],
+			name:          row[columns["Name"]],
+			category:      row[columns["Category"]],
+			amount:        amount,
+			notes:         row[columns["Notes and #tags"]],
+			categorySplit: categorySplit,
+		})
+	}
+
+	m.entries = entries
+	return nil
+}
+
+// parseMonzoCategorySplit parses a "Category split" like "Groceries:10.00,Eating out:5.00".
+// An empty split returns nil.
+func parseMonzoCategorySplit(s string) ([]monzoCategorySplit, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var split []monzoCategorySplit
+	sum := 0.0
+	for _, part := range strings.Split(s, ",") {
+		index := strings.LastIndex(part, ":")
+		if index < 0 {
+			return nil, errors.New("missing value in category split")
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(part[index+1:]), 64)
+		if err != nil {
+			return nil, err
+		}
+		split = append(split, monzoCategorySplit{
+			category: strings.TrimSpace(part[:index]),
+			value:    math.Abs(value),
+		})
+		sum += math.Abs(value)
+	}
+	if sum == 0 {
+		return nil, errors.New("category split without values")
+	}
+	return split, nil
+}
+
+func (m *monzoParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, m.Parse)
+}
+
+func (m *monzoParser) GetFormat() SourceFormat {
+	return Monzo
+}
+
+func (m *monzoParser) GetNumberOfEntries() int {
+	return len(m.entries)
+}
+
+func (m *monzoParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, m.WriteHomebank)
+}
+
+func (m *monzoParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, m.GetEntries(), WithOutputOptions(m.outputOptions))
+}
+
+func (m *monzoParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(m.entries))
+	for _, mRecord := range m.entries {
+		hRecords = append(hRecords, mRecord.convertRecords()...)
+	}
+	return toTransactions(m.processRecords(hRecords, m.GetFormat()))
+}
+
+// convertRecords converts a single record from monzo to homebank format.
+// A record with category split results in one homebank record per category.
+func (m *monzoRecord) convertRecords() []homebankRecord {
+	var h homebankRecord
+	h.payment = 0
+	h.date = m.date.Format("2006-01-02")
+	h.amount = m.amount
+	h.info = m.typ
+	h.payee = m.name
+	h.category = m.category
+	h.memo, h.tags = splitMonzoNotes(m.notes)
+	if len(m.categorySplit) == 0 {
+		return []homebankRecord{h}
+	}
+
+	sum := 0.0
+	for _, part := range m.categorySplit {
+		sum += part.value
+	}
+	records := make([]homebankRecord, 0, len(m.categorySplit))
+	remaining := m.amount
+	for i, part := range m.categorySplit {
+		r := h
+		r.category = part.category
+		if i == len(m.categorySplit)-1 {
+			// The last part gets the rest, so that rounding does not change the total
+			r.amount = math.Round(remaining*100) / 100
+		} else {
+			r.amount = math.Round(m.amount*part.value/sum*100) / 100
+			remaining -= r.amount
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// splitMonzoNotes separates the hashtags like "#holiday" from the notes.
+// The tags are returned without "#", separated by space.
+func splitMonzoNotes(notes string) (memo string, tags string) {
+	var memoWords, tagWords []string
+	for _, word := range strings.Fields(notes) {
+		if len(word) > 1 && strings.HasPrefix(word, "#") {
+			tagWords = append(tagWords, word[1:])
+		} else {
+			memoWords = append(memoWords, word)
+		}
+	}
+	return strings.Join(memoWords, " "), strings.Join(tagWords, " ")
+}
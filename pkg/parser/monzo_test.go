@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMonzoName(t *testing.T) {
+	p := &monzoParser{}
+	if p.GetFormat() != Monzo {
+		t.Error("Wrong format")
+	}
+}
+
+func TestMonzoParseFileNonExisting(t *testing.T) {
+	p := &monzoParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMonzoParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "monzo", "MonzoDataExport_nok_noheader.csv")
+	p := &monzoParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMonzoParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"MonzoDataExport_nok_wrongdate.csv", 3, "Date"},
+		{"MonzoDataExport_nok_wrongtime.csv", 3, "Time"},
+		{"MonzoDataExport_nok_wrongamount.csv", 2, "Amount"},
+		{"MonzoDataExport_nok_wrongcategorysplit.csv", 4, "Category split"},
+	}
+	for _, tc := range testCases {
+		p := &monzoParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "monzo", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestMonzoParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "monzo", "MonzoDataExport_onlyheader.csv")
+	p := &monzoParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMonzoParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "monzo", "MonzoDataExport.csv")
+	p := &monzoParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestMonzoConvertRecords(t *testing.T) {
+	m := monzoRecord{
+		date:     time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC),
+		typ:      "Card payment",
+		name:     "Tesco",
+		category: "Groceries",
+		amount:   -10,
+		notes:    "Weekly shop #home",
+		categorySplit: []monzoCategorySplit{
+			{category: "Groceries", value: 2},
+			{category: "Household", value: 1},
+		},
+	}
+	records := m.convertRecords()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	expected := []struct {
+		category string
+		amount   float64
+	}{
+		{"Groceries", -6.67},
+		{"Household", -3.33},
+	}
+	for i, h := range records {
+		if h.date != "2023-10-07" {
+			t.Errorf("Expected date to be 2023-10-07, got '%s'", h.date)
+		}
+		if h.payee != m.name {
+			t.Errorf("Expected payee to be '%s', got '%s'", m.name, h.payee)
+		}
+		if h.memo != "Weekly shop" {
+			t.Errorf("Expected memo to be 'Weekly shop', got '%s'", h.memo)
+		}
+		if h.tags != "home" {
+			t.Errorf("Expected tags to be 'home', got '%s'", h.tags)
+		}
+		if h.category != expected[i].category {
+			t.Errorf("Expected category to be '%s', got '%s'", expected[i].category, h.category)
+		}
+		if h.amount != expected[i].amount {
+			t.Errorf("Expected amount to be %f, got %f", expected[i].amount, h.amount)
+		}
+	}
+
+	m.categorySplit = nil
+	records = m.convertRecords()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if records[0].category != m.category || records[0].amount != m.amount {
+		t.Errorf("Expected unsplit record, got '%s' %f", records[0].category, records[0].amount)
+	}
+}
+
+func TestSplitMonzoNotes(t *testing.T) {
+	testCases := []struct {
+		notes string
+		memo  string
+		tags  string
+	}{
+		{"", "", ""},
+		{"Lunch", "Lunch", ""},
+		{"#work", "", "work"},
+		{"Lunch with  #work colleagues #food", "Lunch with colleagues", "work food"},
+		{"Price in # only", "Price in # only", ""},
+	}
+	for _, tc := range testCases {
+		memo, tags := splitMonzoNotes(tc.notes)
+		if memo != tc.memo || tags != tc.tags {
+			t.Errorf("'%s': expected '%s'/'%s', got '%s'/'%s'", tc.notes, tc.memo, tc.tags, memo, tags)
+		}
+	}
+}
+
+func TestParseMonzoCategorySplit(t *testing.T) {
+	split, err := parseMonzoCategorySplit("Groceries:-20.00, Eating out: 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []monzoCategorySplit{{"Groceries", 20}, {"Eating out", 10}}
+	if len(split) != len(expected) {
+		t.Fatalf("Expected %d parts, got %d", len(expected), len(split))
+	}
+	for i := range expected {
+		if split[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], split[i])
+		}
+	}
+	for _, s := range []string{"Groceries", "Groceries:abc", "Groceries:0"} {
+		if _, err := parseMonzoCategorySplit(s); err == nil {
+			t.Errorf("'%s' should fail", s)
+		}
+	}
+	if split, err := parseMonzoCategorySplit(""); split != nil || err != nil {
+		t.Error("Empty split should return nil")
+	}
+}
+
+func TestMonzoConvertToHomebank(t *testing.T) {
+	p := &monzoParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "monzo", "MonzoDataExport.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "monzo", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,281 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- MT940 is the SWIFT format for account statements, often stored with the extension ".sta"
+- The file is ISO-8859-1 encoded unless it is valid UTF-8
+- A file can contain several statements, each starting with the field ":20:" and ending with "-"
+- Each transaction consists of a ":61:" line followed by an optional ":86:" field,
+  which can span several lines
+- Homebanks "date" is the value date of ":61:", "amount" is the amount of ":61:"
+  where the debit/credit mark gives the sign. Both "," and "." are accepted as decimal separator
+- If ":86:" is structured with "?" subfields as used by German banks, homebanks "info" is the
+  posting text "?00", "payee" is the name "?32"/"?33" and "memo" is the purpose "?20"-"?29"
+  and "?60"-"?63". Otherwise the whole ":86:" field is used as "memo"
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single transaction of a MT940 statement
+type mt940Record struct {
+	valueDate       time.Time
+	amount          float64
+	transactionType string // e.g. "NTRF" for transfer
+	postingText     string // Subfield ?00 of :86:
+	payee           string // Subfields ?32 and ?33 of :86:
+	purpose         string // Subfields ?20-?29 and ?60-?63 of :86: or the whole unstructured :86:
+}
+
+type mt940Parser struct {
+	converter
+	entries []mt940Record
+}
+
+// mt940Field is a single field like ":61:" including its continuation lines
+type mt940Field struct {
+	tag     string // e.g. "61" or "60F"
+	content string
+	line    int // Line number where the field starts
+}
+
+var mt940FieldRegexp = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+
+// mt940TransactionRegexp matches the ":61:" content: value date (YYMMDD), optional entry
+// date (MMDD), debit/credit mark, optional funds code, amount and the remaining fields
+var mt940TransactionRegexp = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+[,.]\d*)(.*)$`)
+
+func (p *mt940Parser) ParseFile(filepath string) error {
+	p.entries = make([]mt940Record, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *mt940Parser) Parse(in io.Reader) error {
+	p.entries = make([]mt940Record, 0)
+	p.rowErrors = nil
+	content, err := io.ReadAll(newDecodingReader(in, p.parseOptions.Encoding))
+	if err != nil {
+		return &ParserError{ErrorType: IOError, Err: err}
+	}
+
+	fields, err := p.splitMt940Fields(content)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+	if fields[0].tag != "20" {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      fields[0].line,
+		}
+	}
+
+	entries := make([]mt940Record, 0)
+	for i, field := range fields {
+		if field.tag != "61" {
+			continue
+		}
+		record, err := parseMt940Transaction(field)
+		if err != nil {
+			if p.skipRowError(err) {
+				continue
+			}
+			return err
+		}
+		if i+1 < len(fields) && fields[i+1].tag == "86" {
+			record.setInformation(fields[i+1].content)
+		}
+		entries = append(entries, record)
+	}
+
+	p.entries = entries
+	return nil
+}
+
+// splitMt940Fields splits the content into its fields.
+// Lines outside of fields like the SWIFT header blocks "{1:...}" or the
+// statement end "-" are skipped.
+func (p *mt940Parser) splitMt940Fields(content []byte) ([]mt940Field, error) {
+	maxLineBytes := p.parseOptions.withDefaults().MaxLineBytes
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	var fields []mt940Field
+	inField := false
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if match := mt940FieldRegexp.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], content: match[2], line: lineNr})
+			inField = true
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "-") || strings.HasPrefix(line, "{") {
+			inField = false
+			continue
+		}
+		if !inField {
+			return nil, &ParserError{ErrorType: HeaderError, Line: lineNr}
+		}
+		fields[len(fields)-1].content += line
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr + 1,
+				Field:     "record",
+				Err:       fmt.Errorf("%w: line longer than %d bytes", errLimitExceeded, maxLineBytes),
+			}
+		}
+		return nil, &ParserError{ErrorType: IOError, Err: err}
+	}
+	return fields, nil
+}
+
+// parseMt940Transaction parses the content of a ":61:" field
+func parseMt940Transaction(field mt940Field) (mt940Record, error) {
+	parseError := &ParserError{
+		ErrorType: DataParsingError,
+		Line:      field.line,
+		Field:     ":61:",
+	}
+	match := mt940TransactionRegexp.FindStringSubmatch(field.content)
+	if match == nil {
+		return mt940Record{}, parseError
+	}
+	valueDate, err := time.Parse("060102", match[1])
+	if err != nil {
+		return mt940Record{}, parseError
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(match[5], ",", ".", 1), 64)
+	if err != nil {
+		return mt940Record{}, parseError
+	}
+	// Debit and reversal of credit reduce the balance
+	if match[3] == "D" || match[3] == "RC" {
+		amount = -amount
+	}
+	record := mt940Record{
+		valueDate: valueDate,
+		amount:    amount,
+	}
+	if rest := match[6]; len(rest) >= 4 {
+		record.transactionType = rest[:4]
+	}
+	return record, nil
+}
+
+// mt940SepaIdentifiers start a new part of the SEPA purpose, e.g. "EREF+" the end to end reference
+var mt940SepaIdentifiers = []string{"EREF+", "KREF+", "MREF+", "CRED+", "DEBT+", "SVWZ+", "ABWA+", "ABWE+"}
+
+// setInformation sets the fields from the content of a ":86:" field
+func (r *mt940Record) setInformation(content string) {
+	subfields, ok := splitMt940Subfields(content)
+	if !ok {
+		r.purpose = strings.TrimSpace(content)
+		return
+	}
+	var purpose, payee strings.Builder
+	// The purpose is split into subfields of fixed length, so they are joined without
+	// separator except for the start of a new SEPA part
+	for _, key := range []string{"20", "21", "22", "23", "24", "25", "26", "27", "28", "29", "60", "61", "62", "63"} {
+		value := subfields[key]
+		if purpose.Len() > 0 && hasAnyPrefix(value, mt940SepaIdentifiers) {
+			purpose.WriteString(" ")
+		}
+		purpose.WriteString(value)
+	}
+	payee.WriteString(subfields["32"])
+	payee.WriteString(subfields["33"])
+	r.postingText = strings.TrimSpace(subfields["00"])
+	r.purpose = strings.TrimSpace(purpose.String())
+	r.payee = strings.TrimSpace(payee.String())
+}
+
+// splitMt940Subfields splits a structured ":86:" field like "106?00KARTENZAHLUNG?20Text"
+// into its subfields. The first three digits are the business transaction code, followed
+// by the separator character. ok is false if the content is not structured.
+func splitMt940Subfields(content string) (subfields map[string]string, ok bool) {
+	if len(content) < 4 {
+		return nil, false
+	}
+	if _, err := strconv.Atoi(content[:3]); err != nil {
+		return nil, false
+	}
+	separator := content[3:4]
+	if separator != "?" {
+		return nil, false
+	}
+	subfields = make(map[string]string)
+	for _, part := range strings.Split(content[4:], separator) {
+		if len(part) < 2 {
+			continue
+		}
+		subfields[part[:2]] += part[2:]
+	}
+	return subfields, true
+}
+
+// hasAnyPrefix reports whether s begins with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *mt940Parser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *mt940Parser) GetFormat() SourceFormat {
+	return MT940
+}
+
+func (p *mt940Parser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *mt940Parser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *mt940Parser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *mt940Parser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, mRecord := range p.entries {
+		hRecord := mRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func (r *mt940Record) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = r.valueDate.Format("2006-01-02")
+	h.amount = r.amount
+	h.info = r.postingText
+	h.payee = r.payee
+	h.memo = r.purpose
+	return
+}
@@ -0,0 +1,249 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMt940Name(t *testing.T) {
+	p := &mt940Parser{}
+	if p.GetFormat() != MT940 {
+		t.Error("Wrong format")
+	}
+}
+
+func TestMt940ParseFileNonExisting(t *testing.T) {
+	p := &mt940Parser{}
+	err := p.ParseFile("non_existing_file.sta")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMt940ParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "mt940", "mt940_nok_noheader.sta")
+	p := &mt940Parser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMt940ParseFileNokCsv(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	p := &mt940Parser{}
+	err := p.ParseFile(fpath)
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+}
+
+func TestMt940ParseFileNokWrongTransaction(t *testing.T) {
+	fpath := filepath.Join("testfiles", "mt940", "mt940_nok_wrong61.sta")
+	p := &mt940Parser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 7 {
+			t.Errorf("Expected error on line 7, got %d", pError.Line)
+		}
+		if pError.Field != ":61:" {
+			t.Errorf("Expected error on field ':61:', got '%s'", pError.Field)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMt940ParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "mt940", "mt940_onlyheader.sta")
+	p := &mt940Parser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestMt940ParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "mt940", "statement.sta")
+	p := &mt940Parser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	// Two statements with two transactions each
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestParseMt940Transaction(t *testing.T) {
+	testCases := []struct {
+		content         string
+		valueDate       time.Time
+		amount          float64
+		transactionType string
+	}{
+		{"2310021002DR12,34NMSCNONREF", time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC), -12.34, "NMSC"},
+		{"2310051005CR2500,00NTRFNONREF", time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC), 2500, "NTRF"},
+		{"231010D45.5NDDTKREF+ABC", time.Date(2023, 10, 10, 0, 0, 0, 0, time.UTC), -45.5, "NDDT"},
+		{"231011RD10,00NMSCNONREF", time.Date(2023, 10, 11, 0, 0, 0, 0, time.UTC), 10, "NMSC"},
+		{"231012RC1,NMSC", time.Date(2023, 10, 12, 0, 0, 0, 0, time.UTC), -1, "NMSC"},
+		{"231013C7,5", time.Date(2023, 10, 13, 0, 0, 0, 0, time.UTC), 7.5, ""},
+	}
+	for _, tc := range testCases {
+		r, err := parseMt940Transaction(mt940Field{tag: "61", content: tc.content, line: 1})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.content, err)
+			continue
+		}
+		if !r.valueDate.Equal(tc.valueDate) {
+			t.Errorf("%s: expected value date %s, got %s", tc.content, tc.valueDate, r.valueDate)
+		}
+		if r.amount != tc.amount {
+			t.Errorf("%s: expected amount %f, got %f", tc.content, tc.amount, r.amount)
+		}
+		if r.transactionType != tc.transactionType {
+			t.Errorf("%s: expected transaction type '%s', got '%s'", tc.content, tc.transactionType, r.transactionType)
+		}
+	}
+
+	for _, content := range []string{"", "2310X2D1,00", "231302D1,00", "231002X1,00", "231002D"} {
+		if _, err := parseMt940Transaction(mt940Field{tag: "61", content: content, line: 3}); err == nil {
+			t.Errorf("%s: expected error", content)
+		}
+	}
+}
+
+func TestSplitMt940Subfields(t *testing.T) {
+	subfields, ok := splitMt940Subfields("106?00KARTENZAHLUNG?20Text 1?21Text 2?32Name")
+	if !ok {
+		t.Fatal("Expected structured content")
+	}
+	expected := map[string]string{
+		"00": "KARTENZAHLUNG",
+		"20": "Text 1",
+		"21": "Text 2",
+		"32": "Name",
+	}
+	if !reflect.DeepEqual(subfields, expected) {
+		t.Errorf("Expected %v, got %v", expected, subfields)
+	}
+
+	for _, content := range []string{"", "Rueckbuchung Gebuehr", "10?00", "ABC?00Text"} {
+		if _, ok := splitMt940Subfields(content); ok {
+			t.Errorf("'%s' should not be structured", content)
+		}
+	}
+}
+
+func TestMt940ConvertRecord(t *testing.T) {
+	r := mt940Record{
+		valueDate:   time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		amount:      -12.34,
+		postingText: "KARTENZAHLUNG",
+		payee:       "Bäckerei",
+		purpose:     "SVWZ+Einkauf",
+	}
+	h := r.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != r.amount {
+		t.Errorf("Expected amount to be %f, got %f", r.amount, h.amount)
+	}
+	if h.info != r.postingText {
+		t.Errorf("Expected info to be '%s', got '%s'", r.postingText, h.info)
+	}
+	if h.payee != r.payee {
+		t.Errorf("Expected payee to be '%s', got '%s'", r.payee, h.payee)
+	}
+	if h.memo != r.purpose {
+		t.Errorf("Expected memo to be '%s', got '%s'", r.purpose, h.memo)
+	}
+}
+
+func TestMt940ConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "mt940", "statement.sta")
+	p := &mt940Parser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = p.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "mt940", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
+
+func TestMt940ParseFileLineTooLong(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "statement.sta")
+	content := ":20:STARTUMSE\n:86:" + strings.Repeat("x", 2*DefaultMaxLineBytes) + "\n"
+	if err := os.WriteFile(fpath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := &mt940Parser{}
+	err := p.ParseFile(fpath)
+	var pError *ParserError
+	if !errors.As(err, &pError) || !isLimitError(pError) {
+		t.Errorf("Expected DataParsingError of the limits for field 'record', got %v", err)
+	}
+}
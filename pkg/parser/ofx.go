@@ -0,0 +1,283 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- OFX (Open Financial Exchange) files are also offered as ".qfx" (Quicken) or ".qbo" (QuickBooks)
+- Both the SGML based OFX 1.x and the XML based OFX 2.x are supported. In SGML the closing
+  tags of elements containing data, e.g. "<TRNAMT>-12.34", are optional
+- The file is Windows-1252 encoded unless it is valid UTF-8
+- A file can contain several bank ("STMTRS") and credit card ("CCSTMTRS") statements,
+  the transactions ("STMTTRN") of all statements are converted
+- Homebanks "date" is "DTPOSTED", "amount" is "TRNAMT". Both "," and "." are accepted as decimal separator
+- Homebanks "payee" is "NAME", "memo" is "MEMO" and "info" is the check number "CHECKNUM"
+- Homebanks "payment" is derived from "TRNTYPE". Transactions of credit card statements
+  without a more specific type get the payment "credit card"
+*/
+
+import (
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single transaction of an OFX statement
+type ofxRecord struct {
+	datePosted time.Time
+	amount     float64
+	trnType    string // e.g. "DEBIT" or "POS"
+	name       string
+	memo       string
+	checkNum   string
+	creditCard bool // Transaction is part of a credit card statement
+}
+
+type ofxParser struct {
+	converter
+	entries []ofxRecord
+}
+
+// ofxElement is a single start or end tag with the text following it
+type ofxElement struct {
+	name  string // Upper case tag name, e.g. "TRNAMT"
+	end   bool   // End tag like "</STMTTRN>"
+	value string // Text up to the next tag, only set for start tags
+	line  int    // Line number of the tag
+}
+
+// ofxPaymentTypes maps "TRNTYPE" to the homebank payment code.
+// Generic types like "DEBIT" or "CREDIT" are not listed and map to 0.
+var ofxPaymentTypes = map[string]int8{
+	"CHECK":       2,  // Check
+	"CASH":        3,  // Cash
+	"ATM":         3,  // Cash
+	"XFER":        4,  // Bank transfer
+	"POS":         6,  // Debit card
+	"REPEATPMT":   7,  // Standing order
+	"PAYMENT":     8,  // Electronic payment
+	"DEP":         9,  // Deposit
+	"DIRECTDEP":   9,  // Deposit
+	"FEE":         10, // FI fee
+	"SRVCHG":      10, // FI fee
+	"DIRECTDEBIT": 11, // Direct debit
+}
+
+func (p *ofxParser) ParseFile(filepath string) error {
+	p.entries = make([]ofxRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *ofxParser) Parse(in io.Reader) error {
+	p.entries = make([]ofxRecord, 0)
+	p.rowErrors = nil
+	content, err := io.ReadAll(newDecodingReader(in, p.parseOptions.Encoding))
+	if err != nil {
+		return &ParserError{ErrorType: IOError, Err: err}
+	}
+
+	elements := splitOfxElements(string(content))
+	if len(elements) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+	if elements[0].name != "OFX" || elements[0].end {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      elements[0].line,
+		}
+	}
+
+	entries := make([]ofxRecord, 0)
+	creditCard := false
+	var transaction map[string]ofxElement // Data elements of the current transaction
+	var transactionStart ofxElement
+	for _, element := range elements {
+		switch {
+		case element.name == "STMTRS" && !element.end:
+			creditCard = false
+		case element.name == "CCSTMTRS" && !element.end:
+			creditCard = true
+		case element.name == "STMTTRN" && !element.end:
+			if transaction != nil {
+				// Missing end tag of the previous transaction
+				return &ParserError{
+					ErrorType: DataParsingError,
+					Line:      transactionStart.line,
+					Field:     "STMTTRN",
+				}
+			}
+			transaction = make(map[string]ofxElement)
+			transactionStart = element
+		case element.name == "STMTTRN" && element.end:
+			if transaction == nil {
+				return &ParserError{
+					ErrorType: DataParsingError,
+					Line:      element.line,
+					Field:     "STMTTRN",
+				}
+			}
+			record, err := parseOfxTransaction(transaction, transactionStart.line)
+			if err != nil {
+				if p.skipRowError(err) {
+					transaction = nil
+					continue
+				}
+				return err
+			}
+			record.creditCard = creditCard
+			entries = append(entries, record)
+			transaction = nil
+		case transaction != nil && !element.end:
+			// Keep the first occurrence, e.g. "NAME" directly in the transaction or in "PAYEE"
+			if _, ok := transaction[element.name]; !ok {
+				transaction[element.name] = element
+			}
+		}
+	}
+	if transaction != nil {
+		return &ParserError{
+			ErrorType: DataParsingError,
+			Line:      transactionStart.line,
+			Field:     "STMTTRN",
+		}
+	}
+
+	p.entries = entries
+	return nil
+}
+
+// splitOfxElements splits the content into its start and end tags.
+// The SGML header "OFXHEADER:100" as well as the XML declaration, processing
+// instructions like "<?OFX ...?>" and comments are skipped.
+func splitOfxElements(content string) []ofxElement {
+	var elements []ofxElement
+	lineNr := 1
+	pos := 0
+	for {
+		start := strings.IndexByte(content[pos:], '<')
+		if start < 0 {
+			return elements
+		}
+		start += pos
+		lineNr += strings.Count(content[pos:start], "\n")
+		end := strings.IndexByte(content[start:], '>')
+		if end < 0 {
+			return elements
+		}
+		end += start
+		tag := content[start+1 : end]
+		next := strings.IndexByte(content[end:], '<')
+		if next < 0 {
+			next = len(content)
+		} else {
+			next += end
+		}
+		pos = end + 1
+		tagLine := lineNr
+		lineNr += strings.Count(tag, "\n")
+
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+		element := ofxElement{line: tagLine}
+		if tag[0] == '/' {
+			element.end = true
+			tag = tag[1:]
+		} else {
+			element.value = html.UnescapeString(strings.TrimSpace(content[end+1 : next]))
+		}
+		// Strip attributes and the slash of empty elements like "<MEMO/>"
+		if fields := strings.Fields(strings.TrimSuffix(tag, "/")); len(fields) > 0 {
+			element.name = strings.ToUpper(fields[0])
+		}
+		elements = append(elements, element)
+	}
+}
+
+// parseOfxTransaction parses the data elements of a "STMTTRN" aggregate starting at line
+func parseOfxTransaction(transaction map[string]ofxElement, line int) (ofxRecord, error) {
+	// Report missing elements at the start of the transaction
+	elementLine := func(name string) int {
+		if element, ok := transaction[name]; ok {
+			return element.line
+		}
+		return line
+	}
+
+	// Date and time with optional time zone like "20231002120000.000[-5:EST]",
+	// only the date is used
+	datePosted := transaction["DTPOSTED"].value
+	if len(datePosted) > 8 {
+		datePosted = datePosted[:8]
+	}
+	date, err := time.Parse("20060102", datePosted)
+	if err != nil {
+		return ofxRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      elementLine("DTPOSTED"),
+			Field:     "DTPOSTED",
+			Err:       err,
+		}
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(transaction["TRNAMT"].value, ",", ".", 1), 64)
+	if err != nil {
+		return ofxRecord{}, &ParserError{
+			ErrorType: DataParsingError,
+			Line:      elementLine("TRNAMT"),
+			Field:     "TRNAMT",
+			Err:       err,
+		}
+	}
+	return ofxRecord{
+		datePosted: date,
+		amount:     amount,
+		trnType:    strings.ToUpper(transaction["TRNTYPE"].value),
+		name:       transaction["NAME"].value,
+		memo:       transaction["MEMO"].value,
+		checkNum:   transaction["CHECKNUM"].value,
+	}, nil
+}
+
+func (p *ofxParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *ofxParser) GetFormat() SourceFormat {
+	return OFX
+}
+
+func (p *ofxParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *ofxParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *ofxParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *ofxParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, oRecord := range p.entries {
+		hRecord := oRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func (r *ofxRecord) convertRecord() (h homebankRecord) {
+	h.payment = ofxPaymentTypes[r.trnType]
+	if h.payment == 0 && r.creditCard {
+		h.payment = 1 // Credit card
+	}
+	h.date = r.datePosted.Format("2006-01-02")
+	h.amount = r.amount
+	h.info = r.checkNum
+	h.payee = r.name
+	h.memo = r.memo
+	return
+}
@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOfxName(t *testing.T) {
+	p := &ofxParser{}
+	if p.GetFormat() != OFX {
+		t.Error("Wrong format")
+	}
+}
+
+func TestOfxParseFileNonExisting(t *testing.T) {
+	p := &ofxParser{}
+	err := p.ParseFile("non_existing_file.ofx")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOfxParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "ofx", "ofx_nok_noheader.ofx")
+	p := &ofxParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOfxParseFileNokCsv(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	p := &ofxParser{}
+	err := p.ParseFile(fpath)
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+}
+
+func TestOfxParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"ofx_nok_wrongdtposted.ofx", 49, "DTPOSTED"},
+		{"ofx_nok_wrongtrnamt.ofx", 89, "TRNAMT"},
+		{"ofx_nok_unclosed.ofx", 39, "STMTTRN"},
+	}
+	for _, tc := range testCases {
+		p := &ofxParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "ofx", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestOfxParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "ofx", "ofx_onlyheader.ofx")
+	p := &ofxParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOfxParseFileOk(t *testing.T) {
+	testCases := []struct {
+		file    string
+		entries int
+	}{
+		// Bank statement with three and credit card statement with two transactions
+		{"statement.ofx", 5},
+		// Two bank statements with two and one transactions
+		{"statement.qfx", 3},
+	}
+	for _, tc := range testCases {
+		p := &ofxParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "ofx", tc.file)); err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if p.GetNumberOfEntries() != tc.entries {
+			t.Errorf("%s: expected %d entries, got %d", tc.file, tc.entries, p.GetNumberOfEntries())
+		}
+	}
+}
+
+func TestSplitOfxElements(t *testing.T) {
+	content := "OFXHEADER:100\n\n<OFX>\n<NAME>A &amp; B\n<MEMO/><?xml?>\n<TRNAMT type=\"x\">1.00</TRNAMT>\n</ofx>"
+	expected := []ofxElement{
+		{name: "OFX", line: 3},
+		{name: "NAME", value: "A & B", line: 4},
+		{name: "MEMO", line: 5},
+		{name: "TRNAMT", value: "1.00", line: 6},
+		{name: "TRNAMT", end: true, line: 6},
+		{name: "OFX", end: true, line: 7},
+	}
+	elements := splitOfxElements(content)
+	if !reflect.DeepEqual(elements, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, elements)
+	}
+
+	if elements := splitOfxElements("no tags"); len(elements) != 0 {
+		t.Errorf("Expected no elements, got %+v", elements)
+	}
+}
+
+func TestOfxConvertRecord(t *testing.T) {
+	testCases := []struct {
+		trnType    string
+		creditCard bool
+		payment    int8
+	}{
+		{"POS", false, 6},
+		{"DEBIT", false, 0},
+		{"DEBIT", true, 1},
+		{"FEE", true, 10},
+		{"", false, 0},
+	}
+	for _, tc := range testCases {
+		r := ofxRecord{
+			datePosted: time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+			amount:     -12.34,
+			trnType:    tc.trnType,
+			name:       "Bäckerei",
+			memo:       "Brötchen",
+			checkNum:   "1001",
+			creditCard: tc.creditCard,
+		}
+		h := r.convertRecord()
+		if h.payment != tc.payment {
+			t.Errorf("%s: expected payment to be %d, got %d", tc.trnType, tc.payment, h.payment)
+		}
+		if h.date != "2023-10-02" {
+			t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+		}
+		if h.amount != r.amount {
+			t.Errorf("Expected amount to be %f, got %f", r.amount, h.amount)
+		}
+		if h.info != r.checkNum {
+			t.Errorf("Expected info to be '%s', got '%s'", r.checkNum, h.info)
+		}
+		if h.payee != r.name {
+			t.Errorf("Expected payee to be '%s', got '%s'", r.name, h.payee)
+		}
+		if h.memo != r.memo {
+			t.Errorf("Expected memo to be '%s', got '%s'", r.memo, h.memo)
+		}
+	}
+}
+
+func TestOfxConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		file     string
+		expected string
+	}{
+		{"statement.ofx", "homebank.csv"},
+		{"statement.qfx", "homebank_qfx.csv"},
+	}
+	for _, tc := range testCases {
+		p := &ofxParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "ofx", tc.file)); err != nil {
+			t.Error(err)
+		}
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "ofx", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the unified export of Outbank (also Banking4) across several accounts, comma separated
+- Only the records of the account given in FormatOptions.OutbankAccount are converted.
+  Without it all records are converted
+- The date format is detected like in the YNAB format, the decimal separator of "Amount" is the last "." or ","
+- Homebanks "payee" is "Name", "info" is "Number"
+- Homebanks "memo" is "Purpose" followed by "Note"
+- Homebanks "category" is "Category" and "Subcategory" joined by ":"
+- The comma separated "Tags" are converted into space separated homebank tags, spaces within
+  a tag are replaced by "_"
+*/
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Single record of outbank data
+type outbankRecord struct {
+	account     string
+	date        time.Time
+	amount      float64
+	name        string
+	number      string
+	purpose     string
+	category    string
+	subcategory string
+	tags        string
+	note        string
+}
+
+type outbankParser struct {
+	converter
+	entries []outbankRecord
+}
+
+func (p *outbankParser) ParseFile(filepath string) error {
+	p.entries = make([]outbankRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *outbankParser) Parse(in io.Reader) error {
+	p.entries = make([]outbankRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidOutbankHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+	if len(records) == 1 {
+		return nil
+	}
+
+	dateLayout, err := getYnabDateLayout(records[1][2])
+	if err != nil {
+		return &ParserError{
+			ErrorType: DataParsingError,
+			Line:      csvReader.recordLine(1),
+			Field:     "Date",
+			Err:       err,
+		}
+	}
+
+	entries := make([]outbankRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse(dateLayout, row[2])
+		if err != nil {
+			if p.skipRow(lineNr, "Date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Date",
+				Err:       err,
+			}
+		}
+		amount, err := parseYnabAmount(row[4])
+		if err != nil {
+			if p.skipRow(lineNr, "Amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Amount",
+				Err:       err,
+			}
+		}
+		entries = append(entries, outbankRecord{
+			account:     row[1],
+			date:        date,
+			amount:      amount,
+			name:        row[6],
+			number:      row[7],
+			purpose:     row[8],
+			category:    row[9],
+			subcategory: row[10],
+			tags:        row[11],
+			note:        row[12],
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *outbankParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *outbankParser) GetFormat() SourceFormat {
+	return Outbank
+}
+
+func (p *outbankParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *outbankParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *outbankParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *outbankParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, oRecord := range p.entries {
+		if p.formatOptions.OutbankAccount != "" && oRecord.account != p.formatOptions.OutbankAccount {
+			continue
+		}
+		hRecord := oRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidOutbankHeader(record []string) bool {
+	expected := []string{
+		"#",
+		"Account",
+		"Date",
+		"Value Date",
+		"Amount",
+		"Currency",
+		"Name",
+		"Number",
+		"Purpose",
+		"Category",
+		"Subcategory",
+		"Tags",
+		"Note",
+	}
+	return reflect.DeepEqual(record, expected)
+}
+
+// convertRecord converts a single record from outbank to homebank format
+func (o *outbankRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = o.date.Format("2006-01-02")
+	h.amount = o.amount
+	h.info = o.number
+	h.payee = o.name
+	h.memo = joinNonEmpty(singleLine(o.purpose), singleLine(o.note))
+	h.category = o.category
+	if o.subcategory != "" {
+		h.category = o.category + ":" + o.subcategory
+	}
+
+	// Homebank separates tags by space, Outbank tags may contain spaces
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(o.tags, ",") {
+		if tag = strings.Join(strings.Fields(tag), "_"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	h.tags = strings.Join(tags, " ")
+	return
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOutbankName(t *testing.T) {
+	p := &outbankParser{}
+	if p.GetFormat() != Outbank {
+		t.Error("Wrong format")
+	}
+}
+
+func TestOutbankParseFileNonExisting(t *testing.T) {
+	p := &outbankParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOutbankParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "outbank", "outbank_nok_noheader.csv")
+	p := &outbankParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOutbankParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"outbank_nok_wrongdate.csv", 3, "Date"},
+		{"outbank_nok_wrongamount.csv", 5, "Amount"},
+	}
+	for _, tc := range testCases {
+		p := &outbankParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "outbank", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestOutbankParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "outbank", "outbank_onlyheader.csv")
+	p := &outbankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestOutbankParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "outbank", "outbank.csv")
+	p := &outbankParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestOutbankConvertRecord(t *testing.T) {
+	o := outbankRecord{
+		account:     "DE89370400440532013000",
+		date:        time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		amount:      -45.2,
+		name:        "Supermarket",
+		number:      "4711",
+		purpose:     "Kartenzahlung\n02.10.2023",
+		category:    "Lebensmittel",
+		subcategory: "Supermarkt",
+		tags:        "food, weekly shop,",
+		note:        "Note",
+	}
+	h := o.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.amount != o.amount {
+		t.Errorf("Expected amount to be %f, got %f", o.amount, h.amount)
+	}
+	if h.info != o.number {
+		t.Errorf("Expected info to be '%s', got '%s'", o.number, h.info)
+	}
+	if h.payee != o.name {
+		t.Errorf("Expected payee to be '%s', got '%s'", o.name, h.payee)
+	}
+	if h.memo != "Kartenzahlung 02.10.2023 Note" {
+		t.Errorf("Expected memo to be 'Kartenzahlung 02.10.2023 Note', got '%s'", h.memo)
+	}
+	if h.category != "Lebensmittel:Supermarkt" {
+		t.Errorf("Expected category to be 'Lebensmittel:Supermarkt', got '%s'", h.category)
+	}
+	if h.tags != "food weekly_shop" {
+		t.Errorf("Expected tags to be 'food weekly_shop', got '%s'", h.tags)
+	}
+	o.subcategory = ""
+	if h := o.convertRecord(); h.category != "Lebensmittel" {
+		t.Errorf("Expected category to be 'Lebensmittel', got '%s'", h.category)
+	}
+}
+
+func TestOutbankConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		account  string
+		expected string
+	}{
+		{"", "homebank.csv"},
+		{"Visa 1234", "homebank_account.csv"},
+	}
+	for _, tc := range testCases {
+		p := &outbankParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "outbank", "outbank.csv")); err != nil {
+			t.Error(err)
+		}
+		p.SetFormatOptions(FormatOptions{OutbankAccount: tc.account})
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "outbank", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
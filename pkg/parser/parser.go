@@ -4,8 +4,17 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // SourceFormat is the source file format
@@ -18,16 +27,62 @@ const (
 	Volksbank
 	Comdirect
 	DKB
+	PayPal
+	Wise
+	DKBVisa
+	DKBLegacy
+	MT940
+	OFX
+	YNAB
+	Homebank
+	Amex
+	Consorsbank
+	DeutscheBank
+	TradeRepublic
+	Bunq
+	Monzo
+	VolksbankMastercard
+	Klarna
+	MilesAndMore
+	Sparda
+	GnuCash
+	FireflyIII
+	Santander
+	ComdirectDepot
+	Outbank
 )
 
 // sourceFormats is the internal mapping between SourceFormat and its textual representation
 // it is used in the functions below to avoid duplicate code
 var sourceFormats = map[SourceFormat]string{
-	MoneyWallet: "MoneyWallet",
-	Barclaycard: "Barclaycard",
-	Volksbank:   "Volksbank",
-	Comdirect:   "Comdirect",
-	DKB:         "DKB",
+	MoneyWallet:         "MoneyWallet",
+	Barclaycard:         "Barclaycard",
+	Volksbank:           "Volksbank",
+	Comdirect:           "Comdirect",
+	DKB:                 "DKB",
+	PayPal:              "PayPal",
+	Wise:                "Wise",
+	DKBVisa:             "DKBVisa",
+	DKBLegacy:           "DKBLegacy",
+	MT940:               "MT940",
+	OFX:                 "OFX",
+	YNAB:                "YNAB",
+	Homebank:            "Homebank",
+	Amex:                "Amex",
+	Consorsbank:         "Consorsbank",
+	DeutscheBank:        "DeutscheBank",
+	TradeRepublic:       "TradeRepublic",
+	Bunq:                "Bunq",
+	Monzo:               "Monzo",
+	VolksbankMastercard: "VolksbankMastercard",
+	Klarna:              "Klarna",
+	MilesAndMore:        "MilesAndMore",
+	Sparda:              "Sparda",
+	GnuCash:             "GnuCash",
+	FireflyIII:          "FireflyIII",
+	Santander:           "Santander",
+	ComdirectDepot:      "ComdirectDepot",
+	Outbank:             "Outbank",
 }
 
 // GetParser returns a parser for the given source format
@@ -43,16 +98,97 @@ func GetParser(s SourceFormat) Parser {
 		return &comdirectParser{}
 	case DKB:
 		return &dkbParser{}
+	case PayPal:
+		return &paypalParser{}
+	case Wise:
+		return &wiseParser{}
+	case DKBVisa:
+		return &dkbVisaParser{}
+	case DKBLegacy:
+		return &dkbLegacyParser{}
+	case MT940:
+		return &mt940Parser{}
+	case OFX:
+		return &ofxParser{}
+	case YNAB:
+		return &ynabParser{}
+	case Homebank:
+		return &homebankParser{}
+	case Amex:
+		return &amexParser{}
+	case Consorsbank:
+		return &consorsbankParser{}
+	case DeutscheBank:
+		return &deutscheBankParser{}
+	case TradeRepublic:
+		return &tradeRepublicParser{}
+	case Bunq:
+		return &bunqParser{}
+	case Monzo:
+		return &monzoParser{}
+	case VolksbankMastercard:
+		return &volksbankMastercardParser{}
+	case Klarna:
+		return &klarnaParser{}
+	case MilesAndMore:
+		return &milesAndMoreParser{}
+	case Sparda:
+		return &spardaParser{}
+	case GnuCash:
+		return &gnuCashParser{}
+	case FireflyIII:
+		return &fireflyIIIParser{}
+	case Santander:
+		return &santanderParser{}
+	case ComdirectDepot:
+		return &comdirectDepotParser{}
+	case Outbank:
+		return &outbankParser{}
 	}
 	return nil
 }
 
-// GetSourceFormats returns the list of supported source formats.
+// sourceFormatPriority is the priority table of the formats, the order in which
+// GuessParser and GetGuessedParser try them, see GetSourceFormats.
+// Formats with a fixed header or file structure come first. Formats which look up
+// their columns by name and therefore accept additional columns are tried last,
+// as they are more likely to also accept files of other formats.
+var sourceFormatPriority = []SourceFormat{
+	MoneyWallet,
+	Barclaycard,
+	Comdirect,
+	DKB,
+	DKBVisa,
+	DKBLegacy,
+	MT940,
+	OFX,
+	Homebank,
+	Consorsbank,
+	TradeRepublic,
+	Bunq,
+	VolksbankMastercard,
+	Klarna,
+	MilesAndMore,
+	Sparda,
+	Santander,
+	ComdirectDepot,
+	Outbank,
+	Volksbank,
+	Amex,
+	DeutscheBank,
+	PayPal,
+	Wise,
+	Monzo,
+	GnuCash,
+	FireflyIII,
+	YNAB,
+}
+
+// GetSourceFormats returns all supported formats in the order of their priority
+// when guessing the format of a file
 func GetSourceFormats() []SourceFormat {
-	formats := make([]SourceFormat, 0, len(sourceFormats))
-	for key := range sourceFormats {
-		formats = append(formats, key)
-	}
+	formats := make([]SourceFormat, len(sourceFormatPriority))
+	copy(formats, sourceFormatPriority)
 	return formats
 }
 
@@ -67,15 +203,36 @@ func (s SourceFormat) String() string {
 	return "unknown format"
 }
 
+// MarshalText returns the textual representation of the source format, e.g. for JSON.
+// An error is returned for unsupported formats.
+func (s SourceFormat) MarshalText() ([]byte, error) {
+	if _, ok := sourceFormats[s]; !ok {
+		return nil, fmt.Errorf("unsupported format %d", int(s))
+	}
+	return []byte(sourceFormats[s]), nil
+}
+
+// UnmarshalText sets the source format from its textual representation.
+// The comparison is case-insensitive and ignores surrounding whitespace.
 func (s *SourceFormat) UnmarshalText(text []byte) error {
-	textString := string(text)
+	textString := strings.TrimSpace(string(text))
 	for key, value := range sourceFormats {
-		if value == textString {
+		if strings.EqualFold(value, textString) {
 			*s = key
 			return nil
 		}
 	}
-	return fmt.Errorf("unsupported format '%s'", textString)
+	return fmt.Errorf("unsupported format '%s', valid formats are: %s", string(text), strings.Join(getSourceFormatNames(), ", "))
+}
+
+// getSourceFormatNames returns the alphabetically sorted textual representations of all source formats
+func getSourceFormatNames() []string {
+	names := make([]string, 0, len(sourceFormats))
+	for _, value := range sourceFormats {
+		names = append(names, value)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // NewSourceFormat returns a pointer to a new SourceFormat
@@ -90,6 +247,7 @@ const (
 	IOError          ParserErrorType = iota // Error during file I/O
 	HeaderError                             // Error in expected header
 	DataParsingError                        // Error during parsing section
+	FormatError                             // Malformed file, e.g. unbalanced quotes in a CSV file
 )
 
 func (e ParserErrorType) String() string {
@@ -100,6 +258,8 @@ func (e ParserErrorType) String() string {
 		return "HeaderError"
 	case DataParsingError:
 		return "DataParsingError"
+	case FormatError:
+		return "FormatError"
 	default:
 		return "unknown error"
 	}
@@ -110,53 +270,289 @@ type ParserError struct {
 	ErrorType ParserErrorType
 
 	// Optional line number where the error occurs. Line numbers are
-	// 1 based. The value "0" means no line number applies here.
+	// 1 based and refer to the physical line in the file, i.e. header and
+	// empty lines are counted. For records spanning several lines it is the
+	// line where the record starts, for xlsx files the row number.
+	// The value "0" means no line number applies here.
 	Line int
 
+	// Optional column where the error occurs, 1 based. Only set together with Line
+	// for FormatError.
+	Column int
+
 	// Optional field name where the error occured
 	Field string
+
+	// Optional name of the parsed file, set by ParseFile
+	File string
+
+	// Optional underlying error, e.g. from opening the file or parsing a date
+	Err error
 }
 
 func (e *ParserError) Error() string {
 	var msg string
 	msg = e.ErrorType.String()
+	if e.File != "" {
+		msg += fmt.Sprintf(" in file '%s'", e.File)
+	}
 	if e.Line > 0 {
 		msg += fmt.Sprintf(" in line %d", e.Line)
 	}
+	if e.Column > 0 {
+		msg += fmt.Sprintf(" in column %d", e.Column)
+	}
 	if len(e.Field) > 0 {
 		msg += fmt.Sprintf(" in field name '%s'", e.Field)
 	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
 	return msg
 }
 
+// Unwrap returns the underlying error, so that e.g. errors.Is(err, os.ErrNotExist) works
+func (e *ParserError) Unwrap() error {
+	return e.Err
+}
+
 // Parser is the interface to be implemented by all parsers
 type Parser interface {
 
 	// Parse the given file into internal structure.
 	ParseFile(filepath string) error
 
+	// Parse the content read from the reader into internal structure.
+	Parse(r io.Reader) error
+
+	// Returns false if a file starting with head can't be parsed, without parsing
+	// the complete file. Used by GetGuessedParser to skip parsers quickly.
+	SniffHeader(head []byte) bool
+
 	// Returns the number of parsed entries.
 	GetNumberOfEntries() int
 
 	// Convert the internal structure into HomebankRecord CSV file.
 	ConvertToHomebank(filepath string) error
 
+	// Write the internal structure as HomebankRecord CSV to the writer.
+	WriteHomebank(w io.Writer) error
+
+	// Returns the entries as they are written by ConvertToHomebank.
+	GetEntries() []Transaction
+
 	// Returns the format of the parser.
 	GetFormat() SourceFormat
+
+	// Restrict the records written by ConvertToHomebank to the given date range.
+	SetDateRange(r DateRange)
+
+	// Returns the number of entries dropped by the date range in the last ConvertToHomebank.
+	GetNumberOfDroppedEntries() int
+
+	// Returns the number of entries written by the last ConvertToHomebank.
+	GetNumberOfWrittenEntries() int
+
+	// Prepend the given prefix to the categories written by ConvertToHomebank.
+	// If always is set, records without category get the prefix as category.
+	SetCategoryPrefix(prefix string, always bool)
+
+	// Move the content of the "info" field into "memo" or vice versa in ConvertToHomebank.
+	SetFieldRouting(infoToMemo, memoToInfo bool)
+
+	// Enable or disable the normalization of text fields to Unicode NFC in ConvertToHomebank.
+	SetUnicodeNormalization(enabled bool)
+
+	// Set the limits and the lenient mode used by ParseFile.
+	SetParseOptions(o ParseOptions)
+
+	// Returns the errors of the data rows skipped by ParseFile in lenient mode.
+	GetRowErrors() []ParserError
+
+	// Set the options used by ConvertToHomebank which only apply to some source formats.
+	SetFormatOptions(o FormatOptions)
+
+	// Set the order of the records written by ConvertToHomebank.
+	SetSortOrder(o SortOrder)
+
+	// Set the rules which map the records written by ConvertToHomebank to new field values.
+	SetRules(r *Rules)
+
+	// Set the delimiter, decimal separator, header and date format of the file written by ConvertToHomebank.
+	SetOutputOptions(o OutputOptions)
 }
 
 // GetGuessedParser tries to autodetect the file format.
-// It iterates through the available, calls the ParseFile function and returns the
-// first parser which does not fail with an error.
+// It iterates through the available formats in the order of GetSourceFormats, calls
+// the ParseFile function and returns the first parser which does not fail with an error.
+// Parsers which reject the beginning of the file in SniffHeader are skipped.
 // It returns nil if no parser could be found.
 func GetGuessedParser(filepath string) Parser {
+	return GetGuessedParserWithOptions(filepath, ParseOptions{})
+}
+
+// GetGuessedParserWithOptions is like GetGuessedParser, but the parsers use the
+// given parse options. In lenient mode files with invalid data rows are detected as well.
+func GetGuessedParserWithOptions(filepath string, o ParseOptions) Parser {
+	p, err := GuessParser(filepath, o)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// ErrUnknownFormat is returned by GuessParser if no parser accepts the header of a file
+var ErrUnknownFormat = errors.New("cannot deduce format")
+
+// GuessParser is like GetGuessedParserWithOptions, but returns why no parser could be found.
+//
+// If the header of the file is accepted by a parser, but a data row fails with a
+// DataParsingError, e.g. because of an invalid amount, the error of the first such parser
+// is returned together with the parser. A violation of the limits of the parse options
+// tells nothing about the format, as all formats have the same limits. It is returned
+// without parser if no format fails with another DataParsingError. Otherwise the error
+// wraps ErrUnknownFormat, or is an IOError if the file can't be read.
+func GuessParser(filepath string, o ParseOptions) (Parser, error) {
+	head, err := readHead(filepath)
+	if err != nil {
+		return nil, &ParserError{ErrorType: IOError, File: filepath, Err: err}
+	}
+	var candidate Parser
+	var candidateErr error
+	var limitErr error
 	for _, f := range GetSourceFormats() {
 		p := GetParser(f)
+		p.SetParseOptions(o)
+		if !p.SniffHeader(head) {
+			continue
+		}
+		err := p.ParseFile(filepath)
+		if err == nil {
+			if o.Logger != nil {
+				o.Logger.Debug("detected format", "file", filepath, "format", f.String())
+			}
+			return p, nil
+		}
+		if o.Logger != nil {
+			o.Logger.Debug("format does not match", "file", filepath, "format", f.String(), "error", err)
+		}
+		var parserErr *ParserError
+		if !errors.As(err, &parserErr) || parserErr.ErrorType != DataParsingError {
+			continue
+		}
+		if isLimitError(parserErr) {
+			if limitErr == nil {
+				limitErr = err
+			}
+			continue
+		}
+		if candidate == nil {
+			candidate = p
+			candidateErr = fmt.Errorf("format %s: %w", f, err)
+		}
+	}
+	if candidate != nil {
+		return candidate, candidateErr
+	}
+	if limitErr != nil {
+		return nil, limitErr
+	}
+	return nil, fmt.Errorf("%w of file '%s'", ErrUnknownFormat, filepath)
+}
+
+// GuessFormats returns all formats which are able to parse the file, in the order of
+// GetSourceFormats. More than one format means the file is ambiguous, the first one
+// is the format chosen by GetGuessedParser.
+func GuessFormats(filepath string) []SourceFormat {
+	head, err := readHead(filepath)
+	if err != nil {
+		return nil
+	}
+	var formats []SourceFormat
+	for _, f := range GetSourceFormats() {
+		p := GetParser(f)
+		if !p.SniffHeader(head) {
+			continue
+		}
 		if err := p.ParseFile(filepath); err == nil {
-			return p
+			formats = append(formats, f)
 		}
 	}
-	return nil
+	return formats
+}
+
+// sniffSize is the number of bytes at the beginning of a file passed to SniffHeader
+const sniffSize = 16 * 1024
+
+// readHead returns the first sniffSize bytes of the file or the complete file if it is shorter
+func readHead(filepath string) ([]byte, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// sniffHeader parses the head of a file with the given parse function.
+// Only a header error rejects the file: Other errors may be caused by the truncation
+// and are left to the parsing of the complete file.
+func sniffHeader(head []byte, parse func(io.Reader) error) bool {
+	if len(head) >= sniffSize {
+		// Drop the last line which is most likely incomplete
+		i := bytes.LastIndexByte(head, '\n')
+		if i < 0 {
+			return true
+		}
+		head = head[:i+1]
+	}
+	var parserErr *ParserError
+	if errors.As(parse(bytes.NewReader(head)), &parserErr) {
+		return parserErr.ErrorType != HeaderError
+	}
+	return true
+}
+
+// isXlsx returns true if head starts with the signature of a zip file like xlsx
+func isXlsx(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("PK\x03\x04"))
+}
+
+// getColumns returns the index of each of the given column names in the header.
+// It is used for formats where the set or order of columns may vary.
+// ok is false if a column is missing.
+func getColumns(header []string, names []string) (columns map[string]int, ok bool) {
+	columns = make(map[string]int, len(names))
+	for _, name := range names {
+		found := false
+		for i, field := range header {
+			if field == name {
+				columns[name] = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return columns, true
+}
+
+// parseGermanAmount parses an amount in German notation like "-1.234,56 €".
+// The thousands separator "." and a trailing currency sign "€" are optional.
+func parseGermanAmount(s string) (float64, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "€"))
+	// Some exports use a non-breaking space between amount and currency sign
+	s = strings.TrimRight(s, "\u00a0 ")
+	s = strings.Replace(s, ".", "", -1)
+	s = strings.Replace(s, ",", ".", -1)
+	return strconv.ParseFloat(s, 64)
 }
 
 // homebankRecord reflects the data in the CSV file,
@@ -172,28 +568,64 @@ type homebankRecord struct {
 	tags     string
 }
 
-// writeHomeBankRecords writes a slice of HomebankRecord to a CSV file
-// See "Transaction import CSV format" under http://homebank.free.fr/help/misc-csvformat.html
-func writeHomeBankRecords(records []homebankRecord, filepath string) error {
-	outfile, err := os.Create(filepath)
+// recordID returns an identifier of the record built from a hash over all fields.
+// Records which are written identically to the homebank CSV file have the same ID.
+func (r homebankRecord) recordID() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s\x00%f\x00%s\x00%s",
+		r.date, r.payment, r.info, r.payee, r.memo, r.amount, r.category, r.tags)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseFile opens the file and passes it to the parse function of a parser.
+// The file name is added to a returned ParserError.
+func parseFile(filepath string, parse func(io.Reader) error) error {
+	infile, err := os.Open(filepath)
 	if err != nil {
+		return &ParserError{ErrorType: IOError, File: filepath, Err: err}
+	}
+	defer infile.Close()
+	err = parse(infile)
+	var parserErr *ParserError
+	if errors.As(err, &parserErr) && parserErr.File == "" {
+		parserErr.File = filepath
+	}
+	return err
+}
+
+// ParseFileContext parses the file with p like p.ParseFile. Reading the file fails
+// with the error of ctx as soon as ctx is cancelled.
+func ParseFileContext(ctx context.Context, p Parser, filepath string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer outfile.Close()
+	return parseFile(filepath, func(in io.Reader) error {
+		return p.Parse(&contextReader{ctx: ctx, r: in})
+	})
+}
+
+// contextReader is a reader which fails with the error of ctx once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
 
-	header := "date;payment;info;payee;memo;amount;category;tags"
-	_, err = fmt.Fprintln(outfile, header)
+// convertToFile creates the file and passes it to the write function of a parser
+func convertToFile(filepath string, write func(io.Writer) error) error {
+	outfile, err := os.Create(filepath)
 	if err != nil {
 		return err
 	}
-
-	for _, rec := range records {
-		line := fmt.Sprintf("%s;%d;%s;%s;%s;%f;%s;%s",
-			rec.date, rec.payment, rec.info, rec.payee, rec.memo, rec.amount, rec.category, rec.tags)
-		_, err := fmt.Fprintln(outfile, line)
-		if err != nil {
-			return err
-		}
+	if err := write(outfile); err != nil {
+		outfile.Close()
+		return err
 	}
-	return nil
+	return outfile.Close()
 }
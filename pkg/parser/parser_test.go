@@ -1,8 +1,18 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetParser(t *testing.T) {
@@ -53,6 +63,68 @@ func TestUnmarshalSourceFormatText(t *testing.T) {
 	}
 }
 
+func TestMarshalSourceFormatText(t *testing.T) {
+	for key, value := range sourceFormats {
+		text, err := key.MarshalText()
+		if err != nil {
+			t.Errorf("Expected nil error, got: %v", err)
+		}
+		if string(text) != value {
+			t.Errorf("Expected: %s, got: %s", value, text)
+		}
+		var s SourceFormat
+		if err := s.UnmarshalText(text); err != nil || s != key {
+			t.Errorf("Expected round trip to %v, got %v (%v)", key, s, err)
+		}
+	}
+
+	if _, err := SourceFormat(999999999).MarshalText(); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestUnmarshalSourceFormatTextLenient(t *testing.T) {
+	testCases := map[string]SourceFormat{
+		"dkb":             DKB,
+		"DKB":             DKB,
+		"barclaycard":     Barclaycard,
+		"BARCLAYCARD":     Barclaycard,
+		"BarclayCard":     Barclaycard,
+		" Barclaycard":    Barclaycard,
+		"Volksbank \t":    Volksbank,
+		"\n moneywallet ": MoneyWallet,
+	}
+	for text, expected := range testCases {
+		var s SourceFormat
+		if err := s.UnmarshalText([]byte(text)); err != nil {
+			t.Errorf("'%s': expected nil error, got: %v", text, err)
+		}
+		if s != expected {
+			t.Errorf("'%s': expected: %v, got: %v", text, expected, s)
+		}
+		// String() keeps the canonical casing
+		if s.String() != sourceFormats[expected] {
+			t.Errorf("'%s': expected String() '%s', got '%s'", text, sourceFormats[expected], s.String())
+		}
+	}
+}
+
+func TestUnmarshalSourceFormatTextErrorListsFormats(t *testing.T) {
+	var s SourceFormat
+	err := s.UnmarshalText([]byte("bogus"))
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	for _, name := range sourceFormats {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error '%s' to contain format '%s'", err, name)
+		}
+	}
+	if !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("Expected error '%s' to contain the given value", err)
+	}
+}
+
 func TestNewSourceFormat(t *testing.T) {
 	for _, f := range GetSourceFormats() {
 		s := NewSourceFormat(f)
@@ -62,26 +134,483 @@ func TestNewSourceFormat(t *testing.T) {
 	}
 }
 
+// guessTestfiles maps sample files to the format detected by GetGuessedParser
+var guessTestfiles = map[string]SourceFormat{
+	filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_1.csv"):                     MoneyWallet,
+	filepath.Join("testfiles", "barclaycard", "Umsaetze.xlsx"):                                Barclaycard,
+	filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"): Volksbank,
+	filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.04.03.csv"): Volksbank,
+	filepath.Join("testfiles", "comdirect", "umsaetze_1234567890_20231006_1804.csv"):          Comdirect,
+	filepath.Join("testfiles", "dkb", "dkb.csv"):                                              DKB,
+	filepath.Join("testfiles", "dkb", "dkb_nobom.csv"):                                        DKB,
+	filepath.Join("testfiles", "volksbank", "Umsaetze_bom.csv"):                               Volksbank,
+	filepath.Join("testfiles", "volksbank", "Umsaetze_utf16be.csv"):                           Volksbank,
+	filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_bom.csv"):                   MoneyWallet,
+	filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_utf16le.csv"):               MoneyWallet,
+	filepath.Join("testfiles", "dkbvisa", "dkbvisa.csv"):                                      DKBVisa,
+	filepath.Join("testfiles", "dkblegacy", "dkblegacy.csv"):                                  DKBLegacy,
+	filepath.Join("testfiles", "mt940", "statement.sta"):                                      MT940,
+	filepath.Join("testfiles", "ofx", "statement.ofx"):                                        OFX,
+	filepath.Join("testfiles", "homebank", "homebank_malformed.csv"):                          Homebank,
+	filepath.Join("testfiles", "moneywallet", "converted_1.csv"):                              Homebank,
+	filepath.Join("testfiles", "ofx", "statement.qfx"):                                        OFX,
+	filepath.Join("testfiles", "ynab", "register.csv"):                                        YNAB,
+	filepath.Join("testfiles", "amex", "Umsaetze.csv"):                                        Amex,
+	filepath.Join("testfiles", "consorsbank", "Umsaetze.csv"):                                 Consorsbank,
+	filepath.Join("testfiles", "deutschebank", "Kontoumsaetze.csv"):                           DeutscheBank,
+	filepath.Join("testfiles", "traderepublic", "transaktionen.csv"):                          TradeRepublic,
+	filepath.Join("testfiles", "bunq", "bunq.csv"):                                            Bunq,
+	filepath.Join("testfiles", "monzo", "MonzoDataExport.csv"):                                Monzo,
+	filepath.Join("testfiles", "volksbankmastercard", "Kreditkartenumsaetze.csv"):             VolksbankMastercard,
+	filepath.Join("testfiles", "klarna", "klarna.csv"):                                        Klarna,
+	filepath.Join("testfiles", "milesandmore", "Umsaetze.xlsx"):                               MilesAndMore,
+	filepath.Join("testfiles", "sparda", "Umsaetze.csv"):                                      Sparda,
+	filepath.Join("testfiles", "gnucash", "transactions.csv"):                                 GnuCash,
+	filepath.Join("testfiles", "fireflyiii", "export.csv"):                                    FireflyIII,
+	filepath.Join("testfiles", "santander", "Umsaetze.csv"):                                   Santander,
+	filepath.Join("testfiles", "comdirectdepot", "depotumsaetze.csv"):                         ComdirectDepot,
+	filepath.Join("testfiles", "outbank", "outbank.csv"):                                      Outbank,
+	filepath.Join("testfiles", "paypal", "Download.CSV"):                                      PayPal,
+	filepath.Join("testfiles", "wise", "statement_12345678_EUR_2024-05-01_2024-05-31.csv"):    Wise,
+}
+
 func TestGetGuessedParser(t *testing.T) {
 
-	nilFilepath := filepath.Join("testfiles", "moneywallet", "converted_1.csv")
+	nilFilepath := filepath.Join("testfiles", "paypal", "Download_nok_noheader.CSV")
 	p := GetGuessedParser(nilFilepath)
 	if p != nil {
 		t.Errorf("Expected: nil, got: %v, %s", p, p.GetFormat())
 	}
 
-	formats := map[string]SourceFormat{
-		filepath.Join("testfiles", "moneywallet", "MoneyWallet_export_1.csv"):                     MoneyWallet,
-		filepath.Join("testfiles", "barclaycard", "Umsaetze.xlsx"):                                Barclaycard,
-		filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"): Volksbank,
-		filepath.Join("testfiles", "comdirect", "umsaetze_1234567890_20231006_1804.csv"):          Comdirect,
-		filepath.Join("testfiles", "dkb", "dkb.csv"):                                              DKB,
-	}
-
-	for testfile, format := range formats {
+	for testfile, format := range guessTestfiles {
 		p := GetGuessedParser(testfile)
 		if p.GetFormat() != format {
 			t.Errorf("Parser not correct, expected: %s, got: %s", format, p.GetFormat())
 		}
 	}
 }
+
+func TestGuessParser(t *testing.T) {
+	p, err := GuessParser(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), ParseOptions{})
+	if err != nil || p.GetFormat() != Volksbank {
+		t.Errorf("Expected Volksbank parser, got %v, %v", p, err)
+	}
+
+	_, err = GuessParser(filepath.Join("testfiles", "paypal", "Download_nok_noheader.CSV"), ParseOptions{})
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+
+	var pError *ParserError
+	_, err = GuessParser("non_existing_file.csv", ParseOptions{})
+	if !errors.As(err, &pError) || pError.ErrorType != IOError {
+		t.Errorf("Expected IOError, got %v", err)
+	}
+
+	// The header is accepted, the error of the data row is reported
+	p, err = GuessParser(filepath.Join("testfiles", "volksbank", "Umsaetze_nok_wrongbetrag.csv"), ParseOptions{})
+	if p == nil || p.GetFormat() != Volksbank {
+		t.Errorf("Expected Volksbank parser, got %v", p)
+	}
+	if !errors.As(err, &pError) || pError.ErrorType != DataParsingError || pError.Line != 2 || pError.Field != "Betrag" {
+		t.Errorf("Expected DataParsingError in line 2 and field 'Betrag', got %v", err)
+	}
+
+	// A file exceeding the limits is accepted by several headers, no parser is chosen
+	p, err = GuessParser(writePathologicalFile(t, 2*DefaultMaxFieldsPerRecord), ParseOptions{})
+	if p != nil {
+		t.Errorf("Expected no parser, got %s", p.GetFormat())
+	}
+	if !errors.As(err, &pError) || !isLimitError(pError) || pError.Line != 2 {
+		t.Errorf("Expected DataParsingError of the limits in line 2, got %v", err)
+	}
+}
+
+func TestGuessParserLogger(t *testing.T) {
+	var logs bytes.Buffer
+	o := ParseOptions{
+		Lenient: true,
+		Logger:  slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	p, err := GuessParser(filepath.Join("testfiles", "volksbank", "Umsaetze_nok_wrongbetrag.csv"), o)
+	if err != nil || p.GetFormat() != Volksbank {
+		t.Fatalf("Expected Volksbank parser, got %v, %v", p, err)
+	}
+	for _, expected := range []string{`msg="detected format"`, "format=Volksbank", `msg="skipped row" line=2 field=Betrag`} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected '%s' in logs:\n%s", expected, logs.String())
+		}
+	}
+}
+
+func TestGetColumns(t *testing.T) {
+	header := []string{
+		"Datum", "Uhrzeit", "Zeitzone", "Name", "Typ", "Status", "Währung", "Brutto", "Gebühr", "Netto",
+		"Transaktionscode", "Zugehöriger Transaktionscode", "Betreff", "Hinweis",
+	}
+	columns, ok := getColumns(header, paypalColumns)
+	if !ok {
+		t.Fatal("Header should be OK")
+	}
+	if columns["Datum"] != 0 || columns["Netto"] != 9 || columns["Hinweis"] != 13 {
+		t.Errorf("Wrong column indices %v", columns)
+	}
+
+	if _, ok := getColumns(header[:5], paypalColumns); ok {
+		t.Error("Header should be NOK")
+	}
+}
+
+func TestParseGermanAmount(t *testing.T) {
+	testCases := map[string]float64{
+		"0":               0,
+		"1.000":           1000,
+		"-2.000":          -2000,
+		"-6,00":           -6,
+		"1.234,56":        1234.56,
+		"-1.234,56 €":     -1234.56,
+		"3.600,00\u00a0€": 3600,
+		" 12,3 ":          12.3,
+	}
+	for text, expected := range testCases {
+		amount, err := parseGermanAmount(text)
+		if err != nil {
+			t.Errorf("'%s': expected nil error, got: %v", text, err)
+		}
+		if amount != expected {
+			t.Errorf("'%s': expected %f, got %f", text, expected, amount)
+		}
+	}
+
+	for _, text := range []string{"", "abc", "1,2,3", "€"} {
+		if _, err := parseGermanAmount(text); err == nil {
+			t.Errorf("'%s': expected error", text)
+		}
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	r := homebankRecord{date: "2024-05-06", payee: "Payee", amount: -8.2}
+	if len(r.recordID()) != 64 {
+		t.Errorf("Expected hex encoded SHA-256, got '%s'", r.recordID())
+	}
+	other := r
+	if r.recordID() != other.recordID() {
+		t.Error("Equal records should have the same ID")
+	}
+	other.memo = "Memo"
+	if r.recordID() == other.recordID() {
+		t.Error("Different records should have different IDs")
+	}
+	// Fields are separated, so moving text between fields changes the ID
+	a := homebankRecord{info: "ab", payee: "c"}
+	b := homebankRecord{info: "a", payee: "bc"}
+	if a.recordID() == b.recordID() {
+		t.Error("Expected different IDs for different field boundaries")
+	}
+}
+
+func TestSourceFormatPriority(t *testing.T) {
+	if len(sourceFormatPriority) != len(sourceFormats) {
+		t.Errorf("Expected %d formats in priority list, got %d", len(sourceFormats), len(sourceFormatPriority))
+	}
+	seen := make(map[SourceFormat]bool)
+	for _, f := range sourceFormatPriority {
+		if _, ok := sourceFormats[f]; !ok {
+			t.Errorf("Unknown format %d in priority list", f)
+		}
+		if seen[f] {
+			t.Errorf("Format %s listed twice in priority list", f)
+		}
+		seen[f] = true
+	}
+	if !reflect.DeepEqual(GetSourceFormats(), GetSourceFormats()) {
+		t.Error("Expected the same order on each call")
+	}
+}
+
+func TestGuessFormats(t *testing.T) {
+	noFormat := filepath.Join("testfiles", "paypal", "Download_nok_noheader.CSV")
+	if formats := GuessFormats(noFormat); len(formats) != 0 {
+		t.Errorf("Expected no format, got %v", formats)
+	}
+
+	// Each sample file is only accepted by its own parser and detected the same way each time
+	for testfile, format := range guessTestfiles {
+		formats := GuessFormats(testfile)
+		if !reflect.DeepEqual(formats, []SourceFormat{format}) {
+			t.Errorf("%s: expected [%s], got %v", testfile, format, formats)
+		}
+		for i := 0; i < 5; i++ {
+			if p := GetGuessedParser(testfile); p.GetFormat() != format {
+				t.Errorf("%s: expected %s, got %s", testfile, format, p.GetFormat())
+			}
+		}
+	}
+}
+
+// guessFormatsFullParse returns all formats which parse the file without sniffing its header
+func guessFormatsFullParse(path string) []SourceFormat {
+	var formats []SourceFormat
+	for _, f := range GetSourceFormats() {
+		if err := GetParser(f).ParseFile(path); err == nil {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// writeLargeTestfile writes the header of src followed by its data lines repeated
+// until the file has at least minSize bytes
+func writeLargeTestfile(tb testing.TB, src string, minSize int) string {
+	tb.Helper()
+	content, err := os.ReadFile(src)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	header, data, _ := strings.Cut(string(content), "\n")
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for b.Len() < minSize {
+		b.WriteString(data)
+	}
+	path := filepath.Join(tb.TempDir(), filepath.Base(src))
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestSniffHeaderMatchesFullParse(t *testing.T) {
+	testfiles := []string{
+		writeLargeTestfile(t, filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), 4*sniffSize),
+		writeLargeTestfile(t, filepath.Join("testfiles", "wise", "statement_12345678_EUR_2024-05-01_2024-05-31.csv"), 4*sniffSize),
+	}
+	err := filepath.WalkDir("testfiles", func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			testfiles = append(testfiles, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testfile := range testfiles {
+		expected := guessFormatsFullParse(testfile)
+		formats := GuessFormats(testfile)
+		if !reflect.DeepEqual(formats, expected) {
+			t.Errorf("%s: expected %v, got %v", testfile, expected, formats)
+		}
+		p := GetGuessedParser(testfile)
+		switch {
+		case len(expected) == 0 && p != nil:
+			t.Errorf("%s: expected nil, got %s", testfile, p.GetFormat())
+		case len(expected) > 0 && (p == nil || p.GetFormat() != expected[0]):
+			t.Errorf("%s: expected %s, got %v", testfile, expected[0], p)
+		}
+	}
+}
+
+func TestSniffHeaderSkipsParsers(t *testing.T) {
+	testfile := writeLargeTestfile(t, filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), 4*sniffSize)
+	head, err := readHead(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) != sniffSize {
+		t.Fatalf("Expected %d bytes, got %d", sniffSize, len(head))
+	}
+	// Comma separated formats are not listed, reading the semicolon separated file fails
+	// before the header is checked and is left to the full parse
+	for _, f := range []SourceFormat{Volksbank, Comdirect, DKB, MT940, OFX, Homebank, Santander, Sparda} {
+		if sniffed := GetParser(f).SniffHeader(head); sniffed != (f == Volksbank) {
+			t.Errorf("%s: expected %v, got %v", f, f == Volksbank, sniffed)
+		}
+	}
+	for _, f := range []SourceFormat{Barclaycard, MilesAndMore} {
+		if GetParser(f).SniffHeader(head) {
+			t.Errorf("%s: expected false for CSV file", f)
+		}
+	}
+}
+
+func BenchmarkGetGuessedParser(b *testing.B) {
+	testfile := writeLargeTestfile(b, filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), 5*1024*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if p := GetGuessedParser(testfile); p == nil || p.GetFormat() != Volksbank {
+			b.Fatal("Expected Volksbank parser")
+		}
+	}
+}
+
+// BenchmarkGetGuessedParserFullParse is the format detection without SniffHeader for comparison
+func BenchmarkGetGuessedParserFullParse(b *testing.B) {
+	testfile := writeLargeTestfile(b, filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"), 5*1024*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if formats := guessFormatsFullParse(testfile); len(formats) == 0 || formats[0] != Volksbank {
+			b.Fatal("Expected Volksbank format")
+		}
+	}
+}
+
+// BenchmarkParseLenientLogger shows that parsing without logger has no overhead of logging.
+// In the sub-benchmark "nil" the number of allocations is the same as without logging.
+func BenchmarkParseLenientLogger(b *testing.B) {
+	testfile := writeLargeTestfile(b, filepath.Join("testfiles", "volksbank", "Umsaetze_nok_wrongbetrag.csv"), 1024*1024)
+	loggers := []struct {
+		name   string
+		logger *slog.Logger
+	}{
+		{"nil", nil},
+		{"discard", slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))},
+	}
+	for _, l := range loggers {
+		b.Run(l.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := GetParser(Volksbank)
+				p.SetParseOptions(ParseOptions{Lenient: true, Logger: l.logger})
+				if err := p.ParseFile(testfile); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	nokFiles, err := filepath.Glob(filepath.Join("testfiles", "*", "*nok*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := 0
+	for _, nokFile := range nokFiles {
+		for _, f := range GetSourceFormats() {
+			p := GetParser(f)
+			strictErr := p.ParseFile(nokFile)
+			var parserErr *ParserError
+			if !errors.As(strictErr, &parserErr) || parserErr.ErrorType != DataParsingError {
+				continue
+			}
+			if len(p.GetRowErrors()) != 0 {
+				t.Errorf("%s %s: expected no row errors in strict mode", nokFile, f)
+			}
+
+			p.SetParseOptions(ParseOptions{Lenient: true})
+			err := p.ParseFile(nokFile)
+			if err != nil {
+				// Errors outside of data rows can't be skipped
+				if err.Error() != strictErr.Error() {
+					t.Errorf("%s %s: expected '%v', got '%v'", nokFile, f, strictErr, err)
+				}
+				continue
+			}
+			rowErrors := p.GetRowErrors()
+			// The file name is only added to the error returned by ParseFile
+			expected := *parserErr
+			expected.File = ""
+			if len(rowErrors) == 0 || rowErrors[0].Error() != expected.Error() {
+				t.Errorf("%s %s: expected first row error '%v', got %v", nokFile, f, strictErr, rowErrors)
+			}
+			skipped++
+		}
+	}
+	if skipped < 20 {
+		t.Errorf("Expected at least 20 files with skipped rows, got %d", skipped)
+	}
+}
+
+func TestParserErrorString(t *testing.T) {
+	testCases := []struct {
+		err      ParserError
+		expected string
+	}{
+		{ParserError{ErrorType: HeaderError}, "HeaderError"},
+		{ParserError{ErrorType: DataParsingError, Line: 2, Field: "Betrag"}, "DataParsingError in line 2 in field name 'Betrag'"},
+		{
+			ParserError{ErrorType: IOError, File: "in.csv", Err: os.ErrPermission},
+			"IOError in file 'in.csv': permission denied",
+		},
+		{
+			ParserError{ErrorType: DataParsingError, File: "in.csv", Line: 3, Field: "Date", Err: errors.New("bad date")},
+			"DataParsingError in file 'in.csv' in line 3 in field name 'Date': bad date",
+		},
+	}
+	for _, tc := range testCases {
+		if s := tc.err.Error(); s != tc.expected {
+			t.Errorf("Expected '%s', got '%s'", tc.expected, s)
+		}
+	}
+}
+
+func TestParserErrorFileNotFound(t *testing.T) {
+	fpath := filepath.Join("testfiles", "doesnotexist.csv")
+	for _, f := range GetSourceFormats() {
+		err := GetParser(f).ParseFile(fpath)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected os.ErrNotExist, got %v", f, err)
+		}
+		var pError *ParserError
+		if !errors.As(err, &pError) || pError.ErrorType != IOError || pError.File != fpath {
+			t.Errorf("%s: expected IOError with file '%s', got %v", f, fpath, err)
+		}
+	}
+}
+
+func TestParserErrorWrapsCause(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_nok_wrongbuchungstag.csv")
+	err := GetParser(Volksbank).ParseFile(fpath)
+	var pError *ParserError
+	if !errors.As(err, &pError) {
+		t.Fatalf("Expected ParserError, got %v", err)
+	}
+	if pError.File != fpath {
+		t.Errorf("Expected file '%s', got '%s'", fpath, pError.File)
+	}
+	var timeErr *time.ParseError
+	if !errors.As(err, &timeErr) {
+		t.Errorf("Expected wrapped time.ParseError, got %v", pError.Err)
+	}
+	if !strings.Contains(err.Error(), fpath) || !strings.HasSuffix(err.Error(), timeErr.Error()) {
+		t.Errorf("Expected file name and cause in '%s'", err.Error())
+	}
+
+	// Errors of the CSV reader are wrapped as well
+	err = GetParser(Wise).ParseFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"))
+	var csvErr *csv.ParseError
+	if !errors.As(err, &csvErr) {
+		t.Errorf("Expected wrapped csv.ParseError, got %v", err)
+	}
+}
+
+func TestParseFileContext(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
+	p := GetParser(Volksbank)
+	if err := ParseFileContext(context.Background(), p, fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ParseFileContext(ctx, p, fpath); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Reading fails once the context is cancelled
+	ctx, cancel = context.WithCancel(context.Background())
+	r := &contextReader{ctx: ctx, r: strings.NewReader("content")}
+	buf := make([]byte, 3)
+	if _, err := r.Read(buf); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	cancel()
+	if _, err := r.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PaymentTypes maps the transaction type of a record, e.g. comdirects "Vorgang",
+// to the homebank payment code like 4 for bank transfer or 11 for direct debit.
+// The transaction types are compared case-insensitively.
+type PaymentTypes map[string]int8
+
+// comdirectPaymentTypes maps "Vorgang" of the giro account to the homebank payment code.
+// Records of the credit card section always get the payment code 1 (credit card).
+var comdirectPaymentTypes = PaymentTypes{
+	"Lastschrift / Belastung": 11, // Direct debit
+	"Übertrag / Überweisung":  4,  // Bank transfer
+	"Kartenverfügung":         6,  // Debit card
+	"Auszahlung GAA":          3,  // Cash
+}
+
+// dkbPaymentTypes maps the transaction type of DKB to the homebank payment code.
+// The transaction type is "Lastschrift" for outgoing records with a "Mandatsreferenz",
+// otherwise "Umsatztyp", i.e. "Eingang" or "Ausgang".
+var dkbPaymentTypes = PaymentTypes{
+	"Lastschrift": 11, // Direct debit
+}
+
+// volksbankPaymentTypes maps "Buchungstext" to the homebank payment code
+var volksbankPaymentTypes = PaymentTypes{
+	"Basislastschrift":       11, // Direct debit
+	"Firmenlastschrift":      11, // Direct debit
+	"Überweisung":            4,  // Bank transfer
+	"Echtzeitüberweisung":    4,  // Bank transfer
+	"Gutschrift":             4,  // Bank transfer
+	"Lohn/Gehalt":            4,  // Bank transfer
+	"Dauerauftrag":           7,  // Standing order
+	"Kartenzahlung girocard": 6,  // Debit card
+	"Abschluss":              10, // FI fee
+}
+
+// GetDefaultPaymentTypes returns the payment codes derived from the transaction type
+// by default for the given format. It returns nil for formats which don't support
+// the mapping of payment types with FormatOptions.PaymentTypes.
+func GetDefaultPaymentTypes(f SourceFormat) PaymentTypes {
+	var defaults PaymentTypes
+	switch f {
+	case Comdirect:
+		defaults = comdirectPaymentTypes
+	case DKB:
+		defaults = dkbPaymentTypes
+	case Volksbank:
+		defaults = volksbankPaymentTypes
+	default:
+		return nil
+	}
+	types := make(PaymentTypes, len(defaults))
+	for k, v := range defaults {
+		types[k] = v
+	}
+	return types
+}
+
+// CheckPaymentTypes reports whether the payment types can be used.
+//
+// Possible errors:
+//
+//   - a payment code is not known to homebank
+func CheckPaymentTypes(t PaymentTypes) error {
+	for transactionType, payment := range t {
+		if payment < 0 || payment > homebankMaxPayment {
+			return fmt.Errorf("invalid payment code %d for '%s', must be between 0 and %d",
+				payment, transactionType, homebankMaxPayment)
+		}
+	}
+	return nil
+}
+
+// merge returns the payment types of t overridden by the ones of overrides.
+// The keys of the result are lower case.
+func (t PaymentTypes) merge(overrides PaymentTypes) PaymentTypes {
+	merged := make(PaymentTypes, len(t)+len(overrides))
+	for _, types := range []PaymentTypes{t, overrides} {
+		for k, v := range types {
+			merged[strings.ToLower(k)] = v
+		}
+	}
+	return merged
+}
+
+// lookup returns the payment code of the transaction type, 0 if it is unknown.
+// t has to be the result of merge.
+func (t PaymentTypes) lookup(transactionType string) int8 {
+	return t[strings.ToLower(strings.TrimSpace(transactionType))]
+}
+
+// paymentTypes returns the given default payment types of a format overridden
+// by the ones of the format options
+func (c *converter) paymentTypes(defaults PaymentTypes) PaymentTypes {
+	return defaults.merge(c.formatOptions.PaymentTypes)
+}
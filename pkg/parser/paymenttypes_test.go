@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPaymentTypesLookup(t *testing.T) {
+	types := volksbankPaymentTypes.merge(PaymentTypes{
+		"dauerauftrag": 4, // overrides the default of different case
+		"Bargeld":      3, // new type
+		"Abschluss":    0, // disables the default
+	})
+	testCases := []struct {
+		transactionType string
+		expected        int8
+	}{
+		{"Basislastschrift", 11},
+		{"BASISLASTSCHRIFT", 11},
+		{" Basislastschrift ", 11},
+		{"DAUERAUFTRAG", 4},
+		{"Bargeld", 3},
+		{"ABSCHLUSS", 0},
+		{"Unknown", 0},
+		{"", 0},
+	}
+	for _, tc := range testCases {
+		if got := types.lookup(tc.transactionType); got != tc.expected {
+			t.Errorf("%q: expected %d, got %d", tc.transactionType, tc.expected, got)
+		}
+	}
+	// The defaults are not modified by merge
+	if volksbankPaymentTypes["Dauerauftrag"] != 7 {
+		t.Error("Defaults should not be modified")
+	}
+}
+
+func TestGetDefaultPaymentTypes(t *testing.T) {
+	for _, f := range []SourceFormat{Comdirect, DKB, Volksbank} {
+		types := GetDefaultPaymentTypes(f)
+		if len(types) == 0 {
+			t.Errorf("%s: expected default payment types", f)
+		}
+		if err := CheckPaymentTypes(types); err != nil {
+			t.Errorf("%s: expected valid default payment types, got %v", f, err)
+		}
+	}
+	if types := GetDefaultPaymentTypes(PayPal); types != nil {
+		t.Errorf("Expected no default payment types for PayPal, got %v", types)
+	}
+
+	types := GetDefaultPaymentTypes(DKB)
+	types["Lastschrift"] = 0
+	if dkbPaymentTypes["Lastschrift"] != 11 {
+		t.Error("GetDefaultPaymentTypes should return a copy")
+	}
+}
+
+func TestCheckPaymentTypes(t *testing.T) {
+	if err := CheckPaymentTypes(nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := CheckPaymentTypes(PaymentTypes{"a": 0, "b": homebankMaxPayment}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	for _, payment := range []int8{-1, homebankMaxPayment + 1} {
+		if err := CheckPaymentTypes(PaymentTypes{"a": payment}); err == nil {
+			t.Errorf("Expected error for payment code %d", payment)
+		}
+	}
+}
+
+func TestGetEntriesPaymentTypes(t *testing.T) {
+	p := GetParser(Volksbank)
+	if err := p.ParseFile(filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")); err != nil {
+		t.Fatal(err)
+	}
+	p.SetFormatOptions(FormatOptions{PaymentTypes: PaymentTypes{"Basislastschrift": 8}})
+	entries := p.GetEntries()
+	expected := []int{8, 7, 6, 10}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Payment != expected[i] {
+			t.Errorf("Entry %d: expected payment %d, got %d", i, expected[i], e.Payment)
+		}
+	}
+}
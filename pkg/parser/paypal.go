@@ -0,0 +1,210 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the "Aktivitäten" export of PayPal, comma separated with quoted fields and UTF-8 BOM
+- The columns are looked up by their name in the header as PayPal lets the user choose
+  which optional columns are exported
+- Only rows with "Status"=Abgeschlossen are taken into account
+- A payment in foreign currency is accompanied by two rows of "Typ"=Allgemeine Währungsumrechnung,
+  one in the foreign currency and one in EUR. Both refer to the payment via "Zugehöriger Transaktionscode".
+  The conversion rows are not transferred to Homebank, instead the EUR amount is used for the payment.
+- Payments in foreign currency without a conversion to EUR do not affect the EUR balance and are skipped
+- Homebanks "payee" is PayPals "Name", "memo" is taken from "Betreff" and "Hinweis"
+*/
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of paypal data
+type paypalRecord struct {
+	datum                        time.Time
+	name                         string
+	typ                          string
+	waehrung                     string
+	netto                        float64
+	transaktionscode             string
+	zugehoerigerTransaktionscode string
+	betreff                      string
+	hinweis                      string
+}
+
+type paypalParser struct {
+	converter
+	entries []paypalRecord
+}
+
+const paypalCurrencyConversion = "Allgemeine Währungsumrechnung"
+
+// paypalColumns are the columns needed from the PayPal CSV
+var paypalColumns = []string{
+	"Datum",
+	"Name",
+	"Typ",
+	"Status",
+	"Währung",
+	"Netto",
+	"Transaktionscode",
+	"Zugehöriger Transaktionscode",
+	"Betreff",
+	"Hinweis",
+}
+
+func (p *paypalParser) ParseFile(filepath string) error {
+	p.entries = make([]paypalRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *paypalParser) Parse(in io.Reader) error {
+	p.entries = make([]paypalRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], paypalColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	rows := make([]paypalRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if row[columns["Status"]] != "Abgeschlossen" {
+			continue
+		}
+		datum, err := time.Parse("02.01.2006", row[columns["Datum"]])
+		if err != nil {
+			if p.skipRow(lineNr, "Datum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Datum",
+				Err:       err,
+			}
+		}
+		nettoString := strings.Replace(row[columns["Netto"]], ".", "", -1)
+		nettoString = strings.Replace(nettoString, ",", ".", -1)
+		var netto float64
+		netto, err = strconv.ParseFloat(nettoString, 64)
+		if err != nil {
+			if p.skipRow(lineNr, "Netto", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Netto",
+				Err:       err,
+			}
+		}
+		rows = append(rows, paypalRecord{
+			datum:                        datum,
+			name:                         row[columns["Name"]],
+			typ:                          row[columns["Typ"]],
+			waehrung:                     row[columns["Währung"]],
+			netto:                        netto,
+			transaktionscode:             row[columns["Transaktionscode"]],
+			zugehoerigerTransaktionscode: row[columns["Zugehöriger Transaktionscode"]],
+			betreff:                      row[columns["Betreff"]],
+			hinweis:                      row[columns["Hinweis"]],
+		})
+	}
+
+	p.entries = foldPaypalCurrencyConversions(rows)
+	return nil
+}
+
+// foldPaypalCurrencyConversions removes the currency conversion rows and
+// replaces the amount of the referenced foreign currency payments by the
+// converted EUR amount.
+func foldPaypalCurrencyConversions(rows []paypalRecord) []paypalRecord {
+	// Sum of the EUR conversion rows for each referenced transaction code
+	eurAmounts := make(map[string]float64)
+	for _, row := range rows {
+		if row.typ == paypalCurrencyConversion && row.waehrung == "EUR" {
+			eurAmounts[row.zugehoerigerTransaktionscode] += row.netto
+		}
+	}
+
+	entries := make([]paypalRecord, 0, len(rows))
+	for _, row := range rows {
+		if row.typ == paypalCurrencyConversion {
+			continue
+		}
+		if row.waehrung != "EUR" {
+			amount, ok := eurAmounts[row.transaktionscode]
+			if !ok {
+				continue
+			}
+			row.netto = amount
+			row.waehrung = "EUR"
+		}
+		entries = append(entries, row)
+	}
+	return entries
+}
+
+func (p *paypalParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *paypalParser) GetFormat() SourceFormat {
+	return PayPal
+}
+
+func (p *paypalParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *paypalParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *paypalParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *paypalParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, pRecord := range p.entries {
+		hRecord := pRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// convertRecord converts a single record from paypal to homebank format
+func (p *paypalRecord) convertRecord() homebankRecord {
+	memo := make([]string, 0, 2)
+	for _, s := range []string{p.betreff, p.hinweis} {
+		if s != "" {
+			memo = append(memo, s)
+		}
+	}
+	return homebankRecord{
+		date:    p.datum.Format("2006-01-02"),
+		payment: 8, // Electronic payment
+		info:    p.typ,
+		payee:   p.name,
+		memo:    strings.Join(memo, " "),
+		amount:  p.netto,
+	}
+}
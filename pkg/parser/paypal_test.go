@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPaypalName(t *testing.T) {
+	p := &paypalParser{}
+	if p.GetFormat() != PayPal {
+		t.Error("Wrong format")
+	}
+}
+
+func TestPaypalParseFileNonExisting(t *testing.T) {
+	p := &paypalParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestPaypalParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download_nok_noheader.CSV")
+	p := &paypalParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestPaypalParseFileNokWrongDatum(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download_nok_wrongdatum.CSV")
+	p := &paypalParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Field != "Datum" {
+			t.Errorf("Expected field 'Datum', got '%s' instead", pError.Field)
+		}
+		if pError.Line != 2 {
+			t.Errorf("Expected line 2, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestPaypalParseFileNokWrongNetto(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download_nok_wrongnetto.CSV")
+	p := &paypalParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Field != "Netto" {
+			t.Errorf("Expected field 'Netto', got '%s' instead", pError.Field)
+		}
+		if pError.Line != 3 {
+			t.Errorf("Expected line 3, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestPaypalParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download_onlyheader.CSV")
+	p := &paypalParser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Fatalf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestPaypalParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download.CSV")
+	p := &paypalParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	// 8 rows: one pending, two currency conversions and one GBP payment without conversion are dropped
+	if p.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestFoldPaypalCurrencyConversions(t *testing.T) {
+	rows := []paypalRecord{
+		{typ: "Handelsübliche Zahlung", waehrung: "USD", netto: -10, transaktionscode: "A"},
+		{typ: paypalCurrencyConversion, waehrung: "USD", netto: 10, transaktionscode: "B", zugehoerigerTransaktionscode: "A"},
+		{typ: paypalCurrencyConversion, waehrung: "EUR", netto: -9.37, transaktionscode: "C", zugehoerigerTransaktionscode: "A"},
+		{typ: "Handelsübliche Zahlung", waehrung: "EUR", netto: -5, transaktionscode: "D"},
+		{typ: "Handelsübliche Zahlung", waehrung: "GBP", netto: -3, transaktionscode: "E"},
+	}
+	entries := foldPaypalCurrencyConversions(rows)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].transaktionscode != "A" || entries[0].netto != -9.37 || entries[0].waehrung != "EUR" {
+		t.Errorf("Unexpected folded entry %+v", entries[0])
+	}
+	if entries[1].transaktionscode != "D" || entries[1].netto != -5 {
+		t.Errorf("Unexpected entry %+v", entries[1])
+	}
+}
+
+func TestPaypalConvertRecord(t *testing.T) {
+	p := paypalRecord{
+		datum:    time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC),
+		name:     "US Store Inc.",
+		typ:      "Handelsübliche Zahlung",
+		waehrung: "EUR",
+		netto:    -9.37,
+		betreff:  "Order 123",
+		hinweis:  "Digital download",
+	}
+	h := p.convertRecord()
+	if h.date != "2024-05-05" {
+		t.Errorf("Expected date to be 2024-05-05, got '%s'", h.date)
+	}
+	if h.payment != 8 {
+		t.Errorf("Expected payment to be 8, got %d", h.payment)
+	}
+	if h.info != p.typ {
+		t.Errorf("Expected info to be '%s', got '%s'", p.typ, h.info)
+	}
+	if h.payee != p.name {
+		t.Errorf("Expected payee to be '%s', got '%s'", p.name, h.payee)
+	}
+	if h.memo != "Order 123 Digital download" {
+		t.Errorf("Expected memo to be 'Order 123 Digital download', got '%s'", h.memo)
+	}
+	if h.amount != p.netto {
+		t.Errorf("Expected amount to be %f, got %f", p.netto, h.amount)
+	}
+	if h.category != "" {
+		t.Errorf("Expected category to be empty, got '%s'", h.category)
+	}
+	if h.tags != "" {
+		t.Errorf("Expected tags to be empty, got '%s'", h.tags)
+	}
+
+	p.betreff = ""
+	if h := p.convertRecord(); h.memo != "Digital download" {
+		t.Errorf("Expected memo to be 'Digital download', got '%s'", h.memo)
+	}
+}
+
+func TestPaypalConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "paypal", "Download.CSV")
+	p := &paypalParser{}
+	err := p.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = p.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "paypal", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Rule maps converted records to new field values. A record matches the rule
+// if it matches all conditions set in Match, at least one condition must be set.
+type Rule struct {
+	Name  string    `yaml:"name,omitempty"` // Optional, must be unique, used in error messages
+	Match RuleMatch `yaml:"match,omitempty"`
+	Set   RuleSet   `yaml:"set,omitempty"`
+}
+
+// RuleMatch are the conditions of a rule. The regular expressions use the
+// syntax of the regexp package and match anywhere in the field unless anchored.
+type RuleMatch struct {
+	PayeeRegex string        `yaml:"payee_regex,omitempty"`
+	MemoRegex  string        `yaml:"memo_regex,omitempty"`
+	InfoRegex  string        `yaml:"info_regex,omitempty"`
+	Amount     string        `yaml:"amount,omitempty"`     // "positive" or "negative"
+	AmountMin  *float64      `yaml:"amount_min,omitempty"` // Lowest amount including the sign, inclusive
+	AmountMax  *float64      `yaml:"amount_max,omitempty"` // Highest amount including the sign, inclusive
+	Format     *SourceFormat `yaml:"format,omitempty"`     // Source format of the converted file
+}
+
+// RuleSet are the field values set by a rule. Empty values leave the field unchanged.
+type RuleSet struct {
+	Category string `yaml:"category,omitempty"`
+	Payee    string `yaml:"payee,omitempty"`
+	Memo     string `yaml:"memo,omitempty"`
+	Info     string `yaml:"info,omitempty"`
+	Tags     string `yaml:"tags,omitempty"`    // Tags separated by spaces, replacing the existing ones
+	Payment  *int8  `yaml:"payment,omitempty"` // Homebank payment code from 0 to 11
+}
+
+// Rules is a compiled list of rules, created by NewRules.
+type Rules struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	payee     *regexp.Regexp
+	memo      *regexp.Regexp
+	info      *regexp.Regexp
+	amount    string
+	amountMin *float64
+	amountMax *float64
+	format    *SourceFormat
+	set       RuleSet
+}
+
+// NewRules compiles the given rules. The rules are applied in the given order,
+// the first matching rule wins.
+//
+// Possible errors:
+//
+//   - Match is empty, the rule would match every record
+//   - a regular expression can't be compiled
+//   - Amount is neither empty, "positive" nor "negative"
+//   - AmountMin is greater than AmountMax
+//   - Payment is not a homebank payment code
+//   - Name is given for more than one rule
+func NewRules(rules []Rule) (*Rules, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	var names []string
+	for i, rule := range rules {
+		label := ruleLabel(i, rule)
+		if rule.Name != "" {
+			if slices.Contains(names, rule.Name) {
+				return nil, fmt.Errorf("%s: duplicate rule name", label)
+			}
+			names = append(names, rule.Name)
+		}
+		if rule.Match.isEmpty() {
+			return nil, fmt.Errorf("%s: match is empty, at least one condition is needed", label)
+		}
+		var c compiledRule
+		var err error
+		if c.payee, err = compileRuleRegex(rule.Match.PayeeRegex); err != nil {
+			return nil, fmt.Errorf("%s: invalid payee_regex: %w", label, err)
+		}
+		if c.memo, err = compileRuleRegex(rule.Match.MemoRegex); err != nil {
+			return nil, fmt.Errorf("%s: invalid memo_regex: %w", label, err)
+		}
+		if c.info, err = compileRuleRegex(rule.Match.InfoRegex); err != nil {
+			return nil, fmt.Errorf("%s: invalid info_regex: %w", label, err)
+		}
+		switch rule.Match.Amount {
+		case "", "positive", "negative":
+			c.amount = rule.Match.Amount
+		default:
+			return nil, fmt.Errorf("%s: invalid amount '%s', expected 'positive' or 'negative'", label, rule.Match.Amount)
+		}
+		if amountMin, amountMax := rule.Match.AmountMin, rule.Match.AmountMax; amountMin != nil && amountMax != nil && *amountMin > *amountMax {
+			return nil, fmt.Errorf("%s: amount_min %v is greater than amount_max %v", label, *amountMin, *amountMax)
+		}
+		if p := rule.Set.Payment; p != nil && (*p < 0 || *p > homebankMaxPayment) {
+			return nil, fmt.Errorf("%s: invalid payment %d, expected a code from 0 to %d", label, *p, homebankMaxPayment)
+		}
+		c.amountMin = rule.Match.AmountMin
+		c.amountMax = rule.Match.AmountMax
+		c.format = rule.Match.Format
+		c.set = rule.Set
+		compiled = append(compiled, c)
+	}
+	return &Rules{rules: compiled}, nil
+}
+
+// ruleLabel returns how the rule with index i is named in error messages, by its
+// name if given, otherwise by its number
+func ruleLabel(i int, rule Rule) string {
+	if rule.Name != "" {
+		return fmt.Sprintf("rule '%s'", rule.Name)
+	}
+	return fmt.Sprintf("rule %d", i+1)
+}
+
+// isEmpty reports whether no condition is set
+func (m RuleMatch) isEmpty() bool {
+	return m.PayeeRegex == "" && m.MemoRegex == "" && m.InfoRegex == "" && m.Amount == "" &&
+		m.AmountMin == nil && m.AmountMax == nil && m.Format == nil
+}
+
+// compileRuleRegex compiles expr, an empty expr results in a nil regexp
+func compileRuleRegex(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// matches reports whether the record converted from the given format matches the rule
+func (c *compiledRule) matches(r *homebankRecord, format SourceFormat) bool {
+	if c.format != nil && *c.format != format {
+		return false
+	}
+	switch c.amount {
+	case "positive":
+		if r.amount <= 0 {
+			return false
+		}
+	case "negative":
+		if r.amount >= 0 {
+			return false
+		}
+	}
+	if c.amountMin != nil && r.amount < *c.amountMin {
+		return false
+	}
+	if c.amountMax != nil && r.amount > *c.amountMax {
+		return false
+	}
+	if c.payee != nil && !c.payee.MatchString(r.payee) {
+		return false
+	}
+	if c.memo != nil && !c.memo.MatchString(r.memo) {
+		return false
+	}
+	if c.info != nil && !c.info.MatchString(r.info) {
+		return false
+	}
+	return true
+}
+
+// apply sets the fields of each record according to the first matching rule
+func (rs *Rules) apply(records []homebankRecord, format SourceFormat) {
+	if rs == nil {
+		return
+	}
+	for i := range records {
+		r := &records[i]
+		for j := range rs.rules {
+			rule := &rs.rules[j]
+			if !rule.matches(r, format) {
+				continue
+			}
+			if rule.set.Category != "" {
+				r.category = rule.set.Category
+			}
+			if rule.set.Payee != "" {
+				r.payee = rule.set.Payee
+			}
+			if rule.set.Memo != "" {
+				r.memo = rule.set.Memo
+			}
+			if rule.set.Info != "" {
+				r.info = rule.set.Info
+			}
+			if rule.set.Tags != "" {
+				r.tags = rule.set.Tags
+			}
+			if rule.set.Payment != nil {
+				r.payment = *rule.set.Payment
+			}
+			break
+		}
+	}
+}
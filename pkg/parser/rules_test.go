@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []Rule
+		err   string
+	}{
+		{"payee", []Rule{{Match: RuleMatch{PayeeRegex: "REWE("}}}, "rule 1: invalid payee_regex"},
+		{"memo", []Rule{{Match: RuleMatch{Amount: "negative"}}, {Match: RuleMatch{MemoRegex: "[a-"}}}, "rule 2: invalid memo_regex"},
+		{"info", []Rule{{Match: RuleMatch{InfoRegex: "*"}}}, "rule 1: invalid info_regex"},
+		{"amount", []Rule{{Match: RuleMatch{Amount: "zero"}}}, "rule 1: invalid amount 'zero'"},
+		{"empty match", []Rule{{Set: RuleSet{Category: "All"}}}, "rule 1: match is empty"},
+		{"amount range", []Rule{{Match: RuleMatch{AmountMin: ptr(10.0), AmountMax: ptr(-10.0)}}}, "rule 1: amount_min 10 is greater than amount_max -10"},
+		{"payment", []Rule{{Match: RuleMatch{Amount: "negative"}, Set: RuleSet{Payment: ptr(int8(12))}}}, "rule 1: invalid payment 12"},
+		{"negative payment", []Rule{{Match: RuleMatch{Amount: "negative"}, Set: RuleSet{Payment: ptr(int8(-1))}}}, "rule 1: invalid payment -1"},
+		{"named", []Rule{{Name: "rent", Match: RuleMatch{MemoRegex: "("}}}, "rule 'rent': invalid memo_regex"},
+		{"duplicate name", []Rule{
+			{Name: "rent", Match: RuleMatch{MemoRegex: "rent"}},
+			{Name: "food", Match: RuleMatch{PayeeRegex: "REWE"}},
+			{Name: "rent", Match: RuleMatch{MemoRegex: "Miete"}},
+		}, "rule 'rent': duplicate rule name"},
+	}
+	for _, tc := range testCases {
+		r, err := NewRules(tc.rules)
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+			continue
+		}
+		if r != nil {
+			t.Errorf("%s: expected no rules on error", tc.name)
+		}
+		if !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: expected error starting with %q, got %q", tc.name, tc.err, err.Error())
+		}
+	}
+
+	if _, err := NewRules(nil); err != nil {
+		t.Errorf("Expected no error for empty rules, got %v", err)
+	}
+	// Rules without name may occur several times
+	if _, err := NewRules([]Rule{{Match: RuleMatch{Amount: "negative"}}, {Match: RuleMatch{Amount: "positive"}}}); err != nil {
+		t.Errorf("Expected no error for rules without name, got %v", err)
+	}
+}
+
+// ptr returns a pointer to v
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestRulesApplyAmountRangeTagsPayment(t *testing.T) {
+	rules, err := NewRules([]Rule{
+		{Name: "large", Match: RuleMatch{AmountMax: ptr(-100.0)}, Set: RuleSet{Tags: "large review"}},
+		{Name: "small", Match: RuleMatch{AmountMin: ptr(-10.0), AmountMax: ptr(-0.01)}, Set: RuleSet{Payment: ptr(int8(3))}},
+		{Name: "cash", Match: RuleMatch{PayeeRegex: "ATM", AmountMin: ptr(0.0)}, Set: RuleSet{Payment: ptr(int8(0)), Category: "Cash"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name     string
+		record   homebankRecord
+		expected homebankRecord
+	}{
+		{"maximum inclusive", homebankRecord{amount: -100, tags: "old"}, homebankRecord{amount: -100, tags: "large review"}},
+		{"below maximum", homebankRecord{amount: -250.5}, homebankRecord{amount: -250.5, tags: "large review"}},
+		{"between", homebankRecord{amount: -50, payment: 4}, homebankRecord{amount: -50, payment: 4}},
+		{"minimum inclusive", homebankRecord{amount: -10, payment: 4}, homebankRecord{amount: -10, payment: 3}},
+		{"above range", homebankRecord{amount: 0, payment: 4}, homebankRecord{amount: 0, payment: 4}},
+		{"payment zero", homebankRecord{payee: "ATM 1", amount: 20, payment: 6}, homebankRecord{payee: "ATM 1", amount: 20, category: "Cash"}},
+	}
+	for _, tc := range testCases {
+		records := []homebankRecord{tc.record}
+		rules.apply(records, DKB)
+		if records[0] != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, records[0])
+		}
+	}
+}
+
+func TestRulesApply(t *testing.T) {
+	dkb := DKB
+	rules, err := NewRules([]Rule{
+		{Match: RuleMatch{PayeeRegex: "^REWE", Amount: "positive"}, Set: RuleSet{Category: "Refund"}},
+		{Match: RuleMatch{PayeeRegex: "^REWE"}, Set: RuleSet{Category: "Groceries:Food", Payee: "REWE"}},
+		{Match: RuleMatch{PayeeRegex: "REWE"}, Set: RuleSet{Category: "never reached"}},
+		{Match: RuleMatch{MemoRegex: "(?i)rent"}, Set: RuleSet{Category: "Housing", Info: "Rent"}},
+		{Match: RuleMatch{InfoRegex: "^Card$", Format: &dkb}, Set: RuleSet{Memo: "DKB card"}},
+		{Match: RuleMatch{Amount: "negative"}, Set: RuleSet{Category: "Other"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		record   homebankRecord
+		format   SourceFormat
+		expected homebankRecord
+	}{
+		{
+			"first match wins",
+			homebankRecord{payee: "REWE Markt 123", amount: -10, category: "Old"},
+			Comdirect,
+			homebankRecord{payee: "REWE", amount: -10, category: "Groceries:Food"},
+		},
+		{
+			"amount sign",
+			homebankRecord{payee: "REWE Markt 123", amount: 10},
+			Comdirect,
+			homebankRecord{payee: "REWE Markt 123", amount: 10, category: "Refund"},
+		},
+		{
+			"memo",
+			homebankRecord{memo: "Monthly RENT", info: "Transfer", amount: -500},
+			Comdirect,
+			homebankRecord{memo: "Monthly RENT", info: "Rent", amount: -500, category: "Housing"},
+		},
+		{
+			"format matches",
+			homebankRecord{info: "Card", amount: 5},
+			DKB,
+			homebankRecord{info: "Card", memo: "DKB card", amount: 5},
+		},
+		{
+			"format does not match",
+			homebankRecord{info: "Card", amount: 5},
+			Comdirect,
+			homebankRecord{info: "Card", amount: 5},
+		},
+		{
+			"fallback",
+			homebankRecord{payee: "Someone", amount: -1},
+			Comdirect,
+			homebankRecord{payee: "Someone", amount: -1, category: "Other"},
+		},
+		{
+			"zero amount matches no sign",
+			homebankRecord{payee: "Someone"},
+			Comdirect,
+			homebankRecord{payee: "Someone"},
+		},
+	}
+	for _, tc := range testCases {
+		records := []homebankRecord{tc.record}
+		rules.apply(records, tc.format)
+		if records[0] != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, records[0])
+		}
+	}
+}
+
+func TestRulesApplyNil(t *testing.T) {
+	var rules *Rules
+	records := []homebankRecord{{payee: "REWE"}}
+	rules.apply(records, DKB)
+	if records[0] != (homebankRecord{payee: "REWE"}) {
+		t.Errorf("Expected record to be unchanged, got %+v", records[0])
+	}
+}
+
+func TestGetEntriesRules(t *testing.T) {
+	p := GetParser(DKB)
+	if err := p.ParseFile(filepath.Join("testfiles", "dkb", "dkb.csv")); err != nil {
+		t.Fatal(err)
+	}
+	// The rules are applied before the category prefix, so the rule category is prefixed too
+	p.SetCategoryPrefix("Import", true)
+	rules, err := NewRules([]Rule{
+		{Match: RuleMatch{PayeeRegex: "anderer Bank"}, Set: RuleSet{Category: "Transfer", Payee: "Other bank"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetRules(rules)
+
+	entries := p.GetEntries()
+	matched := 0
+	for _, e := range entries {
+		switch e.Category {
+		case "Import:Transfer":
+			matched++
+			if e.Payee != "Other bank" {
+				t.Errorf("Expected payee 'Other bank', got %q", e.Payee)
+			}
+		case "Import":
+		default:
+			t.Errorf("Unexpected category %q", e.Category)
+		}
+	}
+	if matched == 0 {
+		t.Error("Expected at least one entry to match the rule")
+	}
+}
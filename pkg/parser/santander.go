@@ -0,0 +1,145 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the giro account export of Santander Germany, semicolon separated.
+  The file is ISO-8859-1 encoded unless it is valid UTF-8
+- Homebanks "date" is "Buchungstag" in the format dd.mm.yyyy, "amount" is "Betrag" in German notation
+- The first line of "Verwendungszweck" is the payee, the remaining lines are the memo
+*/
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Single record of santander data
+type santanderRecord struct {
+	buchungstag      time.Time
+	verwendungszweck string
+	betrag           float64
+}
+
+type santanderParser struct {
+	converter
+	entries []santanderRecord
+}
+
+func (p *santanderParser) ParseFile(filepath string) error {
+	p.entries = make([]santanderRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *santanderParser) Parse(in io.Reader) error {
+	p.entries = make([]santanderRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidSantanderHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]santanderRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("02.01.2006", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Buchungstag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Buchungstag",
+				Err:       err,
+			}
+		}
+		betrag, err := parseGermanAmount(row[3])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag",
+				Err:       err,
+			}
+		}
+		entries = append(entries, santanderRecord{
+			buchungstag:      date,
+			verwendungszweck: row[2],
+			betrag:           betrag,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *santanderParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *santanderParser) GetFormat() SourceFormat {
+	return Santander
+}
+
+func (p *santanderParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *santanderParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *santanderParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *santanderParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, sRecord := range p.entries {
+		hRecord := sRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidSantanderHeader(record []string) bool {
+	expected := []string{
+		"Buchungstag",
+		"Wertstellung",
+		"Verwendungszweck",
+		"Betrag",
+		"Währung",
+	}
+	return reflect.DeepEqual(record, expected)
+}
+
+// convertRecord converts a single record from santander to homebank format
+func (s *santanderRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = s.buchungstag.Format("2006-01-02")
+	h.amount = s.betrag
+	payee, memo, _ := strings.Cut(strings.ReplaceAll(s.verwendungszweck, "\r", ""), "\n")
+	h.payee = strings.TrimSpace(payee)
+	h.memo = singleLine(memo)
+	return
+}
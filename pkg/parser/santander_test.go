@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSantanderName(t *testing.T) {
+	p := &santanderParser{}
+	if p.GetFormat() != Santander {
+		t.Error("Wrong format")
+	}
+}
+
+func TestSantanderParseFileNonExisting(t *testing.T) {
+	p := &santanderParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSantanderParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "santander", "Umsaetze_nok_noheader.csv")
+	p := &santanderParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSantanderParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Umsaetze_nok_wrongbuchungstag.csv", 5, "Buchungstag"},
+		{"Umsaetze_nok_wrongbetrag.csv", 7, "Betrag"},
+	}
+	for _, tc := range testCases {
+		p := &santanderParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "santander", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestSantanderParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "santander", "Umsaetze_onlyheader.csv")
+	p := &santanderParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSantanderParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "santander", "Umsaetze.csv")
+	p := &santanderParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestSantanderConvertRecord(t *testing.T) {
+	testCases := []struct {
+		verwendungszweck string
+		payee            string
+		memo             string
+	}{
+		{"Bäckerei Müller\nKartenzahlung 03.10.2023\r\nFiliale Köln", "Bäckerei Müller", "Kartenzahlung 03.10.2023 Filiale Köln"},
+		{"Kontoführung", "Kontoführung", ""},
+		{"", "", ""},
+	}
+	for _, tc := range testCases {
+		s := santanderRecord{
+			buchungstag:      time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC),
+			verwendungszweck: tc.verwendungszweck,
+			betrag:           -12.34,
+		}
+		h := s.convertRecord()
+		if h.date != "2023-10-04" {
+			t.Errorf("Expected date to be 2023-10-04, got '%s'", h.date)
+		}
+		if h.amount != s.betrag {
+			t.Errorf("Expected amount to be %f, got %f", s.betrag, h.amount)
+		}
+		if h.payee != tc.payee {
+			t.Errorf("Expected payee to be '%s', got '%s'", tc.payee, h.payee)
+		}
+		if h.memo != tc.memo {
+			t.Errorf("Expected memo to be '%s', got '%s'", tc.memo, h.memo)
+		}
+	}
+}
+
+func TestSantanderConvertToHomebank(t *testing.T) {
+	p := &santanderParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "santander", "Umsaetze.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "santander", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
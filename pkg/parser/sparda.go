@@ -0,0 +1,171 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the giro account export of a Sparda-Bank, semicolon separated
+- Homebanks "date" is "Buchungstag" in the format dd.mm.yyyy
+- "Umsatz" is the unsigned amount in German notation. The sign is given in the separate
+  column "Soll/Haben" with "S" for debit and "H" for credit
+- Homebanks "payee" is "Zahlungsempfänger", "memo" is "Vorgang/Verwendungszweck"
+*/
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Single record of sparda data
+type spardaRecord struct {
+	buchungstag        time.Time
+	zahlungsempfaenger string
+	verwendungszweck   string
+	umsatz             float64
+}
+
+type spardaParser struct {
+	converter
+	entries []spardaRecord
+}
+
+func (p *spardaParser) ParseFile(filepath string) error {
+	p.entries = make([]spardaRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *spardaParser) Parse(in io.Reader) error {
+	p.entries = make([]spardaRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidSpardaHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]spardaRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("02.01.2006", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Buchungstag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Buchungstag",
+				Err:       err,
+			}
+		}
+		umsatz, err := parseGermanAmount(row[12])
+		if err != nil {
+			if p.skipRow(lineNr, "Umsatz", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Umsatz",
+				Err:       err,
+			}
+		}
+		switch row[13] {
+		case "S":
+			umsatz = -math.Abs(umsatz)
+		case "H":
+			umsatz = math.Abs(umsatz)
+		default:
+			if p.skipRow(lineNr, "Soll/Haben", nil) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Soll/Haben",
+			}
+		}
+		entries = append(entries, spardaRecord{
+			buchungstag:        date,
+			zahlungsempfaenger: row[4],
+			verwendungszweck:   row[9],
+			umsatz:             umsatz,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *spardaParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *spardaParser) GetFormat() SourceFormat {
+	return Sparda
+}
+
+func (p *spardaParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *spardaParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *spardaParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *spardaParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, sRecord := range p.entries {
+		hRecord := sRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidSpardaHeader(record []string) bool {
+	expected := []string{
+		"Buchungstag",
+		"Valuta",
+		"Textschlüssel",
+		"Primanota",
+		"Zahlungsempfänger",
+		"ZahlungsempfängerKto",
+		"ZahlungsempfängerIBAN",
+		"ZahlungsempfängerBLZ",
+		"ZahlungsempfängerBIC",
+		"Vorgang/Verwendungszweck",
+		"Kundenreferenz",
+		"Währung",
+		"Umsatz",
+		"Soll/Haben",
+	}
+	return reflect.DeepEqual(record, expected)
+}
+
+// convertRecord converts a single record from sparda to homebank format
+func (s *spardaRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = s.buchungstag.Format("2006-01-02")
+	h.amount = s.umsatz
+	h.payee = s.zahlungsempfaenger
+	h.memo = s.verwendungszweck
+	return
+}
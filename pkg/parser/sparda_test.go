@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpardaName(t *testing.T) {
+	p := &spardaParser{}
+	if p.GetFormat() != Sparda {
+		t.Error("Wrong format")
+	}
+}
+
+func TestSpardaParseFileNonExisting(t *testing.T) {
+	p := &spardaParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSpardaParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "sparda", "Umsaetze_nok_noheader.csv")
+	p := &spardaParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSpardaParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Umsaetze_nok_wrongbuchungstag.csv", 3, "Buchungstag"},
+		{"Umsaetze_nok_wrongumsatz.csv", 4, "Umsatz"},
+		{"Umsaetze_nok_wrongsollhaben.csv", 2, "Soll/Haben"},
+	}
+	for _, tc := range testCases {
+		p := &spardaParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "sparda", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestSpardaParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "sparda", "Umsaetze_onlyheader.csv")
+	p := &spardaParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestSpardaParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "sparda", "Umsaetze.csv")
+	p := &spardaParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestSpardaConvertRecord(t *testing.T) {
+	s := spardaRecord{
+		buchungstag:        time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC),
+		zahlungsempfaenger: "Stadtwerke GmbH",
+		verwendungszweck:   "Lastschrift Abschlag Oktober",
+		umsatz:             -85.5,
+	}
+	h := s.convertRecord()
+	if h.date != "2023-10-04" {
+		t.Errorf("Expected date to be 2023-10-04, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != s.umsatz {
+		t.Errorf("Expected amount to be %f, got %f", s.umsatz, h.amount)
+	}
+	if h.payee != s.zahlungsempfaenger {
+		t.Errorf("Expected payee to be '%s', got '%s'", s.zahlungsempfaenger, h.payee)
+	}
+	if h.memo != s.verwendungszweck {
+		t.Errorf("Expected memo to be '%s', got '%s'", s.verwendungszweck, h.memo)
+	}
+}
+
+func TestSpardaConvertToHomebank(t *testing.T) {
+	p := &spardaParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "sparda", "Umsaetze.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "sparda", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,175 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the transaction export of Trade Republic, semicolon separated
+- Homebanks "date" is "Datum" in the format yyyy-mm-dd, "amount" is the signed "Betrag"
+  with a dot as decimal separator
+- Homebanks "info" is the transaction type "Typ", "payee" is "Beschreibung"
+- Homebanks "payment" is "debit card" for card payments and "bank transfer" for
+  transfers and deposits
+- Buying and selling of securities is converted as well unless
+  FormatOptions.SkipSecurityTrades is set
+- "Saldo" is ignored
+*/
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of Trade Republic data
+type tradeRepublicRecord struct {
+	datum        time.Time
+	typ          string
+	beschreibung string
+	betrag       float64
+}
+
+type tradeRepublicParser struct {
+	converter
+	entries []tradeRepublicRecord
+}
+
+// tradeRepublicPaymentTypes maps "Typ" to the homebank payment code
+var tradeRepublicPaymentTypes = map[string]int8{
+	"Kartentransaktion": 6, // Debit card
+	"Überweisung":       4, // Bank transfer
+	"Einzahlung":        4, // Bank transfer
+}
+
+// tradeRepublicSecurityTrades are the values of "Typ" for buying and selling securities
+var tradeRepublicSecurityTrades = []string{"Kauf", "Verkauf", "Sparplan"}
+
+func (p *tradeRepublicParser) ParseFile(filepath string) error {
+	p.entries = make([]tradeRepublicRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *tradeRepublicParser) Parse(in io.Reader) error {
+	p.entries = make([]tradeRepublicRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidTradeRepublicHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]tradeRepublicRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		datum, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Datum", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Datum",
+				Err:       err,
+			}
+		}
+		betrag, err := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag",
+				Err:       err,
+			}
+		}
+		entries = append(entries, tradeRepublicRecord{
+			datum:        datum,
+			typ:          row[1],
+			beschreibung: row[2],
+			betrag:       betrag,
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *tradeRepublicParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *tradeRepublicParser) GetFormat() SourceFormat {
+	return TradeRepublic
+}
+
+func (p *tradeRepublicParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *tradeRepublicParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *tradeRepublicParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *tradeRepublicParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, tRecord := range p.entries {
+		if p.formatOptions.SkipSecurityTrades && tRecord.isSecurityTrade() {
+			continue
+		}
+		hRecord := tRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+// isSecurityTrade reports whether the record is buying or selling of securities
+func (t *tradeRepublicRecord) isSecurityTrade() bool {
+	for _, typ := range tradeRepublicSecurityTrades {
+		if t.typ == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// convertRecord converts a single record from Trade Republic to homebank format
+func (t *tradeRepublicRecord) convertRecord() (h homebankRecord) {
+	h.payment = tradeRepublicPaymentTypes[t.typ]
+	h.date = t.datum.Format("2006-01-02")
+	h.amount = t.betrag
+	h.info = t.typ
+	h.payee = t.beschreibung
+	return
+}
+
+func isValidTradeRepublicHeader(record []string) bool {
+	expected := []string{
+		"Datum",
+		"Typ",
+		"Beschreibung",
+		"Betrag",
+		"Saldo",
+	}
+	return reflect.DeepEqual(record, expected)
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTradeRepublicName(t *testing.T) {
+	p := &tradeRepublicParser{}
+	if p.GetFormat() != TradeRepublic {
+		t.Error("Wrong format")
+	}
+}
+
+func TestTradeRepublicParseFileNonExisting(t *testing.T) {
+	p := &tradeRepublicParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestTradeRepublicParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "traderepublic", "transaktionen_nok_noheader.csv")
+	p := &tradeRepublicParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestTradeRepublicParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"transaktionen_nok_wrongdatum.csv", 3, "Datum"},
+		{"transaktionen_nok_wrongbetrag.csv", 4, "Betrag"},
+	}
+	for _, tc := range testCases {
+		p := &tradeRepublicParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "traderepublic", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestTradeRepublicParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "traderepublic", "transaktionen_onlyheader.csv")
+	p := &tradeRepublicParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestTradeRepublicParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "traderepublic", "transaktionen.csv")
+	p := &tradeRepublicParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 7 {
+		t.Errorf("Expected 7 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestTradeRepublicConvertRecord(t *testing.T) {
+	testCases := []struct {
+		typ     string
+		payment int8
+		trade   bool
+	}{
+		{"Kartentransaktion", 6, false},
+		{"Überweisung", 4, false},
+		{"Einzahlung", 4, false},
+		{"Zinsen", 0, false},
+		{"Kauf", 0, true},
+		{"Verkauf", 0, true},
+		{"Sparplan", 0, true},
+	}
+	for _, tc := range testCases {
+		r := tradeRepublicRecord{
+			datum:        time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+			typ:          tc.typ,
+			beschreibung: "Bäckerei",
+			betrag:       -12.34,
+		}
+		if r.isSecurityTrade() != tc.trade {
+			t.Errorf("%s: expected security trade %t", tc.typ, tc.trade)
+		}
+		h := r.convertRecord()
+		if h.payment != tc.payment {
+			t.Errorf("%s: expected payment to be %d, got %d", tc.typ, tc.payment, h.payment)
+		}
+		if h.date != "2023-10-02" {
+			t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+		}
+		if h.amount != r.betrag {
+			t.Errorf("Expected amount to be %f, got %f", r.betrag, h.amount)
+		}
+		if h.info != r.typ {
+			t.Errorf("Expected info to be '%s', got '%s'", r.typ, h.info)
+		}
+		if h.payee != r.beschreibung {
+			t.Errorf("Expected payee to be '%s', got '%s'", r.beschreibung, h.payee)
+		}
+	}
+}
+
+func TestTradeRepublicConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		skipSecurityTrades bool
+		expected           string
+	}{
+		{false, "homebank.csv"},
+		{true, "homebank_skipsecuritytrades.csv"},
+	}
+	for _, tc := range testCases {
+		p := &tradeRepublicParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "traderepublic", "transaktionen.csv")); err != nil {
+			t.Error(err)
+		}
+		p.SetFormatOptions(FormatOptions{SkipSecurityTrades: tc.skipSecurityTrades})
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "traderepublic", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Transaction is a single converted entry as written to the homebank CSV file.
+// It allows to consume the result of a parser without writing a file.
+type Transaction struct {
+	Date     time.Time // Date of the transaction, only the date part is used
+	Payment  int       // Homebank payment type, e.g. 1 for credit card, see http://homebank.free.fr/help/misc-csvformat.html
+	Info     string
+	Payee    string
+	Memo     string
+	Amount   float64  // Rounded to whole cents as written to the CSV file
+	Category string   // Subcategories are separated by ':'
+	Tags     []string // Homebank tags, may not contain spaces
+}
+
+// fingerprintMemoLength is the number of characters at the beginning of the memo
+// which are part of the fingerprint
+const fingerprintMemoLength = 32
+
+// Fingerprint returns an identifier of the transaction, which is the same for the
+// transaction in overlapping exports of an account.
+//
+// It is built from the date, the amount, the payee and the beginning of the memo.
+// Payee and memo are compared case-insensitively with collapsed whitespace, other fields
+// like the category are not taken into account.
+func (t Transaction) Fingerprint() string {
+	memo := []rune(normalizeFingerprintText(t.Memo))
+	if len(memo) > fingerprintMemoLength {
+		memo = memo[:fingerprintMemoLength]
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%.2f\x00%s\x00%s",
+		t.Date.Format(isoDate), roundCents(t.Amount), normalizeFingerprintText(t.Payee), string(memo))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeFingerprintText returns s in lower case, normalized to NFC and with
+// whitespace collapsed to single spaces
+func normalizeFingerprintText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFC.String(s))), " ")
+}
+
+// transaction converts the record into an exported Transaction.
+// The date of all records is formatted as ISO 8601, so parsing does not fail.
+func (r homebankRecord) transaction() Transaction {
+	date, _ := time.Parse(isoDate, r.date)
+	var tags []string
+	if r.tags != "" {
+		tags = strings.Fields(r.tags)
+	}
+	return Transaction{
+		Date:     date,
+		Payment:  int(r.payment),
+		Info:     r.info,
+		Payee:    r.payee,
+		Memo:     r.memo,
+		Amount:   roundCents(r.amount),
+		Category: r.category,
+		Tags:     tags,
+	}
+}
+
+// homebankRecord converts the Transaction into the record written to the CSV file
+func (t Transaction) homebankRecord() homebankRecord {
+	return homebankRecord{
+		date:     t.Date.Format(isoDate),
+		payment:  int8(t.Payment),
+		info:     t.Info,
+		payee:    t.Payee,
+		memo:     t.Memo,
+		amount:   t.Amount,
+		category: t.Category,
+		tags:     strings.Join(t.Tags, " "),
+	}
+}
+
+// toTransactions converts the records into exported Transactions
+func toTransactions(records []homebankRecord) []Transaction {
+	transactions := make([]Transaction, 0, len(records))
+	for _, r := range records {
+		transactions = append(transactions, r.transaction())
+	}
+	return transactions
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransactionHomebankRecord(t *testing.T) {
+	r := homebankRecord{
+		date:     "2023-10-02",
+		payment:  6,
+		info:     "Info",
+		payee:    "Payee",
+		memo:     "Memo",
+		amount:   -12.34,
+		category: "Lebensmittel:Supermarkt",
+		tags:     "food weekly",
+	}
+	tr := r.transaction()
+	expected := Transaction{
+		Date:     time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		Payment:  6,
+		Info:     "Info",
+		Payee:    "Payee",
+		Memo:     "Memo",
+		Amount:   -12.34,
+		Category: "Lebensmittel:Supermarkt",
+		Tags:     []string{"food", "weekly"},
+	}
+	if !reflect.DeepEqual(tr, expected) {
+		t.Errorf("Expected %v, got %v", expected, tr)
+	}
+	if tr.homebankRecord() != r {
+		t.Errorf("Expected %v, got %v", r, tr.homebankRecord())
+	}
+
+	r.tags = ""
+	if tags := r.transaction().Tags; tags != nil {
+		t.Errorf("Expected no tags, got %v", tags)
+	}
+}
+
+// TestGetEntriesRoundTrip checks for each format that the entries are written to the
+// homebank CSV file without loss by reading the written file back in
+func TestGetEntriesRoundTrip(t *testing.T) {
+	testfiles := map[SourceFormat]string{
+		MoneyWallet:         filepath.Join("moneywallet", "MoneyWallet_export_1.csv"),
+		Barclaycard:         filepath.Join("barclaycard", "Umsaetze.xlsx"),
+		Volksbank:           filepath.Join("volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv"),
+		Comdirect:           filepath.Join("comdirect", "umsaetze_mixed.csv"),
+		DKB:                 filepath.Join("dkb", "dkb.csv"),
+		PayPal:              filepath.Join("paypal", "Download.CSV"),
+		Wise:                filepath.Join("wise", "statement_12345678_EUR_2024-05-01_2024-05-31.csv"),
+		DKBVisa:             filepath.Join("dkbvisa", "dkbvisa.csv"),
+		DKBLegacy:           filepath.Join("dkblegacy", "dkblegacy.csv"),
+		MT940:               filepath.Join("mt940", "statement.sta"),
+		OFX:                 filepath.Join("ofx", "statement.ofx"),
+		YNAB:                filepath.Join("ynab", "register.csv"),
+		Homebank:            filepath.Join("homebank", "homebank_malformed.csv"),
+		Amex:                filepath.Join("amex", "Umsaetze.csv"),
+		Consorsbank:         filepath.Join("consorsbank", "Umsaetze.csv"),
+		DeutscheBank:        filepath.Join("deutschebank", "Kontoumsaetze.csv"),
+		TradeRepublic:       filepath.Join("traderepublic", "transaktionen.csv"),
+		Bunq:                filepath.Join("bunq", "bunq.csv"),
+		Monzo:               filepath.Join("monzo", "MonzoDataExport.csv"),
+		VolksbankMastercard: filepath.Join("volksbankmastercard", "Kreditkartenumsaetze.csv"),
+		Klarna:              filepath.Join("klarna", "klarna.csv"),
+		MilesAndMore:        filepath.Join("milesandmore", "Umsaetze.xlsx"),
+		Sparda:              filepath.Join("sparda", "Umsaetze.csv"),
+		GnuCash:             filepath.Join("gnucash", "transactions.csv"),
+		FireflyIII:          filepath.Join("fireflyiii", "export.csv"),
+		Santander:           filepath.Join("santander", "Umsaetze.csv"),
+		ComdirectDepot:      filepath.Join("comdirectdepot", "depotumsaetze.csv"),
+		Outbank:             filepath.Join("outbank", "outbank.csv"),
+	}
+	for _, format := range GetSourceFormats() {
+		testfile, ok := testfiles[format]
+		if !ok {
+			t.Errorf("%s: no testfile", format)
+			continue
+		}
+		p := GetParser(format)
+		if err := p.ParseFile(filepath.Join("testfiles", testfile)); err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		entries := p.GetEntries()
+		if len(entries) == 0 {
+			t.Errorf("%s: expected entries", format)
+		}
+
+		var buf bytes.Buffer
+		if err := p.WriteHomebank(&buf); err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		h := &homebankParser{}
+		if err := h.Parse(&buf); err != nil {
+			t.Errorf("%s: written file can't be parsed: %v", format, err)
+			continue
+		}
+		if !reflect.DeepEqual(h.GetEntries(), entries) {
+			t.Errorf("%s: entries differ after round trip.\nExpected: %v\nGot:      %v", format, entries, h.GetEntries())
+		}
+
+		// Separators, quotes and newlines in text fields must not break the written file
+		for i := range entries {
+			entries[i].Info += "; \"quoted\""
+			entries[i].Payee += "\nsecond line"
+			entries[i].Memo = "Miete Jan; Feb\n" + entries[i].Memo
+		}
+		buf.Reset()
+		if err := WriteHomebankCSV(&buf, entries); err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if err := h.Parse(&buf); err != nil {
+			t.Errorf("%s: written file with special characters can't be parsed: %v", format, err)
+			continue
+		}
+		if !reflect.DeepEqual(h.GetEntries(), entries) {
+			t.Errorf("%s: entries with special characters differ after round trip.\nExpected: %v\nGot:      %v", format, entries, h.GetEntries())
+		}
+	}
+}
+
+func TestTransactionFingerprint(t *testing.T) {
+	base := Transaction{
+		Date:     time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		Payment:  6,
+		Info:     "Info",
+		Payee:    "Umlaute äöß",
+		Memo:     "Verwendungszweck xyz 2023-10-02T12:34:56 Ref. 1234567890",
+		Amount:   -12.34,
+		Category: "Lebensmittel",
+	}
+	fingerprint := base.Fingerprint()
+
+	same := []func(*Transaction){
+		func(tr *Transaction) { tr.Payment = 0 },
+		func(tr *Transaction) { tr.Info = "" },
+		func(tr *Transaction) { tr.Category = "Import:Lebensmittel" },
+		func(tr *Transaction) { tr.Tags = []string{"tag"} },
+		func(tr *Transaction) { tr.Payee = "  UMLAUTE   ÄÖß " },
+		func(tr *Transaction) { tr.Payee = "Umlaute äöß" }, // decomposed umlauts
+		func(tr *Transaction) { tr.Memo = "Verwendungszweck xyz 2023-10-02T99:99:99" },
+		func(tr *Transaction) { tr.Amount = -12.341 },
+	}
+	for i, modify := range same {
+		tr := base
+		modify(&tr)
+		if tr.Fingerprint() != fingerprint {
+			t.Errorf("Case %d: expected same fingerprint for %+v", i, tr)
+		}
+	}
+
+	different := []func(*Transaction){
+		func(tr *Transaction) { tr.Date = tr.Date.AddDate(0, 0, 1) },
+		func(tr *Transaction) { tr.Amount = 12.34 },
+		func(tr *Transaction) { tr.Payee = "Someone else" },
+		func(tr *Transaction) { tr.Memo = "Verwendungszweck abc" },
+	}
+	for i, modify := range different {
+		tr := base
+		modify(&tr)
+		if tr.Fingerprint() == fingerprint {
+			t.Errorf("Case %d: expected different fingerprint for %+v", i, tr)
+		}
+	}
+}
@@ -1,8 +1,19 @@
 package parser
 
+/*
+
+Parsing rules:
+
+- The CSV is the giro account export of a German Volksbank, semicolon separated
+- Two header revisions are accepted. Older exports contain "Gekennzeichneter Umsatz",
+  newer ones "Kategorie" and "Steuerrelevant" instead. The columns used for the
+  conversion are the same in both revisions
+- Homebanks "category" is "Kategorie" if the column is present
+- Homebanks "payment" is derived from "Buchungstext", see volksbankPaymentTypes
+*/
+
 import (
-	"encoding/csv"
-	"os"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
@@ -14,25 +25,30 @@ type volksbankRecord struct {
 	buchungstag             time.Time
 	verwendungszweck        string
 	nameZahlungsbeteiligter string
+	buchungstext            string
 	betrag                  float64
+	kategorie               string
 }
 
 type volksbankParser struct {
+	converter
 	entries []volksbankRecord
 }
 
 func (m *volksbankParser) ParseFile(filepath string) error {
 	m.entries = make([]volksbankRecord, 0)
-	infile, err := os.Open(filepath)
-	if err != nil {
-		return &ParserError{ErrorType: IOError}
-	}
-	defer infile.Close()
-	csvReader := csv.NewReader(infile)
+	return parseFile(filepath, m.Parse)
+}
+
+func (m *volksbankParser) Parse(in io.Reader) error {
+	m.entries = make([]volksbankRecord, 0)
+	m.rowErrors = nil
+	csvReader := m.newCSVReader(in)
 	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1 // Short rows are reported by checkFieldCount
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		return &ParserError{ErrorType: IOError}
+		return err
 	}
 	if len(records) == 0 {
 		return &ParserError{ErrorType: HeaderError}
@@ -45,42 +61,72 @@ func (m *volksbankParser) ParseFile(filepath string) error {
 		}
 	}
 
+	// Only newer exports contain a category
+	kategorieColumn := -1
+	if columns, ok := getColumns(records[0], []string{"Kategorie"}); ok {
+		kategorieColumn = columns["Kategorie"]
+	}
+
 	// Only header found, no entries
 	if len(records) == 1 {
 		return nil
 	}
 
-	for lineNr, row := range records[1:] {
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		if err := checkFieldCount(row, len(records[0]), lineNr); err != nil {
+			if m.skipRowError(err) {
+				continue
+			}
+			return err
+		}
 		date, err := time.Parse("02.01.2006", row[4])
 		if err != nil {
+			if m.skipRow(lineNr, "Buchungstag", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNr + 2,
+				Line:      lineNr,
 				Field:     "Buchungstag",
+				Err:       err,
 			}
 		}
 		betragString := strings.Replace(row[11], ",", ".", -1)
 		var betrag float64
 		betrag, err = strconv.ParseFloat(betragString, 64)
 		if err != nil {
+			if m.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
 			return &ParserError{
 				ErrorType: DataParsingError,
-				Line:      lineNr + 2,
+				Line:      lineNr,
 				Field:     "Betrag",
+				Err:       err,
 			}
 		}
 		vRecord := volksbankRecord{
 			buchungstag:             date,
 			verwendungszweck:        row[10],
 			nameZahlungsbeteiligter: row[6],
+			buchungstext:            row[9],
 			betrag:                  betrag,
 		}
+		if kategorieColumn >= 0 {
+			vRecord.kategorie = row[kategorieColumn]
+		}
 		m.entries = append(m.entries, vRecord)
 	}
 
 	return nil
 }
 
+func (m *volksbankParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, m.Parse)
+}
+
 func (m *volksbankParser) GetFormat() SourceFormat {
 	return Volksbank
 }
@@ -90,53 +136,82 @@ func (m *volksbankParser) GetNumberOfEntries() int {
 }
 
 func (v *volksbankParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, v.WriteHomebank)
+}
+
+func (v *volksbankParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, v.GetEntries(), WithOutputOptions(v.outputOptions))
+}
+
+func (v *volksbankParser) GetEntries() []Transaction {
 	hRecords := make([]homebankRecord, 0, len(v.entries))
+	paymentTypes := v.paymentTypes(volksbankPaymentTypes)
 	for _, mRecord := range v.entries {
-		hRecord := mRecord.convertRecord()
+		hRecord := mRecord.convertRecord(paymentTypes)
 		hRecords = append(hRecords, hRecord)
 	}
+	return toTransactions(v.processRecords(hRecords, v.GetFormat()))
+}
 
-	err := writeHomeBankRecords(hRecords, filepath)
-	if err != nil {
-		return err
-	}
+// volksbankHeader is the header of current exports
+var volksbankHeader = []string{
+	"Bezeichnung Auftragskonto",
+	"IBAN Auftragskonto",
+	"BIC Auftragskonto",
+	"Bankname Auftragskonto",
+	"Buchungstag",
+	"Valutadatum",
+	"Name Zahlungsbeteiligter",
+	"IBAN Zahlungsbeteiligter",
+	"BIC (SWIFT-Code) Zahlungsbeteiligter",
+	"Buchungstext",
+	"Verwendungszweck",
+	"Betrag",
+	"Waehrung",
+	"Saldo nach Buchung",
+	"Bemerkung",
+	"Kategorie",
+	"Steuerrelevant",
+	"Glaeubiger ID",
+	"Mandatsreferenz",
+}
 
-	return nil
+// volksbankHeaderLegacy is the header of older exports without category
+var volksbankHeaderLegacy = []string{
+	"Bezeichnung Auftragskonto",
+	"IBAN Auftragskonto",
+	"BIC Auftragskonto",
+	"Bankname Auftragskonto",
+	"Buchungstag",
+	"Valutadatum",
+	"Name Zahlungsbeteiligter",
+	"IBAN Zahlungsbeteiligter",
+	"BIC (SWIFT-Code) Zahlungsbeteiligter",
+	"Buchungstext",
+	"Verwendungszweck",
+	"Betrag",
+	"Waehrung",
+	"Saldo nach Buchung",
+	"Bemerkung",
+	"Gekennzeichneter Umsatz",
+	"Glaeubiger ID",
+	"Mandatsreferenz",
 }
 
 func isValidVolksbankHeader(record []string) bool {
-	expected := []string{
-		"Bezeichnung Auftragskonto",
-		"IBAN Auftragskonto",
-		"BIC Auftragskonto",
-		"Bankname Auftragskonto",
-		"Buchungstag",
-		"Valutadatum",
-		"Name Zahlungsbeteiligter",
-		"IBAN Zahlungsbeteiligter",
-		"BIC (SWIFT-Code) Zahlungsbeteiligter",
-		"Buchungstext",
-		"Verwendungszweck",
-		"Betrag",
-		"Waehrung",
-		"Saldo nach Buchung",
-		"Bemerkung",
-		"Kategorie",
-		"Steuerrelevant",
-		"Glaeubiger ID",
-		"Mandatsreferenz",
-	}
-	return reflect.DeepEqual(record, expected)
+	return reflect.DeepEqual(record, volksbankHeader) || reflect.DeepEqual(record, volksbankHeaderLegacy)
 }
 
-// convertRecord converts a single record from volksbank to homebank format
-func (v *volksbankRecord) convertRecord() (record homebankRecord) {
+// convertRecord converts a single record from volksbank to homebank format.
+// The payment is looked up by "Buchungstext" in paymentTypes.
+func (v *volksbankRecord) convertRecord(paymentTypes PaymentTypes) (record homebankRecord) {
 	var result homebankRecord
-	result.payment = 0
+	result.payment = paymentTypes.lookup(v.buchungstext)
 	result.memo = v.verwendungszweck
 	result.date = v.buchungstag.Format("2006-01-02")
 	result.amount = v.betrag
 	result.payee = v.nameZahlungsbeteiligter
+	result.category = v.kategorie
 
 	return result
 }
@@ -123,8 +123,9 @@ func TestVolksbankConvertRecord(t *testing.T) {
 		verwendungszweck:        "My Verwendungs-Zweck",
 		nameZahlungsbeteiligter: "Name Zahlungsbeteiligter",
 		betrag:                  200.123,
+		kategorie:               "Lebensmittel",
 	}
-	h := v.convertRecord()
+	h := v.convertRecord(volksbankPaymentTypes.merge(nil))
 	if h.amount != v.betrag {
 		t.Error("Amount does not match")
 	}
@@ -143,7 +144,7 @@ func TestVolksbankConvertRecord(t *testing.T) {
 	if h.memo != v.verwendungszweck {
 		t.Error("Memo does not match")
 	}
-	if h.category != "" {
+	if h.category != v.kategorie {
 		t.Error("Category does not match")
 	}
 	if h.tags != "" {
@@ -161,25 +162,44 @@ func TestVolksbankParseFileOk(t *testing.T) {
 }
 
 func TestVolksbankConvertToHomebank(t *testing.T) {
-	fpath := filepath.Join("testfiles", "volksbank", "Umsaetze_DE12345678901234567890_2023.10.04.csv")
-	v := &volksbankParser{}
-	err := v.ParseFile(fpath)
-	if err != nil {
-		t.Error(err)
-	}
+	testCases := []struct {
+		input    string
+		expected string
+		output   OutputOptions
+	}{
+		{"Umsaetze_DE12345678901234567890_2023.10.04.csv", "homebank.csv", OutputOptions{}},
+		// Older header with "Gekennzeichneter Umsatz" instead of "Kategorie" and "Steuerrelevant"
+		{"Umsaetze_DE12345678901234567890_2023.04.03.csv", "homebank_legacy.csv", OutputOptions{}},
+		// Same content as the first file, with UTF-8 BOM and encoded as UTF-16 BE
+		{"Umsaetze_bom.csv", "homebank.csv", OutputOptions{}},
+		{"Umsaetze_utf16be.csv", "homebank.csv", OutputOptions{}},
+		// Output for a homebank with german locale
+		{"Umsaetze_DE12345678901234567890_2023.10.04.csv", "homebank_comma_tab.csv",
+			OutputOptions{Delimiter: '\t', DecimalSeparator: ','}},
+		{"Umsaetze_DE12345678901234567890_2023.10.04.csv", "homebank_dmy_noheader.csv",
+			OutputOptions{DecimalSeparator: ',', NoHeader: true, DateFormat: "02.01.2006"}},
+	}
+	for _, tc := range testCases {
+		v := &volksbankParser{}
+		err := v.ParseFile(filepath.Join("testfiles", "volksbank", tc.input))
+		if err != nil {
+			t.Error(err)
+		}
+		v.SetOutputOptions(tc.output)
 
-	tmpDir := t.TempDir()
-	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+		tmpDir := t.TempDir()
+		tmpFilepath := filepath.Join(tmpDir, "output.csv")
 
-	err = v.ConvertToHomebank(tmpFilepath)
-	if err != nil {
-		t.Error(err)
-	}
+		err = v.ConvertToHomebank(tmpFilepath)
+		if err != nil {
+			t.Error(err)
+		}
 
-	expected := filepath.Join("testfiles", "volksbank", "homebank.csv")
+		expected := filepath.Join("testfiles", "volksbank", tc.expected)
 
-	if !areFilesEqual(expected, tmpFilepath) {
-		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
 	}
 }
 
@@ -207,6 +227,27 @@ func TestIsValidVolksbankHeader(t *testing.T) {
 		"Mandatsreferenz",
 	}
 
+	headerLegacyOk := []string{
+		"Bezeichnung Auftragskonto",
+		"IBAN Auftragskonto",
+		"BIC Auftragskonto",
+		"Bankname Auftragskonto",
+		"Buchungstag",
+		"Valutadatum",
+		"Name Zahlungsbeteiligter",
+		"IBAN Zahlungsbeteiligter",
+		"BIC (SWIFT-Code) Zahlungsbeteiligter",
+		"Buchungstext",
+		"Verwendungszweck",
+		"Betrag",
+		"Waehrung",
+		"Saldo nach Buchung",
+		"Bemerkung",
+		"Gekennzeichneter Umsatz",
+		"Glaeubiger ID",
+		"Mandatsreferenz",
+	}
+
 	headerNok := []string{
 		"Bezeichnung Auftragskonto xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
 		"IBAN Auftragskonto",
@@ -241,6 +282,9 @@ func TestIsValidVolksbankHeader(t *testing.T) {
 	if !isValidVolksbankHeader(headerOk) {
 		t.Error("Header should be OK")
 	}
+	if !isValidVolksbankHeader(headerLegacyOk) {
+		t.Error("Legacy header should be OK")
+	}
 	if isValidVolksbankHeader(headerNok) {
 		t.Error("Header should be NOK")
 	}
@@ -0,0 +1,151 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the export of the VR-Bank credit card portal (Mastercard), semicolon separated
+- It differs from the Volksbank giro account export, so both can be detected side by side
+- Homebanks "date" is the date part of "Umsatzzeitpunkt" in the format dd.mm.yyyy,
+  an optional time like "14:23" is ignored
+- Homebanks "amount" is "Betrag" in German notation
+- Homebanks "payee" is "Zahlungsempfänger", "info" is "Umsatzart"
+- All records get the payment "credit card"
+*/
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Single record of the VR-Bank credit card data
+type volksbankMastercardRecord struct {
+	umsatzzeitpunkt    time.Time
+	zahlungsempfaenger string
+	betrag             float64
+	umsatzart          string
+}
+
+type volksbankMastercardParser struct {
+	converter
+	entries []volksbankMastercardRecord
+}
+
+func (p *volksbankMastercardParser) ParseFile(filepath string) error {
+	p.entries = make([]volksbankMastercardRecord, 0)
+	return parseFile(filepath, p.Parse)
+}
+
+func (p *volksbankMastercardParser) Parse(in io.Reader) error {
+	p.entries = make([]volksbankMastercardRecord, 0)
+	p.rowErrors = nil
+	csvReader := p.newCSVReader(in)
+	csvReader.Comma = ';'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	if !isValidVolksbankMastercardHeader(records[0]) {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]volksbankMastercardRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("02.01.2006", strings.SplitN(strings.TrimSpace(row[0]), " ", 2)[0])
+		if err != nil {
+			if p.skipRow(lineNr, "Umsatzzeitpunkt", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Umsatzzeitpunkt",
+				Err:       err,
+			}
+		}
+		betrag, err := parseGermanAmount(row[3])
+		if err != nil {
+			if p.skipRow(lineNr, "Betrag", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Betrag",
+				Err:       err,
+			}
+		}
+		entries = append(entries, volksbankMastercardRecord{
+			umsatzzeitpunkt:    date,
+			zahlungsempfaenger: row[2],
+			betrag:             betrag,
+			umsatzart:          row[5],
+		})
+	}
+
+	p.entries = entries
+	return nil
+}
+
+func (p *volksbankMastercardParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, p.Parse)
+}
+
+func (p *volksbankMastercardParser) GetFormat() SourceFormat {
+	return VolksbankMastercard
+}
+
+func (p *volksbankMastercardParser) GetNumberOfEntries() int {
+	return len(p.entries)
+}
+
+func (p *volksbankMastercardParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, p.WriteHomebank)
+}
+
+func (p *volksbankMastercardParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, p.GetEntries(), WithOutputOptions(p.outputOptions))
+}
+
+func (p *volksbankMastercardParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(p.entries))
+	for _, vRecord := range p.entries {
+		hRecord := vRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(p.processRecords(hRecords, p.GetFormat()))
+}
+
+func isValidVolksbankMastercardHeader(record []string) bool {
+	expected := []string{
+		"Umsatzzeitpunkt",
+		"Buchungsdatum",
+		"Zahlungsempfänger",
+		"Betrag",
+		"Währung",
+		"Umsatzart",
+		"Karte",
+	}
+	return reflect.DeepEqual(record, expected)
+}
+
+// convertRecord converts a single record from the VR-Bank credit card to homebank format
+func (v *volksbankMastercardRecord) convertRecord() (h homebankRecord) {
+	h.payment = 1 // Credit card
+	h.date = v.umsatzzeitpunkt.Format("2006-01-02")
+	h.amount = v.betrag
+	h.payee = v.zahlungsempfaenger
+	h.info = v.umsatzart
+	return
+}
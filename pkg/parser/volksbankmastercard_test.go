@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVolksbankMastercardName(t *testing.T) {
+	p := &volksbankMastercardParser{}
+	if p.GetFormat() != VolksbankMastercard {
+		t.Error("Wrong format")
+	}
+}
+
+func TestVolksbankMastercardParseFileNonExisting(t *testing.T) {
+	p := &volksbankMastercardParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestVolksbankMastercardParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbankmastercard", "Kreditkartenumsaetze_nok_noheader.csv")
+	p := &volksbankMastercardParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestVolksbankMastercardParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"Kreditkartenumsaetze_nok_wrongumsatzzeitpunkt.csv", 3, "Umsatzzeitpunkt"},
+		{"Kreditkartenumsaetze_nok_wrongbetrag.csv", 4, "Betrag"},
+	}
+	for _, tc := range testCases {
+		p := &volksbankMastercardParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "volksbankmastercard", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestVolksbankMastercardParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbankmastercard", "Kreditkartenumsaetze_onlyheader.csv")
+	p := &volksbankMastercardParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestVolksbankMastercardParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "volksbankmastercard", "Kreditkartenumsaetze.csv")
+	p := &volksbankMastercardParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if p.GetNumberOfEntries() != 3 {
+		t.Errorf("Expected 3 entries, got %d", p.GetNumberOfEntries())
+	}
+}
+
+func TestVolksbankMastercardConvertRecord(t *testing.T) {
+	v := volksbankMastercardRecord{
+		umsatzzeitpunkt:    time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		zahlungsempfaenger: "REWE Markt GmbH",
+		betrag:             -23.45,
+		umsatzart:          "Kartenzahlung",
+	}
+	h := v.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 1 {
+		t.Errorf("Expected payment to be 1, got %d", h.payment)
+	}
+	if h.amount != v.betrag {
+		t.Errorf("Expected amount to be %f, got %f", v.betrag, h.amount)
+	}
+	if h.payee != v.zahlungsempfaenger {
+		t.Errorf("Expected payee to be '%s', got '%s'", v.zahlungsempfaenger, h.payee)
+	}
+	if h.info != v.umsatzart {
+		t.Errorf("Expected info to be '%s', got '%s'", v.umsatzart, h.info)
+	}
+}
+
+func TestVolksbankMastercardConvertToHomebank(t *testing.T) {
+	p := &volksbankMastercardParser{}
+	if err := p.ParseFile(filepath.Join("testfiles", "volksbankmastercard", "Kreditkartenumsaetze.csv")); err != nil {
+		t.Error(err)
+	}
+	tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+	if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "volksbankmastercard", "homebank.csv")
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
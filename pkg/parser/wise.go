@@ -0,0 +1,173 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the statement export of Wise (formerly TransferWise), comma separated
+- The columns are looked up by their name in the header as newer exports append further columns
+- Homebanks "date" is Wise "Date" in the format dd-mm-yyyy, "amount" is "Amount" with a dot as decimal separator
+- Homebanks "payee" is "Merchant" if set. Otherwise it is "Payee Name" for outgoing and
+  "Payer Name" for incoming transactions
+- Homebanks "memo" is "Payment Reference", "info" is "Description"
+- Transactions in other currencies than EUR are converted as well, but tagged with their currency
+*/
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of wise data
+type wiseRecord struct {
+	date             time.Time
+	amount           float64
+	currency         string
+	description      string
+	paymentReference string
+	payerName        string
+	payeeName        string
+	merchant         string
+}
+
+type wiseParser struct {
+	converter
+	entries []wiseRecord
+}
+
+// wiseColumns are the columns needed from the Wise CSV
+var wiseColumns = []string{
+	"TransferWise ID",
+	"Date",
+	"Amount",
+	"Currency",
+	"Description",
+	"Payment Reference",
+	"Payer Name",
+	"Payee Name",
+	"Merchant",
+}
+
+func (w *wiseParser) ParseFile(filepath string) error {
+	w.entries = make([]wiseRecord, 0)
+	return parseFile(filepath, w.Parse)
+}
+
+func (w *wiseParser) Parse(in io.Reader) error {
+	w.entries = make([]wiseRecord, 0)
+	w.rowErrors = nil
+	csvReader := w.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], wiseColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+
+	entries := make([]wiseRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse("02-01-2006", row[columns["Date"]])
+		if err != nil {
+			if w.skipRow(lineNr, "Date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Date",
+				Err:       err,
+			}
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[columns["Amount"]]), 64)
+		if err != nil {
+			if w.skipRow(lineNr, "Amount", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Amount",
+				Err:       err,
+			}
+		}
+		entries = append(entries, wiseRecord{
+			date:             date,
+			amount:           amount,
+			currency:         row[columns["Currency"]],
+			description:      row[columns["Description"]],
+			paymentReference: row[columns["Payment Reference"]],
+			payerName:        row[columns["Payer Name"]],
+			payeeName:        row[columns["Payee Name"]],
+			merchant:         row[columns["Merchant"]],
+		})
+	}
+
+	w.entries = entries
+	return nil
+}
+
+func (w *wiseParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, w.Parse)
+}
+
+func (w *wiseParser) GetFormat() SourceFormat {
+	return Wise
+}
+
+func (w *wiseParser) GetNumberOfEntries() int {
+	return len(w.entries)
+}
+
+func (w *wiseParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, w.WriteHomebank)
+}
+
+func (w *wiseParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, w.GetEntries(), WithOutputOptions(w.outputOptions))
+}
+
+func (w *wiseParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(w.entries))
+	for _, wRecord := range w.entries {
+		hRecord := wRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(w.processRecords(hRecords, w.GetFormat()))
+}
+
+// convertRecord converts a single record from wise to homebank format
+func (w *wiseRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = w.date.Format("2006-01-02")
+	h.amount = w.amount
+	h.info = w.description
+	h.memo = w.paymentReference
+
+	switch {
+	case w.merchant != "":
+		h.payee = w.merchant
+	case w.amount < 0:
+		h.payee = w.payeeName
+	default:
+		h.payee = w.payerName
+	}
+
+	// Tag foreign currency transactions to be able to filter them in Homebank
+	if w.currency != "EUR" {
+		h.tags = w.currency
+	}
+	return
+}
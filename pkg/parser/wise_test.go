@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWiseName(t *testing.T) {
+	w := &wiseParser{}
+	if w.GetFormat() != Wise {
+		t.Error("Wrong format")
+	}
+}
+
+func TestWiseParseFileNonExisting(t *testing.T) {
+	w := &wiseParser{}
+	err := w.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if w.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestWiseParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_nok_noheader.csv")
+	w := &wiseParser{}
+	err := w.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(w.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestWiseParseFileNokWrongDate(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_nok_wrongdate.csv")
+	w := &wiseParser{}
+	err := w.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Field != "Date" {
+			t.Errorf("Expected field 'Date', got '%s' instead", pError.Field)
+		}
+		if pError.Line != 2 {
+			t.Errorf("Expected line 2, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(w.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestWiseParseFileNokWrongAmount(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_nok_wrongamount.csv")
+	w := &wiseParser{}
+	err := w.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("DataParsingError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Field != "Amount" {
+			t.Errorf("Expected field 'Amount', got '%s' instead", pError.Field)
+		}
+		if pError.Line != 3 {
+			t.Errorf("Expected line 3, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(w.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestWiseParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_onlyheader.csv")
+	w := &wiseParser{}
+	err := w.ParseFile(fpath)
+	if err != nil {
+		t.Fatalf("Should not fail: %v", err)
+	}
+	if len(w.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestWiseParseFileOk(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_12345678_EUR_2024-05-01_2024-05-31.csv")
+	w := &wiseParser{}
+	if err := w.ParseFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	if w.GetNumberOfEntries() != 4 {
+		t.Errorf("Expected 4 entries, got %d", w.GetNumberOfEntries())
+	}
+}
+
+func TestWiseConvertRecord(t *testing.T) {
+	w := wiseRecord{
+		date:             time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC),
+		amount:           -500,
+		currency:         "EUR",
+		description:      "Sent money to Erika Musterfrau",
+		paymentReference: "Rent May 2024",
+		payerName:        "Max Mustermann",
+		payeeName:        "Erika Musterfrau",
+	}
+	h := w.convertRecord()
+	if h.date != "2024-05-03" {
+		t.Errorf("Expected date to be 2024-05-03, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.info != w.description {
+		t.Errorf("Expected info to be '%s', got '%s'", w.description, h.info)
+	}
+	if h.payee != w.payeeName {
+		t.Errorf("Expected payee to be '%s', got '%s'", w.payeeName, h.payee)
+	}
+	if h.memo != w.paymentReference {
+		t.Errorf("Expected memo to be '%s', got '%s'", w.paymentReference, h.memo)
+	}
+	if h.amount != w.amount {
+		t.Errorf("Expected amount to be %f, got %f", w.amount, h.amount)
+	}
+	if h.category != "" {
+		t.Errorf("Expected category to be empty, got '%s'", h.category)
+	}
+	if h.tags != "" {
+		t.Errorf("Expected tags to be empty, got '%s'", h.tags)
+	}
+
+	// Incoming money is paid by the payer
+	w.amount = 500
+	if h := w.convertRecord(); h.payee != w.payerName {
+		t.Errorf("Expected payee to be '%s', got '%s'", w.payerName, h.payee)
+	}
+
+	// Merchant takes precedence, foreign currencies are tagged
+	w.merchant = "Coffee Shop NYC"
+	w.currency = "USD"
+	h = w.convertRecord()
+	if h.payee != w.merchant {
+		t.Errorf("Expected payee to be '%s', got '%s'", w.merchant, h.payee)
+	}
+	if h.tags != "USD" {
+		t.Errorf("Expected tags to be 'USD', got '%s'", h.tags)
+	}
+}
+
+func TestWiseConvertToHomebank(t *testing.T) {
+	fpath := filepath.Join("testfiles", "wise", "statement_12345678_EUR_2024-05-01_2024-05-31.csv")
+	w := &wiseParser{}
+	err := w.ParseFile(fpath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tmpDir := t.TempDir()
+	tmpFilepath := filepath.Join(tmpDir, "output.csv")
+
+	err = w.ConvertToHomebank(tmpFilepath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := filepath.Join("testfiles", "wise", "homebank.csv")
+
+	if !areFilesEqual(expected, tmpFilepath) {
+		t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+	}
+}
@@ -0,0 +1,225 @@
+package parser
+
+/*
+
+Parsing rules:
+
+- The CSV is the register export of YNAB (You Need A Budget), comma separated
+- The date format depends on the locale of the budget. It is detected from the first
+  record and is one of dd.mm.yyyy, mm/dd/yyyy and yyyy-mm-dd
+- Homebanks "amount" is "Inflow" minus "Outflow". Both contain a currency symbol like
+  "€12.34" or "12,34€" which is stripped. The decimal separator is the last "." or ","
+- Homebanks "payee" is "Payee", "memo" is "Memo" and "category" is "Category"
+- A set "Flag" like "Red" is converted into a homebank tag
+*/
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Single record of YNAB register data
+type ynabRecord struct {
+	date     time.Time
+	amount   float64
+	flag     string
+	payee    string
+	category string
+	memo     string
+}
+
+type ynabParser struct {
+	converter
+	entries []ynabRecord
+}
+
+// ynabColumns are the columns needed from the YNAB CSV
+var ynabColumns = []string{
+	"Account",
+	"Flag",
+	"Date",
+	"Payee",
+	"Category Group/Category",
+	"Category Group",
+	"Category",
+	"Memo",
+	"Outflow",
+	"Inflow",
+	"Cleared",
+}
+
+// ynabDateLayouts maps the separator of the date to its layout
+var ynabDateLayouts = map[string]string{
+	".": "02.01.2006",
+	"/": "01/02/2006",
+	"-": "2006-01-02",
+}
+
+func (y *ynabParser) ParseFile(filepath string) error {
+	y.entries = make([]ynabRecord, 0)
+	return parseFile(filepath, y.Parse)
+}
+
+func (y *ynabParser) Parse(in io.Reader) error {
+	y.entries = make([]ynabRecord, 0)
+	y.rowErrors = nil
+	csvReader := y.newCSVReader(in)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return &ParserError{ErrorType: HeaderError}
+	}
+
+	columns, ok := getColumns(records[0], ynabColumns)
+	if !ok {
+		return &ParserError{
+			ErrorType: HeaderError,
+			Line:      1,
+		}
+	}
+	if len(records) == 1 {
+		return nil
+	}
+
+	dateLayout, err := getYnabDateLayout(records[1][columns["Date"]])
+	if err != nil {
+		return &ParserError{
+			ErrorType: DataParsingError,
+			Line:      csvReader.recordLine(1),
+			Field:     "Date",
+			Err:       err,
+		}
+	}
+
+	entries := make([]ynabRecord, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		row := records[i]
+		lineNr := csvReader.recordLine(i)
+		date, err := time.Parse(dateLayout, row[columns["Date"]])
+		if err != nil {
+			if y.skipRow(lineNr, "Date", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Date",
+				Err:       err,
+			}
+		}
+		outflow, err := parseYnabAmount(row[columns["Outflow"]])
+		if err != nil {
+			if y.skipRow(lineNr, "Outflow", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Outflow",
+				Err:       err,
+			}
+		}
+		inflow, err := parseYnabAmount(row[columns["Inflow"]])
+		if err != nil {
+			if y.skipRow(lineNr, "Inflow", err) {
+				continue
+			}
+			return &ParserError{
+				ErrorType: DataParsingError,
+				Line:      lineNr,
+				Field:     "Inflow",
+				Err:       err,
+			}
+		}
+		entries = append(entries, ynabRecord{
+			date:     date,
+			amount:   inflow - outflow,
+			flag:     row[columns["Flag"]],
+			payee:    row[columns["Payee"]],
+			category: row[columns["Category"]],
+			memo:     row[columns["Memo"]],
+		})
+	}
+
+	y.entries = entries
+	return nil
+}
+
+// getYnabDateLayout returns the date layout matching the given date
+func getYnabDateLayout(date string) (string, error) {
+	for separator, layout := range ynabDateLayouts {
+		if strings.Contains(date, separator) {
+			return layout, nil
+		}
+	}
+	return "", errors.New("unknown date format")
+}
+
+// parseYnabAmount parses an amount like "€1,234.56", "1.234,56€" or "$0.00".
+// The currency symbol is stripped, the last "." or "," is taken as decimal separator.
+// An empty amount is taken as 0.
+func parseYnabAmount(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	s = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+	if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+		s = strings.Replace(s, ".", "", -1)
+		s = strings.Replace(s, ",", ".", 1)
+	} else {
+		s = strings.Replace(s, ",", "", -1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func (y *ynabParser) SniffHeader(head []byte) bool {
+	return sniffHeader(head, y.Parse)
+}
+
+func (y *ynabParser) GetFormat() SourceFormat {
+	return YNAB
+}
+
+func (y *ynabParser) GetNumberOfEntries() int {
+	return len(y.entries)
+}
+
+func (y *ynabParser) ConvertToHomebank(filepath string) error {
+	return convertToFile(filepath, y.WriteHomebank)
+}
+
+func (y *ynabParser) WriteHomebank(out io.Writer) error {
+	return WriteHomebankCSV(out, y.GetEntries(), WithOutputOptions(y.outputOptions))
+}
+
+func (y *ynabParser) GetEntries() []Transaction {
+	hRecords := make([]homebankRecord, 0, len(y.entries))
+	for _, yRecord := range y.entries {
+		hRecord := yRecord.convertRecord()
+		hRecords = append(hRecords, hRecord)
+	}
+	return toTransactions(y.processRecords(hRecords, y.GetFormat()))
+}
+
+// convertRecord converts a single record from YNAB to homebank format
+func (y *ynabRecord) convertRecord() (h homebankRecord) {
+	h.payment = 0
+	h.date = y.date.Format("2006-01-02")
+	h.amount = y.amount
+	h.payee = y.payee
+	h.memo = y.memo
+	h.category = y.category
+	// Homebank separates tags by space, custom flag names may contain spaces
+	h.tags = strings.Join(strings.Fields(y.flag), "_")
+	return
+}
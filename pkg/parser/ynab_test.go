@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestYnabName(t *testing.T) {
+	p := &ynabParser{}
+	if p.GetFormat() != YNAB {
+		t.Error("Wrong format")
+	}
+}
+
+func TestYnabParseFileNonExisting(t *testing.T) {
+	p := &ynabParser{}
+	err := p.ParseFile("non_existing_file.csv")
+	if err == nil {
+		t.Error("Non existing file should return error")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != IOError {
+			t.Error("Expected IOError")
+		}
+	} else {
+		t.Error("Expected ParserError")
+	}
+	if p.GetNumberOfEntries() != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestYnabParseFileNokNoHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "ynab", "ynab_nok_noheader.csv")
+	p := &ynabParser{}
+	err := p.ParseFile(fpath)
+	if err == nil {
+		t.Error("Should fail")
+	}
+	var pError *ParserError
+	if errors.As(err, &pError) {
+		if pError.ErrorType != HeaderError {
+			t.Errorf("HeaderError expected, got '%s' instead", pError.ErrorType)
+		}
+		if pError.Line != 1 {
+			t.Errorf("Expected error on line 1, got %d", pError.Line)
+		}
+	} else {
+		t.Error("ParserError expected")
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestYnabParseFileNokData(t *testing.T) {
+	testCases := []struct {
+		file  string
+		line  int
+		field string
+	}{
+		{"ynab_nok_wrongdate.csv", 3, "Date"},
+		{"ynab_nok_wronginflow.csv", 3, "Inflow"},
+	}
+	for _, tc := range testCases {
+		p := &ynabParser{}
+		err := p.ParseFile(filepath.Join("testfiles", "ynab", tc.file))
+		var pError *ParserError
+		if !errors.As(err, &pError) {
+			t.Errorf("%s: ParserError expected, got %v", tc.file, err)
+			continue
+		}
+		if pError.ErrorType != DataParsingError {
+			t.Errorf("%s: DataParsingError expected, got '%s' instead", tc.file, pError.ErrorType)
+		}
+		if pError.Line != tc.line {
+			t.Errorf("%s: expected error on line %d, got %d", tc.file, tc.line, pError.Line)
+		}
+		if pError.Field != tc.field {
+			t.Errorf("%s: expected error on field '%s', got '%s'", tc.file, tc.field, pError.Field)
+		}
+		if len(p.entries) != 0 {
+			t.Errorf("%s: entries should be empty", tc.file)
+		}
+	}
+}
+
+func TestYnabParseFileOnlyHeader(t *testing.T) {
+	fpath := filepath.Join("testfiles", "ynab", "ynab_onlyheader.csv")
+	p := &ynabParser{}
+	if err := p.ParseFile(fpath); err != nil {
+		t.Errorf("Should not fail: %v", err)
+	}
+	if len(p.entries) != 0 {
+		t.Error("Entries should be empty")
+	}
+}
+
+func TestYnabParseFileOk(t *testing.T) {
+	testCases := []struct {
+		file    string
+		entries int
+	}{
+		{"register.csv", 3},
+		{"register_us.csv", 2},
+	}
+	for _, tc := range testCases {
+		p := &ynabParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "ynab", tc.file)); err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if p.GetNumberOfEntries() != tc.entries {
+			t.Errorf("%s: expected %d entries, got %d", tc.file, tc.entries, p.GetNumberOfEntries())
+		}
+	}
+}
+
+func TestGetYnabDateLayout(t *testing.T) {
+	testCases := map[string]string{
+		"02.10.2023": "02.01.2006",
+		"10/02/2023": "01/02/2006",
+		"2023-10-02": "2006-01-02",
+	}
+	for date, expected := range testCases {
+		layout, err := getYnabDateLayout(date)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", date, err)
+		}
+		if layout != expected {
+			t.Errorf("%s: expected layout '%s', got '%s'", date, expected, layout)
+		}
+	}
+
+	if _, err := getYnabDateLayout("20231002"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestParseYnabAmount(t *testing.T) {
+	testCases := []struct {
+		amount   string
+		expected float64
+	}{
+		{"€12.34", 12.34},
+		{"12,34€", 12.34},
+		{"$1,200.00", 1200},
+		{"2.500,00 €", 2500},
+		{"-€5.00", -5},
+		{"", 0},
+	}
+	for _, tc := range testCases {
+		amount, err := parseYnabAmount(tc.amount)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.amount, err)
+		}
+		if amount != tc.expected {
+			t.Errorf("%s: expected %f, got %f", tc.amount, tc.expected, amount)
+		}
+	}
+
+	for _, amount := range []string{"n/a", "1.2.3"} {
+		if _, err := parseYnabAmount(amount); err == nil {
+			t.Errorf("%s: expected error", amount)
+		}
+	}
+}
+
+func TestYnabConvertRecord(t *testing.T) {
+	r := ynabRecord{
+		date:     time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		amount:   -12.34,
+		flag:     "Red Reimburse",
+		payee:    "Bäckerei",
+		category: "Lebensmittel",
+		memo:     "Brötchen",
+	}
+	h := r.convertRecord()
+	if h.date != "2023-10-02" {
+		t.Errorf("Expected date to be 2023-10-02, got '%s'", h.date)
+	}
+	if h.payment != 0 {
+		t.Errorf("Expected payment to be 0, got %d", h.payment)
+	}
+	if h.amount != r.amount {
+		t.Errorf("Expected amount to be %f, got %f", r.amount, h.amount)
+	}
+	if h.payee != r.payee {
+		t.Errorf("Expected payee to be '%s', got '%s'", r.payee, h.payee)
+	}
+	if h.memo != r.memo {
+		t.Errorf("Expected memo to be '%s', got '%s'", r.memo, h.memo)
+	}
+	if h.category != r.category {
+		t.Errorf("Expected category to be '%s', got '%s'", r.category, h.category)
+	}
+	if h.tags != "Red_Reimburse" {
+		t.Errorf("Expected tags to be 'Red_Reimburse', got '%s'", h.tags)
+	}
+}
+
+func TestYnabConvertToHomebank(t *testing.T) {
+	testCases := []struct {
+		file     string
+		expected string
+	}{
+		{"register.csv", "homebank.csv"},
+		{"register_us.csv", "homebank_us.csv"},
+	}
+	for _, tc := range testCases {
+		p := &ynabParser{}
+		if err := p.ParseFile(filepath.Join("testfiles", "ynab", tc.file)); err != nil {
+			t.Error(err)
+		}
+
+		tmpFilepath := filepath.Join(t.TempDir(), "output.csv")
+		if err := p.ConvertToHomebank(tmpFilepath); err != nil {
+			t.Error(err)
+		}
+
+		expected := filepath.Join("testfiles", "ynab", tc.expected)
+		if !areFilesEqual(expected, tmpFilepath) {
+			t.Errorf("Files are not equal %s, %s", expected, tmpFilepath)
+		}
+	}
+}